@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -61,6 +63,11 @@ type Common interface {
 	LogList(context.Context) ([]string, error)
 	LogSetLevel(context.Context, string, string) error
 
+	// JournalQuery returns the last limit journal events of the given system
+	// and event, recorded between since and until, oldest first. Empty
+	// strings, zero times and a zero limit match all events.
+	JournalQuery(ctx context.Context, system, event string, since, until time.Time, limit int) ([]JournalEvent, error)
+
 	// GC handle the db gc
 	GC(context.Context) error
 
@@ -93,6 +100,14 @@ func (v APIVersion) String() string {
 	return fmt.Sprintf("%s+api%s", v.Version, v.APIVersion.String())
 }
 
+// JournalEvent is an event recorded by the journal of a node.
+type JournalEvent struct {
+	System    string
+	Event     string
+	Timestamp time.Time
+	Data      json.RawMessage
+}
+
 type NatInfo struct {
 	Reachability network.Reachability
 	PublicAddr   string
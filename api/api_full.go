@@ -33,6 +33,7 @@ import (
 
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/power"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	marketevents "github.com/EpiK-Protocol/go-epik/markets/loggers"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
@@ -140,6 +141,25 @@ type FullNode interface {
 	// If oldmsgskip is set, messages from before the requested roots are also not included.
 	ChainExport(ctx context.Context, nroots abi.ChainEpoch, oldmsgskip bool, tsk types.TipSetKey) (<-chan []byte, error)
 
+	// ChainExportRange returns a stream of bytes with CAR dump of chain data,
+	// like ChainExport. The header chain can be limited to an epoch range, and
+	// the exported state to the state of a set of actors.
+	ChainExportRange(ctx context.Context, tsk types.TipSetKey, cfg ChainExportConfig) (<-chan []byte, error)
+
+	// ChainSplitstoreStatus returns the state of the splitstore and of the
+	// compaction in progress, if any. It fails when the node doesn't run a
+	// splitstore.
+	ChainSplitstoreStatus(ctx context.Context) (*SplitstoreStatus, error)
+	// ChainSplitstoreCompact starts a splitstore compaction, regardless of the
+	// compaction threshold. When pruneCold is positive, coldstore objects which
+	// aren't reachable from the last pruneCold epochs are deleted afterwards.
+	ChainSplitstoreCompact(ctx context.Context, pruneCold abi.ChainEpoch) error
+	// ChainSplitstoreGC starts a garbage collection of the hotstore, moving if
+	// full is set
+	ChainSplitstoreGC(ctx context.Context, full bool) error
+	// ChainSplitstoreWarmup starts a warmup of the hotstore from the current head
+	ChainSplitstoreWarmup(ctx context.Context) error
+
 	// MethodGroup: Beacon
 	// The Beacon method group contains methods for interacting with the random beacon (DRAND)
 
@@ -199,6 +219,10 @@ type FullNode interface {
 	// SyncValidateTipset indicates whether the provided tipset is valid or not
 	SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (bool, error)
 
+	// SyncPeerScores returns the reputation of the peers the chain exchange
+	// client fetched chain data from, including banned peers.
+	SyncPeerScores(ctx context.Context) ([]SyncPeerScore, error)
+
 	// MethodGroup: Mpool
 	// The Mpool methods are for interacting with the message pool. The message pool
 	// manages all incoming and outgoing 'messages' going over the network.
@@ -245,6 +269,13 @@ type FullNode interface {
 	// MpoolSetConfig sets the mpool config to (a copy of) the supplied config
 	MpoolSetConfig(context.Context, *types.MpoolConfig) error
 
+	// MpoolExport returns all pending messages with the time they were
+	// received and whether they were pushed locally
+	MpoolExport(context.Context) ([]MpoolPendingMessage, error)
+	// MpoolImport adds the messages to the mpool after validating them as
+	// if they were received from the network
+	MpoolImport(context.Context, []MpoolPendingMessage) (*MpoolImportResult, error)
+
 	// MethodGroup: Miner
 
 	MinerGetBaseInfo(context.Context, address.Address, abi.ChainEpoch, types.TipSetKey) (*MiningBaseInfo, error)
@@ -399,6 +430,11 @@ type FullNode interface {
 	StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*ActorState, error)
 	// StateListMessages looks back and returns all messages with a matching to or from address, stopping at the given height.
 	StateListMessages(ctx context.Context, match *MessageMatch, tsk types.TipSetKey, toht abi.ChainEpoch) ([]cid.Cid, error)
+	// StateAddressHistory returns messages sent or received by the address,
+	// internal transfers to or from it and their receipts, newest first. The
+	// returned cursor can be passed to get the next page; an empty cursor
+	// starts from the newest entry. Requires the address index to be enabled.
+	StateAddressHistory(ctx context.Context, addr address.Address, cursor string, limit int) (*AddressHistory, error)
 	// StateDecodeParams attempts to decode the provided params, based on the recipient actor address and method number.
 	StateDecodeParams(ctx context.Context, toAddr address.Address, method abi.MethodNum, params []byte, tsk types.TipSetKey) (interface{}, error)
 
@@ -488,6 +524,14 @@ type FullNode interface {
 	// StateCompute is a flexible command that applies the given messages on the given tipset.
 	// The messages are run as though the VM were at the provided height.
 	StateCompute(context.Context, abi.ChainEpoch, []*types.Message, types.TipSetKey) (*ComputeStateOutput, error)
+	// StateSimulate applies synthetic messages on top of the given tipset, as
+	// StateCompute does, but without persisting the resulting state. Messages
+	// can be sent by governor, multisig or system actors when applied as
+	// implicit messages, and the upgrade schedule can be overridden to rehearse
+	// network upgrades. It returns the traces of the messages and the typed
+	// diff of the actors they touched, or of all actors when an upgrade runs.
+	// The height can be at most ChainFinality epochs after the tipset.
+	StateSimulate(context.Context, *SimulateSpec, types.TipSetKey) (*SimulateOutput, error)
 	/* // StateVerifierStatus returns the data cap for the given address.
 	// Returns nil if there is no entry in the data cap table for the
 	// address.
@@ -1018,6 +1062,20 @@ type SyncState struct {
 	VMApplied uint64
 }
 
+// SyncPeerScore is the reputation of a chain exchange peer
+type SyncPeerScore struct {
+	ID    peer.ID
+	Score float64
+
+	// Banned is set while the peer is banned, until BannedUntil
+	Banned      bool
+	BannedUntil time.Time
+	Bans        int
+
+	// Penalties counts the penalties of the peer by reason
+	Penalties map[string]int
+}
+
 type SyncStateStage int
 
 const (
@@ -1063,11 +1121,56 @@ type MpoolUpdate struct {
 	Message *types.SignedMessage
 }
 
+type MpoolPendingMessage struct {
+	Message  *types.SignedMessage
+	Received time.Time
+	Local    bool
+}
+
+type MpoolImportResult struct {
+	Added   int
+	Skipped int
+	// Errors of messages which couldn't be added, by message CID
+	Errors map[string]string
+}
+
 type ComputeStateOutput struct {
 	Root  cid.Cid
 	Trace []*InvocResult
 }
 
+type SimulateSpec struct {
+	// Height is the epoch the VM sees, the epoch after the tipset when zero.
+	// It can be at most ChainFinality epochs after the tipset.
+	Height abi.ChainEpoch
+	// Messages are applied in order
+	Messages []*types.Message
+	// Implicit applies the messages as implicit messages, skipping the nonce,
+	// gas and sender checks, so that any actor can send them
+	Implicit bool
+	// Upgrades override the upgrade schedule of the node for the epochs
+	// between the tipset and Height
+	Upgrades []UpgradeOverride
+}
+
+// UpgradeOverride moves the upgrade to Network to Height, or adds a network
+// version change without migration if the schedule has no such upgrade. A
+// negative height removes the upgrade.
+type UpgradeOverride struct {
+	Network network.Version
+	Height  abi.ChainEpoch
+}
+
+type SimulateOutput struct {
+	// Root is the simulated state root, it isn't persisted
+	Root  cid.Cid
+	Trace []*InvocResult
+	// Diff holds the changes of the actors touched by the messages, and of
+	// the reward and burnt funds actors. It holds the changes of all actors
+	// when the upgrade schedule is overridden or an upgrade runs.
+	Diff []*statediff.ActorDiff
+}
+
 /* type DealCollateralBounds struct {
 	Min abi.TokenAmount
 	Max abi.TokenAmount
@@ -1123,6 +1226,48 @@ type CommPRet struct {
 	Root cid.Cid
 	Size abi.UnpaddedPieceSize
 }
+
+// ChainExportConfig configures ChainExportRange
+type ChainExportConfig struct {
+	// From is the lowest epoch of the exported header chain, 0 exports back
+	// to genesis. Genesis state is only included when exporting to genesis.
+	From abi.ChainEpoch
+	// RecentRoots is the number of state roots to include, counting back
+	// from the exported tipset
+	RecentRoots abi.ChainEpoch
+	// SkipOldMsgs skips messages of tipsets outside of RecentRoots
+	SkipOldMsgs bool
+	// Actors limits the exported state trees to the state of these actors.
+	// The actors table is always exported in full, so that the actors can be
+	// looked up. Empty exports the entire state.
+	Actors []address.Address
+}
+
+// SplitstoreStatus is the state of the splitstore
+type SplitstoreStatus struct {
+	// BaseEpoch is the compaction boundary of the last compaction
+	BaseEpoch       abi.ChainEpoch
+	WarmupEpoch     abi.ChainEpoch
+	CompactionIndex int64
+	MarkSetSize     int64
+
+	// HotSize and ColdSize are the on-disk sizes in bytes, -1 if unknown
+	HotSize  int64
+	ColdSize int64
+
+	// Compacting is set while a compaction, prune, warmup, gc or check runs,
+	// Stage is the step it is at
+	Compacting bool
+	Stage      string
+	StageStart time.Time
+
+	// LastCompactionEpoch is the head at the last compaction since startup
+	LastCompactionEpoch abi.ChainEpoch
+	LastCompactionTime  time.Time
+	LastCompactionTook  time.Duration
+	LastError           string
+}
+
 type HeadChange struct {
 	Type string
 	Val  *types.TipSet
@@ -1170,6 +1315,45 @@ type MessageMatch struct {
 	From address.Address
 }
 
+const (
+	// AddressHistoryMessage entries are messages included on chain
+	AddressHistoryMessage = "message"
+	// AddressHistoryTransfer entries are value transfers made by actors
+	// while executing a message
+	AddressHistoryTransfer = "transfer"
+
+	AddressHistoryIn  = "in"
+	AddressHistoryOut = "out"
+)
+
+type AddressHistoryEntry struct {
+	// Message is the CID of the on-chain message the entry originates from.
+	// For transfers made by implicit messages (e.g. block rewards and cron)
+	// this is the CID of the implicit message.
+	Message cid.Cid
+	// TipSet is the tipset the message was included in
+	TipSet         types.TipSetKey
+	Height         abi.ChainEpoch
+	ExecutedTipSet types.TipSetKey
+
+	Type      string
+	Direction string
+
+	From   address.Address
+	To     address.Address
+	Value  abi.TokenAmount
+	Method abi.MethodNum
+
+	Receipt types.MessageReceipt
+	Error   string
+}
+
+type AddressHistory struct {
+	Entries []AddressHistoryEntry
+	// Cursor of the next page, empty when there are no more entries
+	Cursor string
+}
+
 type ExpertRegisterFileParams struct {
 	Expert    address.Address
 	RootID    cid.Cid
@@ -10,6 +10,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	datatransfer "github.com/filecoin-project/go-data-transfer"
 	"github.com/filecoin-project/go-fil-markets/piecestore"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
@@ -92,6 +93,8 @@ type StorageMiner interface {
 	// SealingSchedDiag dumps internal sealing scheduler state
 	SealingSchedDiag(ctx context.Context, doSched bool) (interface{}, error)
 	SealingAbort(ctx context.Context, call storiface.CallID) error
+	// SealingJobHistory returns records of finished sealing jobs matching the filter
+	SealingJobHistory(ctx context.Context, filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error)
 
 	stores.SectorIndex
 
@@ -104,6 +107,9 @@ type StorageMiner interface {
 	MarketGetAsk(ctx context.Context) (*storagemarket.SignedStorageAsk, error)
 	MarketSetRetrievalAsk(ctx context.Context, rask *retrievalmarket.Ask) error
 	MarketGetRetrievalAsk(ctx context.Context) (*retrievalmarket.Ask, error)
+	// MarketRetrievalQuote returns the retrieval terms the pricing policy of
+	// the miner gives for retrieving the payload, optionally for a specific client
+	MarketRetrievalQuote(ctx context.Context, payloadCid cid.Cid, piece *cid.Cid, client address.Address) (*retrievalmarket.Ask, error)
 	MarketListDataTransfers(ctx context.Context) ([]DataTransferChannel, error)
 	MarketDataTransferUpdates(ctx context.Context) (<-chan DataTransferChannel, error)
 	// MarketRestartDataTransfer attempts to restart a data transfer with the given transfer ID and other peer
@@ -140,6 +146,11 @@ type StorageMiner interface {
 	CreateBackup(ctx context.Context, fpath string) error
 
 	CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, expensive bool) (map[abi.SectorNumber]string, error)
+
+	// ProvingSimulate runs WindowPoSt for the next instance of the deadline
+	// against the current chain state with mock randomness, without
+	// submitting any messages
+	ProvingSimulate(ctx context.Context, deadline uint64) (*WdPoStSimulation, error)
 }
 
 type SealRes struct {
@@ -248,3 +259,52 @@ type PendingDealInfo struct {
 	PublishPeriodStart time.Time
 	PublishPeriod      time.Duration
 }
+
+// WdPoStSimulation is the report of a WindowPoSt dry run for a deadline
+type WdPoStSimulation struct {
+	Deadline     uint64
+	Open         abi.ChainEpoch
+	FaultCutoff  abi.ChainEpoch
+	CurrentEpoch abi.ChainEpoch
+
+	Partitions []WdPoStPartitionRisk
+	Messages   []WdPoStMessageSim
+
+	// ProveTime is the time it took to generate all proofs
+	ProveTime time.Duration
+	// MaxFee is the configured MaxWindowPoStGasFee
+	MaxFee abi.TokenAmount
+}
+
+// WdPoStPartitionRisk describes the state of a partition in a WindowPoSt dry run
+type WdPoStPartitionRisk struct {
+	Index      uint64
+	Live       uint64
+	Faulty     uint64
+	Recovering uint64
+
+	// Unprovable sectors are expected to be proven, but failed the
+	// provability check or were skipped while proving
+	Unprovable bitfield.BitField
+	// Recoverable sectors are faulty, but provable again
+	Recoverable bitfield.BitField
+}
+
+// WdPoStMessageSim describes a SubmitWindowedPoSt message which would be sent
+type WdPoStMessageSim struct {
+	Partitions []uint64
+	Skipped    uint64
+	ParamsSize int
+
+	// GasEstimated is set when the gas limit was estimated by executing the
+	// message with a real proof, otherwise it's a fixed limit per partition
+	GasEstimated bool
+	GasLimit     int64
+	GasFeeCap    abi.TokenAmount
+	// Fee is the maximum fee paid for the message, GasLimit * GasFeeCap
+	Fee abi.TokenAmount
+	// GasError is the reason gas couldn't be estimated, proofs of deadlines
+	// other than the currently open one are made with mock randomness and
+	// can't be executed
+	GasError string
+}
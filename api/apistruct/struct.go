@@ -77,6 +77,8 @@ type CommonStruct struct {
 		LogList     func(context.Context) ([]string, error)     `perm:"write"`
 		LogSetLevel func(context.Context, string, string) error `perm:"write"`
 
+		JournalQuery func(ctx context.Context, system, event string, since, until time.Time, limit int) ([]api.JournalEvent, error) `perm:"read"`
+
 		GC func(context.Context) error `perm:"admin"`
 
 		Shutdown func(context.Context) error                    `perm:"admin"`
@@ -112,6 +114,11 @@ type FullNodeStruct struct {
 		ChainGetMessage               func(context.Context, cid.Cid) (*types.Message, error)                                                             `perm:"read"`
 		ChainGetPath                  func(context.Context, types.TipSetKey, types.TipSetKey) ([]*api.HeadChange, error)                                 `perm:"read"`
 		ChainExport                   func(context.Context, abi.ChainEpoch, bool, types.TipSetKey) (<-chan []byte, error)                                `perm:"read"`
+		ChainExportRange              func(context.Context, types.TipSetKey, api.ChainExportConfig) (<-chan []byte, error)                               `perm:"read"`
+		ChainSplitstoreStatus         func(context.Context) (*api.SplitstoreStatus, error)                                                               `perm:"read"`
+		ChainSplitstoreCompact        func(context.Context, abi.ChainEpoch) error                                                                        `perm:"admin"`
+		ChainSplitstoreGC             func(context.Context, bool) error                                                                                  `perm:"admin"`
+		ChainSplitstoreWarmup         func(context.Context) error                                                                                        `perm:"admin"`
 
 		BeaconGetEntry func(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error) `perm:"read"`
 
@@ -129,9 +136,12 @@ type FullNodeStruct struct {
 		SyncUnmarkAllBad   func(ctx context.Context) error                              `perm:"admin"`
 		SyncCheckBad       func(ctx context.Context, bcid cid.Cid) (string, error)      `perm:"read"`
 		SyncValidateTipset func(ctx context.Context, tsk types.TipSetKey) (bool, error) `perm:"read"`
+		SyncPeerScores     func(ctx context.Context) ([]api.SyncPeerScore, error)       `perm:"read"`
 
-		MpoolGetConfig func(context.Context) (*types.MpoolConfig, error) `perm:"read"`
-		MpoolSetConfig func(context.Context, *types.MpoolConfig) error   `perm:"write"`
+		MpoolGetConfig func(context.Context) (*types.MpoolConfig, error)                                `perm:"read"`
+		MpoolExport    func(context.Context) ([]api.MpoolPendingMessage, error)                         `perm:"read"`
+		MpoolImport    func(context.Context, []api.MpoolPendingMessage) (*api.MpoolImportResult, error) `perm:"write"`
+		MpoolSetConfig func(context.Context, *types.MpoolConfig) error                                  `perm:"write"`
 
 		MpoolSelect func(context.Context, types.TipSetKey, float64) ([]*types.SignedMessage, error) `perm:"read"`
 
@@ -246,8 +256,10 @@ type FullNodeStruct struct {
 		StateGetReceipt           func(context.Context, cid.Cid, types.TipSetKey) (*types.MessageReceipt, error)                                  `perm:"read"`
 		StateMinerSectorCount     func(context.Context, address.Address, types.TipSetKey) (api.MinerSectors, error)                               `perm:"read"`
 		StateListMessages         func(ctx context.Context, match *api.MessageMatch, tsk types.TipSetKey, toht abi.ChainEpoch) ([]cid.Cid, error) `perm:"read"`
+		StateAddressHistory       func(ctx context.Context, addr address.Address, cursor string, limit int) (*api.AddressHistory, error)          `perm:"read"`
 		StateDecodeParams         func(context.Context, address.Address, abi.MethodNum, []byte, types.TipSetKey) (interface{}, error)             `perm:"read"`
 		StateCompute              func(context.Context, abi.ChainEpoch, []*types.Message, types.TipSetKey) (*api.ComputeStateOutput, error)       `perm:"read"`
+		StateSimulate             func(context.Context, *api.SimulateSpec, types.TipSetKey) (*api.SimulateOutput, error)                          `perm:"read"`
 		/* StateVerifierStatus                func(context.Context, address.Address, types.TipSetKey) (*abi.StoragePower, error)                                   `perm:"read"`
 		StateVerifiedClientStatus         func(context.Context, address.Address, types.TipSetKey) (*abi.StoragePower, error)                                   `perm:"read"`
 		StateVerifiedRegistryRootKey      func(ctx context.Context, tsk types.TipSetKey) (address.Address, error)                                              `perm:"read"`
@@ -359,6 +371,7 @@ type StorageMinerStruct struct {
 		MarketSetAsk              func(ctx context.Context, price types.BigInt, verifiedPrice types.BigInt, duration abi.ChainEpoch, minPieceSize abi.PaddedPieceSize, maxPieceSize abi.PaddedPieceSize) error `perm:"admin"`
 		MarketGetAsk              func(ctx context.Context) (*storagemarket.SignedStorageAsk, error)                                                                                                           `perm:"read"`
 		MarketSetRetrievalAsk     func(ctx context.Context, rask *retrievalmarket.Ask) error                                                                                                                   `perm:"admin"`
+		MarketRetrievalQuote      func(ctx context.Context, payloadCid cid.Cid, piece *cid.Cid, client address.Address) (*retrievalmarket.Ask, error)                                                          `perm:"read"`
 		MarketGetRetrievalAsk     func(ctx context.Context) (*retrievalmarket.Ask, error)                                                                                                                      `perm:"read"`
 		MarketListDataTransfers   func(ctx context.Context) ([]api.DataTransferChannel, error)                                                                                                                 `perm:"write"`
 		MarketDataTransferUpdates func(ctx context.Context) (<-chan api.DataTransferChannel, error)                                                                                                            `perm:"write"`
@@ -401,8 +414,9 @@ type StorageMinerStruct struct {
 		ReturnReadPiece       func(ctx context.Context, callID storiface.CallID, ok bool, err *storiface.CallError) error                   `perm:"admin" retry:"true"`
 		ReturnFetch           func(ctx context.Context, callID storiface.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
 
-		SealingSchedDiag func(context.Context, bool) (interface{}, error)       `perm:"admin"`
-		SealingAbort     func(ctx context.Context, call storiface.CallID) error `perm:"admin"`
+		SealingSchedDiag  func(context.Context, bool) (interface{}, error)                                                 `perm:"admin"`
+		SealingAbort      func(ctx context.Context, call storiface.CallID) error                                           `perm:"admin"`
+		SealingJobHistory func(ctx context.Context, filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error) `perm:"admin"`
 
 		StorageList          func(context.Context) (map[stores.ID][]stores.Decl, error)                                                                                   `perm:"admin"`
 		StorageLocal         func(context.Context) (map[stores.ID]string, error)                                                                                          `perm:"admin"`
@@ -440,6 +454,8 @@ type StorageMinerStruct struct {
 		CreateBackup func(ctx context.Context, fpath string) error `perm:"admin"`
 
 		CheckProvable func(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, expensive bool) (map[abi.SectorNumber]string, error) `perm:"admin"`
+
+		ProvingSimulate func(ctx context.Context, deadline uint64) (*api.WdPoStSimulation, error) `perm:"admin"`
 	}
 }
 
@@ -620,6 +636,10 @@ func (c *CommonStruct) LogSetLevel(ctx context.Context, group, level string) err
 	return c.Internal.LogSetLevel(ctx, group, level)
 }
 
+func (c *CommonStruct) JournalQuery(ctx context.Context, system, event string, since, until time.Time, limit int) ([]api.JournalEvent, error) {
+	return c.Internal.JournalQuery(ctx, system, event, since, until, limit)
+}
+
 func (c *CommonStruct) GC(ctx context.Context) error {
 	return c.Internal.GC(ctx)
 }
@@ -802,6 +822,14 @@ func (c *FullNodeStruct) MpoolSetConfig(ctx context.Context, cfg *types.MpoolCon
 	return c.Internal.MpoolSetConfig(ctx, cfg)
 }
 
+func (c *FullNodeStruct) MpoolExport(ctx context.Context) ([]api.MpoolPendingMessage, error) {
+	return c.Internal.MpoolExport(ctx)
+}
+
+func (c *FullNodeStruct) MpoolImport(ctx context.Context, msgs []api.MpoolPendingMessage) (*api.MpoolImportResult, error) {
+	return c.Internal.MpoolImport(ctx, msgs)
+}
+
 func (c *FullNodeStruct) MpoolSelect(ctx context.Context, tsk types.TipSetKey, tq float64) ([]*types.SignedMessage, error) {
 	return c.Internal.MpoolSelect(ctx, tsk, tq)
 }
@@ -994,6 +1022,26 @@ func (c *FullNodeStruct) ChainExport(ctx context.Context, nroots abi.ChainEpoch,
 	return c.Internal.ChainExport(ctx, nroots, iom, tsk)
 }
 
+func (c *FullNodeStruct) ChainExportRange(ctx context.Context, tsk types.TipSetKey, cfg api.ChainExportConfig) (<-chan []byte, error) {
+	return c.Internal.ChainExportRange(ctx, tsk, cfg)
+}
+
+func (c *FullNodeStruct) ChainSplitstoreStatus(ctx context.Context) (*api.SplitstoreStatus, error) {
+	return c.Internal.ChainSplitstoreStatus(ctx)
+}
+
+func (c *FullNodeStruct) ChainSplitstoreCompact(ctx context.Context, pruneCold abi.ChainEpoch) error {
+	return c.Internal.ChainSplitstoreCompact(ctx, pruneCold)
+}
+
+func (c *FullNodeStruct) ChainSplitstoreGC(ctx context.Context, full bool) error {
+	return c.Internal.ChainSplitstoreGC(ctx, full)
+}
+
+func (c *FullNodeStruct) ChainSplitstoreWarmup(ctx context.Context) error {
+	return c.Internal.ChainSplitstoreWarmup(ctx)
+}
+
 func (c *FullNodeStruct) BeaconGetEntry(ctx context.Context, epoch abi.ChainEpoch) (*types.BeaconEntry, error) {
 	return c.Internal.BeaconGetEntry(ctx, epoch)
 }
@@ -1034,6 +1082,10 @@ func (c *FullNodeStruct) SyncValidateTipset(ctx context.Context, tsk types.TipSe
 	return c.Internal.SyncValidateTipset(ctx, tsk)
 }
 
+func (c *FullNodeStruct) SyncPeerScores(ctx context.Context) ([]api.SyncPeerScore, error) {
+	return c.Internal.SyncPeerScores(ctx)
+}
+
 func (c *FullNodeStruct) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
 	return c.Internal.StateNetworkName(ctx)
 }
@@ -1206,6 +1258,10 @@ func (c *FullNodeStruct) StateListMessages(ctx context.Context, match *api.Messa
 	return c.Internal.StateListMessages(ctx, match, tsk, toht)
 }
 
+func (c *FullNodeStruct) StateAddressHistory(ctx context.Context, addr address.Address, cursor string, limit int) (*api.AddressHistory, error) {
+	return c.Internal.StateAddressHistory(ctx, addr, cursor, limit)
+}
+
 func (c *FullNodeStruct) StateDecodeParams(ctx context.Context, toAddr address.Address, method abi.MethodNum, params []byte, tsk types.TipSetKey) (interface{}, error) {
 	return c.Internal.StateDecodeParams(ctx, toAddr, method, params, tsk)
 }
@@ -1214,6 +1270,10 @@ func (c *FullNodeStruct) StateCompute(ctx context.Context, height abi.ChainEpoch
 	return c.Internal.StateCompute(ctx, height, msgs, tsk)
 }
 
+func (c *FullNodeStruct) StateSimulate(ctx context.Context, spec *api.SimulateSpec, tsk types.TipSetKey) (*api.SimulateOutput, error) {
+	return c.Internal.StateSimulate(ctx, spec, tsk)
+}
+
 /* func (c *FullNodeStruct) StateVerifierStatus(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*abi.StoragePower, error) {
 	return c.Internal.StateVerifierStatus(ctx, addr, tsk)
 }
@@ -1678,6 +1738,10 @@ func (c *StorageMinerStruct) SealingAbort(ctx context.Context, call storiface.Ca
 	return c.Internal.SealingAbort(ctx, call)
 }
 
+func (c *StorageMinerStruct) SealingJobHistory(ctx context.Context, filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error) {
+	return c.Internal.SealingJobHistory(ctx, filter)
+}
+
 func (c *StorageMinerStruct) StorageAttach(ctx context.Context, si stores.StorageInfo, st fsutil.FsStat) error {
 	return c.Internal.StorageAttach(ctx, si, st)
 }
@@ -1762,6 +1826,10 @@ func (c *StorageMinerStruct) MarketGetRetrievalAsk(ctx context.Context) (*retrie
 	return c.Internal.MarketGetRetrievalAsk(ctx)
 }
 
+func (c *StorageMinerStruct) MarketRetrievalQuote(ctx context.Context, payloadCid cid.Cid, piece *cid.Cid, client address.Address) (*retrievalmarket.Ask, error) {
+	return c.Internal.MarketRetrievalQuote(ctx, payloadCid, piece, client)
+}
+
 func (c *StorageMinerStruct) MarketListDataTransfers(ctx context.Context) ([]api.DataTransferChannel, error) {
 	return c.Internal.MarketListDataTransfers(ctx)
 }
@@ -1862,6 +1930,10 @@ func (c *StorageMinerStruct) CheckProvable(ctx context.Context, pp abi.Registere
 	return c.Internal.CheckProvable(ctx, pp, sectors, expensive)
 }
 
+func (c *StorageMinerStruct) ProvingSimulate(ctx context.Context, deadline uint64) (*api.WdPoStSimulation, error) {
+	return c.Internal.ProvingSimulate(ctx, deadline)
+}
+
 // WorkerStruct
 
 func (w *WorkerStruct) Version(ctx context.Context) (api.Version, error) {
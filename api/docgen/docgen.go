@@ -216,6 +216,27 @@ func init() {
 			},
 		},
 	})
+	addExample([]storiface.WorkerJobRecord{
+		{
+			ID: storiface.CallID{
+				Sector: abi.SectorID{Miner: 1000, Number: 100},
+				ID:     uuid.MustParse("76081ba0-61bd-45a5-bc08-af05f1c26e5d"),
+			},
+			Sector:   abi.SectorID{Miner: 1000, Number: 100},
+			Task:     sealtasks.TTPreCommit2,
+			Worker:   uuid.MustParse("ef8d99a2-6865-4189-8ffa-9fef0f806eee"),
+			Hostname: "host",
+			Start:    time.Unix(1605172927, 0).UTC(),
+			End:      time.Unix(1605174727, 0).UTC(),
+			Bytes:    32 << 30,
+		},
+	})
+	addExample(storiface.WorkerJobFilter{
+		Since:  time.Unix(1605172927, 0).UTC(),
+		Task:   sealtasks.TTPreCommit2,
+		Host:   "host",
+		Worker: "ef8d99a2",
+	})
 	addExample(map[uuid.UUID]storiface.WorkerStats{
 		uuid.MustParse("ef8d99a2-6865-4189-8ffa-9fef0f806eee"): {
 			Info: storiface.WorkerInfo{
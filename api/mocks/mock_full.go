@@ -39,6 +39,7 @@ import (
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	protocol "github.com/libp2p/go-libp2p-core/protocol"
 	reflect "reflect"
+	time "time"
 )
 
 // MockFullNode is a mock of FullNode interface
@@ -153,6 +154,21 @@ func (mr *MockFullNodeMockRecorder) ChainExport(arg0, arg1, arg2, arg3 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainExport", reflect.TypeOf((*MockFullNode)(nil).ChainExport), arg0, arg1, arg2, arg3)
 }
 
+// ChainExportRange mocks base method
+func (m *MockFullNode) ChainExportRange(arg0 context.Context, arg1 types.TipSetKey, arg2 api.ChainExportConfig) (<-chan []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChainExportRange", arg0, arg1, arg2)
+	ret0, _ := ret[0].(<-chan []byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChainExportRange indicates an expected call of ChainExportRange
+func (mr *MockFullNodeMockRecorder) ChainExportRange(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainExportRange", reflect.TypeOf((*MockFullNode)(nil).ChainExportRange), arg0, arg1, arg2)
+}
+
 // ChainGetBlock mocks base method
 func (m *MockFullNode) ChainGetBlock(arg0 context.Context, arg1 cid.Cid) (*types.BlockHeader, error) {
 	m.ctrl.T.Helper()
@@ -407,6 +423,63 @@ func (mr *MockFullNodeMockRecorder) ChainSetHead(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainSetHead", reflect.TypeOf((*MockFullNode)(nil).ChainSetHead), arg0, arg1)
 }
 
+// ChainSplitstoreCompact mocks base method
+func (m *MockFullNode) ChainSplitstoreCompact(arg0 context.Context, arg1 abi.ChainEpoch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChainSplitstoreCompact", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChainSplitstoreCompact indicates an expected call of ChainSplitstoreCompact
+func (mr *MockFullNodeMockRecorder) ChainSplitstoreCompact(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainSplitstoreCompact", reflect.TypeOf((*MockFullNode)(nil).ChainSplitstoreCompact), arg0, arg1)
+}
+
+// ChainSplitstoreGC mocks base method
+func (m *MockFullNode) ChainSplitstoreGC(arg0 context.Context, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChainSplitstoreGC", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChainSplitstoreGC indicates an expected call of ChainSplitstoreGC
+func (mr *MockFullNodeMockRecorder) ChainSplitstoreGC(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainSplitstoreGC", reflect.TypeOf((*MockFullNode)(nil).ChainSplitstoreGC), arg0, arg1)
+}
+
+// ChainSplitstoreStatus mocks base method
+func (m *MockFullNode) ChainSplitstoreStatus(arg0 context.Context) (*api.SplitstoreStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChainSplitstoreStatus", arg0)
+	ret0, _ := ret[0].(*api.SplitstoreStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChainSplitstoreStatus indicates an expected call of ChainSplitstoreStatus
+func (mr *MockFullNodeMockRecorder) ChainSplitstoreStatus(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainSplitstoreStatus", reflect.TypeOf((*MockFullNode)(nil).ChainSplitstoreStatus), arg0)
+}
+
+// ChainSplitstoreWarmup mocks base method
+func (m *MockFullNode) ChainSplitstoreWarmup(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChainSplitstoreWarmup", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChainSplitstoreWarmup indicates an expected call of ChainSplitstoreWarmup
+func (mr *MockFullNodeMockRecorder) ChainSplitstoreWarmup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainSplitstoreWarmup", reflect.TypeOf((*MockFullNode)(nil).ChainSplitstoreWarmup), arg0)
+}
+
 // ChainStatObj mocks base method
 func (m *MockFullNode) ChainStatObj(arg0 context.Context, arg1, arg2 cid.Cid) (api.ObjStat, error) {
 	m.ctrl.T.Helper()
@@ -1298,6 +1371,21 @@ func (mr *MockFullNodeMockRecorder) ID(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ID", reflect.TypeOf((*MockFullNode)(nil).ID), arg0)
 }
 
+// JournalQuery mocks base method
+func (m *MockFullNode) JournalQuery(arg0 context.Context, arg1, arg2 string, arg3, arg4 time.Time, arg5 int) ([]api.JournalEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JournalQuery", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]api.JournalEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// JournalQuery indicates an expected call of JournalQuery
+func (mr *MockFullNodeMockRecorder) JournalQuery(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JournalQuery", reflect.TypeOf((*MockFullNode)(nil).JournalQuery), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // LogList mocks base method
 func (m *MockFullNode) LogList(arg0 context.Context) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -1416,6 +1504,21 @@ func (mr *MockFullNodeMockRecorder) MpoolClear(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MpoolClear", reflect.TypeOf((*MockFullNode)(nil).MpoolClear), arg0, arg1)
 }
 
+// MpoolExport mocks base method
+func (m *MockFullNode) MpoolExport(arg0 context.Context) ([]api.MpoolPendingMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MpoolExport", arg0)
+	ret0, _ := ret[0].([]api.MpoolPendingMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MpoolExport indicates an expected call of MpoolExport
+func (mr *MockFullNodeMockRecorder) MpoolExport(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MpoolExport", reflect.TypeOf((*MockFullNode)(nil).MpoolExport), arg0)
+}
+
 // MpoolGetConfig mocks base method
 func (m *MockFullNode) MpoolGetConfig(arg0 context.Context) (*types.MpoolConfig, error) {
 	m.ctrl.T.Helper()
@@ -1446,6 +1549,21 @@ func (mr *MockFullNodeMockRecorder) MpoolGetNonce(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MpoolGetNonce", reflect.TypeOf((*MockFullNode)(nil).MpoolGetNonce), arg0, arg1)
 }
 
+// MpoolImport mocks base method
+func (m *MockFullNode) MpoolImport(arg0 context.Context, arg1 []api.MpoolPendingMessage) (*api.MpoolImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MpoolImport", arg0, arg1)
+	ret0, _ := ret[0].(*api.MpoolImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MpoolImport indicates an expected call of MpoolImport
+func (mr *MockFullNodeMockRecorder) MpoolImport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MpoolImport", reflect.TypeOf((*MockFullNode)(nil).MpoolImport), arg0, arg1)
+}
+
 // MpoolPending mocks base method
 func (m *MockFullNode) MpoolPending(arg0 context.Context, arg1 types.TipSetKey) ([]*types.SignedMessage, error) {
 	m.ctrl.T.Helper()
@@ -2309,6 +2427,21 @@ func (mr *MockFullNodeMockRecorder) StateAccountKey(arg0, arg1, arg2 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateAccountKey", reflect.TypeOf((*MockFullNode)(nil).StateAccountKey), arg0, arg1, arg2)
 }
 
+// StateAddressHistory mocks base method
+func (m *MockFullNode) StateAddressHistory(arg0 context.Context, arg1 address.Address, arg2 string, arg3 int) (*api.AddressHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StateAddressHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*api.AddressHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StateAddressHistory indicates an expected call of StateAddressHistory
+func (mr *MockFullNodeMockRecorder) StateAddressHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateAddressHistory", reflect.TypeOf((*MockFullNode)(nil).StateAddressHistory), arg0, arg1, arg2, arg3)
+}
+
 // StateAllMinerFaults mocks base method
 func (m *MockFullNode) StateAllMinerFaults(arg0 context.Context, arg1 abi.ChainEpoch, arg2 types.TipSetKey) ([]*api.Fault, error) {
 	m.ctrl.T.Helper()
@@ -3089,6 +3222,21 @@ func (mr *MockFullNodeMockRecorder) StateSectorPreCommitInfo(arg0, arg1, arg2, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateSectorPreCommitInfo", reflect.TypeOf((*MockFullNode)(nil).StateSectorPreCommitInfo), arg0, arg1, arg2, arg3)
 }
 
+// StateSimulate mocks base method
+func (m *MockFullNode) StateSimulate(arg0 context.Context, arg1 *api.SimulateSpec, arg2 types.TipSetKey) (*api.SimulateOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StateSimulate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*api.SimulateOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StateSimulate indicates an expected call of StateSimulate
+func (mr *MockFullNodeMockRecorder) StateSimulate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateSimulate", reflect.TypeOf((*MockFullNode)(nil).StateSimulate), arg0, arg1, arg2)
+}
+
 // StateTotalMinedDetail mocks base method
 func (m *MockFullNode) StateTotalMinedDetail(arg0 context.Context, arg1 types.TipSetKey) (*reward.TotalMinedDetail, error) {
 	m.ctrl.T.Helper()
@@ -3237,6 +3385,21 @@ func (mr *MockFullNodeMockRecorder) SyncMarkBad(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncMarkBad", reflect.TypeOf((*MockFullNode)(nil).SyncMarkBad), arg0, arg1)
 }
 
+// SyncPeerScores mocks base method
+func (m *MockFullNode) SyncPeerScores(arg0 context.Context) ([]api.SyncPeerScore, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncPeerScores", arg0)
+	ret0, _ := ret[0].([]api.SyncPeerScore)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncPeerScores indicates an expected call of SyncPeerScores
+func (mr *MockFullNodeMockRecorder) SyncPeerScores(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncPeerScores", reflect.TypeOf((*MockFullNode)(nil).SyncPeerScores), arg0)
+}
+
 // SyncState mocks base method
 func (m *MockFullNode) SyncState(arg0 context.Context) (*api.SyncState, error) {
 	m.ctrl.T.Helper()
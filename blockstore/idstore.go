@@ -172,3 +172,24 @@ func (b *idstore) Close() error {
 	}
 	return nil
 }
+
+func (b *idstore) ForEachKey(f func(cid.Cid) error) error {
+	if it, ok := b.bs.(BlockstoreIterator); ok {
+		return it.ForEachKey(f)
+	}
+	return xerrors.Errorf("underlying blockstore does not support iteration: %T", b.bs)
+}
+
+func (b *idstore) CollectGarbage(options ...BlockstoreGCOption) error {
+	if gc, ok := b.bs.(BlockstoreGC); ok {
+		return gc.CollectGarbage(options...)
+	}
+	return xerrors.Errorf("underlying blockstore does not support garbage collection: %T", b.bs)
+}
+
+func (b *idstore) Size() (int64, error) {
+	if sz, ok := b.bs.(BlockstoreSize); ok {
+		return sz.Size()
+	}
+	return 0, xerrors.Errorf("underlying blockstore does not report its size: %T", b.bs)
+}
@@ -130,6 +130,15 @@ type SplitStore struct {
 
 	compactionIndex int64
 
+	// progress of the running compaction, warmup, gc or check; see Status
+	statusMx            sync.Mutex
+	stage               string
+	stageStart          time.Time
+	lastCompactionEpoch abi.ChainEpoch
+	lastCompactionTime  time.Time
+	lastCompactionTook  time.Duration
+	lastError           string
+
 	ctx    context.Context
 	cancel func()
 
@@ -584,6 +593,9 @@ func (s *SplitStore) checkClosing() error {
 }
 
 func (s *SplitStore) setBaseEpoch(epoch abi.ChainEpoch) error {
+	s.mx.Lock()
 	s.baseEpoch = epoch
+	s.mx.Unlock()
+
 	return s.ds.Put(baseEpochKey, epochToBytes(epoch))
 }
@@ -44,7 +44,9 @@ func (s *SplitStore) Check() error {
 		log.Info("checking splitstore health")
 		start := time.Now()
 
+		s.setStage("check")
 		err := s.doCheck(curTs)
+		s.endStage(err)
 		if err != nil {
 			log.Errorf("error checking splitstore health: %s", err)
 			return
@@ -90,7 +92,7 @@ func (s *SplitStore) doCheck(curTs *types.TipSet) error {
 	}
 	defer visitor.Close() //nolint
 
-	err = s.walkChain(curTs, boundaryEpoch, boundaryEpoch, visitor,
+	err = s.walkChain(curTs, boundaryEpoch, boundaryEpoch, 0, visitor,
 		func(c cid.Cid) error {
 			if isUnitaryObject(c) {
 				return errStopWalk
@@ -110,9 +110,9 @@ func (s *SplitStore) HeadChange(_, apply []*types.TipSet) error {
 			log.Info("compacting splitstore")
 			start := time.Now()
 
-			s.compact(curTs)
-
-			log.Infow("compaction done", "took", time.Since(start))
+			if err := s.compact(curTs); err == nil {
+				log.Infow("compaction done", "took", time.Since(start))
+			}
 		}()
 	} else {
 		// no compaction necessary
@@ -354,7 +354,8 @@ func (s *SplitStore) applyProtectors() error {
 //   - We sort cold objects heaviest first, so as to never delete the consituents of a DAG before the DAG itself (which would leave dangling references)
 //   - We delete in small batches taking a lock; each batch is checked again for marks, from the concurrent transactional mark, so as to never delete anything live
 // - We then end the transaction and compact/gc the hotstore.
-func (s *SplitStore) compact(curTs *types.TipSet) {
+func (s *SplitStore) compact(curTs *types.TipSet) error {
+	s.setStage("wait-views")
 	log.Info("waiting for active views to complete")
 	start := time.Now()
 	s.viewWait()
@@ -362,12 +363,21 @@ func (s *SplitStore) compact(curTs *types.TipSet) {
 
 	start = time.Now()
 	err := s.doCompact(curTs)
-	took := time.Since(start).Milliseconds()
-	stats.Record(s.ctx, metrics.SplitstoreCompactionTimeSeconds.M(float64(took)/1e3))
+	took := time.Since(start)
+	stats.Record(s.ctx, metrics.SplitstoreCompactionTimeSeconds.M(float64(took.Milliseconds())/1e3))
 
 	if err != nil {
 		log.Errorf("COMPACTION ERROR: %s", err)
+	} else {
+		s.statusMx.Lock()
+		s.lastCompactionEpoch = curTs.Height()
+		s.lastCompactionTime = time.Now()
+		s.lastCompactionTook = took
+		s.statusMx.Unlock()
 	}
+
+	s.endStage(err)
+	return err
 }
 
 func (s *SplitStore) doCompact(curTs *types.TipSet) error {
@@ -406,11 +416,12 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 
 	// 1. mark reachable objects by walking the chain from the current epoch; we keep state roots
 	//   and messages until the boundary epoch.
+	s.setStage("mark")
 	log.Info("marking reachable objects")
 	startMark := time.Now()
 
 	var count int64
-	err = s.walkChain(curTs, boundaryEpoch, inclMsgsEpoch, &noopVisitor{},
+	err = s.walkChain(curTs, boundaryEpoch, inclMsgsEpoch, 0, &noopVisitor{},
 		func(c cid.Cid) error {
 			if isUnitaryObject(c) {
 				return errStopWalk
@@ -433,7 +444,9 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 		return xerrors.Errorf("error marking: %w", err)
 	}
 
+	s.mx.Lock()
 	s.markSetSize = count + count>>2 // overestimate a bit
+	s.mx.Unlock()
 
 	log.Infow("marking done", "took", time.Since(startMark), "marked", count)
 
@@ -442,6 +455,7 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 	}
 
 	// 1.1 protect transactional refs
+	s.setStage("protect")
 	err = s.protectTxnRefs(markSet)
 	if err != nil {
 		return xerrors.Errorf("error protecting transactional refs: %w", err)
@@ -452,6 +466,7 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 	}
 
 	// 2. iterate through the hotstore to collect cold objects
+	s.setStage("collect")
 	log.Info("collecting cold objects")
 	startCollect := time.Now()
 
@@ -507,6 +522,7 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 
 	// 3. copy the cold objects to the coldstore -- if we have one
 	if !s.cfg.DiscardColdBlocks {
+		s.setStage("move")
 		log.Info("moving cold objects to the coldstore")
 		startMove := time.Now()
 		err = s.moveColdBlocks(cold)
@@ -523,6 +539,7 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 	// 4. sort cold objects so that the dags with most references are deleted first
 	//    this ensures that we can't refer to a dag with its consituents already deleted, ie
 	//    we lave no dangling references.
+	s.setStage("sort")
 	log.Info("sorting cold objects")
 	startSort := time.Now()
 	err = s.sortObjects(cold)
@@ -545,9 +562,10 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 	}
 
 	// 5. purge cold objects from the hotstore, taking protected references into account
+	s.setStage("purge")
 	log.Info("purging cold objects from the hotstore")
 	startPurge := time.Now()
-	err = s.purge(cold, markSet)
+	err = s.purge(s.hot, cold, markSet)
 	if err != nil {
 		return xerrors.Errorf("error purging cold blocks: %w", err)
 	}
@@ -555,6 +573,7 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 
 	// we are done; do some housekeeping
 	s.endTxnProtect()
+	s.setStage("gc")
 	s.gcHotstore()
 
 	err = s.setBaseEpoch(boundaryEpoch)
@@ -567,7 +586,9 @@ func (s *SplitStore) doCompact(curTs *types.TipSet) error {
 		return xerrors.Errorf("error saving mark set size: %w", err)
 	}
 
+	s.mx.Lock()
 	s.compactionIndex++
+	s.mx.Unlock()
 	err = s.ds.Put(compactionIndexKey, int64ToBytes(s.compactionIndex))
 	if err != nil {
 		return xerrors.Errorf("error saving compaction index: %w", err)
@@ -604,7 +625,11 @@ func (s *SplitStore) endTxnProtect() {
 	s.txnMissing = nil
 }
 
-func (s *SplitStore) walkChain(ts *types.TipSet, inclState, inclMsgs abi.ChainEpoch,
+// walkChain walks the chain from ts, including the state roots down to
+// inclState and the messages and receipts down to inclMsgs. The objects of
+// blocks below inclComplete may be missing, e.g. below the snapshot a node
+// synced from, and are walked incompletely.
+func (s *SplitStore) walkChain(ts *types.TipSet, inclState, inclMsgs, inclComplete abi.ChainEpoch,
 	visitor ObjectVisitor, f func(cid.Cid) error) error {
 	var walked *cid.Set
 	toWalk := ts.Cids()
@@ -633,9 +658,11 @@ func (s *SplitStore) walkChain(ts *types.TipSet, inclState, inclMsgs abi.ChainEp
 			return xerrors.Errorf("error unmarshaling block header (cid: %s): %w", c, err)
 		}
 
+		incomplete := hdr.Height < inclComplete
+
 		// message are retained if within the inclMsgs boundary
 		if hdr.Height >= inclMsgs && hdr.Height > 0 {
-			if inclMsgs < inclState {
+			if inclMsgs < inclState || incomplete {
 				// we need to use walkObjectIncomplete here, as messages/receipts may be missing early on if we
 				// synced from snapshot and have a long HotStoreMessageRetentionPolicy.
 				if err := s.walkObjectIncomplete(hdr.Messages, visitor, f, stopWalk); err != nil {
@@ -658,8 +685,14 @@ func (s *SplitStore) walkChain(ts *types.TipSet, inclState, inclMsgs abi.ChainEp
 
 		// state is only retained if within the inclState boundary, with the exception of genesis
 		if hdr.Height >= inclState || hdr.Height == 0 {
-			if err := s.walkObject(hdr.ParentStateRoot, visitor, f); err != nil {
-				return xerrors.Errorf("error walking state root (cid: %s): %w", hdr.ParentStateRoot, err)
+			if incomplete {
+				if err := s.walkObjectIncomplete(hdr.ParentStateRoot, visitor, f, stopWalk); err != nil {
+					return xerrors.Errorf("error walking state root (cid: %s): %w", hdr.ParentStateRoot, err)
+				}
+			} else {
+				if err := s.walkObject(hdr.ParentStateRoot, visitor, f); err != nil {
+					return xerrors.Errorf("error walking state root (cid: %s): %w", hdr.ParentStateRoot, err)
+				}
 			}
 			scanCnt++
 		}
@@ -1000,7 +1033,8 @@ func (s *SplitStore) purgeBatch(cids []cid.Cid, deleteBatch func([]cid.Cid) erro
 	return nil
 }
 
-func (s *SplitStore) purge(cids []cid.Cid, markSet MarkSetVisitor) error {
+// purge deletes the objects which aren't marked live from the given store
+func (s *SplitStore) purge(b bstore.Blockstore, cids []cid.Cid, markSet MarkSetVisitor) error {
 	deadCids := make([]cid.Cid, 0, batchSize)
 	var purgeCnt, liveCnt int
 	defer func() {
@@ -1047,7 +1081,7 @@ func (s *SplitStore) purge(cids []cid.Cid, markSet MarkSetVisitor) error {
 				deadCids = append(deadCids, c)
 			}
 
-			err := s.hot.DeleteMany(deadCids)
+			err := b.DeleteMany(deadCids)
 			if err != nil {
 				return xerrors.Errorf("error purging cold objects: %w", err)
 			}
@@ -0,0 +1,186 @@
+package splitstore
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+
+	cid "github.com/ipfs/go-cid"
+
+	bstore "github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// checkPruneCold verifies that the coldstore can be pruned with the given
+// retention
+func (s *SplitStore) checkPruneCold(retention abi.ChainEpoch) error {
+	if s.cfg.DiscardColdBlocks {
+		return xerrors.Errorf("can't prune the coldstore; cold blocks are discarded")
+	}
+
+	if _, ok := s.cold.(bstore.BlockstoreIterator); !ok {
+		return xerrors.Errorf("coldstore does not support efficient iteration: %T", s.cold)
+	}
+
+	// objects within the compaction boundary may only have been copied to the
+	// coldstore, they must be kept
+	if retention < CompactionBoundary {
+		return xerrors.Errorf("cold retention must be at least the compaction boundary (%d epochs)", CompactionBoundary)
+	}
+
+	return nil
+}
+
+// pruneCold deletes the coldstore objects which aren't reachable from the
+// chain when keeping state roots and messages for retention epochs. Block
+// headers are kept all the way to genesis. Must be called with the compaction
+// lock held and a transaction in progress.
+func (s *SplitStore) pruneCold(curTs *types.TipSet, retention abi.ChainEpoch) error {
+	s.setStage("wait-views")
+	log.Info("waiting for active views to complete")
+	start := time.Now()
+	s.viewWait()
+	log.Infow("waiting for active views done", "took", time.Since(start))
+
+	err := s.doPruneCold(curTs, retention)
+	if err != nil {
+		log.Errorf("PRUNE ERROR: %s", err)
+	}
+
+	s.endStage(err)
+	return err
+}
+
+func (s *SplitStore) doPruneCold(curTs *types.TipSet, retention abi.ChainEpoch) error {
+	currentEpoch := curTs.Height()
+	boundaryEpoch := currentEpoch - retention
+	if boundaryEpoch < 0 {
+		boundaryEpoch = 0
+	}
+
+	log.Infow("running coldstore prune", "currentEpoch", currentEpoch, "boundaryEpoch", boundaryEpoch)
+
+	markSet, err := s.markSetEnv.CreateVisitor("prune", s.markSetSize)
+	if err != nil {
+		return xerrors.Errorf("error creating mark set: %w", err)
+	}
+	defer markSet.Close() //nolint:errcheck
+
+	if err := s.checkClosing(); err != nil {
+		return err
+	}
+
+	s.beginTxnMarking(markSet)
+
+	log.Info("protecting references with registered protectors")
+	err = s.applyProtectors()
+	if err != nil {
+		return err
+	}
+
+	// 1. mark the objects reachable within the retention
+	s.setStage("prune-mark")
+	log.Info("marking reachable objects")
+	startMark := time.Now()
+
+	// the history below the warmup may be incomplete, e.g. when the node
+	// synced from a snapshot
+	s.mx.Lock()
+	warmupEpoch := s.warmupEpoch
+	s.mx.Unlock()
+
+	var count int64
+	err = s.walkChain(curTs, boundaryEpoch, boundaryEpoch, warmupEpoch, &noopVisitor{},
+		func(c cid.Cid) error {
+			if isUnitaryObject(c) {
+				return errStopWalk
+			}
+
+			visit, err := markSet.Visit(c)
+			if err != nil {
+				return xerrors.Errorf("error visiting object: %w", err)
+			}
+
+			if !visit {
+				return errStopWalk
+			}
+
+			count++
+			return nil
+		})
+
+	if err != nil {
+		return xerrors.Errorf("error marking: %w", err)
+	}
+
+	log.Infow("marking done", "took", time.Since(startMark), "marked", count)
+
+	if err := s.checkClosing(); err != nil {
+		return err
+	}
+
+	s.setStage("prune-protect")
+	err = s.protectTxnRefs(markSet)
+	if err != nil {
+		return xerrors.Errorf("error protecting transactional refs: %w", err)
+	}
+
+	// 2. iterate through the coldstore to collect dead objects
+	s.setStage("prune-collect")
+	log.Info("collecting dead cold objects")
+	startCollect := time.Now()
+
+	var liveCnt int
+	var dead []cid.Cid
+	err = s.cold.(bstore.BlockstoreIterator).ForEachKey(func(c cid.Cid) error {
+		mark, err := markSet.Has(c)
+		if err != nil {
+			return xerrors.Errorf("error checking mark set for %s: %w", c, err)
+		}
+
+		if mark {
+			liveCnt++
+			return nil
+		}
+
+		dead = append(dead, c)
+		return nil
+	})
+
+	if err != nil {
+		return xerrors.Errorf("error collecting dead cold objects: %w", err)
+	}
+
+	log.Infow("dead cold collection done", "took", time.Since(startCollect), "live", liveCnt, "dead", len(dead))
+
+	if err := s.checkClosing(); err != nil {
+		return err
+	}
+
+	s.waitForMissingRefs(markSet)
+
+	if err := s.checkClosing(); err != nil {
+		return err
+	}
+
+	// 3. purge dead objects from the coldstore; dead objects are unreachable from
+	//    the retained chain, so there is no need to sort them
+	s.setStage("prune-purge")
+	log.Info("purging dead objects from the coldstore")
+	startPurge := time.Now()
+	err = s.purge(s.cold, dead, markSet)
+	if err != nil {
+		return xerrors.Errorf("error purging dead cold objects: %w", err)
+	}
+	log.Infow("purging dead objects from the coldstore done", "took", time.Since(startPurge))
+
+	s.endTxnProtect()
+
+	s.setStage("prune-gc")
+	if err := s.gcBlockstore(s.cold, nil); err != nil {
+		log.Warnf("error garbage collecting coldstore: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package splitstore
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	bstore "github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// Status is a snapshot of the splitstore state and of the operation in progress
+type Status struct {
+	BaseEpoch       abi.ChainEpoch
+	WarmupEpoch     abi.ChainEpoch
+	CompactionIndex int64
+	MarkSetSize     int64
+
+	// HotSize and ColdSize are the on-disk sizes of the stores in bytes, or -1
+	// when the store can't report its size
+	HotSize  int64
+	ColdSize int64
+
+	// Compacting is set while a compaction, prune, warmup, gc or check is
+	// running; Stage is the step it is at
+	Compacting bool
+	Stage      string
+	StageStart time.Time
+
+	// LastCompactionEpoch is the chain head at the last compaction completed
+	// since startup
+	LastCompactionEpoch abi.ChainEpoch
+	LastCompactionTime  time.Time
+	LastCompactionTook  time.Duration
+	// LastError is the error of the last failed operation, if any
+	LastError string
+}
+
+// Status returns the current state of the splitstore
+func (s *SplitStore) Status() Status {
+	var st Status
+
+	s.mx.Lock()
+	st.BaseEpoch = s.baseEpoch
+	st.WarmupEpoch = s.warmupEpoch
+	st.CompactionIndex = s.compactionIndex
+	st.MarkSetSize = s.markSetSize
+	s.mx.Unlock()
+
+	st.Compacting = atomic.LoadInt32(&s.compacting) == 1
+
+	s.statusMx.Lock()
+	st.Stage = s.stage
+	st.StageStart = s.stageStart
+	st.LastCompactionEpoch = s.lastCompactionEpoch
+	st.LastCompactionTime = s.lastCompactionTime
+	st.LastCompactionTook = s.lastCompactionTook
+	st.LastError = s.lastError
+	s.statusMx.Unlock()
+
+	st.HotSize = storeSize(s.hot)
+	st.ColdSize = -1
+	if !s.cfg.DiscardColdBlocks {
+		st.ColdSize = storeSize(s.cold)
+	}
+
+	return st
+}
+
+func storeSize(b bstore.Blockstore) int64 {
+	sizer, ok := b.(bstore.BlockstoreSize)
+	if !ok {
+		return -1
+	}
+
+	size, err := sizer.Size()
+	if err != nil {
+		log.Warnf("error getting blockstore size: %s", err)
+		return -1
+	}
+
+	return size
+}
+
+// setStage records the step the running operation is at
+func (s *SplitStore) setStage(stage string) {
+	s.statusMx.Lock()
+	defer s.statusMx.Unlock()
+
+	s.stage = stage
+	s.stageStart = time.Now()
+}
+
+// endStage records the end of the running operation and its error, if any
+func (s *SplitStore) endStage(err error) {
+	s.statusMx.Lock()
+	defer s.statusMx.Unlock()
+
+	s.stage = ""
+	s.stageStart = time.Time{}
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// Compact starts a compaction in the background, regardless of the compaction
+// threshold. When pruneCold is positive, objects which aren't reachable from
+// the last pruneCold epochs of the chain are then deleted from the coldstore.
+func (s *SplitStore) Compact(pruneCold abi.ChainEpoch) error {
+	if pruneCold > 0 {
+		if err := s.checkPruneCold(pruneCold); err != nil {
+			return err
+		}
+	}
+
+	s.headChangeMx.Lock()
+	defer s.headChangeMx.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&s.compacting, 0, 1) {
+		return xerrors.Errorf("can't acquire compaction lock; compacting operation in progress")
+	}
+
+	if err := s.checkClosing(); err != nil {
+		atomic.StoreInt32(&s.compacting, 0)
+		return err
+	}
+
+	curTs := s.chain.GetHeaviestTipSet()
+	timestamp := time.Unix(int64(curTs.MinTimestamp()), 0)
+	if time.Since(timestamp) > SyncGapTime {
+		atomic.StoreInt32(&s.compacting, 0)
+		return xerrors.Errorf("can't compact before the chain is synced; head is at epoch %d", curTs.Height())
+	}
+
+	needCompact := curTs.Height()-CompactionBoundary > s.baseEpoch
+	if !needCompact && pruneCold == 0 {
+		atomic.StoreInt32(&s.compacting, 0)
+		return xerrors.Errorf("nothing to compact; base epoch %d is within the compaction boundary", s.baseEpoch)
+	}
+
+	s.beginTxnProtect()
+	go func() {
+		defer atomic.StoreInt32(&s.compacting, 0)
+		defer s.endTxnProtect()
+
+		if needCompact {
+			log.Info("compacting splitstore")
+			start := time.Now()
+
+			if err := s.compact(curTs); err != nil {
+				return
+			}
+
+			log.Infow("compaction done", "took", time.Since(start))
+		}
+
+		if pruneCold > 0 {
+			// compaction ends its transaction before collecting garbage
+			s.beginTxnProtect()
+
+			log.Infow("pruning coldstore", "retention", pruneCold)
+			start := time.Now()
+
+			if err := s.pruneCold(curTs, pruneCold); err != nil {
+				return
+			}
+
+			log.Infow("coldstore pruning done", "took", time.Since(start))
+		}
+	}()
+
+	return nil
+}
+
+// GC garbage collects the hotstore in the background, with a full (moving) GC
+// if requested and supported by the hotstore
+func (s *SplitStore) GC(full bool) error {
+	if _, ok := s.hot.(bstore.BlockstoreGC); !ok {
+		return xerrors.Errorf("hotstore doesn't support garbage collection: %T", s.hot)
+	}
+
+	s.headChangeMx.Lock()
+	defer s.headChangeMx.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&s.compacting, 0, 1) {
+		return xerrors.Errorf("can't acquire compaction lock; compacting operation in progress")
+	}
+
+	if err := s.checkClosing(); err != nil {
+		atomic.StoreInt32(&s.compacting, 0)
+		return err
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&s.compacting, 0)
+
+		var opts []bstore.BlockstoreGCOption
+		if full {
+			opts = append(opts, bstore.WithFullGC(true))
+		}
+
+		s.setStage("gc")
+		err := s.gcBlockstore(s.hot, opts)
+		s.endStage(err)
+		if err != nil {
+			log.Errorf("error garbage collecting hotstore: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Warmup starts a hotstore warmup in the background, copying the objects
+// reachable from the current head which are missing from the hotstore
+func (s *SplitStore) Warmup() error {
+	s.headChangeMx.Lock()
+	defer s.headChangeMx.Unlock()
+
+	if err := s.checkClosing(); err != nil {
+		return err
+	}
+
+	return s.warmup(s.chain.GetHeaviestTipSet())
+}
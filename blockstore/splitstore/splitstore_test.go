@@ -220,6 +220,139 @@ func TestSplitStoreCompactionWithBadger(t *testing.T) {
 	testSplitStore(t, &Config{MarkSetType: "badger"})
 }
 
+func TestSplitStoreManualCompactionAndPrune(t *testing.T) {
+	chain := &mockChain{t: t}
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	hot := newMockStore()
+	cold := newMockStore()
+
+	garbage := blocks.NewBlock([]byte{1, 2, 3})
+	if err := cold.Put(garbage); err != nil {
+		t.Fatal(err)
+	}
+
+	genBlock := mock.MkBlock(nil, 0, 0)
+	genBlock.Messages = garbage.Cid()
+	genBlock.ParentMessageReceipts = garbage.Cid()
+	genBlock.ParentStateRoot = garbage.Cid()
+	genBlock.Timestamp = uint64(time.Now().Unix())
+
+	genTs := mock.TipSet(genBlock)
+	chain.push(genTs)
+
+	blk, err := genBlock.ToStorageBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cold.Put(blk); err != nil {
+		t.Fatal(err)
+	}
+
+	// an object in the coldstore which isn't referenced by the chain
+	dead := blocks.NewBlock([]byte("dead!"))
+	if err := cold.Put(dead); err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := Open("", ds, hot, cold, &Config{MarkSetType: "map"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close() //nolint
+
+	if err := ss.Start(chain); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCompaction := func() {
+		for atomic.LoadInt32(&ss.compacting) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	waitForCompaction()
+
+	// not enough tipsets for an automatic compaction
+	curTs := genTs
+	for i := 1; i < 4; i++ {
+		stateRoot := blocks.NewBlock([]byte{byte(i), 3, 3, 7})
+
+		blk := mock.MkBlock(curTs, uint64(i), uint64(i))
+		blk.Messages = garbage.Cid()
+		blk.ParentMessageReceipts = garbage.Cid()
+		blk.ParentStateRoot = stateRoot.Cid()
+		blk.Timestamp = uint64(time.Now().Unix())
+
+		sblk, err := blk.ToStorageBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ss.Put(stateRoot); err != nil {
+			t.Fatal(err)
+		}
+		if err := ss.Put(sblk); err != nil {
+			t.Fatal(err)
+		}
+
+		curTs = mock.TipSet(blk)
+		chain.push(curTs)
+		waitForCompaction()
+	}
+
+	if st := ss.Status(); st.CompactionIndex != 0 {
+		t.Fatalf("expected no compaction, but got %d", st.CompactionIndex)
+	}
+
+	// retention must cover the compaction boundary
+	if err := ss.Compact(CompactionBoundary - 1); err == nil {
+		t.Fatal("expected an error for a retention below the compaction boundary")
+	}
+
+	if err := ss.Compact(CompactionBoundary); err != nil {
+		t.Fatal(err)
+	}
+	waitForCompaction()
+
+	st := ss.Status()
+	if st.CompactionIndex != 1 {
+		t.Errorf("expected %d compactions, but got %d", 1, st.CompactionIndex)
+	}
+	if st.BaseEpoch != curTs.Height()-CompactionBoundary {
+		t.Errorf("expected base epoch %d, but got %d", curTs.Height()-CompactionBoundary, st.BaseEpoch)
+	}
+	if st.LastCompactionEpoch != curTs.Height() {
+		t.Errorf("expected last compaction at %d, but got %d", curTs.Height(), st.LastCompactionEpoch)
+	}
+	if st.Compacting || st.Stage != "" || st.LastError != "" {
+		t.Errorf("unexpected status after compaction: %+v", st)
+	}
+	if st.HotSize != -1 || st.ColdSize != -1 {
+		t.Errorf("expected unknown sizes, but got %d/%d", st.HotSize, st.ColdSize)
+	}
+
+	// the dead object is pruned, the chain is kept
+	has, err := cold.Has(dead.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has {
+		t.Error("dead object is still in the coldstore")
+	}
+
+	has, err = ss.Has(genBlock.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !has {
+		t.Error("genesis block was pruned")
+	}
+
+	// nothing left to compact
+	if err := ss.Compact(0); err == nil {
+		t.Fatal("expected an error when there is nothing to compact")
+	}
+}
+
 type mockChain struct {
 	t testing.TB
 
@@ -34,7 +34,9 @@ func (s *SplitStore) warmup(curTs *types.TipSet) error {
 		log.Info("warming up hotstore")
 		start := time.Now()
 
+		s.setStage("warmup")
 		err := s.doWarmup(curTs)
+		s.endStage(err)
 		if err != nil {
 			log.Errorf("error warming up hotstore: %s", err)
 			return
@@ -66,7 +68,7 @@ func (s *SplitStore) doWarmup(curTs *types.TipSet) error {
 	}
 	defer visitor.Close() //nolint
 
-	err = s.walkChain(curTs, boundaryEpoch, epoch+1, // we don't load messages/receipts in warmup
+	err = s.walkChain(curTs, boundaryEpoch, epoch+1, 0, // we don't load messages/receipts in warmup
 		visitor,
 		func(c cid.Cid) error {
 			if isUnitaryObject(c) {
@@ -120,7 +122,9 @@ func (s *SplitStore) doWarmup(curTs *types.TipSet) error {
 
 	log.Infow("warmup stats", "visited", count, "warm", xcount, "missing", missing)
 
+	s.mx.Lock()
 	s.markSetSize = count + count>>2 // overestimate a bit
+	s.mx.Unlock()
 	err = s.ds.Put(markSetSizeKey, int64ToBytes(s.markSetSize))
 	if err != nil {
 		log.Warnf("error saving mark set size: %s", err)
@@ -41,6 +41,15 @@ type State interface {
 	Data(cid.Cid) (*DataOnChainInfo, error)
 }
 
+type ExpertState = expert2.ExpertState
+
+const (
+	ExpertStateRegistered  = expert2.ExpertStateRegistered
+	ExpertStateUnqualified = expert2.ExpertStateUnqualified
+	ExpertStateQualified   = expert2.ExpertStateQualified
+	ExpertStateBlocked     = expert2.ExpertStateBlocked
+)
+
 type BatchImportDataParams = expert2.BatchImportDataParams
 type ImportDataParams = expert2.ImportDataParams
 type DataOnChainInfo = expert2.DataOnChainInfo
@@ -48,7 +57,7 @@ type DataOnChainInfo = expert2.DataOnChainInfo
 type ExpertInfo struct {
 	expert2.ExpertInfo
 	LostEpoch       abi.ChainEpoch
-	Status          expert2.ExpertState
+	Status          ExpertState
 	StatusDesc      string // fill in state.go
 	ImplicatedTimes uint64
 	DataCount       uint64
@@ -0,0 +1,647 @@
+package addrindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+var log = logging.Logger("addrindex")
+
+var ErrDisabled = errors.New("address index is disabled, set AddrIndex.Enable in the node config")
+
+// AddrIndex returns the history of addresses
+type AddrIndex interface {
+	// History returns up to limit entries of the address, newest first,
+	// starting after the cursor. Addresses are indexed by their ID address
+	// where one exists.
+	History(ctx context.Context, addr address.Address, cursor string, limit int) ([]api.AddressHistoryEntry, string, error)
+	Close() error
+}
+
+type disabledAddrIndex struct{}
+
+func (disabledAddrIndex) History(context.Context, address.Address, string, int) ([]api.AddressHistoryEntry, string, error) {
+	return nil, "", ErrDisabled
+}
+
+func (disabledAddrIndex) Close() error {
+	return nil
+}
+
+// DisabledAddrIndex is used when the index is disabled
+var DisabledAddrIndex AddrIndex = disabledAddrIndex{}
+
+// ChainStore is the part of the chain store the index is maintained from
+type ChainStore interface {
+	GetHeaviestTipSet() *types.TipSet
+	LoadTipSet(types.TipSetKey) (*types.TipSet, error)
+	GetTipsetByHeight(ctx context.Context, h abi.ChainEpoch, ts *types.TipSet, prev bool) (*types.TipSet, error)
+	SubscribeHeadChanges(store.ReorgNotifee)
+}
+
+// StateManager executes tipsets to get the internal calls of messages
+type StateManager interface {
+	ExecutionTrace(ctx context.Context, ts *types.TipSet) (cid.Cid, []*api.InvocResult, error)
+	LookupID(ctx context.Context, addr address.Address, ts *types.TipSet) (address.Address, error)
+}
+
+var (
+	headKey    = datastore.NewKey("/meta/head")
+	versionKey = datastore.NewKey("/meta/version")
+)
+
+// version of the key layout, indexes with an older layout are rebuilt
+const version = 1
+
+// bucketBits sets the number of epochs in a bucket of address entries to
+// 4096, about a day and a half
+const bucketBits = 12
+
+// record is an index entry as stored
+type record struct {
+	api.AddressHistoryEntry
+	ExecutedEpoch abi.ChainEpoch
+}
+
+// applied lists the keys written when applying a tipset, so that they can
+// be removed when it's reverted
+type applied struct {
+	TipSet types.TipSetKey
+	Keys   []string
+}
+
+// Index is an AddrIndex persisted in a datastore. Entries are keyed by
+// address, then by bucket of epochs, then by inverted height and message
+// index, so that iterating an address prefix yields its newest entries first.
+// The buckets holding entries of an address are listed separately, so that
+// paging can start from the bucket of the cursor.
+//
+// Indexing a tipset requires re-executing its messages, so head changes are
+// processed in the background rather than in the head change notification.
+// Notifications only record the latest head, the index then moves from the
+// last indexed tipset to it.
+type Index struct {
+	ds datastore.Batching
+	cs ChainStore
+	sm StateManager
+
+	headLk  sync.Mutex
+	pending *types.TipSet
+	notify  chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(ds datastore.Batching, cs ChainStore, sm StateManager) *Index {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Index{
+		ds: ds,
+		cs: cs,
+		sm: sm,
+
+		notify: make(chan struct{}, 1),
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start catches up with the chain since the index last ran and then follows
+// head changes
+func (x *Index) Start() {
+	head := x.cs.GetHeaviestTipSet()
+
+	x.cs.SubscribeHeadChanges(func(rev, app []*types.TipSet) error {
+		if len(app) == 0 {
+			return nil
+		}
+
+		x.headLk.Lock()
+		x.pending = app[len(app)-1]
+		x.headLk.Unlock()
+
+		select {
+		case x.notify <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	x.wg.Add(1)
+	go x.run(head)
+}
+
+func (x *Index) Close() error {
+	x.cancel()
+	x.wg.Wait()
+	return nil
+}
+
+func (x *Index) run(head *types.TipSet) {
+	defer x.wg.Done()
+
+	if err := x.migrate(); err != nil {
+		log.Errorf("migrating address index: %+v", err)
+		return
+	}
+
+	if err := x.catchUp(head); err != nil {
+		log.Errorf("catching up address index: %+v", err)
+	}
+
+	indexed := head
+	for {
+		select {
+		case <-x.notify:
+			x.headLk.Lock()
+			next := x.pending
+			x.headLk.Unlock()
+
+			rev, app, err := store.ReorgOps(x.cs.LoadTipSet, indexed, next)
+			if err != nil {
+				log.Errorf("computing address index head change: %+v", err)
+				continue
+			}
+			if err := x.headChange(rev, app); err != nil {
+				log.Errorf("updating address index: %+v", err)
+			}
+			indexed = next
+		case <-x.ctx.Done():
+			return
+		}
+	}
+}
+
+// migrate clears indexes built with an older key layout, they are rebuilt
+// from the current head
+func (x *Index) migrate() error {
+	v, found, err := x.getHeight(versionKey)
+	if err != nil {
+		return err
+	}
+	if found && v == version {
+		return nil
+	}
+
+	res, err := x.ds.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return xerrors.Errorf("querying index: %w", err)
+	}
+	keys, err := res.Rest()
+	if err != nil {
+		return xerrors.Errorf("reading index keys: %w", err)
+	}
+
+	if len(keys) > 0 {
+		log.Infof("rebuilding address index with key layout version %d", version)
+	}
+
+	b, err := x.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+	for _, e := range keys {
+		if err := b.Delete(datastore.NewKey(e.Key)); err != nil {
+			return err
+		}
+	}
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("clearing index: %w", err)
+	}
+
+	return x.putHeight(versionKey, version)
+}
+
+func (x *Index) catchUp(head *types.TipSet) error {
+	last, found, err := x.getHeight(headKey)
+	if err != nil {
+		return err
+	}
+	if !found {
+		last = head.Height() - 1
+	}
+
+	// index oldest first, so that an interruption doesn't leave gaps
+	var todo []*types.TipSet
+	for ts := head; ts.Height() > last && ts.Height() > 0; {
+		todo = append(todo, ts)
+		if ts, err = x.cs.LoadTipSet(ts.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	for i := len(todo) - 1; i >= 0; i-- {
+		if x.ctx.Err() != nil {
+			return nil
+		}
+
+		if err := x.indexTipSet(x.ctx, todo[i]); err != nil {
+			return xerrors.Errorf("indexing tipset %d: %w", todo[i].Height(), err)
+		}
+		if err := x.putHeight(headKey, todo[i].Height()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *Index) headChange(rev, app []*types.TipSet) error {
+	for _, ts := range rev {
+		if err := x.revertTipSet(ts); err != nil {
+			return xerrors.Errorf("reverting tipset %d: %w", ts.Height(), err)
+		}
+	}
+
+	for _, ts := range app {
+		if err := x.indexTipSet(x.ctx, ts); err != nil {
+			return xerrors.Errorf("indexing tipset %d: %w", ts.Height(), err)
+		}
+		if err := x.putHeight(headKey, ts.Height()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexTipSet indexes the messages executed by the tipset, which are the
+// messages included in its parent
+func (x *Index) indexTipSet(ctx context.Context, ts *types.TipSet) error {
+	if ts.Height() == 0 {
+		return nil
+	}
+
+	pts, err := x.cs.LoadTipSet(ts.Parents())
+	if err != nil {
+		return xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	_, trace, err := x.sm.ExecutionTrace(ctx, pts)
+	if err != nil {
+		return xerrors.Errorf("computing execution trace: %w", err)
+	}
+
+	ids := map[address.Address]address.Address{}
+	resolve := func(a address.Address) address.Address {
+		if a.Protocol() == address.ID {
+			return a
+		}
+		if id, ok := ids[a]; ok {
+			return id
+		}
+
+		// resolve in the state after execution, where actors created by
+		// the messages exist
+		id, err := x.sm.LookupID(ctx, a, ts)
+		if err != nil {
+			id = a
+		}
+		ids[a] = id
+		return id
+	}
+
+	b, err := x.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	done := applied{TipSet: ts.Key()}
+	buckets := map[address.Address]struct{}{}
+	for i, ir := range trace {
+		base := record{
+			AddressHistoryEntry: api.AddressHistoryEntry{
+				Message:        ir.MsgCid,
+				TipSet:         pts.Key(),
+				Height:         pts.Height(),
+				ExecutedTipSet: ts.Key(),
+			},
+			ExecutedEpoch: ts.Height(),
+		}
+
+		var seq int
+		put := func(on address.Address, r record) error {
+			on = resolve(on)
+			k := entryKey(on, pts.Height(), i, seq)
+			seq++
+
+			// bucket markers are kept on revert, an empty bucket is
+			// only a wasted query when paging
+			if _, ok := buckets[on]; !ok {
+				if err := b.Put(bucketKey(on, pts.Height()), nil); err != nil {
+					return err
+				}
+				buckets[on] = struct{}{}
+			}
+
+			v, err := json.Marshal(&r)
+			if err != nil {
+				return xerrors.Errorf("marshaling entry: %w", err)
+			}
+			if err := b.Put(k, v); err != nil {
+				return err
+			}
+			done.Keys = append(done.Keys, k.String())
+			return nil
+		}
+
+		// implicit messages (rewards, cron) are only indexed for the
+		// transfers they make
+		implicit := ir.Msg.From == builtin.SystemActorAddr
+		err := walkTrace(ir.ExecutionTrace, 0, func(et types.ExecutionTrace, depth int) error {
+			r := base
+			r.From, r.To = et.Msg.From, et.Msg.To
+			r.Value, r.Method = et.Msg.Value, et.Msg.Method
+			r.Error = et.Error
+			if et.MsgRct != nil {
+				r.Receipt = *et.MsgRct
+			}
+
+			if depth == 0 {
+				if implicit {
+					return nil
+				}
+				r.Type = api.AddressHistoryMessage
+				// the receipt on chain, with gas used by the whole message
+				r.Receipt = *ir.MsgRct
+				r.Error = ir.Error
+			} else {
+				if et.Msg.Value.NilOrZero() {
+					return nil
+				}
+				r.Type = api.AddressHistoryTransfer
+			}
+
+			r.Direction = api.AddressHistoryOut
+			if err := put(et.Msg.From, r); err != nil {
+				return err
+			}
+			r.Direction = api.AddressHistoryIn
+			return put(et.Msg.To, r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err := json.Marshal(&done)
+	if err != nil {
+		return xerrors.Errorf("marshaling applied keys: %w", err)
+	}
+	if err := b.Put(appliedKey(ts.Height()), v); err != nil {
+		return err
+	}
+
+	return b.Commit()
+}
+
+func walkTrace(et types.ExecutionTrace, depth int, cb func(types.ExecutionTrace, int) error) error {
+	if et.Msg == nil {
+		return nil
+	}
+	if err := cb(et, depth); err != nil {
+		return err
+	}
+	for _, sub := range et.Subcalls {
+		if err := walkTrace(sub, depth+1, cb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *Index) revertTipSet(ts *types.TipSet) error {
+	v, err := x.ds.Get(appliedKey(ts.Height()))
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("getting applied keys: %w", err)
+	}
+
+	var done applied
+	if err := json.Unmarshal(v, &done); err != nil {
+		return xerrors.Errorf("unmarshaling applied keys: %w", err)
+	}
+
+	// another tipset was indexed at this height since
+	if done.TipSet != ts.Key() {
+		return nil
+	}
+
+	b, err := x.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+	for _, k := range done.Keys {
+		if err := b.Delete(datastore.NewKey(k)); err != nil {
+			return err
+		}
+	}
+	if err := b.Delete(appliedKey(ts.Height())); err != nil {
+		return err
+	}
+
+	return b.Commit()
+}
+
+func (x *Index) History(ctx context.Context, addr address.Address, cursor string, limit int) ([]api.AddressHistoryEntry, string, error) {
+	prefix := addrPrefix(addr)
+
+	var cursorBucket string
+	if cursor != "" {
+		parts := strings.Split(cursor, "/")
+		if len(parts) != 2 {
+			return nil, "", xerrors.Errorf("invalid cursor %q", cursor)
+		}
+		cursorBucket = parts[0]
+	}
+
+	res, err := x.ds.Query(query.Query{
+		Prefix:   bucketPrefix(addr).String(),
+		Orders:   []query.Order{query.OrderByKey{}},
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, "", xerrors.Errorf("querying buckets: %w", err)
+	}
+	bucketEntries, err := res.Rest()
+	if err != nil {
+		return nil, "", xerrors.Errorf("reading buckets: %w", err)
+	}
+
+	head := x.cs.GetHeaviestTipSet()
+	canonical := map[abi.ChainEpoch]types.TipSetKey{}
+
+	var out []api.AddressHistoryEntry
+	var next string
+	for _, be := range bucketEntries {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+
+		bucket := datastore.RawKey(be.Key).Name()
+		if bucket < cursorBucket {
+			continue
+		}
+
+		q := query.Query{
+			Prefix: prefix.ChildString(bucket).String(),
+			Orders: []query.Order{query.OrderByKey{}},
+		}
+		if bucket == cursorBucket {
+			q.Filters = []query.Filter{query.FilterKeyCompare{
+				Op:  query.GreaterThan,
+				Key: prefix.ChildString(cursor).String(),
+			}}
+		}
+
+		want := 0
+		if limit > 0 {
+			want = limit - len(out)
+		}
+
+		err := x.readBucket(ctx, q, want, head, canonical, func(key string, e api.AddressHistoryEntry) {
+			next = strings.TrimPrefix(key, prefix.String()+"/")
+			out = append(out, e)
+		}, func(key string) {
+			next = strings.TrimPrefix(key, prefix.String()+"/")
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// a short page means there is nothing after it
+	if limit <= 0 || len(out) < limit {
+		next = ""
+	}
+
+	return out, next, nil
+}
+
+// readBucket reads the entries matching the query, calling cb for entries of
+// canonical tipsets and skipped for the others. It stops once cb was called
+// want times, unless want is 0. Skipped entries don't count toward want.
+func (x *Index) readBucket(ctx context.Context, q query.Query, want int, head *types.TipSet, canonical map[abi.ChainEpoch]types.TipSetKey, cb func(string, api.AddressHistoryEntry), skipped func(string)) error {
+	res, err := x.ds.Query(q)
+	if err != nil {
+		return xerrors.Errorf("querying index: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var returned int
+	for r := range res.Next() {
+		if want > 0 && returned >= want {
+			break
+		}
+		if r.Error != nil {
+			return xerrors.Errorf("reading index: %w", r.Error)
+		}
+
+		var rec record
+		if err := json.Unmarshal(r.Value, &rec); err != nil {
+			return xerrors.Errorf("unmarshaling entry: %w", err)
+		}
+
+		// entries of tipsets reverted while the index wasn't running may
+		// linger, skip them
+		tsk, ok := canonical[rec.ExecutedEpoch]
+		if !ok {
+			if rec.ExecutedEpoch > head.Height() {
+				skipped(r.Key)
+				continue
+			}
+			xts, err := x.cs.GetTipsetByHeight(ctx, rec.ExecutedEpoch, head, false)
+			if err != nil {
+				return xerrors.Errorf("getting tipset at %d: %w", rec.ExecutedEpoch, err)
+			}
+			tsk = xts.Key()
+			canonical[rec.ExecutedEpoch] = tsk
+		}
+		if tsk != rec.ExecutedTipSet {
+			skipped(r.Key)
+			continue
+		}
+
+		cb(r.Key, rec.AddressHistoryEntry)
+		returned++
+	}
+
+	return nil
+}
+
+func addrPrefix(addr address.Address) datastore.Key {
+	return datastore.NewKey("/addr/" + addr.String())
+}
+
+func bucketPrefix(addr address.Address) datastore.Key {
+	return datastore.NewKey("/bucket/" + addr.String())
+}
+
+// bucket returns the name of the bucket of entries at the height, inverted
+// like the height in entry keys
+func bucket(h abi.ChainEpoch) string {
+	return fmt.Sprintf("%013x", uint64(1<<63-1-h)>>bucketBits)
+}
+
+func bucketKey(addr address.Address, h abi.ChainEpoch) datastore.Key {
+	return bucketPrefix(addr).ChildString(bucket(h))
+}
+
+func entryKey(addr address.Address, h abi.ChainEpoch, msgIdx, seq int) datastore.Key {
+	// invert the height and message index so that newer entries sort first
+	return addrPrefix(addr).ChildString(bucket(h)).ChildString(fmt.Sprintf("%016x%08x%08x", uint64(1<<63-1-h), uint32(1<<32-1-msgIdx), seq))
+}
+
+func appliedKey(h abi.ChainEpoch) datastore.Key {
+	return datastore.NewKey("/applied/" + strconv.FormatInt(int64(h), 10))
+}
+
+func (x *Index) getHeight(k datastore.Key) (abi.ChainEpoch, bool, error) {
+	v, err := x.ds.Get(k)
+	if err == datastore.ErrNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, xerrors.Errorf("getting %s: %w", k, err)
+	}
+
+	h, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, false, xerrors.Errorf("parsing %s: %w", k, err)
+	}
+
+	return abi.ChainEpoch(h), true, nil
+}
+
+func (x *Index) putHeight(k datastore.Key, h abi.ChainEpoch) error {
+	if err := x.ds.Put(k, []byte(strconv.FormatInt(int64(h), 10))); err != nil {
+		return xerrors.Errorf("putting %s: %w", k, err)
+	}
+	return nil
+}
+
+var _ AddrIndex = (*Index)(nil)
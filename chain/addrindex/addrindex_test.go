@@ -0,0 +1,285 @@
+package addrindex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+)
+
+type testChain struct {
+	head    *types.TipSet
+	tipsets map[types.TipSetKey]*types.TipSet
+	traces  map[types.TipSetKey][]*api.InvocResult
+	notifee store.ReorgNotifee
+}
+
+func (tc *testChain) GetHeaviestTipSet() *types.TipSet {
+	return tc.head
+}
+
+func (tc *testChain) LoadTipSet(tsk types.TipSetKey) (*types.TipSet, error) {
+	return tc.tipsets[tsk], nil
+}
+
+func (tc *testChain) GetTipsetByHeight(_ context.Context, h abi.ChainEpoch, ts *types.TipSet, _ bool) (*types.TipSet, error) {
+	for ts.Height() > h {
+		ts = tc.tipsets[ts.Parents()]
+	}
+	return ts, nil
+}
+
+func (tc *testChain) SubscribeHeadChanges(n store.ReorgNotifee) {
+	tc.notifee = n
+}
+
+func (tc *testChain) ExecutionTrace(_ context.Context, ts *types.TipSet) (cid.Cid, []*api.InvocResult, error) {
+	return cid.Undef, tc.traces[ts.Key()], nil
+}
+
+func (tc *testChain) LookupID(_ context.Context, a address.Address, _ *types.TipSet) (address.Address, error) {
+	return a, nil
+}
+
+func (tc *testChain) grow(parent *types.TipSet, nonce uint64, trace ...*api.InvocResult) *types.TipSet {
+	return tc.growAt(parent, parent.Height()+1, nonce, trace...)
+}
+
+func (tc *testChain) growAt(parent *types.TipSet, h abi.ChainEpoch, nonce uint64, trace ...*api.InvocResult) *types.TipSet {
+	blk := mock.MkBlock(parent, 1, nonce)
+	blk.Height = h
+	ts := mock.TipSet(blk)
+	tc.tipsets[ts.Key()] = ts
+	tc.traces[ts.Key()] = trace
+	return ts
+}
+
+func invoc(from, to address.Address, value int64, subcalls ...types.ExecutionTrace) *api.InvocResult {
+	msg := &types.Message{From: from, To: to, Value: types.NewInt(uint64(value))}
+	return &api.InvocResult{
+		MsgCid: msg.Cid(),
+		Msg:    msg,
+		MsgRct: &types.MessageReceipt{GasUsed: 10},
+		ExecutionTrace: types.ExecutionTrace{
+			Msg:      msg,
+			MsgRct:   &types.MessageReceipt{},
+			Subcalls: subcalls,
+		},
+	}
+}
+
+func TestIndexHistory(t *testing.T) {
+	ctx := context.Background()
+
+	alice, bob, fund := mock.Address(100), mock.Address(101), mock.Address(102)
+
+	gen := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	tc := &testChain{
+		head:    gen,
+		tipsets: map[types.TipSetKey]*types.TipSet{gen.Key(): gen},
+		traces:  map[types.TipSetKey][]*api.InvocResult{},
+	}
+
+	x := New(dssync.MutexWrap(datastore.NewMapDatastore()), tc, tc)
+
+	send := invoc(alice, bob, 5)
+	// a call to the fund paying out to alice, and a call without value
+	payout := invoc(bob, fund, 0,
+		types.ExecutionTrace{Msg: &types.Message{From: fund, To: alice, Value: types.NewInt(7)}},
+		types.ExecutionTrace{Msg: &types.Message{From: fund, To: bob, Value: types.NewInt(0)}},
+	)
+
+	ts1 := tc.grow(gen, 1, send)
+	ts2 := tc.grow(ts1, 2, payout)
+	ts3 := tc.grow(ts2, 3)
+	tc.head = ts3
+	require.NoError(t, x.headChange(nil, []*types.TipSet{ts1, ts2, ts3}))
+
+	entries, cursor, err := x.History(ctx, alice, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, "", cursor)
+	require.Len(t, entries, 2)
+
+	// newest first
+	require.Equal(t, api.AddressHistoryTransfer, entries[0].Type)
+	require.Equal(t, api.AddressHistoryIn, entries[0].Direction)
+	require.Equal(t, payout.MsgCid, entries[0].Message)
+	require.True(t, types.NewInt(7).Equals(entries[0].Value))
+	require.Equal(t, ts2.Key(), entries[0].TipSet)
+
+	require.Equal(t, api.AddressHistoryMessage, entries[1].Type)
+	require.Equal(t, api.AddressHistoryOut, entries[1].Direction)
+	require.Equal(t, int64(10), entries[1].Receipt.GasUsed)
+
+	// paging
+	entries, cursor, err = x.History(ctx, bob, "", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, payout.MsgCid, entries[0].Message)
+	require.NotEqual(t, "", cursor)
+
+	entries, cursor, err = x.History(ctx, bob, cursor, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, send.MsgCid, entries[0].Message)
+	require.Equal(t, api.AddressHistoryIn, entries[0].Direction)
+	require.Equal(t, "", cursor)
+
+	// reorg to a fork without the payout message
+	ts2b := tc.grow(ts1, 4)
+	tc.head = ts2b
+	require.NoError(t, x.headChange([]*types.TipSet{ts3, ts2}, []*types.TipSet{ts2b}))
+
+	entries, _, err = x.History(ctx, alice, "", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, send.MsgCid, entries[0].Message)
+	require.Equal(t, ts2b.Key(), entries[0].ExecutedTipSet)
+}
+
+func TestIndexHistoryBuckets(t *testing.T) {
+	ctx := context.Background()
+
+	alice, bob := mock.Address(100), mock.Address(101)
+
+	gen := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	tc := &testChain{
+		head:    gen,
+		tipsets: map[types.TipSetKey]*types.TipSet{gen.Key(): gen},
+		traces:  map[types.TipSetKey][]*api.InvocResult{},
+	}
+
+	x := New(dssync.MutexWrap(datastore.NewMapDatastore()), tc, tc)
+
+	// messages spread over several buckets, with a gap of empty buckets
+	var sends []*api.InvocResult
+	var applied []*types.TipSet
+	ts := gen
+	for i, h := range []abi.ChainEpoch{1, 2, 5000, 5001, 30000, 30001} {
+		send := invoc(alice, bob, int64(i+1))
+		sends = append(sends, send)
+		// the message is executed by the child of its tipset
+		ts = tc.growAt(ts, h, uint64(2*i+1), send)
+		applied = append(applied, ts)
+		ts = tc.growAt(ts, h+1, uint64(2*i+2))
+		applied = append(applied, ts)
+	}
+	tc.head = ts
+	require.NoError(t, x.headChange(nil, applied))
+
+	var got []cid.Cid
+	var cursor string
+	for {
+		entries, next, err := x.History(ctx, bob, cursor, 2)
+		require.NoError(t, err)
+		for _, e := range entries {
+			got = append(got, e.Message)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	require.Len(t, got, len(sends))
+	for i, send := range sends {
+		require.Equal(t, send.MsgCid, got[len(got)-1-i])
+	}
+
+	_, _, err := x.History(ctx, bob, "bad", 2)
+	require.Error(t, err)
+}
+
+func TestIndexHistorySkipsStale(t *testing.T) {
+	ctx := context.Background()
+
+	alice, bob, fund := mock.Address(100), mock.Address(101), mock.Address(102)
+
+	gen := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	tc := &testChain{
+		head:    gen,
+		tipsets: map[types.TipSetKey]*types.TipSet{gen.Key(): gen},
+		traces:  map[types.TipSetKey][]*api.InvocResult{},
+	}
+
+	x := New(dssync.MutexWrap(datastore.NewMapDatastore()), tc, tc)
+
+	send := invoc(alice, bob, 5)
+	payout := invoc(bob, fund, 0,
+		types.ExecutionTrace{Msg: &types.Message{From: fund, To: alice, Value: types.NewInt(7)}},
+	)
+
+	ts1 := tc.grow(gen, 1, send)
+	ts2 := tc.grow(ts1, 2, payout)
+	ts3 := tc.grow(ts2, 3)
+	tc.head = ts3
+	require.NoError(t, x.headChange(nil, []*types.TipSet{ts1, ts2, ts3}))
+
+	// the tipset executing the payout is reverted while the index isn't
+	// running, so its entry lingers
+	tc.head = tc.growAt(ts2, 3, 4)
+
+	// the stale entry doesn't take a slot of the page
+	entries, cursor, err := x.History(ctx, alice, "", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, send.MsgCid, entries[0].Message)
+
+	entries, cursor, err = x.History(ctx, alice, cursor, 1)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Equal(t, "", cursor)
+}
+
+func TestIndexHeadChangeCoalesced(t *testing.T) {
+	ctx := context.Background()
+
+	alice, bob := mock.Address(100), mock.Address(101)
+
+	gen := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	tc := &testChain{
+		head:    gen,
+		tipsets: map[types.TipSetKey]*types.TipSet{gen.Key(): gen},
+		traces:  map[types.TipSetKey][]*api.InvocResult{},
+	}
+
+	var sends []*api.InvocResult
+	var heads []*types.TipSet
+	ts := gen
+	for i := 0; i < 200; i++ {
+		send := invoc(alice, bob, int64(i+1))
+		sends = append(sends, send)
+		ts = tc.grow(ts, uint64(i+1), send)
+		heads = append(heads, ts)
+	}
+	ts = tc.grow(ts, 1000)
+	heads = append(heads, ts)
+
+	x := New(dssync.MutexWrap(datastore.NewMapDatastore()), tc, tc)
+	x.Start()
+	defer x.Close() //nolint:errcheck
+
+	// notifications don't wait for the index, which catches up with the
+	// latest head
+	tc.head = ts
+	for _, h := range heads {
+		require.NoError(t, tc.notifee(nil, []*types.TipSet{h}))
+	}
+
+	require.Eventually(t, func() bool {
+		entries, _, err := x.History(ctx, bob, "", 0)
+		return err == nil && len(entries) == len(sends)
+	}, 10*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,186 @@
+package economics
+
+import (
+	"context"
+	"math/big"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/metrics"
+)
+
+// CollectorAPI is the full node API needed to run the collector
+type CollectorAPI interface {
+	SampleAPI
+	ChainNotify(context.Context) (<-chan []*api.HeadChange, error)
+}
+
+// Collector samples the economics at each head change and records them as
+// the metrics.EconomicsViews gauges.
+type Collector struct {
+	api CollectorAPI
+	// candidates is the number of candidates with the most votes whose votes
+	// are recorded
+	candidates int
+	// recorded are the candidates whose votes were last recorded
+	recorded map[string]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCollector returns a collector recording the votes of the given number
+// of candidates with the most votes, besides the total.
+func NewCollector(api CollectorAPI, candidates int) *Collector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Collector{
+		api:        api,
+		candidates: candidates,
+		recorded:   map[string]struct{}{},
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+func (c *Collector) Start() error {
+	if err := view.Register(metrics.EconomicsViews...); err != nil {
+		return xerrors.Errorf("registering economics views: %w", err)
+	}
+
+	notifs, err := c.api.ChainNotify(c.ctx)
+	if err != nil {
+		return xerrors.Errorf("subscribing to head changes: %w", err)
+	}
+
+	go c.run(notifs)
+	return nil
+}
+
+func (c *Collector) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *Collector) run(notifs <-chan []*api.HeadChange) {
+	defer close(c.done)
+
+	// sampling can take longer than a head change, only the latest head is
+	// sampled
+	heads := make(chan *types.TipSet, 1)
+	go func() {
+		defer close(heads)
+		for {
+			select {
+			case changes, ok := <-notifs:
+				if !ok {
+					log.Warn("head change notifications closed, stopping economics collector")
+					return
+				}
+
+				var head *types.TipSet
+				for _, hc := range changes {
+					if hc.Type == store.HCApply || hc.Type == store.HCCurrent {
+						head = hc.Val
+					}
+				}
+				if head == nil {
+					continue
+				}
+
+				// replace the head waiting to be sampled
+				select {
+				case <-heads:
+				default:
+				}
+				heads <- head
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for ts := range heads {
+		s, err := Take(c.ctx, c.api, ts)
+		if err != nil {
+			if c.ctx.Err() == nil {
+				log.Warnw("sampling economics", "height", ts.Height(), "error", err)
+			}
+			continue
+		}
+		c.record(s)
+	}
+}
+
+// record records the sample, with the votes of the top candidates. The gauges
+// of the candidates which aren't in the top anymore are zeroed, as views keep
+// the last value of every candidate recorded.
+func (c *Collector) record(s *Sample) {
+	s.Record(c.ctx)
+
+	top := map[string]struct{}{}
+	for _, candidate := range s.TopCandidates(c.candidates) {
+		top[candidate] = struct{}{}
+		recordCandidateVotes(c.ctx, candidate, EPK(s.CandidateVotes[candidate]))
+	}
+	for candidate := range c.recorded {
+		if _, ok := top[candidate]; !ok {
+			recordCandidateVotes(c.ctx, candidate, 0)
+		}
+	}
+	c.recorded = top
+}
+
+func recordCandidateVotes(ctx context.Context, candidate string, votes float64) {
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(metrics.Type, "candidate"),
+		tag.Upsert(metrics.Candidate, candidate),
+	}, metrics.EconomicsVotes.M(votes))
+}
+
+// Record records the sample as the metrics.EconomicsViews gauges, except for
+// the votes by candidate which are recorded by the collector.
+func (s *Sample) Record(ctx context.Context) {
+	stats.Record(ctx, metrics.EconomicsHeight.M(int64(s.Height)))
+
+	for _, status := range ExpertStatuses {
+		stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(metrics.Type, status)}, metrics.EconomicsExperts.M(s.Experts[status]))
+	}
+
+	stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(metrics.Type, "total")}, metrics.EconomicsVotes.M(EPK(s.TotalVotes)))
+
+	for typ, amt := range map[string]abi.TokenAmount{
+		"pledge":         s.RetrievalPledge,
+		"reward":         s.RetrievalReward,
+		"pending_reward": s.RetrievalPendingReward,
+	} {
+		stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(metrics.Type, typ)}, metrics.EconomicsRetrieval.M(EPK(amt)))
+	}
+
+	for typ, amt := range map[string]abi.TokenAmount{
+		"expertfund":     s.ExpertFundPool,
+		"knowledge":      s.KnowledgeFund,
+		"vesting_locked": s.VestingLocked,
+	} {
+		stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(metrics.Type, typ)}, metrics.EconomicsFunds.M(EPK(amt)))
+	}
+}
+
+// EPK converts an amount to EPK, the unit of the gauges.
+func EPK(amt abi.TokenAmount) float64 {
+	if amt.Int == nil {
+		return 0
+	}
+	f, _ := new(big.Rat).SetFrac(amt.Int, big.NewInt(int64(build.EpkPrecision))).Float64()
+	return f
+}
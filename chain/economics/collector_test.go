@@ -0,0 +1,114 @@
+package economics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/metrics"
+)
+
+func TestSampleRecord(t *testing.T) {
+	req := require.New(t)
+
+	req.NoError(view.Register(metrics.EconomicsViews...))
+	defer view.Unregister(metrics.EconomicsViews...)
+
+	epk := func(n int64) abi.TokenAmount {
+		return big.Mul(big.NewInt(n), big.NewInt(int64(build.EpkPrecision)))
+	}
+
+	s := &Sample{
+		Height:  100,
+		Experts: map[string]int64{"qualified": 3, "registered": 1},
+
+		TotalVotes:     epk(30),
+		CandidateVotes: map[string]abi.TokenAmount{"f01000": epk(10), "f01001": epk(20)},
+
+		RetrievalPledge:        epk(5),
+		RetrievalReward:        epk(2),
+		RetrievalPendingReward: big.Zero(),
+
+		ExpertFundPool: epk(7),
+		KnowledgeFund:  epk(1),
+		VestingLocked:  epk(1000),
+	}
+	s.Record(context.Background())
+
+	// every status is recorded, the votes by candidate are left to the
+	// collector
+	rows, err := view.RetrieveData(metrics.EconomicsExpertsView.Name)
+	req.NoError(err)
+	req.Len(rows, len(ExpertStatuses))
+
+	rows, err = view.RetrieveData(metrics.EconomicsVotesView.Name)
+	req.NoError(err)
+	req.Len(rows, 1)
+
+	rows, err = view.RetrieveData(metrics.EconomicsFundsView.Name)
+	req.NoError(err)
+	req.Len(rows, 3)
+	for _, row := range rows {
+		if row.Tags[0].Value == "vesting_locked" {
+			req.Equal(1000.0, row.Data.(*view.LastValueData).Value)
+		}
+	}
+
+	req.Equal(1.5, EPK(big.Div(epk(3), big.NewInt(2))))
+}
+
+func TestCollectorRecordCandidates(t *testing.T) {
+	req := require.New(t)
+
+	req.NoError(view.Register(metrics.EconomicsViews...))
+	defer view.Unregister(metrics.EconomicsViews...)
+
+	votes := func() map[string]float64 {
+		rows, err := view.RetrieveData(metrics.EconomicsVotesView.Name)
+		req.NoError(err)
+		out := map[string]float64{}
+		for _, row := range rows {
+			for _, tg := range row.Tags {
+				if tg.Key == metrics.Candidate {
+					out[tg.Value] = row.Data.(*view.LastValueData).Value
+				}
+			}
+		}
+		return out
+	}
+
+	sample := func(candidates map[string]int64) *Sample {
+		s := &Sample{
+			Experts:        map[string]int64{},
+			TotalVotes:     big.Zero(),
+			CandidateVotes: map[string]abi.TokenAmount{},
+		}
+		for c, n := range candidates {
+			s.CandidateVotes[c] = big.Mul(big.NewInt(n), big.NewInt(int64(build.EpkPrecision)))
+			s.TotalVotes = big.Add(s.TotalVotes, s.CandidateVotes[c])
+		}
+		return s
+	}
+
+	c := NewCollector(nil, 2)
+
+	// only the top candidates are recorded
+	c.record(sample(map[string]int64{"f01000": 10, "f01001": 20, "f01002": 5}))
+	req.Equal(map[string]float64{"f01000": 10, "f01001": 20}, votes())
+
+	// candidates which disappear or leave the top are zeroed
+	c.record(sample(map[string]int64{"f01001": 20, "f01002": 30, "f01003": 1}))
+	req.Equal(map[string]float64{"f01000": 0, "f01001": 20, "f01002": 30}, votes())
+
+	// none by default
+	c = NewCollector(nil, 0)
+	c.recorded = map[string]struct{}{"f01001": {}, "f01002": {}}
+	c.record(sample(map[string]int64{"f01001": 20}))
+	req.Equal(map[string]float64{"f01000": 0, "f01001": 0, "f01002": 0}, votes())
+}
@@ -0,0 +1,181 @@
+// Package economics samples the network-level economics of the EpiK actors:
+// experts, votes, retrieval pledges and rewards, and the funds held by the
+// expertfund, knowledge and vesting actors.
+package economics
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	expert2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expert"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expertfund"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/knowledge"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vesting"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+var log = logging.Logger("economics")
+
+// SampleAPI is the subset of the full node API a sample is taken with, in
+// process or over RPC.
+type SampleAPI interface {
+	ChainReadObj(context.Context, cid.Cid) ([]byte, error)
+	ChainHasObj(context.Context, cid.Cid) (bool, error)
+	StateGetActor(context.Context, address.Address, types.TipSetKey) (*types.Actor, error)
+	StateListExperts(context.Context, types.TipSetKey) ([]address.Address, error)
+	StateVoteTally(context.Context, types.TipSetKey) (*vote.Tally, error)
+	StateRetrievalInfo(context.Context, types.TipSetKey) (*api.RetrievalInfo, error)
+}
+
+// Sample holds the economics of the network at a tipset.
+type Sample struct {
+	Height abi.ChainEpoch
+
+	// Experts is the number of experts by status
+	Experts map[string]int64
+
+	// TotalVotes is the total of the votes tallied by the vote actor
+	TotalVotes abi.TokenAmount
+	// CandidateVotes are the votes of each candidate
+	CandidateVotes map[string]abi.TokenAmount
+
+	RetrievalPledge        abi.TokenAmount
+	RetrievalReward        abi.TokenAmount
+	RetrievalPendingReward abi.TokenAmount
+
+	// ExpertFundPool is the balance of the expertfund actor
+	ExpertFundPool abi.TokenAmount
+	// KnowledgeFund is the balance of the knowledge fund actor
+	KnowledgeFund abi.TokenAmount
+	// VestingLocked is the total of the funds locked by the vesting actor
+	VestingLocked abi.TokenAmount
+}
+
+// ExpertStatuses are the names of the expert statuses, the experts are counted
+// for each of them even when there is none.
+var ExpertStatuses = []string{"registered", "unqualified", "qualified", "blocked", "unknown"}
+
+// ExpertStatus returns the name of an expert status used in the samples.
+func ExpertStatus(s expert2.ExpertState) string {
+	switch s {
+	case expert2.ExpertStateRegistered:
+		return "registered"
+	case expert2.ExpertStateUnqualified:
+		return "unqualified"
+	case expert2.ExpertStateQualified:
+		return "qualified"
+	case expert2.ExpertStateBlocked:
+		return "blocked"
+	default:
+		return "unknown"
+	}
+}
+
+// TopCandidates returns the n candidates with the most votes, by decreasing
+// votes.
+func (s *Sample) TopCandidates(n int) []string {
+	out := make([]string, 0, len(s.CandidateVotes))
+	for candidate := range s.CandidateVotes {
+		out = append(out, candidate)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		vi, vj := s.CandidateVotes[out[i]], s.CandidateVotes[out[j]]
+		if !vi.Equals(vj) {
+			return vi.GreaterThan(vj)
+		}
+		return out[i] < out[j]
+	})
+	if n < 0 {
+		n = 0
+	}
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Take samples the economics of the network at the given tipset.
+func Take(ctx context.Context, sapi SampleAPI, ts *types.TipSet) (*Sample, error) {
+	tsk := ts.Key()
+	store := adt.WrapStore(ctx, cbor.NewCborStore(blockstore.NewAPIBlockstore(sapi)))
+
+	out := &Sample{
+		Height:         ts.Height(),
+		Experts:        map[string]int64{},
+		CandidateVotes: map[string]abi.TokenAmount{},
+	}
+
+	experts, err := sapi.StateListExperts(ctx, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("listing experts: %w", err)
+	}
+	for _, addr := range experts {
+		act, err := sapi.StateGetActor(ctx, addr, tsk)
+		if err != nil {
+			return nil, xerrors.Errorf("loading expert actor %s: %w", addr, err)
+		}
+		st, err := expert.Load(store, act)
+		if err != nil {
+			return nil, xerrors.Errorf("loading expert state %s: %w", addr, err)
+		}
+		info, err := st.Info()
+		if err != nil {
+			return nil, xerrors.Errorf("getting expert info %s: %w", addr, err)
+		}
+		out.Experts[ExpertStatus(info.Status)]++
+	}
+
+	tally, err := sapi.StateVoteTally(ctx, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("getting vote tally: %w", err)
+	}
+	out.TotalVotes = tally.TotalVotes
+	for candidate, votes := range tally.Candidates {
+		out.CandidateVotes[candidate] = votes
+	}
+
+	ri, err := sapi.StateRetrievalInfo(ctx, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("getting retrieval info: %w", err)
+	}
+	out.RetrievalPledge = ri.TotalPledge
+	out.RetrievalReward = ri.TotalReward
+	out.RetrievalPendingReward = ri.PendingReward
+
+	efAct, err := sapi.StateGetActor(ctx, expertfund.Address, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expertfund actor: %w", err)
+	}
+	out.ExpertFundPool = efAct.Balance
+
+	kAct, err := sapi.StateGetActor(ctx, knowledge.Address, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading knowledge actor: %w", err)
+	}
+	out.KnowledgeFund = kAct.Balance
+
+	vAct, err := sapi.StateGetActor(ctx, vesting.Address, tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vesting actor: %w", err)
+	}
+	vst, err := vesting.Load(store, vAct)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vesting state: %w", err)
+	}
+	out.VestingLocked = vst.TotalLocked()
+
+	return out, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"time"
 
@@ -16,12 +17,16 @@ import (
 	"golang.org/x/xerrors"
 
 	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
 
+	bstore "github.com/EpiK-Protocol/go-epik/blockstore"
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	incrt "github.com/EpiK-Protocol/go-epik/lib/increadtimeout"
 	"github.com/EpiK-Protocol/go-epik/lib/peermgr"
+	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 )
 
 // client implements exchange.Client, using the libp2p ChainExchange protocol
@@ -40,10 +45,10 @@ var _ Client = (*client)(nil)
 
 // NewClient creates a new libp2p-based exchange.Client that uses the libp2p
 // ChainExhange protocol as the fetching mechanism.
-func NewClient(lc fx.Lifecycle, host host.Host, pmgr peermgr.MaybePeerMgr) Client {
+func NewClient(lc fx.Lifecycle, host host.Host, pmgr peermgr.MaybePeerMgr, ds dtypes.MetadataDS) Client {
 	return &client{
 		host:        host,
-		peerTracker: newPeerTracker(lc, host, pmgr.Mgr),
+		peerTracker: newPeerTracker(lc, host, pmgr.Mgr, ds),
 	}
 }
 
@@ -90,6 +95,9 @@ func (c *client) doRequest(
 	// by an internal peer tracker with some randomness injected).
 	var peers []peer.ID
 	if singlePeer != nil {
+		if c.peerTracker.isBanned(*singlePeer) {
+			return nil, xerrors.Errorf("peer %s is banned", *singlePeer)
+		}
 		peers = []peer.ID{*singlePeer}
 	} else {
 		peers = c.getShuffledPeers()
@@ -123,13 +131,14 @@ func (c *client) doRequest(
 		}
 
 		// Process and validate response.
-		validRes, err := c.processResponse(req, res, tipsets)
+		validRes, err := c.processResponse(peer, req, res, tipsets)
 		if err != nil {
 			log.Warnf("processing peer %s response failed: %s",
 				peer.String(), err)
 			continue
 		}
 
+		c.peerTracker.logValid(peer)
 		c.peerTracker.logGlobalSuccess(build.Clock.Since(globalTime))
 		c.host.ConnManager().TagPeer(peer, "bsync", SuccessPeerTagValue)
 		return validRes, nil
@@ -148,34 +157,49 @@ func (c *client) doRequest(
 // need.
 //
 // We are conflating in the single error returned both status and validation
-// errors. Peer penalization should happen here then, before returning, so
-// we can apply the correct penalties depending on the cause of the error.
-// FIXME: Add the `peer` as argument once we implement penalties.
-func (c *client) processResponse(req *Request, res *Response, tipsets []*types.TipSet) (*validatedResponse, error) {
+// errors. Peer penalization happens here then, before returning, so we can
+// apply the correct penalties depending on the cause of the error. Status
+// errors are not penalised, as those are the peer telling us it can't serve
+// the request.
+func (c *client) processResponse(p peer.ID, req *Request, res *Response, tipsets []*types.TipSet) (*validatedResponse, error) {
 	err := res.statusToError()
 	if err != nil {
 		return nil, xerrors.Errorf("status error: %s", err)
 	}
 
+	validRes, penalty, err := c.validateResponse(req, res, tipsets)
+	if err != nil {
+		c.peerTracker.penalize(p, penalty)
+		return nil, err
+	}
+
+	return validRes, nil
+}
+
+// validateResponse checks a response with a successful status, returning the
+// penalty for the peer if the response is invalid.
+func (c *client) validateResponse(req *Request, res *Response, tipsets []*types.TipSet) (*validatedResponse, Penalty, error) {
+	var err error
+
 	options := parseOptions(req.Options)
 	if options.noOptionsSet() {
 		// Safety check: this shouldn't have been sent, and even if it did
 		// it should have been caught by the peer in its error status.
-		return nil, xerrors.Errorf("nothing was requested")
+		return nil, PenaltyInvalidResponse, xerrors.Errorf("nothing was requested")
 	}
 
 	// Verify that the chain segment returned is in the valid range.
 	// Note that the returned length might be less than requested.
 	resLength := len(res.Chain)
 	if resLength == 0 {
-		return nil, xerrors.Errorf("got no chain in successful response")
+		return nil, PenaltyShortResponse, xerrors.Errorf("got no chain in successful response")
 	}
 	if resLength > int(req.Length) {
-		return nil, xerrors.Errorf("got longer response (%d) than requested (%d)",
+		return nil, PenaltyInvalidResponse, xerrors.Errorf("got longer response (%d) than requested (%d)",
 			resLength, req.Length)
 	}
 	if resLength < int(req.Length) && res.Status != Partial {
-		return nil, xerrors.Errorf("got less than requested without a proper status: %d", res.Status)
+		return nil, PenaltyShortResponse, xerrors.Errorf("got less than requested without a proper status: %d", res.Status)
 	}
 
 	validRes := &validatedResponse{}
@@ -184,30 +208,30 @@ func (c *client) processResponse(req *Request, res *Response, tipsets []*types.T
 		validRes.tipsets = make([]*types.TipSet, resLength)
 		for i := 0; i < resLength; i++ {
 			if res.Chain[i] == nil {
-				return nil, xerrors.Errorf("response with nil tipset in pos %d", i)
+				return nil, PenaltyInvalidResponse, xerrors.Errorf("response with nil tipset in pos %d", i)
 			}
 			for blockIdx, block := range res.Chain[i].Blocks {
 				if block == nil {
-					return nil, xerrors.Errorf("tipset with nil block in pos %d", blockIdx)
+					return nil, PenaltyInvalidResponse, xerrors.Errorf("tipset with nil block in pos %d", blockIdx)
 					// FIXME: Maybe we should move this check to `NewTipSet`.
 				}
 			}
 
 			validRes.tipsets[i], err = types.NewTipSet(res.Chain[i].Blocks)
 			if err != nil {
-				return nil, xerrors.Errorf("invalid tipset blocks at height (head - %d): %w", i, err)
+				return nil, PenaltyInvalidResponse, xerrors.Errorf("invalid tipset blocks at height (head - %d): %w", i, err)
 			}
 		}
 
 		// Check that the returned head matches the one requested.
 		if !types.CidArrsEqual(validRes.tipsets[0].Cids(), req.Head) {
-			return nil, xerrors.Errorf("returned chain head does not match request")
+			return nil, PenaltyInvalidResponse, xerrors.Errorf("returned chain head does not match request")
 		}
 
 		// Check `TipSet`s are connected (valid chain).
 		for i := 0; i < len(validRes.tipsets)-1; i++ {
 			if validRes.tipsets[i].IsChildOf(validRes.tipsets[i+1]) == false {
-				return nil, fmt.Errorf("tipsets are not connected at height (head - %d)/(head - %d)",
+				return nil, PenaltyInvalidResponse, fmt.Errorf("tipsets are not connected at height (head - %d)/(head - %d)",
 					i, i+1)
 				// FIXME: Maybe give more information here, like CIDs.
 			}
@@ -218,26 +242,19 @@ func (c *client) processResponse(req *Request, res *Response, tipsets []*types.T
 		validRes.messages = make([]*CompactedMessages, resLength)
 		for i := 0; i < resLength; i++ {
 			if res.Chain[i].Messages == nil {
-				return nil, xerrors.Errorf("no messages included for tipset at height (head - %d)", i)
+				return nil, PenaltyShortResponse, xerrors.Errorf("no messages included for tipset at height (head - %d)", i)
 			}
 			validRes.messages[i] = res.Chain[i].Messages
 		}
 
-		if options.IncludeHeaders {
-			// If the headers were also returned check that the compression
-			// indexes are valid before `toFullTipSets()` is called by the
-			// consumer.
-			err := c.validateCompressedIndices(res.Chain)
-			if err != nil {
-				return nil, err
-			}
-		} else {
+		chain := res.Chain
+		if !options.IncludeHeaders {
 			// If we didn't request the headers they should have been provided
 			// by the caller.
 			if len(tipsets) < len(res.Chain) {
-				return nil, xerrors.Errorf("not enought tipsets provided for message response validation, needed %d, have %d", len(res.Chain), len(tipsets))
+				return nil, PenaltyInvalidResponse, xerrors.Errorf("not enought tipsets provided for message response validation, needed %d, have %d", len(res.Chain), len(tipsets))
 			}
-			chain := make([]*BSTipSet, 0, resLength)
+			chain = make([]*BSTipSet, 0, resLength)
 			for i, resChain := range res.Chain {
 				next := &BSTipSet{
 					Blocks:   tipsets[i].Blocks(),
@@ -245,15 +262,20 @@ func (c *client) processResponse(req *Request, res *Response, tipsets []*types.T
 				}
 				chain = append(chain, next)
 			}
+		}
 
-			err := c.validateCompressedIndices(chain)
-			if err != nil {
-				return nil, err
-			}
+		// Check that the compression indexes are valid before
+		// `toFullTipSets()` is called by the consumer.
+		if err := c.validateCompressedIndices(chain); err != nil {
+			return nil, PenaltyInvalidResponse, err
+		}
+
+		if err := c.validateMsgMetas(chain); err != nil {
+			return nil, PenaltyMismatchedMsgMeta, err
 		}
 	}
 
-	return validRes, nil
+	return validRes, 0, nil
 }
 
 func (c *client) validateCompressedIndices(chain []*BSTipSet) error {
@@ -292,6 +314,37 @@ func (c *client) validateCompressedIndices(chain []*BSTipSet) error {
 	return nil
 }
 
+// validateMsgMetas checks that the messages of each block match the message
+// root in its header.
+func (c *client) validateMsgMetas(chain []*BSTipSet) error {
+	cst := cbor.NewCborStore(bstore.NewMemory())
+	for tipsetIdx, bts := range chain {
+		msgs := bts.Messages
+		for blockIdx, b := range bts.Blocks {
+			bmsgCids := make([]cid.Cid, 0, len(msgs.BlsIncludes[blockIdx]))
+			for _, mi := range msgs.BlsIncludes[blockIdx] {
+				bmsgCids = append(bmsgCids, msgs.Bls[mi].Cid())
+			}
+
+			smsgCids := make([]cid.Cid, 0, len(msgs.SecpkIncludes[blockIdx]))
+			for _, mi := range msgs.SecpkIncludes[blockIdx] {
+				smsgCids = append(smsgCids, msgs.Secpk[mi].Cid())
+			}
+
+			mrcid, err := store.ComputeMsgMeta(cst, bmsgCids, smsgCids)
+			if err != nil {
+				return xerrors.Errorf("computing msgmeta: %w", err)
+			}
+
+			if b.Messages != mrcid {
+				return xerrors.Errorf("messages of block %s at height (head - %d) don't match message root in header", b.Cid(), tipsetIdx)
+			}
+		}
+	}
+
+	return nil
+}
+
 // GetBlocks implements Client.GetBlocks(). Refer to the godocs there.
 func (c *client) GetBlocks(ctx context.Context, tsk types.TipSetKey, count int) ([]*types.TipSet, error) {
 	ctx, span := trace.StartSpan(ctx, "bsync.GetBlocks")
@@ -422,6 +475,10 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 	if err != nil {
 		_ = stream.SetWriteDeadline(time.Time{})
 		c.peerTracker.logFailure(peer, build.Clock.Since(connectionStart), req.Length)
+		// the peer isn't at fault if we gave up on the request ourselves
+		if ctx.Err() == nil {
+			c.peerTracker.penalize(peer, PenaltyTimeout)
+		}
 		// FIXME: Should we also remove peer here?
 		return nil, err
 	}
@@ -430,11 +487,19 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 
 	// Read response.
 	var res Response
-	err = cborutil.ReadCborRPC(
-		bufio.NewReader(incrt.New(stream, ReadResMinSpeed, ReadResDeadline)),
-		&res)
+	rd := &streamErrReader{r: incrt.New(stream, ReadResMinSpeed, ReadResDeadline)}
+	err = cborutil.ReadCborRPC(bufio.NewReader(rd), &res)
 	if err != nil {
 		c.peerTracker.logFailure(peer, build.Clock.Since(connectionStart), req.Length)
+		switch {
+		case ctx.Err() != nil:
+			// we gave up on the request ourselves
+		case rd.err != nil && rd.err != io.EOF:
+			c.peerTracker.penalize(peer, PenaltyTimeout)
+		default:
+			// the peer sent data which isn't a valid response
+			c.peerTracker.penalize(peer, PenaltyInvalidResponse)
+		}
 		return nil, xerrors.Errorf("failed to read chainxchg response: %w", err)
 	}
 
@@ -454,6 +519,21 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 	return &res, nil
 }
 
+// streamErrReader remembers the last error of the stream, so that failures
+// to read a response can be told apart from failures to decode it
+type streamErrReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *streamErrReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
 // AddPeer implements Client.AddPeer(). Refer to the godocs there.
 func (c *client) AddPeer(p peer.ID) {
 	c.peerTracker.addPeer(p)
@@ -464,6 +544,16 @@ func (c *client) RemovePeer(p peer.ID) {
 	c.peerTracker.removePeer(p)
 }
 
+// PenalizePeer implements Client.PenalizePeer(). Refer to the godocs there.
+func (c *client) PenalizePeer(p peer.ID, reason Penalty) {
+	c.peerTracker.penalize(p, reason)
+}
+
+// PeerScores implements Client.PeerScores(). Refer to the godocs there.
+func (c *client) PeerScores() []PeerScore {
+	return c.peerTracker.peerScores()
+}
+
 // getShuffledPeers returns a preference-sorted set of peers (by latency
 // and failure counting), shuffling the first few peers so we don't always
 // pick the same peer.
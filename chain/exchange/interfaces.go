@@ -47,4 +47,12 @@ type Client interface {
 	// RemovePeer removes a peer from the pool of peers that the Client
 	// requests data from.
 	RemovePeer(peer peer.ID)
+
+	// PenalizePeer lowers the score of a peer for misbehaviour noticed
+	// outside of the Client, such as sending us invalid blocks. Peers with
+	// a low enough score are banned for a while.
+	PenalizePeer(peer peer.ID, reason Penalty)
+
+	// PeerScores returns the scores of the peers known to the Client.
+	PeerScores() []PeerScore
 }
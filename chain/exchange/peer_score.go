@@ -0,0 +1,338 @@
+package exchange
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+)
+
+// Penalty is the reason a peer is penalised for a chain exchange request
+type Penalty int
+
+const (
+	// PenaltyTimeout is applied when a peer fails to answer a request in time
+	PenaltyTimeout Penalty = iota
+	// PenaltyShortResponse is applied when a peer returns less than requested
+	// without a partial status
+	PenaltyShortResponse
+	// PenaltyInvalidResponse is applied when a response fails validation
+	PenaltyInvalidResponse
+	// PenaltyMismatchedMsgMeta is applied when the messages of a response
+	// don't match the message roots of the block headers
+	PenaltyMismatchedMsgMeta
+	// PenaltyInvalidBlock is applied when a peer sends us an invalid block
+	PenaltyInvalidBlock
+)
+
+func (p Penalty) String() string {
+	switch p {
+	case PenaltyTimeout:
+		return "timeout"
+	case PenaltyShortResponse:
+		return "short-response"
+	case PenaltyInvalidResponse:
+		return "invalid-response"
+	case PenaltyMismatchedMsgMeta:
+		return "mismatched-msgmeta"
+	case PenaltyInvalidBlock:
+		return "invalid-block"
+	default:
+		return "unknown"
+	}
+}
+
+var penaltyScores = map[Penalty]float64{
+	PenaltyTimeout:           5,
+	PenaltyShortResponse:     10,
+	PenaltyInvalidResponse:   25,
+	PenaltyMismatchedMsgMeta: 50,
+	PenaltyInvalidBlock:      100,
+}
+
+const (
+	// successScore is added to the score of a peer for each valid response
+	successScore = 1
+	// maxPeerScore caps the score peers can build up with valid responses,
+	// so that a long history doesn't outweigh recent misbehaviour
+	maxPeerScore = 100
+	// goodPeerScore is the score from which connections to a peer are kept
+	goodPeerScore = 20
+	// banPeerScore is the score at which a peer is banned
+	banPeerScore = -100
+)
+
+var (
+	// PeerBanDuration is how long a peer is banned the first time, each
+	// following ban doubles the duration
+	PeerBanDuration = 10 * time.Minute
+	// MaxPeerBanDuration caps the duration of a ban
+	MaxPeerBanDuration = 24 * time.Hour
+	// PeerScoreSaveInterval is how often changed scores are saved
+	PeerScoreSaveInterval = time.Minute
+	// PeerScoreExpiry is how long the score of a peer is kept after its
+	// last request, unless the peer is banned
+	PeerScoreExpiry = 7 * 24 * time.Hour
+)
+
+const peerScoresDs = "/chainxchg/scores"
+
+// PeerScore is the reputation of a chain exchange peer
+type PeerScore struct {
+	ID    peer.ID
+	Score float64
+	// Bans is the number of times the peer was banned, it is reset once the
+	// peer behaves for MaxPeerBanDuration after a ban
+	Bans int
+	// BannedUntil is the end of the current or last ban
+	BannedUntil time.Time
+	// Penalties counts the penalties of the peer by reason
+	Penalties map[string]int
+	// LastSeen is the time of the last scored request to the peer
+	LastSeen time.Time
+}
+
+func (ps *PeerScore) banned(now time.Time) bool {
+	return now.Before(ps.BannedUntil)
+}
+
+func (ps *PeerScore) expired(now time.Time) bool {
+	return now.Sub(ps.LastSeen) > PeerScoreExpiry && !ps.banned(now)
+}
+
+// peerScores keeps the scores of chain exchange peers, including peers we
+// are not connected to anymore, so that scores and bans survive reconnects
+// and restarts
+type peerScores struct {
+	lk     sync.Mutex
+	scores map[peer.ID]*PeerScore
+	dirty  map[peer.ID]struct{}
+
+	ds datastore.Batching
+}
+
+func newPeerScores(ds datastore.Batching) *peerScores {
+	ps := &peerScores{
+		scores: map[peer.ID]*PeerScore{},
+		dirty:  map[peer.ID]struct{}{},
+	}
+	if ds != nil {
+		ps.ds = namespace.Wrap(ds, datastore.NewKey(peerScoresDs))
+	}
+	return ps
+}
+
+// load reads the saved scores
+func (ps *peerScores) load() error {
+	if ps.ds == nil {
+		return nil
+	}
+
+	res, err := ps.ds.Query(query.Query{})
+	if err != nil {
+		return xerrors.Errorf("query peer scores: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := build.Clock.Now()
+	for r := range res.Next() {
+		if r.Error != nil {
+			return xerrors.Errorf("r.Error: %w", r.Error)
+		}
+
+		var s PeerScore
+		if err := json.Unmarshal(r.Value, &s); err != nil {
+			log.Warnf("invalid peer score (key %s): %s", r.Key, err)
+			continue
+		}
+		if s.LastSeen.IsZero() {
+			// saved before scores expired, start counting now
+			s.LastSeen = now
+		}
+		ps.scores[s.ID] = &s
+	}
+
+	return nil
+}
+
+// save writes the scores changed since the last save
+func (ps *peerScores) save() error {
+	if ps.ds == nil {
+		return nil
+	}
+
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	if len(ps.dirty) == 0 {
+		return nil
+	}
+
+	b, err := ps.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	for p := range ps.dirty {
+		v, err := json.Marshal(ps.scores[p])
+		if err != nil {
+			return xerrors.Errorf("marshaling peer score: %w", err)
+		}
+		if err := b.Put(datastore.NewKey(p.String()), v); err != nil {
+			return xerrors.Errorf("putting peer score: %w", err)
+		}
+	}
+
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("committing peer scores: %w", err)
+	}
+
+	ps.dirty = map[peer.ID]struct{}{}
+	return nil
+}
+
+// expire forgets the scores of peers which weren't seen for PeerScoreExpiry
+func (ps *peerScores) expire() error {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := build.Clock.Now()
+
+	var expired []peer.ID
+	for p, s := range ps.scores {
+		if s.expired(now) {
+			expired = append(expired, p)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, p := range expired {
+		delete(ps.scores, p)
+		delete(ps.dirty, p)
+	}
+
+	if ps.ds == nil {
+		return nil
+	}
+
+	b, err := ps.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+	for _, p := range expired {
+		if err := b.Delete(datastore.NewKey(p.String())); err != nil {
+			return xerrors.Errorf("deleting peer score: %w", err)
+		}
+	}
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("committing expired peer scores: %w", err)
+	}
+
+	return nil
+}
+
+// get returns the score of the peer, creating it if needed. Must be called
+// with ps.lk held.
+func (ps *peerScores) get(p peer.ID) *PeerScore {
+	s, ok := ps.scores[p]
+	if !ok {
+		s = &PeerScore{ID: p}
+		ps.scores[p] = s
+	}
+	return s
+}
+
+// success rewards a valid response, returning the new score
+func (ps *peerScores) success(p peer.ID) float64 {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	s := ps.get(p)
+	s.LastSeen = build.Clock.Now()
+	ps.dirty[p] = struct{}{}
+
+	if s.Score < maxPeerScore {
+		s.Score += successScore
+	}
+
+	// forgive earlier bans of peers which behave for long enough
+	if s.Bans > 0 && build.Clock.Since(s.BannedUntil) > MaxPeerBanDuration {
+		s.Bans = 0
+	}
+
+	return s.Score
+}
+
+// penalize lowers the score of the peer, banning it once the score drops to
+// banPeerScore. Returns the new score and whether the peer was banned.
+func (ps *peerScores) penalize(p peer.ID, reason Penalty) (float64, bool) {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := build.Clock.Now()
+
+	s := ps.get(p)
+	s.LastSeen = now
+	if s.Penalties == nil {
+		s.Penalties = map[string]int{}
+	}
+	s.Penalties[reason.String()]++
+	s.Score -= penaltyScores[reason]
+	ps.dirty[p] = struct{}{}
+
+	if s.Score > banPeerScore || s.banned(now) {
+		return s.Score, false
+	}
+
+	ban := PeerBanDuration << uint(s.Bans)
+	if ban > MaxPeerBanDuration || ban <= 0 {
+		ban = MaxPeerBanDuration
+	}
+	s.Bans++
+	s.BannedUntil = now.Add(ban)
+
+	// give the peer a second chance after the ban, but a short one
+	s.Score = banPeerScore / 2
+
+	log.Warnw("banning chain exchange peer", "peer", p, "reason", reason, "duration", ban, "bans", s.Bans)
+
+	return s.Score, true
+}
+
+// banned returns whether the peer is currently banned
+func (ps *peerScores) banned(p peer.ID) bool {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	s, ok := ps.scores[p]
+	return ok && s.banned(build.Clock.Now())
+}
+
+// list returns a copy of all scores
+func (ps *peerScores) list() []PeerScore {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	out := make([]PeerScore, 0, len(ps.scores))
+	for _, s := range ps.scores {
+		cpy := *s
+		cpy.Penalties = make(map[string]int, len(s.Penalties))
+		for k, v := range s.Penalties {
+			cpy.Penalties[k] = v
+		}
+		out = append(out, cpy)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+)
+
+func TestPeerScoreBanBackoff(t *testing.T) {
+	ps := newPeerScores(nil)
+	p := peer.ID("peer")
+
+	// 3 invalid responses get the peer from 0 to -75, the fourth bans it
+	for i := 0; i < 3; i++ {
+		_, banned := ps.penalize(p, PenaltyInvalidResponse)
+		require.False(t, banned)
+	}
+	require.False(t, ps.banned(p))
+
+	_, banned := ps.penalize(p, PenaltyInvalidResponse)
+	require.True(t, banned)
+	require.True(t, ps.banned(p))
+
+	first := ps.list()[0]
+	require.Equal(t, 1, first.Bans)
+	require.Equal(t, float64(banPeerScore/2), first.Score)
+	require.Equal(t, 4, first.Penalties[PenaltyInvalidResponse.String()])
+	require.WithinDuration(t, build.Clock.Now().Add(PeerBanDuration), first.BannedUntil, time.Second)
+
+	// penalties while banned don't extend the ban
+	_, banned = ps.penalize(p, PenaltyInvalidBlock)
+	require.False(t, banned)
+
+	// after the ban, the next ban lasts twice as long
+	ps.scores[p].BannedUntil = build.Clock.Now().Add(-time.Second)
+	require.False(t, ps.banned(p))
+
+	_, banned = ps.penalize(p, PenaltyInvalidBlock)
+	require.True(t, banned)
+	second := ps.list()[0]
+	require.Equal(t, 2, second.Bans)
+	require.WithinDuration(t, build.Clock.Now().Add(2*PeerBanDuration), second.BannedUntil, time.Second)
+}
+
+func TestPeerScorePersist(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	// peer IDs need to be valid to round-trip through json
+	good, err := peer.Decode("12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf")
+	require.NoError(t, err)
+	bad, err := peer.Decode("QmYyQSo1c1Ym7orWxLYvCrM2EmxFTANf8wXmmE7DWjhx5N")
+	require.NoError(t, err)
+
+	ps := newPeerScores(ds)
+	for i := 0; i < 2*maxPeerScore; i++ {
+		ps.success(good)
+	}
+	ps.penalize(bad, PenaltyMismatchedMsgMeta)
+	ps.penalize(bad, PenaltyInvalidBlock)
+	require.NoError(t, ps.save())
+
+	loaded := newPeerScores(ds)
+	require.NoError(t, loaded.load())
+
+	require.Equal(t, float64(maxPeerScore), loaded.scores[good].Score)
+	require.True(t, loaded.banned(bad))
+	require.Equal(t, 1, loaded.scores[bad].Penalties[PenaltyMismatchedMsgMeta.String()])
+}
+
+func TestPeerScoreExpiry(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	idle, err := peer.Decode("12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf")
+	require.NoError(t, err)
+	banned, err := peer.Decode("QmYyQSo1c1Ym7orWxLYvCrM2EmxFTANf8wXmmE7DWjhx5N")
+	require.NoError(t, err)
+
+	ps := newPeerScores(ds)
+	ps.success(idle)
+	ps.penalize(banned, PenaltyInvalidBlock)
+	ps.penalize(banned, PenaltyInvalidBlock)
+	require.NoError(t, ps.save())
+
+	// recently seen peers are kept
+	require.NoError(t, ps.expire())
+	require.Len(t, ps.list(), 2)
+
+	ps.scores[idle].LastSeen = build.Clock.Now().Add(-PeerScoreExpiry - time.Minute)
+	ps.scores[banned].LastSeen = build.Clock.Now().Add(-PeerScoreExpiry - time.Minute)
+	require.NoError(t, ps.expire())
+
+	// banned peers are kept until the end of the ban
+	require.Len(t, ps.list(), 1)
+	require.True(t, ps.banned(banned))
+
+	loaded := newPeerScores(ds)
+	require.NoError(t, loaded.load())
+	require.Len(t, loaded.list(), 1)
+	require.Contains(t, loaded.scores, banned)
+}
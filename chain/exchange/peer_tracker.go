@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ipfs/go-datastore"
 	host "github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"go.uber.org/fx"
@@ -29,13 +30,22 @@ type bsPeerTracker struct {
 	peers         map[peer.ID]*peerStats
 	avgGlobalTime time.Duration
 
+	scores *peerScores
+
+	h    host.Host
 	pmgr *peermgr.PeerMgr
 }
 
-func newPeerTracker(lc fx.Lifecycle, h host.Host, pmgr *peermgr.PeerMgr) *bsPeerTracker {
+func newPeerTracker(lc fx.Lifecycle, h host.Host, pmgr *peermgr.PeerMgr, ds datastore.Batching) *bsPeerTracker {
 	bsPt := &bsPeerTracker{
-		peers: make(map[peer.ID]*peerStats),
-		pmgr:  pmgr,
+		peers:  make(map[peer.ID]*peerStats),
+		scores: newPeerScores(ds),
+		h:      h,
+		pmgr:   pmgr,
+	}
+
+	if err := bsPt.scores.load(); err != nil {
+		log.Errorf("loading peer scores: %s", err)
 	}
 
 	evtSub, err := h.EventBus().Subscribe(new(peermgr.FilPeerEvt))
@@ -55,8 +65,32 @@ func newPeerTracker(lc fx.Lifecycle, h host.Host, pmgr *peermgr.PeerMgr) *bsPeer
 		}
 	}()
 
+	done := make(chan struct{})
+	go func() {
+		tick := build.Clock.Ticker(PeerScoreSaveInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				if err := bsPt.scores.expire(); err != nil {
+					log.Errorf("expiring peer scores: %s", err)
+				}
+				if err := bsPt.scores.save(); err != nil {
+					log.Errorf("saving peer scores: %s", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
 	lc.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
+			close(done)
+			if err := bsPt.scores.save(); err != nil {
+				log.Errorf("saving peer scores: %s", err)
+			}
 			return evtSub.Close()
 		},
 	})
@@ -88,6 +122,9 @@ func (bpt *bsPeerTracker) prefSortedPeers() []peer.ID {
 	defer bpt.lk.Unlock()
 	out := make([]peer.ID, 0, len(bpt.peers))
 	for p := range bpt.peers {
+		if bpt.scores.banned(p) {
+			continue
+		}
 		out = append(out, p)
 	}
 
@@ -193,3 +230,33 @@ func (bpt *bsPeerTracker) removePeer(p peer.ID) {
 	defer bpt.lk.Unlock()
 	delete(bpt.peers, p)
 }
+
+// logValid rewards a peer for a response which passed validation
+func (bpt *bsPeerTracker) logValid(p peer.ID) {
+	score := bpt.scores.success(p)
+	if score >= goodPeerScore && bpt.pmgr != nil {
+		bpt.pmgr.SetGoodPeer(p, true)
+	}
+}
+
+// penalize lowers the score of a peer, banning it when the score gets too
+// low. Banned peers are not requested until the ban expires.
+func (bpt *bsPeerTracker) penalize(p peer.ID, reason Penalty) {
+	score, banned := bpt.scores.penalize(p, reason)
+	log.Debugw("penalized chain exchange peer", "peer", p, "reason", reason, "score", score)
+
+	if score < goodPeerScore && bpt.pmgr != nil {
+		bpt.pmgr.SetGoodPeer(p, false)
+	}
+	if banned && bpt.h != nil {
+		bpt.h.ConnManager().UntagPeer(p, "bsync")
+	}
+}
+
+func (bpt *bsPeerTracker) isBanned(p peer.ID) bool {
+	return bpt.scores.banned(p)
+}
+
+func (bpt *bsPeerTracker) peerScores() []PeerScore {
+	return bpt.scores.list()
+}
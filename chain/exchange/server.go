@@ -20,21 +20,31 @@ import (
 
 	"github.com/ipfs/go-cid"
 	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 // server implements exchange.Server. It services requests for the
 // libp2p ChainExchange protocol.
 type server struct {
 	cs *store.ChainStore
+
+	limiter *serverLimiter
 }
 
 var _ Server = (*server)(nil)
 
 // NewServer creates a new libp2p-based exchange.Server. It services requests
-// for the libp2p ChainExchange protocol.
+// for the libp2p ChainExchange protocol, within the DefaultServerLimits.
 func NewServer(cs *store.ChainStore) Server {
+	return NewServerWithLimits(cs, DefaultServerLimits)
+}
+
+// NewServerWithLimits creates a new libp2p-based exchange.Server, which
+// bounds the chain data served to each peer by the given limits.
+func NewServerWithLimits(cs *store.ChainStore, limits ServerLimits) Server {
 	return &server{
-		cs: cs,
+		cs:      cs,
+		limiter: newServerLimiter(limits),
 	}
 }
 
@@ -54,7 +64,7 @@ func (s *server) HandleStream(stream inet.Stream) {
 	log.Debugw("block sync request",
 		"start", req.Head, "len", req.Length)
 
-	resp, err := s.processRequest(ctx, &req)
+	resp, err := s.processRequest(ctx, stream.Conn().RemotePeer(), &req)
 	if err != nil {
 		log.Warn("failed to process request: ", err)
 		recordSyncFailure(ctx, "process")
@@ -79,9 +89,9 @@ func (s *server) HandleStream(stream inet.Stream) {
 	recordSyncSuccess(ctx, req.Options, cw.Count())
 }
 
-// Validate and service the request. We return either a protocol
-// response or an internal error.
-func (s *server) processRequest(ctx context.Context, req *Request) (*Response, error) {
+// Validate and service the request within the budget of the peer. We
+// return either a protocol response or an internal error.
+func (s *server) processRequest(ctx context.Context, p peer.ID, req *Request) (*Response, error) {
 	validReq, errResponse := validateRequest(ctx, req)
 	if errResponse != nil {
 		// The request did not pass validation, return the response
@@ -89,7 +99,36 @@ func (s *server) processRequest(ctx context.Context, req *Request) (*Response, e
 		return errResponse, nil
 	}
 
-	return s.serviceRequest(ctx, validReq)
+	requested := validReq.length
+	length, reject := s.limiter.acquire(p, validReq.length, validReq.options.IncludeMessages)
+	if reject != "" {
+		log.Debugw("rate limited chainxchg request", "peer", p, "reason", reject, "len", requested)
+		recordSyncRejected(ctx, reject)
+		return &Response{
+			Status:       GoAway,
+			ErrorMessage: fmt.Sprintf("rate limited (%s)", reject),
+		}, nil
+	}
+	validReq.length = length
+
+	resp, err := s.serviceRequest(ctx, validReq)
+
+	var msgs int
+	if resp != nil {
+		for _, bst := range resp.Chain {
+			if bst.Messages != nil {
+				msgs += len(bst.Messages.Bls) + len(bst.Messages.Secpk)
+			}
+		}
+
+		// serve what the budget allows, clients ask for the rest later
+		if resp.Status == Ok && length < requested {
+			resp.Status = Partial
+		}
+	}
+	s.limiter.release(p, msgs)
+
+	return resp, err
 }
 
 // Validate request. We either return a `validatedRequest`, or an error
@@ -265,6 +304,11 @@ func recordSyncFailure(ctx context.Context, failureType string) {
 	stats.RecordWithTags(ctx, tags, metrics.ServeSyncFailure.M(1))
 }
 
+func recordSyncRejected(ctx context.Context, reason string) {
+	tags := []tag.Mutator{tag.Insert(metrics.FailureType, reason)}
+	stats.RecordWithTags(ctx, tags, metrics.ServeSyncRejected.M(1))
+}
+
 func recordSyncSuccess(ctx context.Context, options uint64, size int64) {
 	typ := strconv.Itoa(int(options))
 	switch options {
@@ -0,0 +1,222 @@
+package exchange
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+)
+
+// ServerLimits bounds the chain data the server sends to each peer, so that
+// a single peer can't saturate it.
+type ServerLimits struct {
+	// TipSetsPerSecond is the rate at which the tipset budget of a peer
+	// refills, zero disables the budget.
+	TipSetsPerSecond float64
+	// TipSetBurst is the largest tipset budget a peer can save up.
+	TipSetBurst float64
+	// MessagesPerSecond is the rate at which the message budget of a peer
+	// refills, zero disables the budget.
+	MessagesPerSecond float64
+	// MessageBurst is the largest message budget a peer can save up.
+	MessageBurst float64
+
+	// MaxStreamsPerPeer is the number of requests served concurrently to a
+	// single peer, zero is unlimited.
+	MaxStreamsPerPeer int
+	// MaxStreams is the number of requests served concurrently to all
+	// peers which aren't protected, zero is unlimited.
+	MaxStreams int
+
+	// ProtectedPeers are served even when MaxStreams is reached, and their
+	// budgets are scaled by ProtectedMultiplier.
+	ProtectedPeers      []peer.ID
+	ProtectedMultiplier float64
+}
+
+// DefaultServerLimits are the limits of NewServer
+var DefaultServerLimits = ServerLimits{
+	TipSetsPerSecond:  200,
+	TipSetBurst:       2000,
+	MessagesPerSecond: 20000,
+	MessageBurst:      200000,
+
+	MaxStreamsPerPeer: 16,
+	MaxStreams:        256,
+
+	ProtectedMultiplier: 4,
+}
+
+const (
+	rejectStreams     = "streams"
+	rejectPeerStreams = "peer-streams"
+	rejectTipSets     = "tipsets"
+	rejectMessages    = "messages"
+)
+
+// budgetSweepInterval is how often budgets of idle peers are dropped
+const budgetSweepInterval = 5 * time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last refill
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+type peerBudget struct {
+	tipsets tokenBucket
+	msgs    tokenBucket
+	streams int
+}
+
+// serverLimiter keeps the budgets of the peers requesting chain data
+type serverLimiter struct {
+	lk sync.Mutex
+
+	limits    ServerLimits
+	protected map[peer.ID]struct{}
+
+	peers     map[peer.ID]*peerBudget
+	streams   int
+	lastSweep time.Time
+}
+
+func newServerLimiter(limits ServerLimits) *serverLimiter {
+	protected := make(map[peer.ID]struct{}, len(limits.ProtectedPeers))
+	for _, p := range limits.ProtectedPeers {
+		protected[p] = struct{}{}
+	}
+
+	return &serverLimiter{
+		limits:    limits,
+		protected: protected,
+		peers:     map[peer.ID]*peerBudget{},
+		lastSweep: build.Clock.Now(),
+	}
+}
+
+// rates returns the budget rates and bursts of the peer as tipset rate,
+// tipset burst, message rate, message burst
+func (l *serverLimiter) rates(p peer.ID) (float64, float64, float64, float64) {
+	mul := 1.0
+	if _, ok := l.protected[p]; ok && l.limits.ProtectedMultiplier > 0 {
+		mul = l.limits.ProtectedMultiplier
+	}
+	return l.limits.TipSetsPerSecond * mul, l.limits.TipSetBurst * mul,
+		l.limits.MessagesPerSecond * mul, l.limits.MessageBurst * mul
+}
+
+// budget returns the budget of the peer, creating a full one if needed. Must
+// be called with l.lk held.
+func (l *serverLimiter) budget(p peer.ID, now time.Time) *peerBudget {
+	pb, ok := l.peers[p]
+	if !ok {
+		_, tburst, _, mburst := l.rates(p)
+		pb = &peerBudget{
+			tipsets: tokenBucket{tokens: tburst, last: now},
+			msgs:    tokenBucket{tokens: mburst, last: now},
+		}
+		l.peers[p] = pb
+	}
+	return pb
+}
+
+// sweep drops the budgets of idle peers which refilled completely, as those
+// are the same as new budgets. Must be called with l.lk held.
+func (l *serverLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < budgetSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for p, pb := range l.peers {
+		if pb.streams > 0 {
+			continue
+		}
+
+		trate, tburst, mrate, mburst := l.rates(p)
+		pb.tipsets.refill(now, trate, tburst)
+		pb.msgs.refill(now, mrate, mburst)
+		if pb.tipsets.tokens >= tburst && pb.msgs.tokens >= mburst {
+			delete(l.peers, p)
+		}
+	}
+}
+
+// acquire admits a request of the peer for length tipsets. It returns the
+// number of tipsets which may be served, which can be less than requested
+// when the tipset budget of the peer is low, or the reason the request is
+// rejected. Admitted requests must be released.
+func (l *serverLimiter) acquire(p peer.ID, length uint64, msgs bool) (uint64, string) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	now := build.Clock.Now()
+	l.sweep(now)
+
+	_, protected := l.protected[p]
+	if !protected && l.limits.MaxStreams > 0 && l.streams >= l.limits.MaxStreams {
+		return 0, rejectStreams
+	}
+
+	pb := l.budget(p, now)
+	if l.limits.MaxStreamsPerPeer > 0 && pb.streams >= l.limits.MaxStreamsPerPeer {
+		return 0, rejectPeerStreams
+	}
+
+	trate, tburst, mrate, mburst := l.rates(p)
+	if msgs && mrate > 0 {
+		pb.msgs.refill(now, mrate, mburst)
+		// messages are charged once served, peers in debt have to wait
+		if pb.msgs.tokens <= 0 {
+			return 0, rejectMessages
+		}
+	}
+
+	if trate > 0 {
+		pb.tipsets.refill(now, trate, tburst)
+		if pb.tipsets.tokens < 1 {
+			return 0, rejectTipSets
+		}
+		if avail := uint64(pb.tipsets.tokens); avail < length {
+			length = avail
+		}
+		pb.tipsets.tokens -= float64(length)
+	}
+
+	pb.streams++
+	if !protected {
+		l.streams++
+	}
+
+	return length, ""
+}
+
+// release ends an admitted request, charging the messages served
+func (l *serverLimiter) release(p peer.ID, msgs int) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	pb, ok := l.peers[p]
+	if !ok {
+		return
+	}
+
+	pb.streams--
+	if _, protected := l.protected[p]; !protected {
+		l.streams--
+	}
+
+	if _, _, mrate, mburst := l.rates(p); mrate > 0 {
+		pb.msgs.refill(build.Clock.Now(), mrate, mburst)
+		pb.msgs.tokens = math.Max(-mburst, pb.msgs.tokens-float64(msgs))
+	}
+}
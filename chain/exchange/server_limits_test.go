@@ -0,0 +1,76 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerLimiterBudgets(t *testing.T) {
+	l := newServerLimiter(ServerLimits{
+		TipSetsPerSecond:  0.001,
+		TipSetBurst:       10,
+		MessagesPerSecond: 0.001,
+		MessageBurst:      100,
+	})
+	p := peer.ID("peer")
+
+	// requests are trimmed to the remaining tipset budget
+	n, reject := l.acquire(p, 6, false)
+	require.Empty(t, reject)
+	require.EqualValues(t, 6, n)
+	l.release(p, 0)
+
+	n, reject = l.acquire(p, 6, false)
+	require.Empty(t, reject)
+	require.EqualValues(t, 4, n)
+	l.release(p, 0)
+
+	_, reject = l.acquire(p, 1, false)
+	require.Equal(t, rejectTipSets, reject)
+
+	// messages are charged once served
+	other := peer.ID("other")
+	_, reject = l.acquire(other, 1, true)
+	require.Empty(t, reject)
+	l.release(other, 150)
+
+	_, reject = l.acquire(other, 1, true)
+	require.Equal(t, rejectMessages, reject)
+
+	// headers only requests don't need a message budget
+	_, reject = l.acquire(other, 1, false)
+	require.Empty(t, reject)
+	l.release(other, 0)
+}
+
+func TestServerLimiterStreams(t *testing.T) {
+	protected := peer.ID("protected")
+	l := newServerLimiter(ServerLimits{
+		MaxStreamsPerPeer: 2,
+		MaxStreams:        3,
+		ProtectedPeers:    []peer.ID{protected},
+	})
+	a, b := peer.ID("a"), peer.ID("b")
+
+	for i := 0; i < 2; i++ {
+		_, reject := l.acquire(a, 1, true)
+		require.Empty(t, reject)
+	}
+	_, reject := l.acquire(a, 1, true)
+	require.Equal(t, rejectPeerStreams, reject)
+
+	_, reject = l.acquire(b, 1, true)
+	require.Empty(t, reject)
+	_, reject = l.acquire(b, 1, true)
+	require.Equal(t, rejectStreams, reject)
+
+	// protected peers are served when the server is busy
+	_, reject = l.acquire(protected, 1, true)
+	require.Empty(t, reject)
+
+	l.release(a, 0)
+	_, reject = l.acquire(b, 1, true)
+	require.Empty(t, reject)
+}
@@ -0,0 +1,337 @@
+package feebump
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/messagepool"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/journal"
+	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
+)
+
+var log = logging.Logger("feebump")
+
+const (
+	ReasonStuck       = "stuck"
+	ReasonUnderpriced = "underpriced"
+)
+
+// ErrBudgetExhausted is returned when a message can't be replaced because the
+// minimum replace-by-fee premium doesn't fit in what is left of the budget of
+// the sender
+var ErrBudgetExhausted = xerrors.New("fee bump budget exhausted")
+
+// API is the full node API the FeeBumper uses
+type API interface {
+	ChainHead(context.Context) (*types.TipSet, error)
+	ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error)
+	MpoolExport(context.Context) ([]api.MpoolPendingMessage, error)
+	GasEstimateMessageGas(context.Context, *types.Message, *api.MessageSendSpec, types.TipSetKey) (*types.Message, error)
+	WalletSignMessage(context.Context, address.Address, *types.Message) (*types.SignedMessage, error)
+	MpoolPush(context.Context, *types.SignedMessage) (cid.Cid, error)
+}
+
+// Config configures which messages are bumped and how much they may cost
+type Config struct {
+	// Messages pending for StuckEpochs epochs are bumped
+	StuckEpochs uint64
+	// Number of tipsets the base fee trend is computed over
+	BaseFeeLookback uint64
+	// Senders whose messages are bumped, empty bumps all local senders
+	Addresses []address.Address
+	// Budget by sender, falls back to the default max fee. It caps the sum
+	// of the max fee increases of all the messages of the sender bumped
+	// within BudgetEpochs.
+	MaxFee map[address.Address]abi.TokenAmount
+	// Number of epochs the budget of a sender covers, defaults to a day
+	BudgetEpochs uint64
+}
+
+// BumpEvt is the journal event recorded for every replaced message. Spent is
+// the max fee increase of the replacement, counted against the budget of the
+// sender.
+type BumpEvt struct {
+	From          address.Address
+	Nonce         uint64
+	Reason        string
+	OldCID        cid.Cid
+	NewCID        cid.Cid
+	OldGasFeeCap  abi.TokenAmount
+	NewGasFeeCap  abi.TokenAmount
+	OldGasPremium abi.TokenAmount
+	NewGasPremium abi.TokenAmount
+	Spent         abi.TokenAmount
+	Error         string `json:",omitempty"`
+}
+
+// spend is the max fee increase of a bump
+type spend struct {
+	epoch abi.ChainEpoch
+	fee   abi.TokenAmount
+}
+
+// FeeBumper replaces local messages which are stuck in the mpool, or priced
+// below the current base fee trend, with messages paying higher fees
+type FeeBumper struct {
+	ctx      context.Context
+	shutdown context.CancelFunc
+	api      API
+	cfg      Config
+	mff      dtypes.DefaultMaxFeeFunc
+
+	// spent holds the bumps of every sender within the budget window
+	spent map[address.Address][]spend
+
+	journal journal.Journal
+	evtType journal.EventType
+}
+
+func NewFeeBumper(api API, cfg Config, mff dtypes.DefaultMaxFeeFunc, j journal.Journal) *FeeBumper {
+	if j == nil {
+		j = journal.NilJournal()
+	}
+	if cfg.BaseFeeLookback == 0 {
+		cfg.BaseFeeLookback = 1
+	}
+	if cfg.BudgetEpochs == 0 {
+		cfg.BudgetEpochs = builtin.EpochsInDay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &FeeBumper{
+		ctx:      ctx,
+		shutdown: cancel,
+		api:      api,
+		cfg:      cfg,
+		mff:      mff,
+		spent:    map[address.Address][]spend{},
+		journal:  j,
+		evtType:  j.RegisterEventType("feebump", "bump"),
+	}
+}
+
+func (fb *FeeBumper) Start() {
+	go fb.run()
+}
+
+func (fb *FeeBumper) Stop() {
+	fb.shutdown()
+}
+
+func (fb *FeeBumper) run() {
+	tk := build.Clock.Ticker(time.Duration(build.BlockDelaySecs) * time.Second)
+	defer tk.Stop()
+
+	for {
+		select {
+		case <-tk.C:
+			if err := fb.check(fb.ctx); err != nil {
+				log.Errorf("checking pending messages: %+v", err)
+			}
+		case <-fb.ctx.Done():
+			return
+		}
+	}
+}
+
+// check looks at all pending local messages and replaces the ones which need
+// a higher fee
+func (fb *FeeBumper) check(ctx context.Context) error {
+	head, err := fb.api.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	baseFee, err := fb.projectedBaseFee(ctx, head)
+	if err != nil {
+		return xerrors.Errorf("computing base fee trend: %w", err)
+	}
+
+	pending, err := fb.api.MpoolExport(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting pending messages: %w", err)
+	}
+
+	fb.expireSpent(head.Height())
+
+	stuckAfter := time.Duration(fb.cfg.StuckEpochs*build.BlockDelaySecs) * time.Second
+	for _, pm := range pending {
+		if !pm.Local || !fb.watched(pm.Message.Message.From) {
+			continue
+		}
+
+		var reason string
+		switch {
+		case pm.Message.Message.GasFeeCap.LessThan(baseFee):
+			reason = ReasonUnderpriced
+		case fb.cfg.StuckEpochs > 0 && !pm.Received.IsZero() && build.Clock.Since(pm.Received) >= stuckAfter:
+			reason = ReasonStuck
+		default:
+			continue
+		}
+
+		if err := fb.bump(ctx, head.Height(), pm.Message, baseFee, reason); err != nil {
+			log.Warnw("failed to bump message", "cid", pm.Message.Cid(), "from", pm.Message.Message.From, "nonce", pm.Message.Message.Nonce, "reason", reason, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (fb *FeeBumper) watched(addr address.Address) bool {
+	if len(fb.cfg.Addresses) == 0 {
+		return true
+	}
+	for _, a := range fb.cfg.Addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// projectedBaseFee returns the highest base fee over the lookback window,
+// raised by the maximum per-epoch change when the base fee is rising
+func (fb *FeeBumper) projectedBaseFee(ctx context.Context, head *types.TipSet) (abi.TokenAmount, error) {
+	fees := []abi.TokenAmount{head.Blocks()[0].ParentBaseFee}
+
+	ts := head
+	for i := uint64(1); i < fb.cfg.BaseFeeLookback && ts.Height() > 0; i++ {
+		pts, err := fb.api.ChainGetTipSet(ctx, ts.Parents())
+		if err != nil {
+			return abi.TokenAmount{}, xerrors.Errorf("loading tipset %s: %w", ts.Parents(), err)
+		}
+		ts = pts
+		fees = append(fees, ts.Blocks()[0].ParentBaseFee)
+	}
+
+	return projectBaseFee(fees), nil
+}
+
+// projectBaseFee projects the base fee from a list of base fees, newest first
+func projectBaseFee(fees []abi.TokenAmount) abi.TokenAmount {
+	max := fees[0]
+	for _, f := range fees[1:] {
+		max = big.Max(max, f)
+	}
+
+	if len(fees) > 1 && fees[0].GreaterThan(fees[len(fees)-1]) {
+		// see store.ComputeNextBaseFee
+		max = big.Add(max, big.Div(max, big.NewInt(build.BaseFeeMaxChangeDenom)))
+	}
+
+	return max
+}
+
+// expireSpent forgets the bumps which left the budget window
+func (fb *FeeBumper) expireSpent(height abi.ChainEpoch) {
+	from := height - abi.ChainEpoch(fb.cfg.BudgetEpochs)
+	for addr, spends := range fb.spent {
+		var i int
+		for i < len(spends) && spends[i].epoch <= from {
+			i++
+		}
+		if i == len(spends) {
+			delete(fb.spent, addr)
+			continue
+		}
+		fb.spent[addr] = spends[i:]
+	}
+}
+
+// remaining returns what is left of the budget of the sender
+func (fb *FeeBumper) remaining(addr address.Address) (abi.TokenAmount, error) {
+	budget, ok := fb.cfg.MaxFee[addr]
+	if !ok {
+		var err error
+		budget, err = fb.mff()
+		if err != nil {
+			return abi.TokenAmount{}, err
+		}
+	}
+
+	for _, s := range fb.spent[addr] {
+		budget = big.Sub(budget, s.fee)
+	}
+	return budget, nil
+}
+
+// bump replaces the message with a re-estimated one, paying at least the
+// minimum replace-by-fee premium and a fee cap covering the base fee trend.
+// The max fee increase of the replacement is limited to, and counted against,
+// the budget of the sender.
+func (fb *FeeBumper) bump(ctx context.Context, height abi.ChainEpoch, smsg *types.SignedMessage, baseFee abi.TokenAmount, reason string) error {
+	old := smsg.Message
+
+	remaining, err := fb.remaining(old.From)
+	if err != nil {
+		return xerrors.Errorf("getting budget: %w", err)
+	}
+	if remaining.Sign() <= 0 {
+		return xerrors.Errorf("sender %s: %w", old.From, ErrBudgetExhausted)
+	}
+	oldMaxFee := big.Mul(old.GasFeeCap, big.NewInt(old.GasLimit))
+	spec := &api.MessageSendSpec{MaxFee: big.Add(oldMaxFee, remaining)}
+
+	msg := old
+	msg.GasFeeCap = big.Zero()
+	msg.GasPremium = big.Zero()
+	est, err := fb.api.GasEstimateMessageGas(ctx, &msg, spec, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("estimating gas: %w", err)
+	}
+
+	minRBF := messagepool.ComputeMinRBF(old.GasPremium)
+	msg.GasPremium = big.Max(est.GasPremium, minRBF)
+	msg.GasFeeCap = big.Max(big.Max(est.GasFeeCap, msg.GasPremium), baseFee)
+	messagepool.CapGasFee(fb.mff, &msg, spec)
+
+	evt := BumpEvt{
+		From:          old.From,
+		Nonce:         old.Nonce,
+		Reason:        reason,
+		OldCID:        smsg.Cid(),
+		OldGasFeeCap:  old.GasFeeCap,
+		NewGasFeeCap:  msg.GasFeeCap,
+		OldGasPremium: old.GasPremium,
+		NewGasPremium: msg.GasPremium,
+		Spent:         big.Zero(),
+	}
+	defer fb.journal.RecordEvent(fb.evtType, func() interface{} {
+		return evt
+	})
+
+	if msg.GasPremium.LessThan(minRBF) {
+		evt.Error = ErrBudgetExhausted.Error()
+		return xerrors.Errorf("premium %s, required %s: %w", msg.GasPremium, minRBF, ErrBudgetExhausted)
+	}
+
+	signed, err := fb.api.WalletSignMessage(ctx, msg.From, &msg)
+	if err != nil {
+		evt.Error = err.Error()
+		return xerrors.Errorf("signing message: %w", err)
+	}
+
+	evt.NewCID, err = fb.api.MpoolPush(ctx, signed)
+	if err != nil {
+		evt.Error = err.Error()
+		return xerrors.Errorf("pushing message: %w", err)
+	}
+	evt.Spent = big.Max(big.Sub(big.Mul(msg.GasFeeCap, big.NewInt(msg.GasLimit)), oldMaxFee), big.Zero())
+	fb.spent[old.From] = append(fb.spent[old.From], spend{epoch: height, fee: evt.Spent})
+
+	log.Infow("bumped message fees", "from", old.From, "nonce", old.Nonce, "reason", reason,
+		"old", evt.OldCID, "new", evt.NewCID, "premium", msg.GasPremium, "feecap", msg.GasFeeCap, "spent", evt.Spent)
+	return nil
+}
@@ -0,0 +1,181 @@
+package feebump
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/messagepool"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+)
+
+type mockAPI struct {
+	head      *types.TipSet
+	pending   []api.MpoolPendingMessage
+	pushed    []*types.SignedMessage
+	estimates int
+}
+
+func (m *mockAPI) ChainHead(context.Context) (*types.TipSet, error) {
+	return m.head, nil
+}
+
+func (m *mockAPI) ChainGetTipSet(context.Context, types.TipSetKey) (*types.TipSet, error) {
+	return m.head, nil
+}
+
+func (m *mockAPI) MpoolExport(context.Context) ([]api.MpoolPendingMessage, error) {
+	return m.pending, nil
+}
+
+func (m *mockAPI) GasEstimateMessageGas(_ context.Context, msg *types.Message, _ *api.MessageSendSpec, _ types.TipSetKey) (*types.Message, error) {
+	m.estimates++
+	est := *msg
+	est.GasPremium = abi.NewTokenAmount(50)
+	est.GasFeeCap = abi.NewTokenAmount(1500)
+	return &est, nil
+}
+
+func (m *mockAPI) WalletSignMessage(_ context.Context, _ address.Address, msg *types.Message) (*types.SignedMessage, error) {
+	return &types.SignedMessage{Message: *msg}, nil
+}
+
+func (m *mockAPI) MpoolPush(_ context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
+	m.pushed = append(m.pushed, smsg)
+	return smsg.Cid(), nil
+}
+
+func pendingMsg(from address.Address, feeCap int64, local bool) api.MpoolPendingMessage {
+	return api.MpoolPendingMessage{
+		Message: &types.SignedMessage{Message: types.Message{
+			From:       from,
+			To:         mock.Address(1),
+			Value:      big.Zero(),
+			GasLimit:   1000,
+			GasFeeCap:  abi.NewTokenAmount(feeCap),
+			GasPremium: abi.NewTokenAmount(100),
+		}},
+		Received: build.Clock.Now(),
+		Local:    local,
+	}
+}
+
+func TestBumpUnderpriced(t *testing.T) {
+	blk := mock.MkBlock(nil, 1, 1)
+	blk.ParentBaseFee = abi.NewTokenAmount(1000)
+
+	rich, poor, remote := mock.Address(100), mock.Address(101), mock.Address(102)
+
+	mapi := &mockAPI{
+		head: mock.TipSet(blk),
+		pending: []api.MpoolPendingMessage{
+			pendingMsg(rich, 500, true),
+			pendingMsg(rich, 2000, true),
+			pendingMsg(poor, 500, true),
+			pendingMsg(remote, 500, false),
+		},
+	}
+
+	mff := func() (abi.TokenAmount, error) {
+		return abi.NewTokenAmount(1e9), nil
+	}
+	fb := NewFeeBumper(mapi, Config{
+		StuckEpochs: 10,
+		MaxFee:      map[address.Address]abi.TokenAmount{poor: big.Zero()},
+	}, mff, nil)
+
+	require.NoError(t, fb.check(context.Background()))
+
+	// only the underpriced message of the sender with a budget
+	require.Len(t, mapi.pushed, 1)
+	bumped := mapi.pushed[0].Message
+	require.Equal(t, rich, bumped.From)
+	require.Equal(t, messagepool.ComputeMinRBF(abi.NewTokenAmount(100)), bumped.GasPremium)
+	require.Equal(t, abi.NewTokenAmount(1500), bumped.GasFeeCap)
+	require.Equal(t, int64(1000), bumped.GasLimit)
+}
+
+func TestBumpBudget(t *testing.T) {
+	blk := mock.MkBlock(nil, 1, 1)
+	blk.Height = 100
+	blk.ParentBaseFee = abi.NewTokenAmount(1000)
+
+	sender := mock.Address(100)
+
+	var pending []api.MpoolPendingMessage
+	for i := uint64(0); i < 3; i++ {
+		pm := pendingMsg(sender, 500, true)
+		pm.Message.Message.Nonce = i
+		pending = append(pending, pm)
+	}
+
+	mapi := &mockAPI{
+		head:    mock.TipSet(blk),
+		pending: pending,
+	}
+
+	mff := func() (abi.TokenAmount, error) {
+		return abi.NewTokenAmount(1e9), nil
+	}
+	fb := NewFeeBumper(mapi, Config{
+		BudgetEpochs: 10,
+		MaxFee: map[address.Address]abi.TokenAmount{
+			sender: abi.NewTokenAmount(1500 * 1000),
+		},
+	}, mff, nil)
+
+	require.NoError(t, fb.check(context.Background()))
+
+	// the first message gets the estimated fee cap, the second what is left
+	// of the budget, and the third nothing
+	require.Len(t, mapi.pushed, 2)
+	require.Equal(t, uint64(0), mapi.pushed[0].Message.Nonce)
+	require.Equal(t, abi.NewTokenAmount(1500), mapi.pushed[0].Message.GasFeeCap)
+	require.Equal(t, uint64(1), mapi.pushed[1].Message.Nonce)
+	require.Equal(t, abi.NewTokenAmount(1000), mapi.pushed[1].Message.GasFeeCap)
+	require.Equal(t, 2, mapi.estimates)
+
+	remaining, err := fb.remaining(sender)
+	require.NoError(t, err)
+	require.True(t, remaining.IsZero())
+
+	// nothing is bumped while the budget is spent
+	require.NoError(t, fb.check(context.Background()))
+	require.Len(t, mapi.pushed, 2)
+	require.Equal(t, 2, mapi.estimates)
+
+	// the budget is available again once the bumps leave the window
+	blk = mock.MkBlock(nil, 1, 2)
+	blk.Height = 110
+	blk.ParentBaseFee = abi.NewTokenAmount(1000)
+	mapi.head = mock.TipSet(blk)
+
+	require.NoError(t, fb.check(context.Background()))
+	require.Len(t, mapi.pushed, 4)
+	require.Len(t, fb.spent[sender], 2)
+}
+
+func TestProjectBaseFee(t *testing.T) {
+	fees := func(f ...int64) []abi.TokenAmount {
+		var out []abi.TokenAmount
+		for _, v := range f {
+			out = append(out, abi.NewTokenAmount(v))
+		}
+		return out
+	}
+
+	// falling
+	require.Equal(t, abi.NewTokenAmount(1000), projectBaseFee(fees(800, 900, 1000)))
+	// rising
+	require.Equal(t, abi.NewTokenAmount(1000+1000/build.BaseFeeMaxChangeDenom), projectBaseFee(fees(1000, 900, 800)))
+	require.Equal(t, abi.NewTokenAmount(1000), projectBaseFee(fees(1000)))
+}
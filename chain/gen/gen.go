@@ -111,6 +111,14 @@ var DefaultRemainderAccountActor = genesis.Actor{
 */
 
 func NewGeneratorWithSectors(numSectors int) (*ChainGen, error) {
+	return NewGeneratorWithTemplate(numSectors, nil)
+}
+
+// NewGeneratorWithTemplate is like NewGeneratorWithSectors, but calls modify
+// with the generator wallet and the genesis template before the genesis block
+// is made, so that callers can fund their own accounts, seed experts or
+// override governed parameters.
+func NewGeneratorWithTemplate(numSectors int, modify func(w *wallet.LocalWallet, tpl *genesis.Template) error) (*ChainGen, error) {
 	j := journal.NilJournal()
 	// TODO: we really shouldn't modify a global variable here.
 	policy.SetSupportedProofTypes(abi.RegisteredSealProof_StackedDrg2KiBV1)
@@ -231,6 +239,12 @@ func NewGeneratorWithSectors(numSectors int) (*ChainGen, error) {
 		Timestamp:   uint64(build.Clock.Now().Add(-500 * time.Duration(build.BlockDelaySecs) * time.Second).Unix()),
 	}
 
+	if modify != nil {
+		if err := modify(w, &tpl); err != nil {
+			return nil, xerrors.Errorf("modifying genesis template: %w", err)
+		}
+	}
+
 	genb, err := genesis2.MakeGenesisBlock(context.TODO(), j, bs, sys, tpl)
 	if err != nil {
 		return nil, xerrors.Errorf("make genesis block failed: %w", err)
@@ -1,11 +1,25 @@
 package gen
 
 import (
+	"context"
 	"testing"
 
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
 
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expertfund"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/govern"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/policy"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/wallet"
+	"github.com/EpiK-Protocol/go-epik/genesis"
 	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/bls"
 	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/secp"
 )
@@ -38,6 +52,95 @@ func TestChainGeneration(t *testing.T) {
 	t.Run("10-20-25", func(t *testing.T) { testGeneration(t, 10, 20, 25) })
 }
 
+func TestGeneratorTemplateSeeds(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+	piece, err := cid.Parse("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	require.NoError(t, err)
+	votes := types.FromEpk(100)
+
+	var owner, voter, governor address.Address
+	cg, err := NewGeneratorWithTemplate(1, func(w *wallet.LocalWallet, tpl *genesis.Template) error {
+		for _, a := range []*address.Address{&owner, &voter, &governor} {
+			k, err := w.WalletNew(ctx, types.KTSecp256k1)
+			if err != nil {
+				return err
+			}
+			*a = k
+			tpl.Accounts = append(tpl.Accounts, genesis.Actor{
+				Type:    genesis.TAccount,
+				Balance: types.FromEpk(1000),
+				Meta:    (&genesis.AccountMeta{Owner: *a}).ActorMeta(),
+			})
+		}
+
+		tpl.Experts = append(tpl.Experts, genesis.Expert{
+			Owner: owner,
+			Datas: []genesis.ExpertData{{RootID: root, PieceID: piece, PieceSize: 2048}},
+		})
+		tpl.Votes = append(tpl.Votes, genesis.Vote{Voter: voter, Expert: owner, Amount: votes})
+		tpl.Governors = append(tpl.Governors, genesis.Governor{Address: governor, All: true})
+		return nil
+	})
+	require.NoError(t, err)
+
+	store := cg.ChainStore().ActorStore(ctx)
+	st, err := state.LoadStateTree(store, cg.Genesis().ParentStateRoot)
+	require.NoError(t, err)
+
+	ownerID, err := st.LookupID(owner)
+	require.NoError(t, err)
+	governorID, err := st.LookupID(governor)
+	require.NoError(t, err)
+
+	// the expert of the template, besides the default expert
+	act, err := st.GetActor(builtin2.ExpertFundActorAddr)
+	require.NoError(t, err)
+	efs, err := expertfund.Load(store, act)
+	require.NoError(t, err)
+	experts, err := efs.ListAllExperts()
+	require.NoError(t, err)
+	require.Len(t, experts, 2)
+
+	var seeded address.Address
+	for _, e := range experts {
+		act, err := st.GetActor(e)
+		require.NoError(t, err)
+		es, err := expert.Load(store, act)
+		require.NoError(t, err)
+		info, err := es.Info()
+		require.NoError(t, err)
+		if info.Owner != ownerID {
+			continue
+		}
+
+		seeded = e
+		data, err := es.Data(piece)
+		require.NoError(t, err)
+		require.Equal(t, root, data.RootID)
+		require.Equal(t, abi.PaddedPieceSize(2048), data.PieceSize)
+	}
+	require.NotEqual(t, address.Undef, seeded, "template expert not found")
+
+	act, err = st.GetActor(builtin2.VoteFundActorAddr)
+	require.NoError(t, err)
+	vs, err := vote.Load(store, act)
+	require.NoError(t, err)
+	tally, err := vs.Tally()
+	require.NoError(t, err)
+	require.True(t, votes.Equals(tally.Candidates[seeded.String()]))
+
+	act, err = st.GetActor(builtin2.GovernActorAddr)
+	require.NoError(t, err)
+	gs, err := govern.Load(store, act)
+	require.NoError(t, err)
+	gi, err := gs.Governor(governorID)
+	require.NoError(t, err)
+	require.Equal(t, governorID, gi.Address)
+}
+
 func BenchmarkChainGeneration(b *testing.B) {
 	b.Run("0-messages", func(b *testing.B) {
 		testGeneration(b, b.N, 0, 1)
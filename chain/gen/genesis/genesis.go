@@ -83,6 +83,10 @@ The process:
       - Calculate pledge
       - Precommit
       - Confirm valid
+  - Apply template seeds
+    - Grant governors, override governed parameters
+    - Create experts and import their data
+    - Cast votes, pledge to the retrieval fund
 
 Data Types:
 
@@ -583,6 +587,11 @@ func MakeGenesisBlock(ctx context.Context, j journal.Journal, bs bstore.Blocksto
 		return nil, xerrors.Errorf("setup miners failed: %w", err)
 	}
 
+	stateroot, err = SetupTemplateSeeds(ctx, cs, stateroot, template, inis, keyIDs)
+	if err != nil {
+		return nil, xerrors.Errorf("setup template seeds failed: %w", err)
+	}
+
 	store := adt2.WrapStore(ctx, cbor.NewCborStore(bs))
 	emptyroot, err := adt2.StoreEmptyArray(store, builtin2.DefaultAmtBitwidth)
 	if err != nil {
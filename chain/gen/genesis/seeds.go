@@ -0,0 +1,231 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	expert2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expert"
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/expertfund"
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/govern"
+	power2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/power"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/retrieval"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/vm"
+	"github.com/EpiK-Protocol/go-epik/genesis"
+)
+
+// SetupTemplateSeeds applies the governors, governed parameters, experts,
+// votes and retrieval pledges of the template. It runs after the miners are
+// created, so that the experts don't shift the miner IDs.
+func SetupTemplateSeeds(ctx context.Context, cs *store.ChainStore, sroot cid.Cid, tpl genesis.Template, inis InitDatas, keyIDs map[address.Address]address.Address) (cid.Cid, error) {
+	if len(tpl.Governors) == 0 && tpl.GovParams == nil && len(tpl.Experts) == 0 &&
+		len(tpl.Votes) == 0 && len(tpl.RetrievalPledges) == 0 {
+		return sroot, nil
+	}
+
+	csc := func(context.Context, abi.ChainEpoch, *state.StateTree) (abi.TokenAmount, error) {
+		return big.Zero(), nil
+	}
+
+	vmopt := &vm.VMOpts{
+		StateBase:      sroot,
+		Epoch:          0,
+		Rand:           &fakeRand{},
+		Bstore:         cs.StateBlockstore(),
+		Syscalls:       mkFakedSigSyscalls(cs.VMSys()),
+		CircSupplyCalc: csc,
+		NtwkVersion:    genesisNetworkVersion,
+		BaseFee:        types.NewInt(0),
+	}
+
+	vm, err := vm.NewVM(ctx, vmopt)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("failed to create NewVM: %w", err)
+	}
+
+	// Grant governors
+	for i, g := range tpl.Governors {
+		ida, err := templateIDAddress(g.Address, keyIDs)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("governor %d: %w", i, err)
+		}
+
+		_, err = doExecValue(ctx, vm, builtin2.GovernActorAddr, builtin.FoundationIDAddress, big.Zero(), builtin2.MethodsGovern.Grant, mustEnc(&govern.GrantOrRevokeParams{
+			Governor:    ida,
+			All:         g.All,
+			Authorities: g.Authorities,
+		}))
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to grant governor %s: %w", g.Address, err)
+		}
+		fmt.Printf("grant genesis governor %s: %s\n", ida, g.Address)
+	}
+
+	// Override governed parameters, through the default governor
+	if gp := tpl.GovParams; gp != nil {
+		if err := setupGovParams(ctx, vm, gp); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	// Create experts and import their data
+	experts := map[address.Address]address.Address{
+		inis.ExpertOwner: inis.Expert,
+	}
+	var foundation address.Address
+	if len(tpl.Experts) > 0 {
+		idas, err := ParseIDAddresses(tpl.FoundationAccountActor, keyIDs)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to parse id addresses: %w", err)
+		}
+		foundation = idas[0]
+	}
+	for i, e := range tpl.Experts {
+		if _, ok := keyIDs[e.Owner]; !ok {
+			return cid.Undef, xerrors.Errorf("expert %d: owner %s is not a template account", i, e.Owner)
+		}
+		if _, ok := experts[e.Owner]; ok {
+			return cid.Undef, xerrors.Errorf("expert %d: owner %s already owns an expert", i, e.Owner)
+		}
+
+		rval, err := doExecValue(ctx, vm, builtin2.ExpertFundActorAddr, foundation, big.Zero(), builtin2.MethodsExpertFunds.ApplyForExpert, mustEnc(&expertfund.ApplyForExpertParams{Owner: e.Owner}))
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to create genesis expert %s: %w", e.Owner, err)
+		}
+		var ret expertfund.ApplyForExpertReturn
+		if err := ret.UnmarshalCBOR(bytes.NewReader(rval)); err != nil {
+			return cid.Undef, xerrors.Errorf("unmarshaling ApplyForExpertReturn: %w", err)
+		}
+		experts[e.Owner] = ret.IDAddress
+		fmt.Printf("create genesis expert %s: %s\n", ret.IDAddress, e.Owner)
+
+		if len(e.Datas) == 0 {
+			continue
+		}
+
+		datas := make([]expert2.ImportDataParams, len(e.Datas))
+		for j, d := range e.Datas {
+			datas[j] = expert2.ImportDataParams{
+				RootID:    d.RootID,
+				PieceID:   d.PieceID,
+				PieceSize: d.PieceSize,
+			}
+		}
+		_, err = doExecValue(ctx, vm, ret.IDAddress, e.Owner, big.Zero(), builtin2.MethodsExpert.ImportData, mustEnc(&expert2.BatchImportDataParams{Datas: datas}))
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to import data of expert %s: %w", ret.IDAddress, err)
+		}
+	}
+
+	// Cast votes
+	for i, v := range tpl.Votes {
+		voter, err := templateIDAddress(v.Voter, keyIDs)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("vote %d: %w", i, err)
+		}
+
+		expert := v.Expert
+		if expert.Protocol() != address.ID {
+			ida, ok := experts[expert]
+			if !ok {
+				return cid.Undef, xerrors.Errorf("vote %d: %s doesn't own a template expert", i, expert)
+			}
+			expert = ida
+		}
+
+		_, err = doExecValue(ctx, vm, builtin2.VoteFundActorAddr, voter, v.Amount, builtin2.MethodsVote.Vote, mustEnc(&expert))
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to vote %s for expert %s: %w", types.EPK(v.Amount), expert, err)
+		}
+	}
+
+	// Pledge to the retrieval fund
+	for i, p := range tpl.RetrievalPledges {
+		pledger, err := templateIDAddress(p.Pledger, keyIDs)
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("retrieval pledge %d: %w", i, err)
+		}
+
+		_, err = doExecValue(ctx, vm, builtin2.RetrievalFundActorAddr, pledger, p.Amount, builtin2.MethodsRetrieval.Pledge, mustEnc(&retrieval.PledgeParams{
+			Address: p.Target,
+			Miners:  p.Miners,
+		}))
+		if err != nil {
+			return cid.Undef, xerrors.Errorf("failed to pledge %s for %s: %w", types.EPK(p.Amount), p.Target, err)
+		}
+	}
+
+	st, err := vm.Flush(ctx)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("vm flush: %w", err)
+	}
+
+	return st, nil
+}
+
+func setupGovParams(ctx context.Context, vm *vm.VM, gp *genesis.GovParams) error {
+	gov := builtin.DefaultGovernorIDAddress
+
+	if gp.MinersPoStRatio > 0 {
+		_, err := doExecValue(ctx, vm, builtin2.StoragePowerActorAddr, gov, big.Zero(), builtin2.MethodsPower.ChangeWdPoStRatio, mustEnc(&power2.ChangeWdPoStRatioParams{Ratio: gp.MinersPoStRatio}))
+		if err != nil {
+			return xerrors.Errorf("failed to set miners post ratio: %w", err)
+		}
+	}
+
+	if gp.MinersPledgePeriod > 0 {
+		_, err := doExecValue(ctx, vm, builtin2.StoragePowerActorAddr, gov, big.Zero(), builtin2.MethodsPower.ChangePledgeReleasePeriod, mustEnc(&power2.ChangePledgeParams{Period: gp.MinersPledgePeriod}))
+		if err != nil {
+			return xerrors.Errorf("failed to set miners pledge release period: %w", err)
+		}
+	}
+
+	if gp.MarketInitialQuota > 0 {
+		quota := cbg.CborInt(gp.MarketInitialQuota)
+		_, err := doExecValue(ctx, vm, builtin2.StorageMarketActorAddr, gov, big.Zero(), builtin2.MethodsMarket.SetInitialQuota, mustEnc(&quota))
+		if err != nil {
+			return xerrors.Errorf("failed to set market initial quota: %w", err)
+		}
+	}
+
+	// zero thresholds are left unchanged by the expert fund actor
+	if gp.ExpertDataThreshold > 0 || gp.ExpertDailyThreshold > 0 {
+		_, err := doExecValue(ctx, vm, builtin2.ExpertFundActorAddr, gov, big.Zero(), builtin2.MethodsExpertFunds.ChangeThreshold, mustEnc(&expertfund.ChangeThresholdParams{
+			DataStoreThreshold:   gp.ExpertDataThreshold,
+			DailyImportThreshold: gp.ExpertDailyThreshold,
+		}))
+		if err != nil {
+			return xerrors.Errorf("failed to set expert thresholds: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// templateIDAddress returns the ID address of a template account owner, ID
+// addresses are returned as is
+func templateIDAddress(addr address.Address, keyIDs map[address.Address]address.Address) (address.Address, error) {
+	if addr.Protocol() == address.ID {
+		return addr, nil
+	}
+
+	ida, ok := keyIDs[addr]
+	if !ok {
+		return address.Undef, xerrors.Errorf("%s is not a template account", addr)
+	}
+	return ida, nil
+}
@@ -65,6 +65,14 @@ func validateConfg(cfg *types.MpoolConfig) error {
 	if cfg.GasLimitOverestimation < 1 {
 		return fmt.Errorf("'GasLimitOverestimation' cannot be less than 1")
 	}
+	if cfg.MaxPendingPerSender < 0 {
+		return fmt.Errorf("'MaxPendingPerSender' cannot be negative")
+	}
+	for i, mp := range cfg.MinPremiums {
+		if mp.MinGasPremium.Int == nil {
+			return fmt.Errorf("'MinPremiums' entry %d has no 'MinGasPremium'", i)
+		}
+	}
 	return nil
 }
 
@@ -156,6 +156,11 @@ type MessagePool struct {
 
 	localMsgs datastore.Datastore
 
+	// snapshot holds all pending messages, so that they survive restarts
+	snapshot     datastore.Batching
+	snapshotKeys map[datastore.Key]struct{}
+	snapshotLk   sync.Mutex
+
 	netName dtypes.NetworkName
 
 	sigValCache *lru.TwoQueueCache
@@ -166,6 +171,7 @@ type MessagePool struct {
 
 type msgSet struct {
 	msgs          map[uint64]*types.SignedMessage
+	received      map[uint64]time.Time
 	nextNonce     uint64
 	requiredFunds *stdbig.Int
 }
@@ -173,6 +179,7 @@ type msgSet struct {
 func newMsgSet(nonce uint64) *msgSet {
 	return &msgSet{
 		msgs:          make(map[uint64]*types.SignedMessage),
+		received:      make(map[uint64]time.Time),
 		nextNonce:     nonce,
 		requiredFunds: stdbig.NewInt(0),
 	}
@@ -281,6 +288,11 @@ func (ms *msgSet) add(m *types.SignedMessage, mp *MessagePool, strict, untrusted
 		return false, ErrTooManyPendingMessages
 	}
 
+	// trusted local messages are not limited by the policy
+	if maxPending := mp.getConfig().MaxPendingPerSender; (strict || untrusted) && !has && maxPending > 0 && len(ms.msgs) >= maxPending {
+		return false, xerrors.Errorf("sender %s has %d pending messages: %w", m.Message.From, len(ms.msgs), ErrSenderPendingPolicy)
+	}
+
 	if strict && nonceGap {
 		log.Debugf("adding nonce-gapped message from %s (nonce: %d, nextNonce: %d)",
 			m.Message.From, m.Message.Nonce, nextNonce)
@@ -288,6 +300,7 @@ func (ms *msgSet) add(m *types.SignedMessage, mp *MessagePool, strict, untrusted
 
 	ms.nextNonce = nextNonce
 	ms.msgs[m.Message.Nonce] = m
+	ms.received[m.Message.Nonce] = build.Clock.Now()
 	ms.requiredFunds.Add(ms.requiredFunds, m.Message.RequiredFunds().Int)
 	//ms.requiredFunds.Add(ms.requiredFunds, m.Message.Value.Int)
 
@@ -311,6 +324,7 @@ func (ms *msgSet) rm(nonce uint64, applied bool) {
 	ms.requiredFunds.Sub(ms.requiredFunds, m.Message.RequiredFunds().Int)
 	//ms.requiredFunds.Sub(ms.requiredFunds, m.Message.Value.Int)
 	delete(ms.msgs, nonce)
+	delete(ms.received, nonce)
 
 	// adjust next nonce
 	if applied {
@@ -341,7 +355,9 @@ func (ms *msgSet) getRequiredFunds(nonce uint64) types.BigInt {
 	return types.BigInt{Int: requiredFunds}
 }
 
-func New(api Provider, ds dtypes.MetadataDS, netName dtypes.NetworkName, j journal.Journal) (*MessagePool, error) {
+// New creates a message pool keeping its config and local messages in ds, and
+// the snapshot of the pending messages in ids.
+func New(api Provider, ds dtypes.MetadataDS, ids dtypes.IndexDS, netName dtypes.NetworkName, j journal.Journal) (*MessagePool, error) {
 	cache, _ := lru.New2Q(build.BlsSignatureCacheSize)
 	verifcache, _ := lru.New2Q(build.VerifSigCacheSize)
 
@@ -369,6 +385,8 @@ func New(api Provider, ds dtypes.MetadataDS, netName dtypes.NetworkName, j journ
 		sigValCache:   verifcache,
 		changes:       lps.New(50),
 		localMsgs:     namespace.Wrap(ds, datastore.NewKey(localMsgsDs)),
+		snapshot:      namespace.Wrap(ids, datastore.NewKey(snapshotDs)),
+		snapshotKeys:  make(map[datastore.Key]struct{}),
 		api:           api,
 		netName:       netName,
 		cfg:           cfg,
@@ -397,12 +415,19 @@ func New(api Provider, ds dtypes.MetadataDS, netName dtypes.NetworkName, j journ
 
 	go func() {
 		err := mp.loadLocal()
+		if err != nil {
+			log.Errorf("loading local messages: %+v", err)
+		}
+
+		// local messages are loaded first, so that they aren't pushed out by
+		// the snapshot
+		err = mp.loadSnapshot()
 
 		mp.lk.Unlock()
 		mp.curTsLk.Unlock()
 
 		if err != nil {
-			log.Errorf("loading local messages: %+v", err)
+			log.Errorf("loading mpool snapshot: %+v", err)
 		}
 
 		log.Info("mpool ready")
@@ -436,6 +461,10 @@ func (mp *MessagePool) ForEachPendingMessage(f func(cid.Cid) error) error {
 
 func (mp *MessagePool) Close() error {
 	close(mp.closer)
+
+	if err := mp.saveSnapshot(); err != nil {
+		log.Errorf("saving mpool snapshot: %+v", err)
+	}
 	return nil
 }
 
@@ -452,8 +481,16 @@ func (mp *MessagePool) Prune() {
 }
 
 func (mp *MessagePool) runLoop() {
+	snapshotTk := build.Clock.Ticker(SnapshotInterval)
+	defer snapshotTk.Stop()
+
 	for {
 		select {
+		case <-snapshotTk.C:
+			if err := mp.saveSnapshot(); err != nil {
+				log.Errorf("saving mpool snapshot: %+v", err)
+			}
+
 		case <-mp.repubTk.C:
 			if err := mp.republishPendingMessages(); err != nil {
 				log.Errorf("error while republishing messages: %s", err)
@@ -578,6 +615,46 @@ func (mp *MessagePool) Push(m *types.SignedMessage) (cid.Cid, error) {
 	return m.Cid(), nil
 }
 
+// PushRemote adds a message signed outside of the node, e.g. pushed through
+// the API. Unless its sender is one of the PolicyExemptAddrs, the message is
+// added like one received from the network: it is checked against the
+// admission policies and its sender doesn't become local.
+func (mp *MessagePool) PushRemote(m *types.SignedMessage) (cid.Cid, error) {
+	if mp.policyExempt(m.Message.From) {
+		return mp.Push(m)
+	}
+
+	err := mp.checkMessage(m)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	// serialize push access to reduce lock contention
+	mp.addSema <- struct{}{}
+	defer func() {
+		<-mp.addSema
+	}()
+
+	mp.curTsLk.Lock()
+	_, err = mp.addTs(m, mp.curTs, false, false)
+	mp.curTsLk.Unlock()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	msgb, err := m.Serialize()
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("error serializing message: %w", err)
+	}
+
+	err = mp.api.PubSubPublish(build.MessagesTopic(mp.netName), msgb)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("error publishing message: %w", err)
+	}
+
+	return m.Cid(), nil
+}
+
 func (mp *MessagePool) checkMessage(m *types.SignedMessage) error {
 	// big messages are bad, anti DOS
 	if m.Size() > 32*1024 {
@@ -702,6 +779,12 @@ func (mp *MessagePool) addTs(m *types.SignedMessage, curTs *types.TipSet, local,
 		return false, xerrors.Errorf("minimum expected nonce is %d: %w", snonce, ErrNonceTooLow)
 	}
 
+	if !local || untrusted {
+		if err := mp.checkPolicy(m, curTs); err != nil {
+			return false, err
+		}
+	}
+
 	mp.lk.Lock()
 	defer mp.lk.Unlock()
 
@@ -0,0 +1,218 @@
+package messagepool
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// SnapshotInterval is how often the pending messages are saved to disk
+var SnapshotInterval = 5 * time.Minute
+
+const snapshotDs = "/mpool/snapshot"
+
+// pendingMessages returns all pending messages. Must be called with mp.lk
+// held.
+func (mp *MessagePool) pendingMessages() []api.MpoolPendingMessage {
+	out := make([]api.MpoolPendingMessage, 0, mp.currentSize)
+	for from, mset := range mp.pending {
+		_, local := mp.localAddrs[from]
+		for nonce, m := range mset.msgs {
+			out = append(out, api.MpoolPendingMessage{
+				Message:  m,
+				Received: mset.received[nonce],
+				Local:    local,
+			})
+		}
+	}
+	return out
+}
+
+// Export returns all pending messages
+func (mp *MessagePool) Export() []api.MpoolPendingMessage {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	return mp.pendingMessages()
+}
+
+// Import adds messages exported from another node. Messages are validated
+// as if they were received from the network.
+func (mp *MessagePool) Import(msgs []api.MpoolPendingMessage) *api.MpoolImportResult {
+	res := &api.MpoolImportResult{
+		Errors: map[string]string{},
+	}
+
+	for _, pm := range msgs {
+		if pm.Message == nil {
+			res.Skipped++
+			continue
+		}
+
+		if err := mp.Add(pm.Message); err != nil {
+			if xerrors.Is(err, ErrSoftValidationFailure) || xerrors.Is(err, ErrNonceTooLow) {
+				res.Skipped++
+				continue
+			}
+			res.Errors[pm.Message.Cid().String()] = err.Error()
+			continue
+		}
+		res.Added++
+	}
+
+	return res
+}
+
+// saveSnapshot writes the pending messages to the datastore, adding new
+// messages and removing the ones which aren't pending anymore
+func (mp *MessagePool) saveSnapshot() error {
+	mp.snapshotLk.Lock()
+	defer mp.snapshotLk.Unlock()
+
+	mp.lk.Lock()
+	pending := mp.pendingMessages()
+	mp.lk.Unlock()
+
+	b, err := mp.snapshot.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	keys := make(map[datastore.Key]struct{}, len(pending))
+	for _, pm := range pending {
+		k := datastore.NewKey(pm.Message.Cid().String())
+		keys[k] = struct{}{}
+		if _, saved := mp.snapshotKeys[k]; saved {
+			continue
+		}
+
+		v, err := json.Marshal(&pm)
+		if err != nil {
+			return xerrors.Errorf("marshaling message: %w", err)
+		}
+		if err := b.Put(k, v); err != nil {
+			return xerrors.Errorf("putting message: %w", err)
+		}
+	}
+
+	for k := range mp.snapshotKeys {
+		if _, ok := keys[k]; ok {
+			continue
+		}
+		if err := b.Delete(k); err != nil {
+			return xerrors.Errorf("deleting message: %w", err)
+		}
+	}
+
+	if err := b.Commit(); err != nil {
+		return xerrors.Errorf("committing snapshot: %w", err)
+	}
+
+	mp.snapshotKeys = keys
+	return nil
+}
+
+// loadSnapshot adds the messages of the last snapshot, revalidating them
+// against the current tipset. Must be called with mp.curTsLk and mp.lk held.
+func (mp *MessagePool) loadSnapshot() error {
+	mp.snapshotLk.Lock()
+	defer mp.snapshotLk.Unlock()
+
+	res, err := mp.snapshot.Query(query.Query{})
+	if err != nil {
+		return xerrors.Errorf("query snapshot: %w", err)
+	}
+	defer res.Close() //nolint:errcheck
+
+	var added, dropped int
+	for r := range res.Next() {
+		if r.Error != nil {
+			return xerrors.Errorf("r.Error: %w", r.Error)
+		}
+
+		// messages which can't be added are removed on the next save
+		mp.snapshotKeys[datastore.NewKey(r.Key)] = struct{}{}
+
+		var pm api.MpoolPendingMessage
+		if err := json.Unmarshal(r.Value, &pm); err != nil || pm.Message == nil {
+			log.Warnf("invalid message in mpool snapshot (key %s): %s", r.Key, err)
+			dropped++
+			continue
+		}
+
+		m := pm.Message
+		if mset, ok := mp.pending[m.Message.From]; ok {
+			if cur, ok := mset.msgs[m.Message.Nonce]; ok && cur.Cid() == m.Cid() {
+				// already loaded from the local messages
+				if !pm.Received.IsZero() {
+					mset.received[m.Message.Nonce] = pm.Received
+				}
+				continue
+			}
+		}
+
+		if err := mp.addSnapshotted(m, pm.Local); err != nil {
+			if !xerrors.Is(err, ErrNonceTooLow) {
+				log.Debugf("dropping message %s from mpool snapshot: %s", m.Cid(), err)
+			}
+			dropped++
+			continue
+		}
+
+		if !pm.Received.IsZero() {
+			mp.pending[m.Message.From].received[m.Message.Nonce] = pm.Received
+		}
+		added++
+	}
+
+	log.Infow("loaded mpool snapshot", "added", added, "dropped", dropped)
+	return nil
+}
+
+// addSnapshotted revalidates a message from the snapshot against the current
+// tipset and adds it. Messages which were received from the network are also
+// checked against the admission policies again, as those may have changed
+// while the node was down.
+func (mp *MessagePool) addSnapshotted(m *types.SignedMessage, local bool) error {
+	err := mp.checkMessage(m)
+	if err != nil {
+		return err
+	}
+
+	curTs := mp.curTs
+	if curTs == nil {
+		return xerrors.Errorf("current tipset not loaded")
+	}
+
+	snonce, err := mp.getStateNonce(m.Message.From, curTs)
+	if err != nil {
+		return xerrors.Errorf("failed to look up actor state nonce: %s: %w", err, ErrSoftValidationFailure)
+	}
+
+	if snonce > m.Message.Nonce {
+		return xerrors.Errorf("minimum expected nonce is %d: %w", snonce, ErrNonceTooLow)
+	}
+
+	if !local {
+		if err := mp.checkPolicy(m, curTs); err != nil {
+			return err
+		}
+	}
+
+	_, err = mp.verifyMsgBeforeAdd(m, curTs, local)
+	if err != nil {
+		return err
+	}
+
+	if err := mp.checkBalance(m, curTs); err != nil {
+		return err
+	}
+
+	return mp.addLocked(m, false, false)
+}
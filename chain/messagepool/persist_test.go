@@ -0,0 +1,74 @@
+package messagepool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+
+	"github.com/EpiK-Protocol/go-epik/chain/messagepool/gasguess"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/wallet"
+)
+
+func TestSnapshotReload(t *testing.T) {
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, ds, "mptest", nil)
+	require.NoError(t, err)
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+
+	a1, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+	a2, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+
+	tma.setBalance(a1, 1)
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin2.StorageMarketActorCodeID, M: 2}]
+
+	for i := 0; i < 5; i++ {
+		mustAdd(t, mp, makeTestMessage(w, a1, a2, uint64(i), gasLimit, uint64(i+1)))
+	}
+
+	exported := mp.Export()
+	require.Len(t, exported, 5)
+	for _, pm := range exported {
+		require.False(t, pm.Local)
+		require.False(t, pm.Received.IsZero())
+	}
+
+	require.NoError(t, mp.Close())
+
+	// the first two messages are included on chain while the node is down
+	tma.setStateNonce(a1, 2)
+
+	mp, err = New(tma, ds, ds, "mptest", nil)
+	require.NoError(t, err)
+
+	pending, _ := mp.Pending()
+	require.Len(t, pending, 3)
+
+	received := map[uint64]int64{}
+	for _, pm := range exported {
+		received[pm.Message.Message.Nonce] = pm.Received.UnixNano()
+	}
+	for _, pm := range mp.Export() {
+		require.Equal(t, received[pm.Message.Message.Nonce], pm.Received.UnixNano())
+	}
+
+	// import into an empty pool
+	mp2, tma2 := makeTestMpool()
+	tma2.setBalance(a1, 1)
+	tma2.setStateNonce(a1, 2)
+
+	res := mp2.Import(exported)
+	require.Equal(t, 3, res.Added)
+	require.Equal(t, 2, res.Skipped)
+	require.Empty(t, res.Errors)
+}
@@ -0,0 +1,177 @@
+package messagepool
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+var (
+	ErrDeniedByPolicy      = errors.New("message denied by mpool policy")
+	ErrPremiumBelowPolicy  = errors.New("gas premium below mpool policy minimum")
+	ErrExpertBlocked       = errors.New("destination expert is blocked")
+	ErrSenderPendingPolicy = errors.New("too many pending messages for sender by mpool policy")
+)
+
+const (
+	actorStorageMiner = "storageminer"
+	actorExpert       = "expert"
+	actorExpertFund   = "expertfund"
+	actorGovern       = "govern"
+)
+
+// actorType returns the type of the actor at the address, e.g.
+// "storageminer", or an empty string if the actor doesn't exist
+func (mp *MessagePool) actorType(addr address.Address, ts *types.TipSet) string {
+	act, err := mp.api.GetActorAfter(addr, ts)
+	if err != nil {
+		return ""
+	}
+
+	name := builtin.ActorNameByCode(act.Code)
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+func matchMessage(match *types.MpoolMessageMatch, m *types.Message, actor func() string) bool {
+	if len(match.To) > 0 {
+		var found bool
+		for _, to := range match.To {
+			if to == m.To {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(match.Methods) > 0 {
+		var found bool
+		for _, method := range match.Methods {
+			if method == m.Method {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if match.Actor != "" && match.Actor != actor() {
+		return false
+	}
+
+	return true
+}
+
+// policyExempt returns whether the messages of the sender pushed through the
+// API are trusted like local ones
+func (mp *MessagePool) policyExempt(from address.Address) bool {
+	for _, a := range mp.getConfig().PolicyExemptAddrs {
+		if a == from {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPolicy checks the message against the admission policies of the
+// mpool config. Messages pushed locally through the trusted path, i.e. those
+// of the wallet keys and of the PolicyExemptAddrs, skip the policies, they
+// would otherwise lose the local lane.
+func (mp *MessagePool) checkPolicy(m *types.SignedMessage, curTs *types.TipSet) error {
+	cfg := mp.getConfig()
+	if len(cfg.DenyRules) == 0 && len(cfg.MinPremiums) == 0 && !cfg.RejectBlockedExperts {
+		return nil
+	}
+
+	var toType *string
+	actor := func() string {
+		if toType == nil {
+			t := mp.actorType(m.Message.To, curTs)
+			toType = &t
+		}
+		return *toType
+	}
+
+	for i := range cfg.DenyRules {
+		if matchMessage(&cfg.DenyRules[i], &m.Message, actor) {
+			return xerrors.Errorf("message to %s, method %d: %w", m.Message.To, m.Message.Method, ErrDeniedByPolicy)
+		}
+	}
+
+	for i := range cfg.MinPremiums {
+		rule := &cfg.MinPremiums[i]
+		if !matchMessage(&rule.MpoolMessageMatch, &m.Message, actor) {
+			continue
+		}
+		if m.Message.GasPremium.LessThan(rule.MinGasPremium) {
+			return xerrors.Errorf("gas premium %s, required %s: %w", m.Message.GasPremium, rule.MinGasPremium, ErrPremiumBelowPolicy)
+		}
+	}
+
+	if cfg.RejectBlockedExperts && actor() == actorExpert {
+		info, err := mp.api.ExpertInfo(m.Message.To, curTs)
+		if err != nil {
+			return xerrors.Errorf("loading expert info: %s: %w", err, ErrSoftValidationFailure)
+		}
+		if info.Status == expert.ExpertStateBlocked {
+			return xerrors.Errorf("message to expert %s: %w", m.Message.To, ErrExpertBlocked)
+		}
+	}
+
+	return nil
+}
+
+// isLaneMessage returns whether the message belongs in the local lane: miner
+// PoSt and fault messages, and messages to expert and governance actors
+func isLaneMessage(m *types.Message, actor string) bool {
+	switch actor {
+	case actorStorageMiner:
+		switch m.Method {
+		case miner.Methods.SubmitWindowedPoSt, miner.Methods.DeclareFaults, miner.Methods.DeclareFaultsRecovered:
+			return true
+		}
+	case actorExpert, actorExpertFund, actorGovern:
+		return true
+	}
+	return false
+}
+
+// localLaneActors returns the local actors with lane messages in the pending
+// set. Must be called with mp.lk held.
+func (mp *MessagePool) localLaneActors(pending map[address.Address]map[uint64]*types.SignedMessage, ts *types.TipSet) []address.Address {
+	actorTypes := map[address.Address]string{}
+
+	var out []address.Address
+	for actor, mset := range pending {
+		if _, local := mp.localAddrs[actor]; !local {
+			continue
+		}
+
+		for _, m := range mset {
+			at, ok := actorTypes[m.Message.To]
+			if !ok {
+				at = mp.actorType(m.Message.To, ts)
+				actorTypes[m.Message.To] = at
+			}
+
+			if isLaneMessage(&m.Message, at) {
+				out = append(out, actor)
+				break
+			}
+		}
+	}
+
+	return out
+}
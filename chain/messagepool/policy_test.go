@@ -0,0 +1,137 @@
+package messagepool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+
+	"github.com/EpiK-Protocol/go-epik/chain/messagepool/gasguess"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+	"github.com/EpiK-Protocol/go-epik/chain/wallet"
+)
+
+func TestMessagePolicy(t *testing.T) {
+	mp, tma := makeTestMpool()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+
+	sender, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+
+	market, other := mock.Address(1001), mock.Address(1002)
+	tma.setBalance(sender, 1000)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin2.StorageMarketActorCodeID, M: 2}]
+
+	cfg := mp.GetConfig()
+	cfg.DenyRules = []types.MpoolMessageMatch{{
+		To:      []address.Address{market},
+		Methods: []abi.MethodNum{2},
+	}}
+	cfg.MinPremiums = []types.MpoolMinPremium{{
+		MpoolMessageMatch: types.MpoolMessageMatch{Actor: "storagemarket"},
+		MinGasPremium:     types.NewInt(10),
+	}}
+	cfg.MaxPendingPerSender = 2
+	require.NoError(t, mp.SetConfig(cfg))
+
+	err = mp.Add(makeTestMessage(w, sender, market, 0, gasLimit, 20))
+	require.True(t, xerrors.Is(err, ErrDeniedByPolicy), err)
+
+	err = mp.Add(makeTestMessage(w, sender, other, 0, gasLimit, 5))
+	require.True(t, xerrors.Is(err, ErrPremiumBelowPolicy), err)
+
+	mustAdd(t, mp, makeTestMessage(w, sender, other, 0, gasLimit, 10))
+	mustAdd(t, mp, makeTestMessage(w, sender, other, 1, gasLimit, 10))
+
+	err = mp.Add(makeTestMessage(w, sender, other, 2, gasLimit, 10))
+	require.True(t, xerrors.Is(err, ErrSenderPendingPolicy), err)
+
+	// replacing a pending message is still allowed
+	mustAdd(t, mp, makeTestMessage(w, sender, other, 1, gasLimit, 20))
+}
+
+func TestMessagePolicyLocal(t *testing.T) {
+	mp, tma := makeTestMpool()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+
+	sender, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+
+	other := mock.Address(1002)
+	tma.setBalance(sender, 1000)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin2.StorageMarketActorCodeID, M: 2}]
+
+	cfg := mp.GetConfig()
+	cfg.DenyRules = []types.MpoolMessageMatch{{
+		To: []address.Address{other},
+	}}
+	cfg.MaxPendingPerSender = 1
+	require.NoError(t, mp.SetConfig(cfg))
+
+	// untrusted pushes are subject to the policies
+	_, err = mp.PushUntrusted(makeTestMessage(w, sender, other, 0, gasLimit, 10))
+	require.True(t, xerrors.Is(err, ErrDeniedByPolicy), err)
+
+	// trusted local pushes are not
+	for i := uint64(0); i < 3; i++ {
+		_, err = mp.Push(makeTestMessage(w, sender, other, i, gasLimit, 10))
+		require.NoError(t, err)
+	}
+}
+
+func TestMessagePolicyRemote(t *testing.T) {
+	mp, tma := makeTestMpool()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	require.NoError(t, err)
+
+	sender, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+	exempt, err := w.WalletNew(context.Background(), types.KTSecp256k1)
+	require.NoError(t, err)
+
+	other := mock.Address(1002)
+	tma.setBalance(sender, 1000)
+	tma.setBalance(exempt, 1000)
+
+	gasLimit := gasguess.Costs[gasguess.CostKey{Code: builtin2.StorageMarketActorCodeID, M: 2}]
+
+	cfg := mp.GetConfig()
+	cfg.MaxPendingPerSender = 1
+	cfg.PolicyExemptAddrs = []address.Address{exempt}
+	require.NoError(t, mp.SetConfig(cfg))
+
+	// messages of other senders pushed through the API are subject to the
+	// policies, and don't make their sender local
+	_, err = mp.PushRemote(makeTestMessage(w, sender, other, 0, gasLimit, 10))
+	require.NoError(t, err)
+	require.Equal(t, 1, tma.published)
+
+	_, err = mp.PushRemote(makeTestMessage(w, sender, other, 1, gasLimit, 10))
+	require.True(t, xerrors.Is(err, ErrSenderPendingPolicy), err)
+
+	_, local := mp.localAddrs[sender]
+	require.False(t, local)
+
+	// exempt senders are trusted like the wallet keys
+	for i := uint64(0); i < 3; i++ {
+		_, err = mp.PushRemote(makeTestMessage(w, exempt, other, i, gasLimit, 10))
+		require.NoError(t, err)
+	}
+
+	_, local = mp.localAddrs[exempt]
+	require.True(t, local)
+}
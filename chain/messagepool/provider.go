@@ -9,6 +9,8 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
@@ -30,6 +32,7 @@ type Provider interface {
 	MessagesForTipset(*types.TipSet) ([]types.ChainMsg, error)
 	LoadTipSet(tsk types.TipSetKey) (*types.TipSet, error)
 	ChainComputeBaseFee(ctx context.Context, ts *types.TipSet) (types.BigInt, error)
+	ExpertInfo(address.Address, *types.TipSet) (*expert.ExpertInfo, error)
 }
 
 type mpoolProvider struct {
@@ -72,6 +75,18 @@ func (mpp *mpoolProvider) GetActorAfter(addr address.Address, ts *types.TipSet)
 	return st.GetActor(addr)
 }
 
+func (mpp *mpoolProvider) ExpertInfo(addr address.Address, ts *types.TipSet) (*expert.ExpertInfo, error) {
+	act, err := mpp.GetActorAfter(addr, ts)
+	if err != nil {
+		return nil, err
+	}
+	st, err := expert.Load(mpp.sm.ChainStore().ActorStore(context.TODO()), act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert state: %w", err)
+	}
+	return st.Info()
+}
+
 func (mpp *mpoolProvider) StateAccountKey(ctx context.Context, addr address.Address, ts *types.TipSet) (address.Address, error) {
 	return mpp.sm.ResolveToKeyAddress(ctx, addr, ts)
 }
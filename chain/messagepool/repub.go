@@ -47,6 +47,14 @@ func (mp *MessagePool) republishPendingMessages() error {
 		}
 		pending[actor] = pend
 	}
+
+	// local lane actors are republished first
+	lane := make(map[address.Address]struct{})
+	if mp.getConfig().LocalLane {
+		for _, actor := range mp.localLaneActors(pending, ts) {
+			lane[actor] = struct{}{}
+		}
+	}
 	mp.lk.Unlock()
 	mp.curTsLk.Unlock()
 
@@ -68,8 +76,22 @@ func (mp *MessagePool) republishPendingMessages() error {
 		return nil
 	}
 
+	inLane := func(c *msgChain) bool {
+		if len(c.msgs) == 0 {
+			return false
+		}
+		_, ok := lane[c.msgs[0].Message.From]
+		return ok
+	}
+	before := func(a, b *msgChain) bool {
+		if aLane, bLane := inLane(a), inLane(b); aLane != bLane {
+			return aLane
+		}
+		return a.Before(b)
+	}
+
 	sort.Slice(chains, func(i, j int) bool {
-		return chains[i].Before(chains[j])
+		return before(chains[i], chains[j])
 	})
 
 	gasLimit := int64(build.BlockGasLimit)
@@ -117,7 +139,7 @@ loop:
 		// trim it and push it down
 		chain.Trim(gasLimit, mp, baseFee)
 		for j := i; j < len(chains)-1; j++ {
-			if chains[j].Before(chains[j+1]) {
+			if before(chains[j], chains[j+1]) {
 				break
 			}
 			chains[j], chains[j+1] = chains[j+1], chains[j]
@@ -535,12 +535,23 @@ func (mp *MessagePool) selectPriorityMessages(pending map[address.Address]map[ui
 	mpCfg := mp.getConfig()
 	result := make([]*types.SignedMessage, 0, mpCfg.SizeLimitLow)
 	gasLimit := int64(build.BlockGasLimit)
+
+	// local lane actors go before any other priority actors
+	if mpCfg.LocalLane {
+		result, gasLimit = mp.selectActorMessages(mp.localLaneActors(pending, ts), pending, baseFee, ts, result, gasLimit)
+	}
+
+	return mp.selectActorMessages(mpCfg.PriorityAddrs, pending, baseFee, ts, result, gasLimit)
+}
+
+// selectActorMessages selects the messages of the given actors that fit in
+// the remaining gas limit, removing the actors from the pending set
+func (mp *MessagePool) selectActorMessages(actors []address.Address, pending map[address.Address]map[uint64]*types.SignedMessage, baseFee types.BigInt, ts *types.TipSet, result []*types.SignedMessage, gasLimit int64) ([]*types.SignedMessage, int64) {
 	minGas := int64(gasguess.MinGas)
 
 	// 1. Get priority actor chains
 	var chains []*msgChain
-	priority := mpCfg.PriorityAddrs
-	for _, actor := range priority {
+	for _, actor := range actors {
 		mset, ok := pending[actor]
 		if ok {
 			// remove actor from pending set as we are already processed these messages
@@ -552,7 +563,7 @@ func (mp *MessagePool) selectPriorityMessages(pending map[address.Address]map[ui
 	}
 
 	if len(chains) == 0 {
-		return nil, gasLimit
+		return result, gasLimit
 	}
 
 	// 2. Sort the chains
@@ -562,7 +573,7 @@ func (mp *MessagePool) selectPriorityMessages(pending map[address.Address]map[ui
 
 	if len(chains) != 0 && chains[0].gasPerf < 0 {
 		log.Warnw("all priority messages in mpool have negative gas performance", "bestGasPerf", chains[0].gasPerf)
-		return nil, gasLimit
+		return result, gasLimit
 	}
 
 	// 3. Merge chains until the block limit, as long as they have non-negative gas performance
@@ -7,6 +7,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.uber.org/fx"
 
@@ -24,8 +25,8 @@ type Update struct {
 	Type string
 }
 
-func SendHeadNotifs(nickname string) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ps *pubsub.PubSub, chain full.ChainAPI) error {
-	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ps *pubsub.PubSub, chain full.ChainAPI) error {
+func SendHeadNotifs(nickname string) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, h host.Host, ps *pubsub.PubSub, chain full.ChainAPI) error {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, h host.Host, ps *pubsub.PubSub, chain full.ChainAPI) error {
 		ctx := helpers.LifecycleCtx(mctx, lc)
 
 		lc.Append(fx.Hook{
@@ -38,7 +39,7 @@ func SendHeadNotifs(nickname string) func(mctx helpers.MetricsCtx, lc fx.Lifecyc
 				topic := baseTopic + gen.Cid().String()
 
 				go func() {
-					if err := sendHeadNotifs(ctx, ps, topic, chain, nickname); err != nil {
+					if err := sendHeadNotifs(ctx, h, ps, topic, chain, nickname); err != nil {
 						log.Error("consensus metrics error", err)
 						return
 					}
@@ -76,10 +77,11 @@ type message struct {
 
 	// Meta
 
-	NodeName string
+	NodeName  string
+	PeerCount int
 }
 
-func sendHeadNotifs(ctx context.Context, ps *pubsub.PubSub, topic string, chain full.ChainAPI, nickname string) error {
+func sendHeadNotifs(ctx context.Context, h host.Host, ps *pubsub.PubSub, topic string, chain full.ChainAPI, nickname string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -102,13 +104,14 @@ func sendHeadNotifs(ctx context.Context, ps *pubsub.PubSub, topic string, chain
 			}
 
 			m := message{
-				Cids:     n.Val.Cids(),
-				Blocks:   n.Val.Blocks(),
-				Height:   n.Val.Height(),
-				Weight:   w,
-				NodeName: nickname,
-				Time:     uint64(build.Clock.Now().UnixNano() / 1000_000),
-				Nonce:    nonce,
+				Cids:      n.Val.Cids(),
+				Blocks:    n.Val.Blocks(),
+				Height:    n.Val.Height(),
+				Weight:    w,
+				NodeName:  nickname,
+				PeerCount: len(h.Network().Peers()),
+				Time:      uint64(build.Clock.Now().UnixNano() / 1000_000),
+				Nonce:     nonce,
 			}
 
 			b, err := json.Marshal(m)
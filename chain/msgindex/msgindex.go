@@ -0,0 +1,421 @@
+package msgindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+var log = logging.Logger("msgindex")
+
+var ErrNotFound = errors.New("message not found in index")
+
+// MsgInfo locates the execution of a message on chain
+type MsgInfo struct {
+	// Message is the CID of the message as included in the block, which for
+	// signed messages is the CID of the signed message
+	Message cid.Cid
+
+	// TipSet is the tipset the message was included in
+	TipSet types.TipSetKey
+	Epoch  abi.ChainEpoch
+
+	// ExecutedTipSet is the child of TipSet, carrying the message receipt
+	ExecutedTipSet types.TipSetKey
+	ExecutedEpoch  abi.ChainEpoch
+	// ReceiptIndex is the index of the message receipt in the parent
+	// message receipts of ExecutedTipSet
+	ReceiptIndex int
+}
+
+// MsgIndex looks up where messages were executed
+type MsgIndex interface {
+	// GetMsgInfo returns ErrNotFound for messages which aren't indexed
+	GetMsgInfo(ctx context.Context, m cid.Cid) (MsgInfo, error)
+	Close() error
+}
+
+type dummyMsgIndex struct{}
+
+func (dummyMsgIndex) GetMsgInfo(context.Context, cid.Cid) (MsgInfo, error) {
+	return MsgInfo{}, ErrNotFound
+}
+
+func (dummyMsgIndex) Close() error {
+	return nil
+}
+
+// DummyMsgIndex is used when the index is disabled, nothing is ever found in it
+var DummyMsgIndex MsgIndex = dummyMsgIndex{}
+
+// ChainStore is the part of the chain store the index is maintained from
+type ChainStore interface {
+	GetHeaviestTipSet() *types.TipSet
+	LoadTipSet(types.TipSetKey) (*types.TipSet, error)
+	MessagesForTipset(*types.TipSet) ([]types.ChainMsg, error)
+	SubscribeHeadChanges(store.ReorgNotifee)
+}
+
+var (
+	// height of the last head the index was caught up to
+	headKey = datastore.NewKey("/meta/head")
+	// lowest height indexed by backfilling
+	lowKey = datastore.NewKey("/meta/low")
+)
+
+const backfillProgressInterval = 100
+
+func msgKey(c cid.Cid) datastore.Key {
+	return datastore.NewKey("/msgs/" + c.String())
+}
+
+// Index is a MsgIndex persisted in a datastore, kept up to date with the
+// chain through head change notifications. Index entries of tipsets which
+// were reverted without being noticed (e.g. while the node was offline) may
+// linger, so users must check that ExecutedTipSet is on their chain.
+type Index struct {
+	ds datastore.Batching
+	cs ChainStore
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	lk        sync.Mutex
+	caughtUp  bool
+	startedAt abi.ChainEpoch
+}
+
+func New(ds datastore.Batching, cs ChainStore) *Index {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Index{
+		ds: ds,
+		cs: cs,
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start subscribes to head changes and indexes tipsets applied since the
+// index last ran in the background
+func (x *Index) Start() {
+	head := x.cs.GetHeaviestTipSet()
+
+	x.lk.Lock()
+	x.startedAt = head.Height()
+	x.lk.Unlock()
+
+	x.cs.SubscribeHeadChanges(x.onHeadChange)
+
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+
+		if err := x.catchUp(head); err != nil {
+			log.Errorf("catching up message index: %+v", err)
+		}
+	}()
+}
+
+// Backfill indexes tipsets from the lowest indexed height down to the given
+// epoch in the background. Progress is persisted, so an interrupted backfill
+// resumes where it stopped.
+func (x *Index) Backfill(from abi.ChainEpoch) {
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+
+		if err := x.backfill(x.ctx, from); err != nil {
+			log.Errorf("backfilling message index: %+v", err)
+		}
+	}()
+}
+
+func (x *Index) Close() error {
+	x.cancel()
+	x.wg.Wait()
+	return nil
+}
+
+func (x *Index) GetMsgInfo(ctx context.Context, m cid.Cid) (MsgInfo, error) {
+	var out MsgInfo
+
+	v, err := x.ds.Get(msgKey(m))
+	if err == datastore.ErrNotFound {
+		return out, ErrNotFound
+	}
+	if err != nil {
+		return out, xerrors.Errorf("getting message info: %w", err)
+	}
+
+	if err := json.Unmarshal(v, &out); err != nil {
+		return out, xerrors.Errorf("unmarshaling message info: %w", err)
+	}
+
+	return out, nil
+}
+
+func (x *Index) onHeadChange(rev, app []*types.TipSet) error {
+	for _, ts := range rev {
+		if err := x.revertTipSet(ts); err != nil {
+			return xerrors.Errorf("reverting tipset %d: %w", ts.Height(), err)
+		}
+	}
+
+	var height abi.ChainEpoch = -1
+	for _, ts := range app {
+		if err := x.indexTipSet(ts); err != nil {
+			return xerrors.Errorf("indexing tipset %d: %w", ts.Height(), err)
+		}
+		if ts.Height() > height {
+			height = ts.Height()
+		}
+	}
+
+	x.lk.Lock()
+	caughtUp := x.caughtUp
+	x.lk.Unlock()
+
+	// the head height is only moved once the index caught up, so that a
+	// restart doesn't skip tipsets which weren't indexed yet
+	if caughtUp && height >= 0 {
+		return x.putHeight(headKey, height)
+	}
+	return nil
+}
+
+// catchUp indexes tipsets between the head the index last saw and the
+// current head
+func (x *Index) catchUp(head *types.TipSet) error {
+	last, found, err := x.getHeight(headKey)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// new index, only index from the current head on; history can be
+		// indexed with Backfill
+		last = head.Height() - 1
+		if err := x.putHeight(lowKey, head.Height()); err != nil {
+			return err
+		}
+	}
+
+	ts := head
+	for ts.Height() > last && ts.Height() > 0 {
+		if x.ctx.Err() != nil {
+			return nil
+		}
+
+		if err := x.indexTipSet(ts); err != nil {
+			return xerrors.Errorf("indexing tipset %d: %w", ts.Height(), err)
+		}
+
+		if ts, err = x.cs.LoadTipSet(ts.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	if err := x.putHeight(headKey, head.Height()); err != nil {
+		return err
+	}
+
+	x.lk.Lock()
+	x.caughtUp = true
+	x.lk.Unlock()
+
+	log.Infow("message index caught up", "from", last, "to", head.Height())
+	return nil
+}
+
+func (x *Index) backfill(ctx context.Context, from abi.ChainEpoch) error {
+	low, found, err := x.getHeight(lowKey)
+	if err != nil {
+		return err
+	}
+	if !found {
+		x.lk.Lock()
+		low = x.startedAt
+		x.lk.Unlock()
+	}
+
+	if low <= from {
+		return nil
+	}
+
+	log.Infow("backfilling message index", "from", from, "to", low)
+
+	// find the tipset at the low height by walking back from the head
+	ts := x.cs.GetHeaviestTipSet()
+	for ts.Height() > low {
+		if ts, err = x.cs.LoadTipSet(ts.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	for ts.Height() > from && ts.Height() > 0 {
+		if ctx.Err() != nil {
+			return x.putHeight(lowKey, ts.Height())
+		}
+
+		if err := x.indexTipSet(ts); err != nil {
+			return xerrors.Errorf("indexing tipset %d: %w", ts.Height(), err)
+		}
+
+		if ts, err = x.cs.LoadTipSet(ts.Parents()); err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+
+		if ts.Height()%backfillProgressInterval == 0 {
+			if err := x.putHeight(lowKey, ts.Height()); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Infow("message index backfill done", "from", from)
+	return x.putHeight(lowKey, ts.Height())
+}
+
+// indexTipSet indexes the messages executed by the tipset, which are the
+// messages included in its parent
+func (x *Index) indexTipSet(ts *types.TipSet) error {
+	if ts.Height() == 0 {
+		return nil
+	}
+
+	pts, msgs, err := x.parentMessages(ts)
+	if err != nil {
+		return err
+	}
+
+	b, err := x.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	for i, m := range msgs {
+		v, err := json.Marshal(&MsgInfo{
+			Message:        m.Cid(),
+			TipSet:         pts.Key(),
+			Epoch:          pts.Height(),
+			ExecutedTipSet: ts.Key(),
+			ExecutedEpoch:  ts.Height(),
+			ReceiptIndex:   i,
+		})
+		if err != nil {
+			return xerrors.Errorf("marshaling message info: %w", err)
+		}
+
+		if err := b.Put(msgKey(m.Cid()), v); err != nil {
+			return err
+		}
+
+		// signed messages can also be looked up by the unsigned message CID
+		if vc := m.VMMessage().Cid(); vc != m.Cid() {
+			if err := b.Put(msgKey(vc), v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.Commit()
+}
+
+// revertTipSet removes the entries of messages executed by the tipset
+func (x *Index) revertTipSet(ts *types.TipSet) error {
+	if ts.Height() == 0 {
+		return nil
+	}
+
+	_, msgs, err := x.parentMessages(ts)
+	if err != nil {
+		return err
+	}
+
+	b, err := x.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	for _, m := range msgs {
+		keys := []cid.Cid{m.Cid()}
+		if vc := m.VMMessage().Cid(); vc != m.Cid() {
+			keys = append(keys, vc)
+		}
+
+		for _, c := range keys {
+			mi, err := x.GetMsgInfo(x.ctx, c)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			// the message may have been indexed again in a tipset on the new chain
+			if mi.ExecutedTipSet != ts.Key() {
+				continue
+			}
+
+			if err := b.Delete(msgKey(c)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.Commit()
+}
+
+func (x *Index) parentMessages(ts *types.TipSet) (*types.TipSet, []types.ChainMsg, error) {
+	pts, err := x.cs.LoadTipSet(ts.Parents())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	msgs, err := x.cs.MessagesForTipset(pts)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("loading messages: %w", err)
+	}
+
+	return pts, msgs, nil
+}
+
+func (x *Index) getHeight(k datastore.Key) (abi.ChainEpoch, bool, error) {
+	v, err := x.ds.Get(k)
+	if err == datastore.ErrNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, xerrors.Errorf("getting %s: %w", k, err)
+	}
+
+	h, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, false, xerrors.Errorf("parsing %s: %w", k, err)
+	}
+
+	return abi.ChainEpoch(h), true, nil
+}
+
+func (x *Index) putHeight(k datastore.Key, h abi.ChainEpoch) error {
+	if err := x.ds.Put(k, []byte(strconv.FormatInt(int64(h), 10))); err != nil {
+		return xerrors.Errorf("putting %s: %w", k, err)
+	}
+	return nil
+}
+
+var _ MsgIndex = (*Index)(nil)
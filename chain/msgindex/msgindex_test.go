@@ -0,0 +1,161 @@
+package msgindex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+)
+
+type testChain struct {
+	head     *types.TipSet
+	tipsets  map[types.TipSetKey]*types.TipSet
+	msgs     map[types.TipSetKey][]types.ChainMsg
+	notifees []store.ReorgNotifee
+}
+
+func newTestChain() *testChain {
+	gen := mock.TipSet(mock.MkBlock(nil, 1, 0))
+	return &testChain{
+		head:    gen,
+		tipsets: map[types.TipSetKey]*types.TipSet{gen.Key(): gen},
+		msgs:    map[types.TipSetKey][]types.ChainMsg{},
+	}
+}
+
+func (tc *testChain) GetHeaviestTipSet() *types.TipSet {
+	return tc.head
+}
+
+func (tc *testChain) LoadTipSet(tsk types.TipSetKey) (*types.TipSet, error) {
+	return tc.tipsets[tsk], nil
+}
+
+func (tc *testChain) MessagesForTipset(ts *types.TipSet) ([]types.ChainMsg, error) {
+	return tc.msgs[ts.Key()], nil
+}
+
+func (tc *testChain) SubscribeHeadChanges(f store.ReorgNotifee) {
+	tc.notifees = append(tc.notifees, f)
+}
+
+// grow adds a tipset on top of parent with the given messages, without
+// notifying subscribers
+func (tc *testChain) grow(parent *types.TipSet, nonce uint64, msgs ...types.ChainMsg) *types.TipSet {
+	ts := mock.TipSet(mock.MkBlock(parent, 1, nonce))
+	tc.tipsets[ts.Key()] = ts
+	tc.msgs[ts.Key()] = msgs
+	return ts
+}
+
+func (tc *testChain) setHead(rev, app []*types.TipSet) error {
+	tc.head = app[len(app)-1]
+	for _, n := range tc.notifees {
+		if err := n(rev, app); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testMsg(nonce uint64) *types.Message {
+	return &types.Message{
+		To:         mock.Address(100),
+		From:       mock.Address(101),
+		Nonce:      nonce,
+		Value:      types.NewInt(1),
+		GasFeeCap:  types.NewInt(1),
+		GasPremium: types.NewInt(1),
+	}
+}
+
+func TestIndexApplyRevert(t *testing.T) {
+	ctx := context.Background()
+	tc := newTestChain()
+
+	x := New(dssync.MutexWrap(datastore.NewMapDatastore()), tc)
+	x.Start()
+	defer x.Close() //nolint:errcheck
+	x.wg.Wait()
+
+	m1, m2 := testMsg(1), testMsg(2)
+
+	ts1 := tc.grow(tc.head, 1, m1, m2)
+	ts2 := tc.grow(ts1, 2)
+	require.NoError(t, tc.setHead(nil, []*types.TipSet{ts1, ts2}))
+
+	mi, err := x.GetMsgInfo(ctx, m2.Cid())
+	require.NoError(t, err)
+	require.Equal(t, ts1.Key(), mi.TipSet)
+	require.Equal(t, ts2.Key(), mi.ExecutedTipSet)
+	require.Equal(t, abi.ChainEpoch(2), mi.ExecutedEpoch)
+	require.Equal(t, 1, mi.ReceiptIndex)
+
+	// reorg ts2 out for a fork executing the messages in a different tipset
+	ts2b := tc.grow(ts1, 3)
+	require.NoError(t, tc.setHead([]*types.TipSet{ts2}, []*types.TipSet{ts2b}))
+
+	mi, err = x.GetMsgInfo(ctx, m1.Cid())
+	require.NoError(t, err)
+	require.Equal(t, ts2b.Key(), mi.ExecutedTipSet)
+
+	// reverting the tipset which included the messages drops them
+	ts1b := tc.grow(tc.tipsets[ts1.Parents()], 4)
+	require.NoError(t, tc.setHead([]*types.TipSet{ts2b, ts1}, []*types.TipSet{ts1b}))
+
+	_, err = x.GetMsgInfo(ctx, m1.Cid())
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestIndexCatchUpAndBackfill(t *testing.T) {
+	ctx := context.Background()
+	tc := newTestChain()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	m1, m2, m3 := testMsg(1), testMsg(2), testMsg(3)
+
+	ts1 := tc.grow(tc.head, 1, m1)
+	ts2 := tc.grow(ts1, 2, m2)
+	ts3 := tc.grow(ts2, 3)
+	tc.head = ts3
+
+	// a new index only indexes the head
+	x := New(ds, tc)
+	x.Start()
+	x.wg.Wait()
+
+	_, err := x.GetMsgInfo(ctx, m2.Cid())
+	require.NoError(t, err)
+	_, err = x.GetMsgInfo(ctx, m1.Cid())
+	require.Equal(t, ErrNotFound, err)
+	require.NoError(t, x.Close())
+
+	// tipsets applied while the index wasn't running are indexed on start
+	ts4 := tc.grow(ts3, 4, m3)
+	ts5 := tc.grow(ts4, 5)
+	tc.head = ts5
+
+	x = New(ds, tc)
+	x.Start()
+	x.wg.Wait()
+
+	mi, err := x.GetMsgInfo(ctx, m3.Cid())
+	require.NoError(t, err)
+	require.Equal(t, ts5.Key(), mi.ExecutedTipSet)
+
+	x.Backfill(0)
+	x.wg.Wait()
+
+	mi, err = x.GetMsgInfo(ctx, m1.Cid())
+	require.NoError(t, err)
+	require.Equal(t, ts2.Key(), mi.ExecutedTipSet)
+	require.NoError(t, x.Close())
+}
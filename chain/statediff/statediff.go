@@ -0,0 +1,351 @@
+// Package statediff computes typed, human-readable diffs between two state
+// trees. The states of the EpiK actors (experts, expert fund, vote fund,
+// retrieval fund, knowledge fund, govern and vesting) and of the miner, power
+// and market actors are expanded into their logical content, so that diffs
+// show the experts, votes, pledges, sectors, claims and deals that changed
+// rather than opaque HAMT roots.
+package statediff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// Change is a change of a single value of an actor. Old is empty for added
+// values, New is empty for removed values.
+type Change struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// ActorDiff holds the changes of an actor between two state trees.
+type ActorDiff struct {
+	Address address.Address
+	// Code is the code of the actor in the right tree, or in the left tree if
+	// the actor was removed
+	Code    cid.Cid
+	Added   bool
+	Removed bool
+	Changes []Change
+}
+
+// Name returns the name of the actor code.
+func (d *ActorDiff) Name() string {
+	return builtin.ActorNameByCode(d.Code)
+}
+
+// Diff returns the diffs of the actors which differ between the left and
+// right state trees, sorted by address. Epoch is the epoch at which epoch
+// dependent values, such as unlocked votes, are computed.
+func Diff(ctx context.Context, bs blockstore.Blockstore, left, right cid.Cid, epoch abi.ChainEpoch) ([]*ActorDiff, error) {
+	cst := cbor.NewCborStore(bs)
+	store := adt.WrapStore(ctx, cst)
+
+	ltree, err := state.LoadStateTree(cst, left)
+	if err != nil {
+		return nil, xerrors.Errorf("loading left state tree: %w", err)
+	}
+	rtree, err := state.LoadStateTree(cst, right)
+	if err != nil {
+		return nil, xerrors.Errorf("loading right state tree: %w", err)
+	}
+
+	changed, err := state.Diff(ltree, rtree)
+	if err != nil {
+		return nil, xerrors.Errorf("diffing state trees: %w", err)
+	}
+
+	var out []*ActorDiff
+	for k, ract := range changed {
+		ract := ract
+
+		addr, err := address.NewFromString(k)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing actor address %s: %w", k, err)
+		}
+
+		lact, err := ltree.GetActor(addr)
+		if err != nil && !xerrors.Is(err, types.ErrActorNotFound) {
+			return nil, xerrors.Errorf("loading left actor %s: %w", addr, err)
+		}
+
+		d, err := diffActor(store, addr, lact, &ract, epoch)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing actor %s: %w", addr, err)
+		}
+		out = append(out, d)
+	}
+
+	// state.Diff only reports the actors of the right tree
+	err = ltree.ForEach(func(addr address.Address, lact *types.Actor) error {
+		_, err := rtree.GetActor(addr)
+		if err == nil {
+			return nil
+		}
+		if !xerrors.Is(err, types.ErrActorNotFound) {
+			return err
+		}
+
+		d, err := diffActor(store, addr, lact, nil, epoch)
+		if err != nil {
+			return xerrors.Errorf("diffing actor %s: %w", addr, err)
+		}
+		out = append(out, d)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("looking for removed actors: %w", err)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i].Address.Bytes(), out[j].Address.Bytes()) < 0
+	})
+	return out, nil
+}
+
+// DiffActors is like Diff, but only compares the supplied actors. Unlike Diff,
+// it doesn't walk the whole state trees, so it is suited to large trees
+// fetched over the network.
+func DiffActors(ctx context.Context, bs blockstore.Blockstore, left, right cid.Cid, epoch abi.ChainEpoch, addrs ...address.Address) ([]*ActorDiff, error) {
+	cst := cbor.NewCborStore(bs)
+	store := adt.WrapStore(ctx, cst)
+
+	ltree, err := state.LoadStateTree(cst, left)
+	if err != nil {
+		return nil, xerrors.Errorf("loading left state tree: %w", err)
+	}
+	rtree, err := state.LoadStateTree(cst, right)
+	if err != nil {
+		return nil, xerrors.Errorf("loading right state tree: %w", err)
+	}
+
+	getActor := func(st *state.StateTree, addr address.Address) (*types.Actor, error) {
+		act, err := st.GetActor(addr)
+		if xerrors.Is(err, types.ErrActorNotFound) {
+			return nil, nil
+		}
+		return act, err
+	}
+
+	seen := make(map[address.Address]struct{}, len(addrs))
+	var out []*ActorDiff
+	for _, addr := range addrs {
+		// actors created in the right tree can only be resolved there
+		ida, err := rtree.LookupID(addr)
+		if err != nil {
+			if ida, err = ltree.LookupID(addr); err != nil {
+				return nil, xerrors.Errorf("resolving %s: %w", addr, err)
+			}
+		}
+		if _, ok := seen[ida]; ok {
+			continue
+		}
+		seen[ida] = struct{}{}
+
+		lact, err := getActor(ltree, ida)
+		if err != nil {
+			return nil, xerrors.Errorf("loading left actor %s: %w", ida, err)
+		}
+		ract, err := getActor(rtree, ida)
+		if err != nil {
+			return nil, xerrors.Errorf("loading right actor %s: %w", ida, err)
+		}
+		if lact != nil && ract != nil && lact.Code == ract.Code && lact.Head == ract.Head &&
+			lact.Nonce == ract.Nonce && lact.Balance.Equals(ract.Balance) {
+			continue
+		}
+
+		d, err := diffActor(store, ida, lact, ract, epoch)
+		if err != nil {
+			return nil, xerrors.Errorf("diffing actor %s: %w", ida, err)
+		}
+		out = append(out, d)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i].Address.Bytes(), out[j].Address.Bytes()) < 0
+	})
+	return out, nil
+}
+
+func diffActor(store adt.Store, addr address.Address, lact, ract *types.Actor, epoch abi.ChainEpoch) (*ActorDiff, error) {
+	d := &ActorDiff{Address: addr}
+
+	switch {
+	case lact == nil:
+		d.Code, d.Added = ract.Code, true
+	case ract == nil:
+		d.Code, d.Removed = lact.Code, true
+	default:
+		d.Code = ract.Code
+	}
+
+	lvals, err := flattenActor(store, lact, epoch)
+	if err != nil {
+		return nil, xerrors.Errorf("left state: %w", err)
+	}
+	rvals, err := flattenActor(store, ract, epoch)
+	if err != nil {
+		return nil, xerrors.Errorf("right state: %w", err)
+	}
+
+	paths := make(map[string]struct{}, len(lvals)+len(rvals))
+	for p := range lvals {
+		paths[p] = struct{}{}
+	}
+	for p := range rvals {
+		paths[p] = struct{}{}
+	}
+
+	for p := range paths {
+		if l, r := lvals[p], rvals[p]; l != r {
+			d.Changes = append(d.Changes, Change{Path: p, Old: l, New: r})
+		}
+	}
+	sort.Slice(d.Changes, func(i, j int) bool {
+		return d.Changes[i].Path < d.Changes[j].Path
+	})
+
+	return d, nil
+}
+
+// flattenActor returns the values of an actor and its state, keyed by path.
+func flattenActor(store adt.Store, act *types.Actor, epoch abi.ChainEpoch) (map[string]string, error) {
+	out := make(map[string]string)
+	if act == nil {
+		return out, nil
+	}
+
+	out["Code"] = builtin.ActorNameByCode(act.Code)
+	out["Nonce"] = fmt.Sprint(act.Nonce)
+	out["Balance"] = types.EPK(act.Balance).String()
+
+	view, err := stateView(store, act, epoch)
+	if err != nil {
+		// the state may be missing from partial trees, such as the ones of
+		// extracted vectors; compare the heads instead
+		out["Head"] = act.Head.String()
+		out["State"] = fmt.Sprintf("<unavailable: %s>", err)
+		return out, nil
+	}
+	if view == nil {
+		// unknown actor, only the head can be compared
+		out["Head"] = act.Head.String()
+		return out, nil
+	}
+
+	b, err := json.Marshal(view)
+	if err != nil {
+		return nil, xerrors.Errorf("marshaling state view: %w", err)
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, xerrors.Errorf("unmarshaling state view: %w", err)
+	}
+
+	flatten("State", v, out)
+	return out, nil
+}
+
+func flatten(path string, v interface{}, out map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			out[path] = "{}"
+		}
+		for k, e := range v {
+			flatten(path+"."+k, e, out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[path] = "[]"
+		}
+		for i, e := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), e, out)
+		}
+	case nil:
+		out[path] = "null"
+	default:
+		out[path] = fmt.Sprint(v)
+	}
+}
+
+// Write writes the diffs to w, one block per actor.
+func Write(w io.Writer, diffs []*ActorDiff) error {
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(w, "no differences")
+		return err
+	}
+
+	for _, d := range diffs {
+		status := "changed"
+		switch {
+		case d.Added:
+			status = "added"
+		case d.Removed:
+			status = "removed"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s (%s) %s:\n", d.Address, d.Name(), status); err != nil {
+			return err
+		}
+
+		for _, c := range d.Changes {
+			var line string
+			switch {
+			case d.Added || c.Old == "" && c.New != "":
+				line = fmt.Sprintf("  + %s: %s", c.Path, c.New)
+			case d.Removed || c.New == "" && c.Old != "":
+				line = fmt.Sprintf("  - %s: %s", c.Path, c.Old)
+			default:
+				line = fmt.Sprintf("  ~ %s: %s => %s", c.Path, c.Old, c.New)
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the diffs as written by Write.
+func String(diffs []*ActorDiff) string {
+	var sb strings.Builder
+	_ = Write(&sb, diffs)
+	return sb.String()
+}
+
+// TraceActors returns the senders and receivers of all the calls of an
+// execution trace, to be passed to DiffActors.
+func TraceActors(trace types.ExecutionTrace) []address.Address {
+	var out []address.Address
+	if trace.Msg != nil {
+		out = append(out, trace.Msg.From, trace.Msg.To)
+	}
+	for _, sub := range trace.Subcalls {
+		out = append(out, TraceActors(sub)...)
+	}
+	return out
+}
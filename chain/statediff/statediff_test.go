@@ -0,0 +1,100 @@
+package statediff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+
+	"github.com/filecoin-project/go-address"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	account2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/account"
+
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	bs := blockstore.NewMemory()
+	cst := cbor.NewCborStore(bs)
+
+	mkAccount := func(id uint64, balance uint64) (address.Address, *types.Actor) {
+		addr, err := address.NewIDAddress(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := cst.Put(ctx, &account2.State{Address: addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr, &types.Actor{Code: builtin2.AccountActorCodeID, Head: head, Balance: types.NewInt(balance)}
+	}
+
+	flush := func(st *state.StateTree) cid.Cid {
+		root, err := st.Flush(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+
+	st, err := state.NewStateTree(cst, types.StateTreeVersion2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, keptAct := mkAccount(100, 1)
+	changed, changedAct := mkAccount(101, 1)
+	removed, removedAct := mkAccount(102, 1)
+	for addr, act := range map[address.Address]*types.Actor{kept: keptAct, changed: changedAct, removed: removedAct} {
+		if err := st.SetActor(addr, act); err != nil {
+			t.Fatal(err)
+		}
+	}
+	left := flush(st)
+
+	changedAct.Balance = types.NewInt(2)
+	changedAct.Nonce = 1
+	if err := st.SetActor(changed, changedAct); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DeleteActor(removed); err != nil {
+		t.Fatal(err)
+	}
+	added, addedAct := mkAccount(103, 1)
+	if err := st.SetActor(added, addedAct); err != nil {
+		t.Fatal(err)
+	}
+	right := flush(st)
+
+	diffs, err := Diff(ctx, bs, left, right, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 actor diffs, got %d:\n%s", len(diffs), String(diffs))
+	}
+
+	if d := diffs[0]; d.Address != changed || d.Added || d.Removed {
+		t.Errorf("expected %s to be changed, got %+v", changed, d)
+	} else if len(d.Changes) != 2 || d.Changes[0].Path != "Balance" || d.Changes[1].Path != "Nonce" {
+		t.Errorf("expected balance and nonce changes, got %+v", d.Changes)
+	}
+
+	if d := diffs[1]; d.Address != removed || !d.Removed {
+		t.Errorf("expected %s to be removed, got %+v", removed, d)
+	}
+
+	if d := diffs[2]; d.Address != added || !d.Added {
+		t.Errorf("expected %s to be added, got %+v", added, d)
+	}
+
+	if out := String(diffs); !strings.Contains(out, "~ Balance: 0.000000000000000001 EPK => 0.000000000000000002 EPK") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
@@ -0,0 +1,440 @@
+package statediff
+
+import (
+	"fmt"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expertfund"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/govern"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/knowledge"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/market"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/power"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/retrieval"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vesting"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	power2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/power"
+)
+
+// stateView returns a JSON-marshalable view of the state of an actor, with
+// the collections of the EpiK, miner, power and market actors expanded. Other
+// actors with a registered state loader are viewed as their raw state. It
+// returns nil for unknown actors.
+func stateView(store adt.Store, act *types.Actor, epoch abi.ChainEpoch) (interface{}, error) {
+	switch act.Code {
+	case builtin2.StorageMinerActorCodeID:
+		return minerView(store, act)
+	case builtin2.StoragePowerActorCodeID:
+		return powerView(store, act)
+	case builtin2.StorageMarketActorCodeID:
+		return marketView(store, act)
+	case builtin2.ExpertActorCodeID:
+		return expertView(store, act)
+	case builtin2.ExpertFundActorCodeID:
+		return expertFundView(store, act)
+	case builtin2.VoteFundActorCodeID:
+		return voteView(store, act, epoch)
+	case builtin2.RetrievalFundActorCodeID:
+		return retrievalView(store, act, epoch)
+	case builtin2.KnowledgeFundActorCodeID:
+		return knowledgeView(store, act)
+	case builtin2.GovernActorCodeID:
+		return governView(store, act)
+	case builtin2.VestingActorCodeID:
+		return vestingView(store, act)
+	}
+
+	if _, ok := builtin.ActorStateLoaders[act.Code]; !ok {
+		return nil, nil
+	}
+	return builtin.Load(store, act)
+}
+
+func expertView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := expert.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert state: %w", err)
+	}
+
+	info, err := st.Info()
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert info: %w", err)
+	}
+	datas, err := st.Datas()
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert datas: %w", err)
+	}
+
+	return struct {
+		Info  *expert.ExpertInfo
+		Datas []*expert.DataOnChainInfo
+	}{info, datas}, nil
+}
+
+func expertFundView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := expertfund.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert fund state: %w", err)
+	}
+
+	experts, err := st.ListAllExperts()
+	if err != nil {
+		return nil, xerrors.Errorf("listing experts: %w", err)
+	}
+
+	infos := make(map[string]*expertfund.ExpertInfo, len(experts))
+	for _, addr := range experts {
+		info, err := st.ExpertInfo(addr)
+		if err != nil {
+			return nil, xerrors.Errorf("loading info of expert %s: %w", addr, err)
+		}
+		infos[addr.String()] = info
+	}
+
+	return struct {
+		DataThreshold  uint64
+		DailyThreshold uint64
+		Experts        map[string]*expertfund.ExpertInfo
+	}{st.DataThreshold(), st.DailyThreshold(), infos}, nil
+}
+
+func voteView(store adt.Store, act *types.Actor, epoch abi.ChainEpoch) (interface{}, error) {
+	st, err := vote.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vote fund state: %w", err)
+	}
+
+	tally, err := st.Tally()
+	if err != nil {
+		return nil, xerrors.Errorf("loading tally: %w", err)
+	}
+	voters, err := st.ListVoterInfos(epoch, act.Balance)
+	if err != nil {
+		return nil, xerrors.Errorf("listing voters: %w", err)
+	}
+
+	infos := make(map[string]*vote.VoterInfo, len(voters))
+	for _, info := range voters {
+		infos[info.Voter.String()] = info
+	}
+
+	return struct {
+		Tally  *vote.Tally
+		Voters map[string]*vote.VoterInfo
+	}{tally, infos}, nil
+}
+
+func retrievalView(store adt.Store, act *types.Actor, epoch abi.ChainEpoch) (interface{}, error) {
+	st, err := retrieval.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading retrieval fund state: %w", err)
+	}
+
+	type pledger struct {
+		*retrieval.RetrievalState
+		Pledges   map[string]abi.TokenAmount
+		DayExpend abi.TokenAmount
+		Locked    *retrieval.LockedState `json:",omitempty"`
+	}
+
+	pledgers := make(map[string]*pledger)
+	err = st.ForEachState(func(addr address.Address, rs *retrieval.RetrievalState) error {
+		p := &pledger{RetrievalState: rs, Pledges: make(map[string]abi.TokenAmount)}
+
+		pledges, err := st.PledgesInfo(addr)
+		if err != nil {
+			return xerrors.Errorf("loading pledges of %s: %w", addr, err)
+		}
+		for target, amount := range pledges {
+			p.Pledges[target.String()] = amount
+		}
+
+		if p.DayExpend, err = st.DayExpend(epoch, addr); err != nil {
+			return xerrors.Errorf("loading day expend of %s: %w", addr, err)
+		}
+
+		var locked retrieval.LockedState
+		found, err := st.LockedState(addr, &locked)
+		if err != nil {
+			return xerrors.Errorf("loading locked state of %s: %w", addr, err)
+		}
+		if found {
+			p.Locked = &locked
+		}
+
+		pledgers[addr.String()] = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collateral, err := st.TotalCollateral()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total collateral: %w", err)
+	}
+	reward, err := st.TotalRetrievalReward()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total retrieval reward: %w", err)
+	}
+	pending, err := st.PendingReward()
+	if err != nil {
+		return nil, xerrors.Errorf("loading pending reward: %w", err)
+	}
+
+	return struct {
+		TotalCollateral      abi.TokenAmount
+		TotalRetrievalReward abi.TokenAmount
+		PendingReward        abi.TokenAmount
+		Pledgers             map[string]*pledger
+	}{collateral, reward, pending, pledgers}, nil
+}
+
+func knowledgeView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := knowledge.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading knowledge fund state: %w", err)
+	}
+
+	return st.Info()
+}
+
+func governView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := govern.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading govern state: %w", err)
+	}
+
+	governors, err := st.ListGovrnors()
+	if err != nil {
+		return nil, xerrors.Errorf("listing governors: %w", err)
+	}
+
+	type authority struct {
+		Actor   string
+		Methods []abi.MethodNum
+	}
+
+	auths := make(map[string][]authority, len(governors))
+	for _, g := range governors {
+		for _, a := range g.Authorities {
+			auths[g.Address.String()] = append(auths[g.Address.String()], authority{
+				Actor:   builtin.ActorNameByCode(a.ActorCodeID),
+				Methods: a.Methods,
+			})
+		}
+	}
+
+	return struct {
+		Supervisor address.Address
+		Governors  map[string][]authority
+	}{st.Supervior(), auths}, nil
+}
+
+func vestingView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := vesting.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vesting state: %w", err)
+	}
+
+	// coinbases are only reachable by address, keep the raw state for them
+	return struct {
+		TotalLocked abi.TokenAmount
+		Raw         interface{}
+	}{st.TotalLocked(), st}, nil
+}
+
+func minerView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := miner.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading miner state: %w", err)
+	}
+
+	info, err := st.Info()
+	if err != nil {
+		return nil, xerrors.Errorf("loading miner info: %w", err)
+	}
+	funds, err := st.Funds()
+	if err != nil {
+		return nil, xerrors.Errorf("loading miner funds: %w", err)
+	}
+
+	sectors, err := st.LoadSectors(nil)
+	if err != nil {
+		return nil, xerrors.Errorf("loading sectors: %w", err)
+	}
+	sinfos := make(map[string]*miner.SectorOnChainInfo, len(sectors))
+	for _, si := range sectors {
+		sinfos[fmt.Sprint(si.SectorNumber)] = si
+	}
+
+	type partition struct {
+		All        bitfield.BitField
+		Faulty     bitfield.BitField
+		Recovering bitfield.BitField
+		Active     bitfield.BitField
+	}
+
+	deadlines := make(map[string]map[string]*partition)
+	err = st.ForEachDeadline(func(dlIdx uint64, dl miner.Deadline) error {
+		parts := make(map[string]*partition)
+		err := dl.ForEachPartition(func(partIdx uint64, part miner.Partition) error {
+			var p partition
+			var err error
+			if p.All, err = part.AllSectors(); err != nil {
+				return err
+			}
+			if p.Faulty, err = part.FaultySectors(); err != nil {
+				return err
+			}
+			if p.Recovering, err = part.RecoveringSectors(); err != nil {
+				return err
+			}
+			if p.Active, err = part.ActiveSectors(); err != nil {
+				return err
+			}
+			parts[fmt.Sprint(partIdx)] = &p
+			return nil
+		})
+		if err != nil {
+			return xerrors.Errorf("loading partitions of deadline %d: %w", dlIdx, err)
+		}
+		if len(parts) > 0 {
+			deadlines[fmt.Sprint(dlIdx)] = parts
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		Info      miner.MinerInfo
+		Funds     miner.Funds
+		Sectors   map[string]*miner.SectorOnChainInfo
+		Deadlines map[string]map[string]*partition
+	}{info, funds, sinfos, deadlines}, nil
+}
+
+func powerView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := power.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading power state: %w", err)
+	}
+
+	total, err := st.TotalPower()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total power: %w", err)
+	}
+	committed, err := st.TotalCommitted()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total committed power: %w", err)
+	}
+	locked, err := st.TotalLocked()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total locked: %w", err)
+	}
+	ratio, err := st.PoStRatio()
+	if err != nil {
+		return nil, xerrors.Errorf("loading post ratio: %w", err)
+	}
+	period, err := st.PledgeReleasePeriod()
+	if err != nil {
+		return nil, xerrors.Errorf("loading pledge release period: %w", err)
+	}
+	participating, miners, err := st.MinerCounts()
+	if err != nil {
+		return nil, xerrors.Errorf("loading miner counts: %w", err)
+	}
+
+	claims := make(map[string]power.Claim)
+	err = st.ForEachClaim(func(maddr address.Address, claim power.Claim) error {
+		claims[maddr.String()] = claim
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("loading claims: %w", err)
+	}
+
+	return struct {
+		TotalPower          power.Claim
+		TotalCommitted      power.Claim
+		TotalLocked         abi.TokenAmount
+		PoStRatio           power2.WdPoStRatio
+		PledgeReleasePeriod abi.ChainEpoch
+		ParticipatingMiners uint64
+		Miners              uint64
+		Claims              map[string]power.Claim
+	}{total, committed, locked, ratio, period, participating, miners, claims}, nil
+}
+
+func marketView(store adt.Store, act *types.Actor) (interface{}, error) {
+	st, err := market.Load(store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading market state: %w", err)
+	}
+
+	locked, err := st.TotalLocked()
+	if err != nil {
+		return nil, xerrors.Errorf("loading total locked: %w", err)
+	}
+
+	proposals, err := st.Proposals()
+	if err != nil {
+		return nil, xerrors.Errorf("loading proposals: %w", err)
+	}
+	props := make(map[string]market.DealProposal)
+	err = proposals.ForEach(func(id abi.DealID, dp market.DealProposal) error {
+		props[fmt.Sprint(id)] = dp
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("listing proposals: %w", err)
+	}
+
+	states, err := st.States()
+	if err != nil {
+		return nil, xerrors.Errorf("loading deal states: %w", err)
+	}
+	dstates := make(map[string]market.DealState)
+	err = states.ForEach(func(id abi.DealID, ds market.DealState) error {
+		dstates[fmt.Sprint(id)] = ds
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("listing deal states: %w", err)
+	}
+
+	quotas, err := st.Quotas()
+	if err != nil {
+		return nil, xerrors.Errorf("loading quotas: %w", err)
+	}
+	// quotas are only reachable by piece, view those of the proposed pieces
+	remaining := make(map[string]int64)
+	for _, dp := range props {
+		q, err := quotas.RemainingQuota(dp.PieceCID)
+		if err != nil {
+			return nil, xerrors.Errorf("loading quota of piece %s: %w", dp.PieceCID, err)
+		}
+		remaining[dp.PieceCID.String()] = q
+	}
+
+	return struct {
+		TotalLocked    abi.TokenAmount
+		InitialQuota   int64
+		RemainingQuota map[string]int64
+		Proposals      map[string]market.DealProposal
+		States         map[string]market.DealState
+	}{locked, quotas.InitialQuota(), remaining, props, dstates}, nil
+}
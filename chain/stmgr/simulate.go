@@ -0,0 +1,178 @@
+package stmgr
+
+import (
+	"context"
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/vm"
+	"github.com/EpiK-Protocol/go-epik/journal"
+)
+
+// Override returns a copy of the schedule with the upgrade to nv moved to
+// height. An upgrade to nv is added, without migration, when the schedule has
+// none, and a negative height removes it.
+func (us UpgradeSchedule) Override(nv network.Version, height abi.ChainEpoch) UpgradeSchedule {
+	out := make(UpgradeSchedule, 0, len(us)+1)
+	found := false
+	for _, u := range us {
+		if u.Network == nv {
+			found = true
+			if height < 0 {
+				continue
+			}
+			u.Height = height
+		}
+		out = append(out, u)
+	}
+	if !found && height >= 0 {
+		out = append(out, Upgrade{Height: height, Network: nv})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Height < out[j].Height
+	})
+	return out
+}
+
+// Simulate applies msgs at height on top of the state computed for ts, running
+// cron for the epochs skipped between them and the upgrades. Nothing is
+// persisted: the new state, including the state written by migrations, is only
+// written to the returned blockstore, which reads through to the chain state.
+//
+// Implicit messages skip the signature, nonce and gas checks, so they can be
+// sent by governor, multisig or system actors. When us isn't nil, it replaces
+// the upgrade schedule of the state manager.
+func (sm *StateManager) Simulate(ctx context.Context, ts *types.TipSet, height abi.ChainEpoch, msgs []*types.Message, implicit bool, us UpgradeSchedule) (cid.Cid, blockstore.Blockstore, []*api.InvocResult, error) {
+	ctx, span := trace.StartSpan(ctx, "statemanager.Simulate")
+	defer span.End()
+
+	if ts == nil {
+		ts = sm.cs.GetHeaviestTipSet()
+	}
+	if height == 0 {
+		height = ts.Height() + 1
+	}
+	if height <= ts.Height() {
+		return cid.Undef, nil, nil, xerrors.Errorf("simulation height %d must be after the tipset at %d", height, ts.Height())
+	}
+	if us == nil {
+		us = sm.upgrades
+	}
+
+	base, _, err := sm.TipSetState(ctx, ts)
+	if err != nil {
+		return cid.Undef, nil, nil, xerrors.Errorf("computing tipset state: %w", err)
+	}
+
+	// migrations write through the chain store of the state manager they run
+	// with, give them one writing to the buffer
+	bs := blockstore.NewBuffered(sm.cs.StateBlockstore())
+	scs := store.NewChainStore(sm.cs.ChainBlockstore(), bs, datastore.NewMapDatastore(), sm.cs.VMSys(), journal.NilJournal())
+	defer scs.Close() //nolint:errcheck
+
+	vsm, err := NewStateManagerWithUpgradeSchedule(scs, us)
+	if err != nil {
+		return cid.Undef, nil, nil, xerrors.Errorf("invalid upgrade schedule: %w", err)
+	}
+	vsm.newVM = sm.newVM
+	vsm.genInfos = sm.genInfos
+
+	makeVM := func(base cid.Cid) (*vm.VM, error) {
+		return sm.newVM(ctx, &vm.VMOpts{
+			StateBase:      base,
+			Epoch:          height,
+			Rand:           store.NewChainRand(sm.cs, ts.Cids()),
+			Bstore:         bs,
+			Syscalls:       sm.cs.VMSys(),
+			CircSupplyCalc: vsm.GetVMCirculatingSupply,
+			NtwkVersion:    vsm.GetNtwkVersion,
+			BaseFee:        ts.Blocks()[0].ParentBaseFee,
+			LookbackState:  LookbackStateGetterForTipset(sm, ts),
+		})
+	}
+	vmi, err := makeVM(base)
+	if err != nil {
+		return cid.Undef, nil, nil, xerrors.Errorf("failed to set up vm: %w", err)
+	}
+
+	var invocs []*api.InvocResult
+	record := traceFunc(&invocs)
+
+	// as in ApplyBlocks, the epochs between ts and height are null rounds
+	for i := ts.Height(); i < height; i++ {
+		if i > ts.Height() {
+			if err := applyCron(ctx, vmi, i, record); err != nil {
+				return cid.Undef, nil, nil, xerrors.Errorf("running cron at %d: %w", i, err)
+			}
+			base, err = vmi.Flush(ctx)
+			if err != nil {
+				return cid.Undef, nil, nil, xerrors.Errorf("flushing vm: %w", err)
+			}
+		}
+
+		next, err := vsm.handleStateForks(ctx, base, i, record, ts)
+		if err != nil {
+			return cid.Undef, nil, nil, xerrors.Errorf("error handling state forks: %w", err)
+		}
+		if next != base {
+			if vmi, err = makeVM(next); err != nil {
+				return cid.Undef, nil, nil, xerrors.Errorf("failed to set up vm: %w", err)
+			}
+		}
+
+		vmi.SetBlockHeight(i + 1)
+		base = next
+	}
+
+	for i, msg := range msgs {
+		if msg.GasLimit == 0 && implicit {
+			msg.GasLimit = build.BlockGasLimit
+		}
+		if msg.GasFeeCap == types.EmptyInt {
+			msg.GasFeeCap = types.NewInt(0)
+		}
+		if msg.GasPremium == types.EmptyInt {
+			msg.GasPremium = types.NewInt(0)
+		}
+		if msg.Value == types.EmptyInt {
+			msg.Value = types.NewInt(0)
+		}
+
+		var ret *vm.ApplyRet
+		if implicit {
+			from, aerr := vmi.StateTree().GetActor(msg.From)
+			if aerr != nil {
+				return cid.Undef, nil, nil, xerrors.Errorf("message %d: loading sender: %w", i, aerr)
+			}
+			msg.Nonce = from.Nonce
+			ret, err = vmi.ApplyImplicitMessage(ctx, msg)
+		} else {
+			ret, err = vmi.ApplyMessage(ctx, msg)
+		}
+		if err != nil {
+			return cid.Undef, nil, nil, xerrors.Errorf("applying message %d: %w", i, err)
+		}
+		if err := record(msg.Cid(), msg, ret); err != nil {
+			return cid.Undef, nil, nil, err
+		}
+	}
+
+	root, err := vmi.Flush(ctx)
+	if err != nil {
+		return cid.Undef, nil, nil, xerrors.Errorf("flushing vm: %w", err)
+	}
+
+	return root, bs, invocs, nil
+}
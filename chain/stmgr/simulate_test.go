@@ -0,0 +1,144 @@
+package stmgr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
+
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/cron"
+	"github.com/EpiK-Protocol/go-epik/chain/gen"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	. "github.com/EpiK-Protocol/go-epik/chain/stmgr"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+func TestUpgradeScheduleOverride(t *testing.T) {
+	us := UpgradeSchedule{
+		{Height: 10, Network: network.Version1},
+		{Height: 20, Network: network.Version2},
+	}
+
+	moved := us.Override(network.Version1, 30)
+	require.Equal(t, []abi.ChainEpoch{20, 30}, heights(moved))
+	require.Equal(t, network.Version2, moved[0].Network)
+	// the original schedule is left alone
+	require.Equal(t, []abi.ChainEpoch{10, 20}, heights(us))
+
+	added := us.Override(network.Version3, 15)
+	require.Equal(t, []abi.ChainEpoch{10, 15, 20}, heights(added))
+	require.Equal(t, network.Version3, added[1].Network)
+	require.Nil(t, added[1].Migration)
+
+	removed := us.Override(network.Version1, -1)
+	require.Equal(t, []abi.ChainEpoch{20}, heights(removed))
+
+	require.Empty(t, us.Override(network.Version3, -1).Override(network.Version1, -1).Override(network.Version2, -1))
+}
+
+func heights(us UpgradeSchedule) []abi.ChainEpoch {
+	out := make([]abi.ChainEpoch, 0, len(us))
+	for _, u := range us {
+		out = append(out, u.Height)
+	}
+	return out
+}
+
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+
+	// the migration credits the burnt funds actor, far after the simulated
+	// epochs unless the schedule is overridden
+	var migrated []cid.Cid
+	sm, err := NewStateManagerWithUpgradeSchedule(cg.ChainStore(), UpgradeSchedule{{
+		Network: network.Version10,
+		Height:  1000,
+		Migration: func(ctx context.Context, sm *StateManager, cache MigrationCache, cb ExecCallback,
+			root cid.Cid, height abi.ChainEpoch, ts *types.TipSet) (cid.Cid, error) {
+			st, err := sm.StateTree(root)
+			if err != nil {
+				return cid.Undef, err
+			}
+			act, err := st.GetActor(builtin.BurntFundsActorAddr)
+			if err != nil {
+				return cid.Undef, err
+			}
+			act.Balance = big.Add(act.Balance, big.NewInt(1))
+			if err := st.SetActor(builtin.BurntFundsActorAddr, act); err != nil {
+				return cid.Undef, err
+			}
+			nroot, err := st.Flush(ctx)
+			if err != nil {
+				return cid.Undef, err
+			}
+			migrated = append(migrated, nroot)
+			return nroot, nil
+		}}})
+	require.NoError(t, err)
+	cg.SetStateManager(sm)
+
+	for i := 0; i < 2; i++ {
+		_, err := cg.NextTipSet()
+		require.NoError(t, err)
+	}
+	head := cg.CurTipset.TipSet()
+	height := head.Height() + 3
+
+	balance := func(bs blockstore.Blockstore, root cid.Cid) abi.TokenAmount {
+		st, err := state.LoadStateTree(cbor.NewCborStore(bs), root)
+		require.NoError(t, err)
+		act, err := st.GetActor(builtin.BurntFundsActorAddr)
+		require.NoError(t, err)
+		return act.Balance
+	}
+
+	// cron runs for each skipped epoch, with the epoch as nonce
+	crons := func(msgs []*types.Message) []uint64 {
+		var out []uint64
+		for _, m := range msgs {
+			if m.To == cron.Address {
+				out = append(out, m.Nonce)
+			}
+		}
+		return out
+	}
+
+	plain, plainBs, trace, err := sm.Simulate(ctx, head, height, nil, false, nil)
+	require.NoError(t, err)
+	require.Empty(t, migrated)
+	var msgs []*types.Message
+	for _, ir := range trace {
+		msgs = append(msgs, ir.Msg)
+	}
+	require.Equal(t, []uint64{uint64(head.Height() + 1), uint64(head.Height() + 2)}, crons(msgs))
+
+	// moving the upgrade into the simulated epochs runs the migration, without
+	// writing to the chain store
+	us := sm.Upgrades().Override(network.Version10, head.Height()+1)
+	root, bs, _, err := sm.Simulate(ctx, head, height, nil, false, us)
+	require.NoError(t, err)
+	require.Len(t, migrated, 1)
+
+	for _, c := range []cid.Cid{root, migrated[0]} {
+		has, err := cg.ChainStore().StateBlockstore().Has(c)
+		require.NoError(t, err)
+		require.False(t, has, "simulated state %s persisted", c)
+
+		has, err = bs.Has(c)
+		require.NoError(t, err)
+		require.True(t, has)
+	}
+
+	require.True(t, balance(bs, root).Equals(big.Add(balance(plainBs, plain), big.NewInt(1))))
+}
@@ -47,6 +47,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/reward"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vesting"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/msgindex"
 	"github.com/EpiK-Protocol/go-epik/chain/state"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
@@ -89,6 +90,8 @@ type StateManager struct {
 	networkVersions []versionSpec
 	latestVersion   network.Version
 
+	// The upgrade schedule the versions and migrations are built from.
+	upgrades UpgradeSchedule
 	// Maps chain epochs to migrations.
 	stateMigrations map[abi.ChainEpoch]*migration
 	// A set of potentially expensive/time consuming upgrades. Explicit
@@ -102,6 +105,8 @@ type StateManager struct {
 	// genesisMsigLk sync.Mutex
 	newVM    func(context.Context, *vm.VMOpts) (*vm.VM, error)
 	genInfos *genesisInfo
+
+	msgIndex msgindex.MsgIndex
 	// preIgnitionGenInfos  *genesisInfo
 	// postIgnitionGenInfos *genesisInfo
 }
@@ -114,6 +119,12 @@ func NewStateManager(cs *store.ChainStore) *StateManager {
 	return sm
 }
 
+// SetMsgIndex sets the index used to find messages quickly in
+// SearchForMessage. It must be called before the state manager is used.
+func (sm *StateManager) SetMsgIndex(mi msgindex.MsgIndex) {
+	sm.msgIndex = mi
+}
+
 func NewStateManagerWithUpgradeSchedule(cs *store.ChainStore, us UpgradeSchedule) (*StateManager, error) {
 	// If we have upgrades, make sure they're in-order and make sense.
 	if err := us.Validate(); err != nil {
@@ -151,6 +162,7 @@ func NewStateManagerWithUpgradeSchedule(cs *store.ChainStore, us UpgradeSchedule
 	}
 
 	return &StateManager{
+		upgrades:          us,
 		networkVersions:   networkVersions,
 		latestVersion:     lastVersion,
 		stateMigrations:   stateMigrations,
@@ -159,9 +171,15 @@ func NewStateManagerWithUpgradeSchedule(cs *store.ChainStore, us UpgradeSchedule
 		cs:                cs,
 		stCache:           make(map[string][]cid.Cid),
 		compWait:          make(map[string]chan struct{}),
+		msgIndex:          msgindex.DummyMsgIndex,
 	}, nil
 }
 
+// Upgrades returns the upgrade schedule of the state manager.
+func (sm *StateManager) Upgrades() UpgradeSchedule {
+	return sm.upgrades
+}
+
 func cidsToKey(cids []cid.Cid) string {
 	var out string
 	for _, c := range cids {
@@ -286,6 +304,35 @@ func (sm *StateManager) ExecutionTrace(ctx context.Context, ts *types.TipSet) (c
 
 type ExecCallback func(cid.Cid, *types.Message, *vm.ApplyRet) error
 
+// applyCron applies the cron tick of epoch on vmi.
+func applyCron(ctx context.Context, vmi *vm.VM, epoch abi.ChainEpoch, cb ExecCallback) error {
+	cronMsg := &types.Message{
+		To:         cron.Address,
+		From:       builtin.SystemActorAddr,
+		Nonce:      uint64(epoch),
+		Value:      types.NewInt(0),
+		GasFeeCap:  types.NewInt(0),
+		GasPremium: types.NewInt(0),
+		GasLimit:   build.BlockGasLimit * 10000, // Make super sure this is never too little
+		Method:     cron.Methods.EpochTick,
+		Params:     nil,
+	}
+	ret, err := vmi.ApplyImplicitMessage(ctx, cronMsg)
+	if err != nil {
+		return err
+	}
+	if cb != nil {
+		if err := cb(cronMsg.Cid(), cronMsg, ret); err != nil {
+			return xerrors.Errorf("callback failed on cron message: %w", err)
+		}
+	}
+	if ret.ExitCode != 0 {
+		return xerrors.Errorf("CheckProofSubmissions exit was non-zero: %d", ret.ExitCode)
+	}
+
+	return nil
+}
+
 func (sm *StateManager) ApplyBlocks(ctx context.Context, parentEpoch abi.ChainEpoch, pstate cid.Cid, bms []store.BlockMessages, epoch abi.ChainEpoch, r vm.Rand, cb ExecCallback, baseFee abi.TokenAmount, ts *types.TipSet) (cid.Cid, cid.Cid, error) {
 
 	// Use parent's circulating
@@ -320,32 +367,7 @@ func (sm *StateManager) ApplyBlocks(ctx context.Context, parentEpoch abi.ChainEp
 	}
 
 	runCron := func(epoch abi.ChainEpoch) error {
-
-		cronMsg := &types.Message{
-			To:         cron.Address,
-			From:       builtin.SystemActorAddr,
-			Nonce:      uint64(epoch),
-			Value:      types.NewInt(0),
-			GasFeeCap:  types.NewInt(0),
-			GasPremium: types.NewInt(0),
-			GasLimit:   build.BlockGasLimit * 10000, // Make super sure this is never too little
-			Method:     cron.Methods.EpochTick,
-			Params:     nil,
-		}
-		ret, err := vmi.ApplyImplicitMessage(ctx, cronMsg)
-		if err != nil {
-			return err
-		}
-		if cb != nil {
-			if err := cb(cronMsg.Cid(), cronMsg, ret); err != nil {
-				return xerrors.Errorf("callback failed on cron message: %w", err)
-			}
-		}
-		if ret.ExitCode != 0 {
-			return xerrors.Errorf("CheckProofSubmissions exit was non-zero: %d", ret.ExitCode)
-		}
-
-		return nil
+		return applyCron(ctx, vmi, epoch, cb)
 	}
 
 	for i := parentEpoch; i < epoch; i++ {
@@ -775,7 +797,15 @@ func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid, look
 		return head, r, foundMsg, nil
 	}
 
-	fts, r, foundMsg, err := sm.searchBackForMsg(ctx, head, msg, lookbackLimit)
+	fts, r, foundMsg, err := sm.searchForIndexedMsg(ctx, head, mcid, lookbackLimit)
+	switch {
+	case err == nil:
+		return fts, r, foundMsg, nil
+	case err != msgindex.ErrNotFound:
+		log.Warnf("error searching message index for %s, falling back to chain search: %s", mcid, err)
+	}
+
+	fts, r, foundMsg, err = sm.searchBackForMsg(ctx, head, msg, lookbackLimit)
 
 	if err != nil {
 		log.Warnf("failed to look back through chain for message %s", mcid)
@@ -789,6 +819,40 @@ func (sm *StateManager) SearchForMessage(ctx context.Context, mcid cid.Cid, look
 	return fts, r, foundMsg, nil
 }
 
+// searchForIndexedMsg looks the message up in the message index. It returns
+// msgindex.ErrNotFound when the message isn't indexed, or was indexed in a
+// tipset which isn't on the chain from the given head.
+func (sm *StateManager) searchForIndexedMsg(ctx context.Context, head *types.TipSet, mcid cid.Cid, limit abi.ChainEpoch) (*types.TipSet, *types.MessageReceipt, cid.Cid, error) {
+	mi, err := sm.msgIndex.GetMsgInfo(ctx, mcid)
+	if err != nil {
+		return nil, nil, cid.Undef, err
+	}
+
+	if limit != LookbackNoLimit && mi.ExecutedEpoch < head.Height()-limit {
+		return nil, nil, cid.Undef, msgindex.ErrNotFound
+	}
+	if mi.ExecutedEpoch > head.Height() {
+		return nil, nil, cid.Undef, msgindex.ErrNotFound
+	}
+
+	xts, err := sm.cs.GetTipsetByHeight(ctx, mi.ExecutedEpoch, head, false)
+	if err != nil {
+		return nil, nil, cid.Undef, xerrors.Errorf("getting tipset at %d: %w", mi.ExecutedEpoch, err)
+	}
+
+	// the index may hold entries of tipsets which got reorged out
+	if xts.Key() != mi.ExecutedTipSet {
+		return nil, nil, cid.Undef, msgindex.ErrNotFound
+	}
+
+	r, err := sm.cs.GetParentReceipt(xts.Blocks()[0], mi.ReceiptIndex)
+	if err != nil {
+		return nil, nil, cid.Undef, xerrors.Errorf("loading receipt: %w", err)
+	}
+
+	return xts, r, mi.Message, nil
+}
+
 // searchBackForMsg searches up to limit tipsets backwards from the given
 // tipset for a message receipt.
 // If limit is
@@ -0,0 +1,469 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/ipld/go-car"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// DefaultSnapshotSegmentSize is the size of the hashed segments of a snapshot
+const DefaultSnapshotSegmentSize = 256 << 20
+
+// SnapshotManifest describes an exported snapshot, so that it can be checked
+// before it's imported
+type SnapshotManifest struct {
+	Roots       []cid.Cid
+	Height      abi.ChainEpoch
+	From        abi.ChainEpoch
+	RecentRoots abi.ChainEpoch
+	Actors      []address.Address `json:",omitempty"`
+
+	Size        int64
+	SHA256      string
+	SegmentSize int64
+	Segments    []SnapshotSegment
+}
+
+type SnapshotSegment struct {
+	Offset int64
+	Size   int64
+	SHA256 string
+}
+
+// SnapshotHasher hashes a snapshot in segments as it's written
+type SnapshotHasher struct {
+	segmentSize int64
+
+	total    hash.Hash
+	seg      hash.Hash
+	size     int64
+	segStart int64
+
+	segments []SnapshotSegment
+}
+
+func NewSnapshotHasher(segmentSize int64) *SnapshotHasher {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSnapshotSegmentSize
+	}
+
+	return &SnapshotHasher{
+		segmentSize: segmentSize,
+		total:       sha256.New(),
+		seg:         sha256.New(),
+	}
+}
+
+func (h *SnapshotHasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		left := h.segStart + h.segmentSize - h.size
+		chunk := p
+		if int64(len(chunk)) > left {
+			chunk = chunk[:left]
+		}
+
+		h.total.Write(chunk) //nolint:errcheck
+		h.seg.Write(chunk)   //nolint:errcheck
+		h.size += int64(len(chunk))
+		p = p[len(chunk):]
+
+		if h.size-h.segStart == h.segmentSize {
+			h.endSegment()
+		}
+	}
+	return n, nil
+}
+
+func (h *SnapshotHasher) endSegment() {
+	h.segments = append(h.segments, SnapshotSegment{
+		Offset: h.segStart,
+		Size:   h.size - h.segStart,
+		SHA256: hex.EncodeToString(h.seg.Sum(nil)),
+	})
+	h.seg.Reset()
+	h.segStart = h.size
+}
+
+// Finish fills the size and hashes of the snapshot into the manifest
+func (h *SnapshotHasher) Finish(m *SnapshotManifest) {
+	if h.size > h.segStart {
+		h.endSegment()
+	}
+
+	m.Size = h.size
+	m.SHA256 = hex.EncodeToString(h.total.Sum(nil))
+	m.SegmentSize = h.segmentSize
+	m.Segments = h.segments
+}
+
+// VerifySnapshotManifest hashes the snapshot and returns the indexes of the
+// segments which don't match the manifest
+func VerifySnapshotManifest(r io.Reader, m *SnapshotManifest) ([]int, error) {
+	h := NewSnapshotHasher(m.SegmentSize)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, xerrors.Errorf("reading snapshot: %w", err)
+	}
+
+	var got SnapshotManifest
+	h.Finish(&got)
+
+	var bad []int
+	for i, seg := range m.Segments {
+		if i >= len(got.Segments) || got.Segments[i] != seg {
+			bad = append(bad, i)
+		}
+	}
+
+	if got.Size != m.Size {
+		return bad, xerrors.Errorf("snapshot size %d doesn't match manifest size %d", got.Size, m.Size)
+	}
+	if got.SHA256 != m.SHA256 {
+		return bad, xerrors.Errorf("snapshot hash %s doesn't match manifest hash %s", got.SHA256, m.SHA256)
+	}
+
+	return bad, nil
+}
+
+// skipActorStates returns a function matching the heads of all actors in the
+// state tree which aren't listed in actors, or nil if actors is empty
+func (cs *ChainStore) skipActorStates(ctx context.Context, root cid.Cid, actors []address.Address) (func(cid.Cid) bool, error) {
+	if len(actors) == 0 {
+		return nil, nil
+	}
+
+	st, err := state.LoadStateTree(cs.ActorStore(ctx), root)
+	if err != nil {
+		return nil, xerrors.Errorf("loading state tree: %w", err)
+	}
+
+	keep := cid.NewSet()
+	for _, a := range actors {
+		act, err := st.GetActor(a)
+		if err != nil {
+			if xerrors.Is(err, types.ErrActorNotFound) {
+				continue
+			}
+			return nil, xerrors.Errorf("loading actor %s: %w", a, err)
+		}
+		keep.Add(act.Head)
+	}
+
+	skip := cid.NewSet()
+	err = st.ForEach(func(_ address.Address, act *types.Actor) error {
+		if !keep.Has(act.Head) {
+			skip.Add(act.Head)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("iterating actors: %w", err)
+	}
+
+	return skip.Has, nil
+}
+
+// SnapshotReport is the result of VerifySnapshot
+type SnapshotReport struct {
+	Roots  []cid.Cid
+	Height abi.ChainEpoch
+	// LowestHeight is the height of the lowest tipset with all its blocks in
+	// the snapshot
+	LowestHeight abi.ChainEpoch
+
+	Objects    int
+	Tipsets    int
+	StateRoots int
+	// MissingStateObjects is the number of objects linked from the state
+	// roots in the snapshot which are missing, expected for snapshots of a
+	// set of actors
+	MissingStateObjects int
+
+	TrustedTipSetFound bool
+}
+
+type carEntry struct {
+	offset int64
+	size   int
+}
+
+// carIndex gives access to the objects in a CAR file without loading them
+// into memory
+type carIndex struct {
+	r       io.ReaderAt
+	header  car.CarHeader
+	entries map[cid.Cid]carEntry
+}
+
+type countingReader struct {
+	*bufio.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.Reader.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) ReadByte() (byte, error) {
+	b, err := cr.Reader.ReadByte()
+	if err == nil {
+		cr.n++
+	}
+	return b, err
+}
+
+// indexCar reads the CAR file, checking that all objects match their CIDs
+func indexCar(ctx context.Context, r io.ReaderAt, size int64) (*carIndex, error) {
+	cr := &countingReader{Reader: bufio.NewReaderSize(io.NewSectionReader(r, 0, size), 1<<20)}
+
+	readSection := func(buf []byte) ([]byte, error) {
+		l, err := binary.ReadUvarint(cr)
+		if err != nil {
+			return nil, err
+		}
+		if int64(l) > size-cr.n {
+			return nil, xerrors.Errorf("section at %d of %d bytes goes past the end of the file", cr.n, l)
+		}
+		if uint64(cap(buf)) < l {
+			buf = make([]byte, l)
+		}
+		buf = buf[:l]
+		if _, err := io.ReadFull(cr, buf); err != nil {
+			return nil, xerrors.Errorf("reading section: %w", err)
+		}
+		return buf, nil
+	}
+
+	hdr, err := readSection(nil)
+	if err != nil {
+		return nil, xerrors.Errorf("reading car header: %w", err)
+	}
+
+	idx := &carIndex{
+		r:       r,
+		entries: map[cid.Cid]carEntry{},
+	}
+	if err := cbor.DecodeInto(hdr, &idx.header); err != nil {
+		return nil, xerrors.Errorf("decoding car header: %w", err)
+	}
+	if idx.header.Version != 1 {
+		return nil, xerrors.Errorf("unsupported car version %d", idx.header.Version)
+	}
+
+	var buf []byte
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		start := cr.n
+		buf, err = readSection(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("reading object at %d: %w", start, err)
+		}
+
+		n, c, err := cid.CidFromBytes(buf)
+		if err != nil {
+			return nil, xerrors.Errorf("reading cid of object at %d: %w", start, err)
+		}
+
+		data := buf[n:]
+		sum, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, xerrors.Errorf("hashing object %s: %w", c, err)
+		}
+		if !sum.Equals(c) {
+			return nil, xerrors.Errorf("object %s at %d doesn't match its cid", c, start)
+		}
+
+		idx.entries[c] = carEntry{
+			offset: cr.n - int64(len(data)),
+			size:   len(data),
+		}
+	}
+
+	return idx, nil
+}
+
+func (idx *carIndex) get(c cid.Cid) ([]byte, bool, error) {
+	e, ok := idx.entries[c]
+	if !ok {
+		return nil, false, nil
+	}
+
+	data := make([]byte, e.size)
+	if _, err := idx.r.ReadAt(data, e.offset); err != nil {
+		return nil, false, xerrors.Errorf("reading object %s: %w", c, err)
+	}
+	return data, true, nil
+}
+
+func (idx *carIndex) loadTipSet(cids []cid.Cid) (*types.TipSet, int, error) {
+	var blks []*types.BlockHeader
+	for _, c := range cids {
+		data, ok, err := idx.get(c)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		blk, err := types.DecodeBlock(data)
+		if err != nil {
+			return nil, 0, xerrors.Errorf("decoding block header %s: %w", c, err)
+		}
+		blks = append(blks, blk)
+	}
+
+	if len(blks) == 0 {
+		return nil, 0, nil
+	}
+	if len(blks) != len(cids) {
+		return nil, len(blks), xerrors.Errorf("only %d of %d blocks of tipset %s in snapshot", len(blks), len(cids), cids)
+	}
+
+	ts, err := types.NewTipSet(blks)
+	if err != nil {
+		return nil, len(blks), xerrors.Errorf("invalid tipset %s: %w", cids, err)
+	}
+
+	for _, b := range blks[1:] {
+		if b.ParentStateRoot != blks[0].ParentStateRoot {
+			return nil, len(blks), xerrors.Errorf("blocks of tipset %s have different parent state roots", cids)
+		}
+	}
+
+	return ts, len(blks), nil
+}
+
+// countMissing walks all objects reachable from root, returning the number of
+// linked objects which aren't in the CAR file
+func (idx *carIndex) countMissing(root cid.Cid, walked *cid.Set) (int, error) {
+	var missing int
+
+	stack := []cid.Cid{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if c.Prefix().Codec != cid.DagCBOR || !walked.Visit(c) {
+			continue
+		}
+
+		data, ok, err := idx.get(c)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			missing++
+			continue
+		}
+
+		err = cbg.ScanForLinks(bytes.NewReader(data), func(l cid.Cid) {
+			stack = append(stack, l)
+		})
+		if err != nil {
+			return 0, xerrors.Errorf("scanning links of %s: %w", c, err)
+		}
+	}
+
+	return missing, nil
+}
+
+// VerifySnapshot checks a snapshot without importing it. It checks that all
+// objects match their CIDs, that the header chain links up from the snapshot
+// roots down to its lowest tipset, and that the state roots in the snapshot
+// are complete. If trusted is not empty, the header chain must include the
+// trusted tipset.
+func VerifySnapshot(ctx context.Context, r io.ReaderAt, size int64, trusted types.TipSetKey) (*SnapshotReport, error) {
+	idx, err := indexCar(ctx, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &SnapshotReport{
+		Roots:   idx.header.Roots,
+		Objects: len(idx.entries),
+	}
+
+	ts, _, err := idx.loadTipSet(idx.header.Roots)
+	if err != nil {
+		return rep, xerrors.Errorf("loading root tipset: %w", err)
+	}
+	if ts == nil {
+		return rep, xerrors.Errorf("root tipset %s not in snapshot", idx.header.Roots)
+	}
+	rep.Height = ts.Height()
+
+	if _, ok := idx.entries[ts.ParentState()]; !ok {
+		return rep, xerrors.Errorf("state root %s of the root tipset not in snapshot", ts.ParentState())
+	}
+
+	walked := cid.NewSet()
+	for {
+		if ctx.Err() != nil {
+			return rep, ctx.Err()
+		}
+
+		rep.Tipsets++
+		rep.LowestHeight = ts.Height()
+		if !trusted.IsEmpty() && ts.Key() == trusted {
+			rep.TrustedTipSetFound = true
+		}
+
+		if _, ok := idx.entries[ts.ParentState()]; ok && !walked.Has(ts.ParentState()) {
+			missing, err := idx.countMissing(ts.ParentState(), walked)
+			if err != nil {
+				return rep, xerrors.Errorf("walking state root at %d: %w", ts.Height(), err)
+			}
+			rep.StateRoots++
+			rep.MissingStateObjects += missing
+		}
+
+		if ts.Height() == 0 {
+			break
+		}
+
+		pts, _, err := idx.loadTipSet(ts.Parents().Cids())
+		if err != nil {
+			return rep, xerrors.Errorf("loading parents of tipset at %d: %w", ts.Height(), err)
+		}
+		if pts == nil {
+			// the lowest tipset of a range export
+			break
+		}
+		if pts.Height() >= ts.Height() {
+			return rep, xerrors.Errorf("parent tipset at %d not below tipset at %d", pts.Height(), ts.Height())
+		}
+
+		ts = pts
+	}
+
+	if !trusted.IsEmpty() && !rep.TrustedTipSetFound {
+		return rep, xerrors.Errorf("trusted tipset %s not in the snapshot header chain", trusted)
+	}
+
+	return rep, nil
+}
@@ -867,6 +867,55 @@ func PutMessage(bs bstore.Blockstore, m storable) (cid.Cid, error) {
 	return b.Cid(), nil
 }
 
+// ComputeMsgMeta computes the root CID of the combined arrays of message CIDs
+// of both types (BLS and Secpk).
+func ComputeMsgMeta(bs cbor.IpldStore, bmsgCids, smsgCids []cid.Cid) (cid.Cid, error) {
+	// block headers use adt0
+	adtStore := blockadt.WrapStore(context.TODO(), bs)
+	bmArr, err := blockadt.MakeEmptyArray(adtStore, adt.DefaultMsgAmtBitwidth)
+	if err != nil {
+		return cid.Undef, err
+	}
+	smArr, err := blockadt.MakeEmptyArray(adtStore, adt.DefaultMsgAmtBitwidth)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	for i, m := range bmsgCids {
+		c := cbg.CborCid(m)
+		if err := bmArr.Set(uint64(i), &c); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	for i, m := range smsgCids {
+		c := cbg.CborCid(m)
+		if err := smArr.Set(uint64(i), &c); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	bmroot, err := bmArr.Root()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	smroot, err := smArr.Root()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	mrcid, err := adtStore.Put(adtStore.Context(), &types.MsgMeta{
+		BlsMessages:   bmroot,
+		SecpkMessages: smroot,
+	})
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("failed to put msgmeta: %w", err)
+	}
+
+	return mrcid, nil
+}
+
 func (cs *ChainStore) PutMessage(m storable) (cid.Cid, error) {
 	return PutMessage(cs.chainBlockstore, m)
 }
@@ -1384,6 +1433,12 @@ func (cs *ChainStore) GetTipsetByHeight(ctx context.Context, h abi.ChainEpoch, t
 }
 
 func recurseLinks(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.Cid) ([]cid.Cid, error) {
+	return recurseLinksSkip(bs, walked, root, in, nil)
+}
+
+// recurseLinksSkip is recurseLinks, but doesn't include or descend into the
+// links skip returns true for
+func recurseLinksSkip(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.Cid, skip func(cid.Cid) bool) ([]cid.Cid, error) {
 	if root.Prefix().Codec != cid.DagCBOR {
 		return in, nil
 	}
@@ -1400,6 +1455,10 @@ func recurseLinks(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.
 			return
 		}
 
+		if skip != nil && skip(c) {
+			return
+		}
+
 		// traversed this already...
 		if !walked.Visit(c) {
 			return
@@ -1407,7 +1466,7 @@ func recurseLinks(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.
 
 		in = append(in, c)
 		var err error
-		in, err = recurseLinks(bs, walked, c, in)
+		in, err = recurseLinksSkip(bs, walked, c, in, skip)
 		if err != nil {
 			rerr = err
 		}
@@ -1420,6 +1479,14 @@ func recurseLinks(bs bstore.Blockstore, walked *cid.Set, root cid.Cid, in []cid.
 }
 
 func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs bool, w io.Writer) error {
+	return cs.ExportRange(ctx, ts, api.ChainExportConfig{
+		RecentRoots: inclRecentRoots,
+		SkipOldMsgs: skipOldMsgs,
+	}, w)
+}
+
+// ExportRange writes a CAR file with the chain data selected by cfg
+func (cs *ChainStore) ExportRange(ctx context.Context, ts *types.TipSet, cfg api.ChainExportConfig, w io.Writer) error {
 	h := &car.CarHeader{
 		Roots:   ts.Cids(),
 		Version: 1,
@@ -1430,7 +1497,7 @@ func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, inclRecentRo
 	}
 
 	unionBs := bstore.Union(cs.stateBlockstore, cs.chainBlockstore)
-	return cs.WalkSnapshot(ctx, ts, inclRecentRoots, skipOldMsgs, true, func(c cid.Cid) error {
+	return cs.walkSnapshot(ctx, ts, cfg, true, func(c cid.Cid) error {
 		blk, err := unionBs.Get(c)
 		if err != nil {
 			return xerrors.Errorf("writing object to car, bs.Get: %w", err)
@@ -1445,10 +1512,22 @@ func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, inclRecentRo
 }
 
 func (cs *ChainStore) WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRecentRoots abi.ChainEpoch, skipOldMsgs, skipMsgReceipts bool, cb func(cid.Cid) error) error {
+	return cs.walkSnapshot(ctx, ts, api.ChainExportConfig{
+		RecentRoots: inclRecentRoots,
+		SkipOldMsgs: skipOldMsgs,
+	}, skipMsgReceipts, cb)
+}
+
+func (cs *ChainStore) walkSnapshot(ctx context.Context, ts *types.TipSet, cfg api.ChainExportConfig, skipMsgReceipts bool, cb func(cid.Cid) error) error {
 	if ts == nil {
 		ts = cs.GetHeaviestTipSet()
 	}
 
+	inclRecentRoots, skipOldMsgs := cfg.RecentRoots, cfg.SkipOldMsgs
+	if cfg.From > ts.Height() {
+		return xerrors.Errorf("export range start %d above the exported tipset at %d", cfg.From, ts.Height())
+	}
+
 	seen := cid.NewSet()
 	walked := cid.NewSet()
 
@@ -1492,11 +1571,11 @@ func (cs *ChainStore) WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRe
 			}
 		}
 
-		if b.Height > 0 {
+		if b.Height > cfg.From {
 			for _, p := range b.Parents {
 				blocksToWalk = append(blocksToWalk, p)
 			}
-		} else {
+		} else if b.Height == 0 {
 			// include the genesis block
 			cids = append(cids, b.Parents...)
 		}
@@ -1505,7 +1584,12 @@ func (cs *ChainStore) WalkSnapshot(ctx context.Context, ts *types.TipSet, inclRe
 
 		if b.Height == 0 || b.Height > ts.Height()-inclRecentRoots {
 			if walked.Visit(b.ParentStateRoot) {
-				cids, err := recurseLinks(cs.stateBlockstore, walked, b.ParentStateRoot, []cid.Cid{b.ParentStateRoot})
+				skip, err := cs.skipActorStates(ctx, b.ParentStateRoot, cfg.Actors)
+				if err != nil {
+					return xerrors.Errorf("filtering actors: %w", err)
+				}
+
+				cids, err := recurseLinksSkip(cs.stateBlockstore, walked, b.ParentStateRoot, []cid.Cid{b.ParentStateRoot}, skip)
 				if err != nil {
 					return xerrors.Errorf("recursing genesis state failed: %w", err)
 				}
@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/crypto"
 
+	"github.com/EpiK-Protocol/go-epik/api"
 	"github.com/EpiK-Protocol/go-epik/blockstore"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/policy"
 	"github.com/EpiK-Protocol/go-epik/chain/gen"
@@ -118,6 +119,66 @@ func TestChainExportImport(t *testing.T) {
 	}
 }
 
+func TestChainExportRangeVerify(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last, mid *types.TipSet
+	for i := 0; i < 40; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last = ts.TipSet.TipSet()
+		if i == 20 {
+			mid = last
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	hasher := store.NewSnapshotHasher(1 << 10)
+	cfg := api.ChainExportConfig{From: 10, RecentRoots: 5}
+	if err := cg.ChainStore().ExportRange(context.TODO(), last, cfg, io.MultiWriter(buf, hasher)); err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest store.SnapshotManifest
+	hasher.Finish(&manifest)
+	if len(manifest.Segments) < 2 {
+		t.Fatalf("expected multiple segments, got %d", len(manifest.Segments))
+	}
+
+	bad, err := store.VerifySnapshotManifest(bytes.NewReader(buf.Bytes()), &manifest)
+	if err != nil || len(bad) > 0 {
+		t.Fatalf("manifest verification failed: %v %v", bad, err)
+	}
+
+	rep, err := store.VerifySnapshot(context.TODO(), bytes.NewReader(buf.Bytes()), int64(buf.Len()), mid.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Height != last.Height() || rep.LowestHeight > cfg.From || rep.LowestHeight == 0 {
+		t.Fatalf("unexpected range %d-%d", rep.LowestHeight, rep.Height)
+	}
+	if !rep.TrustedTipSetFound || rep.MissingStateObjects != 0 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	// a corrupted snapshot fails both checks
+	corrupt := append([]byte{}, buf.Bytes()...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	if bad, _ := store.VerifySnapshotManifest(bytes.NewReader(corrupt), &manifest); len(bad) != 1 {
+		t.Fatalf("expected one bad segment, got %v", bad)
+	}
+	if _, err := store.VerifySnapshot(context.TODO(), bytes.NewReader(corrupt), int64(len(corrupt)), types.EmptyTSK); err == nil {
+		t.Fatal("expected corrupted snapshot to fail verification")
+	}
+}
+
 func TestChainExportImportFull(t *testing.T) {
 	cg, err := gen.NewGenerator()
 	if err != nil {
@@ -241,10 +241,12 @@ func (syncer *Syncer) InformNewHead(from peer.ID, fts *store.FullTipSet) bool {
 	for _, b := range fts.Blocks {
 		if reason, ok := syncer.bad.Has(b.Cid()); ok {
 			log.Warnf("InformNewHead called on block marked as bad: %s (reason: %s)", b.Cid(), reason)
+			syncer.Exchange.PenalizePeer(from, exchange.PenaltyInvalidBlock)
 			return false
 		}
 		if err := syncer.ValidateMsgMeta(b); err != nil {
 			log.Warnf("invalid block received: %s", err)
+			syncer.Exchange.PenalizePeer(from, exchange.PenaltyInvalidBlock)
 			return false
 		}
 	}
@@ -344,7 +346,7 @@ func (syncer *Syncer) ValidateMsgMeta(fblk *types.FullBlock) error {
 	}
 
 	// Compute the root CID of the combined message trie.
-	smroot, err := computeMsgMeta(cst, bcids, scids)
+	smroot, err := store.ComputeMsgMeta(cst, bcids, scids)
 	if err != nil {
 		return xerrors.Errorf("validating msgmeta, compute failed: %w", err)
 	}
@@ -430,7 +432,7 @@ func zipTipSetAndMessages(bs cbor.IpldStore, ts *types.TipSet, allbmsgs []*types
 			bmsgCids = append(bmsgCids, allbmsgs[m].Cid())
 		}
 
-		mrcid, err := computeMsgMeta(bs, bmsgCids, smsgCids)
+		mrcid, err := store.ComputeMsgMeta(bs, bmsgCids, smsgCids)
 		if err != nil {
 			return nil, err
 		}
@@ -451,55 +453,6 @@ func zipTipSetAndMessages(bs cbor.IpldStore, ts *types.TipSet, allbmsgs []*types
 	return fts, nil
 }
 
-// computeMsgMeta computes the root CID of the combined arrays of message CIDs
-// of both types (BLS and Secpk).
-func computeMsgMeta(bs cbor.IpldStore, bmsgCids, smsgCids []cid.Cid) (cid.Cid, error) {
-	// block headers use adt0
-	store := blockadt.WrapStore(context.TODO(), bs)
-	bmArr, err := blockadt.MakeEmptyArray(store, adt.DefaultMsgAmtBitwidth)
-	if err != nil {
-		return cid.Undef, err
-	}
-	smArr, err := blockadt.MakeEmptyArray(store, adt.DefaultMsgAmtBitwidth)
-	if err != nil {
-		return cid.Undef, err
-	}
-
-	for i, m := range bmsgCids {
-		c := cbg.CborCid(m)
-		if err := bmArr.Set(uint64(i), &c); err != nil {
-			return cid.Undef, err
-		}
-	}
-
-	for i, m := range smsgCids {
-		c := cbg.CborCid(m)
-		if err := smArr.Set(uint64(i), &c); err != nil {
-			return cid.Undef, err
-		}
-	}
-
-	bmroot, err := bmArr.Root()
-	if err != nil {
-		return cid.Undef, err
-	}
-
-	smroot, err := smArr.Root()
-	if err != nil {
-		return cid.Undef, err
-	}
-
-	mrcid, err := store.Put(store.Context(), &types.MsgMeta{
-		BlsMessages:   bmroot,
-		SecpkMessages: smroot,
-	})
-	if err != nil {
-		return cid.Undef, xerrors.Errorf("failed to put msgmeta: %w", err)
-	}
-
-	return mrcid, nil
-}
-
 // FetchTipSet tries to load the provided tipset from the store, and falls back
 // to the network (client) by querying the supplied peer if not found
 // locally.
@@ -1035,7 +988,9 @@ func (syncer *Syncer) VerifyWinningPoStProof(ctx context.Context, nv network.Ver
 
 // TODO: We should extract this somewhere else and make the message pool and miner use the same logic
 func (syncer *Syncer) checkBlockMessages(ctx context.Context, b *types.FullBlock, baseTs *types.TipSet) error {
-	{
+	pc := takePrecheck(ctx, b.Cid())
+
+	if pc == nil || !pc.blsAggregate {
 		var sigCids []cid.Cid // this is what we get for people not wanting the marshalcbor method on the cid type
 		var pubks [][]byte
 
@@ -1121,6 +1076,10 @@ func (syncer *Syncer) checkBlockMessages(ctx context.Context, b *types.FullBlock
 			return xerrors.Errorf("block had invalid bls message at index %d: %w", i, err)
 		}
 
+		if pc != nil {
+			continue
+		}
+
 		c, err := store.PutMessage(tmpbs, m)
 		if err != nil {
 			return xerrors.Errorf("failed to store message %s: %w", m.Cid(), err)
@@ -1141,15 +1100,21 @@ func (syncer *Syncer) checkBlockMessages(ctx context.Context, b *types.FullBlock
 			return xerrors.Errorf("block had invalid secpk message at index %d: %w", i, err)
 		}
 
-		// `From` being an account actor is only validated inside the `vm.ResolveToKeyAddr` call
-		// in `StateManager.ResolveToKeyAddress` here (and not in `checkMsg`).
-		kaddr, err := syncer.sm.ResolveToKeyAddress(ctx, m.Message.From, baseTs)
-		if err != nil {
-			return xerrors.Errorf("failed to resolve key addr: %w", err)
+		if !pc.secpkVerified(i) {
+			// `From` being an account actor is only validated inside the `vm.ResolveToKeyAddr` call
+			// in `StateManager.ResolveToKeyAddress` here (and not in `checkMsg`).
+			kaddr, err := syncer.sm.ResolveToKeyAddress(ctx, m.Message.From, baseTs)
+			if err != nil {
+				return xerrors.Errorf("failed to resolve key addr: %w", err)
+			}
+
+			if err := sigs.Verify(&m.Signature, kaddr, m.Message.Cid().Bytes()); err != nil {
+				return xerrors.Errorf("secpk message %s has invalid signature: %w", m.Cid(), err)
+			}
 		}
 
-		if err := sigs.Verify(&m.Signature, kaddr, m.Message.Cid().Bytes()); err != nil {
-			return xerrors.Errorf("secpk message %s has invalid signature: %w", m.Cid(), err)
+		if pc != nil {
+			continue
 		}
 
 		c, err := store.PutMessage(tmpbs, m)
@@ -1162,6 +1127,11 @@ func (syncer *Syncer) checkBlockMessages(ctx context.Context, b *types.FullBlock
 		}
 	}
 
+	if pc != nil {
+		// the message root was checked ahead by the sync pipeline
+		return vm.Copy(ctx, pc.msgs, syncer.store.ChainBlockstore(), pc.msgRoot)
+	}
+
 	bmroot, err := bmArr.Root()
 	if err != nil {
 		return err
@@ -1529,73 +1499,63 @@ func (syncer *Syncer) syncMessagesAndCheckState(ctx context.Context, headers []*
 	})
 }
 
-// fills out each of the given tipsets with messages and calls the callback with it
+// fills out each of the given tipsets with messages and calls the callback
+// with it, in order. Messages are fetched and prechecked ahead of the callback
+// by the sync pipeline, see fetchFullTipsets.
 func (syncer *Syncer) iterFullTipsets(ctx context.Context, headers []*types.TipSet, cb func(context.Context, *store.FullTipSet) error) error {
-	ss := extractSyncState(ctx)
 	ctx, span := trace.StartSpan(ctx, "iterFullTipsets")
 	defer span.End()
 
 	span.AddAttributes(trace.Int64Attribute("num_headers", int64(len(headers))))
 
-	for i := len(headers) - 1; i >= 0; {
-		fts, err := syncer.store.TryFillTipSet(headers[i])
-		if err != nil {
-			return err
-		}
-		if fts != nil {
-			if err := cb(ctx, fts); err != nil {
-				return err
-			}
-			i--
-			continue
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		batchSize := concurrentSyncRequests * syncRequestBatchSize
-		if i < batchSize {
-			batchSize = i + 1
-		}
-
-		ss.SetStage(api.StageFetchingMessages)
-		startOffset := i + 1 - batchSize
-		bstout, batchErr := syncer.fetchMessages(ctx, headers[startOffset:startOffset+batchSize], startOffset)
-		ss.SetStage(api.StageMessages)
+	prechecks := new(sync.Map)
+	ctx = context.WithValue(ctx, precheckKey{}, prechecks)
 
-		if batchErr != nil {
-			return xerrors.Errorf("failed to fetch messages: %w", batchErr)
+	queue := make(chan *pipelineTipSet, SyncPipelineDepth)
+	fetchErr := make(chan error, 1)
+	go func() {
+		defer close(queue)
+		fetchErr <- syncer.fetchFullTipsets(ctx, headers, prechecks, queue)
+	}()
+	defer func() {
+		// stop the fetch and precheck stages before returning
+		cancel()
+		for range queue {
 		}
+	}()
 
-		for bsi := 0; bsi < len(bstout); bsi++ {
-			// temp storage so we don't persist data we dont want to
-			bs := bstore.NewMemory()
-			blks := cbor.NewCborStore(bs)
+	for pts := range queue {
+		stats.Record(ctx, metrics.SyncPipelineQueued.M(int64(len(queue))))
 
-			this := headers[i-bsi]
-			bstip := bstout[len(bstout)-(bsi+1)]
-			fts, err := zipTipSetAndMessages(blks, this, bstip.Bls, bstip.Secpk, bstip.BlsIncludes, bstip.SecpkIncludes)
-			if err != nil {
-				log.Warnw("zipping failed", "error", err, "bsi", bsi, "i", i,
-					"height", this.Height(),
-					"next-height", i+batchSize)
-				return xerrors.Errorf("message processing failed: %w", err)
-			}
+		select {
+		case <-pts.prechecked:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 
-			if err := cb(ctx, fts); err != nil {
-				return err
-			}
+		start := build.Clock.Now()
+		if err := cb(ctx, pts.fts); err != nil {
+			return err
+		}
+		recordSyncStage(ctx, syncStageExecute, start)
 
-			if err := persistMessages(ctx, bs, bstip); err != nil {
-				return err
-			}
+		if pts.msgs == nil {
+			continue
+		}
 
-			if err := copyBlockstore(ctx, bs, syncer.store.ChainBlockstore()); err != nil {
-				return xerrors.Errorf("message processing failed: %w", err)
-			}
+		if err := persistMessages(ctx, pts.bs, pts.msgs); err != nil {
+			return err
 		}
 
-		i -= batchSize
+		if err := copyBlockstore(ctx, pts.bs, syncer.store.ChainBlockstore()); err != nil {
+			return xerrors.Errorf("message processing failed: %w", err)
+		}
 	}
 
-	return nil
+	return <-fetchErr
 }
 
 func (syncer *Syncer) fetchMessages(ctx context.Context, headers []*types.TipSet, startOffset int) ([]*exchange.CompactedMessages, error) {
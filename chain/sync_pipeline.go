@@ -0,0 +1,353 @@
+package chain
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
+
+	blockadt "github.com/filecoin-project/specs-actors/v2/actors/util/adt"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	bstore "github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/exchange"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/lib/sigs"
+	"github.com/EpiK-Protocol/go-epik/metrics"
+)
+
+// SyncPipelineDepth is the number of tipsets which are fetched and checked
+// ahead of state execution when syncing
+var SyncPipelineDepth = 2 * concurrentSyncRequests * syncRequestBatchSize
+
+// SyncPrecheckWorkers is the number of workers checking the messages of
+// fetched blocks ahead of state execution
+var SyncPrecheckWorkers = runtime.NumCPU()
+
+const (
+	syncStageFetch    = "fetch"
+	syncStagePrecheck = "precheck"
+	syncStageExecute  = "execute"
+)
+
+// msgPrecheck holds the results of the checks of checkBlockMessages which
+// don't depend on the parent state, done ahead of execution
+type msgPrecheck struct {
+	// blsAggregate is set when the bls aggregate signature was verified
+	blsAggregate bool
+	// secpk holds the indexes of secpk messages with a verified signature
+	secpk map[int]struct{}
+
+	// msgs holds the message arrays of the block, rooted at msgRoot, which
+	// matches the message root in the header
+	msgs    bstore.Blockstore
+	msgRoot cid.Cid
+}
+
+// secpkVerified returns whether the signature of the secpk message at index i
+// was verified ahead
+func (pc *msgPrecheck) secpkVerified(i int) bool {
+	if pc == nil {
+		return false
+	}
+	_, ok := pc.secpk[i]
+	return ok
+}
+
+type precheckKey struct{}
+
+// extractPrechecks returns the message prechecks of the sync pipeline, keyed
+// by block cid, or nil outside of the pipeline
+func extractPrechecks(ctx context.Context) *sync.Map {
+	v := ctx.Value(precheckKey{})
+	if v != nil {
+		return v.(*sync.Map)
+	}
+	return nil
+}
+
+// takePrecheck returns and forgets the precheck of the block, if any
+func takePrecheck(ctx context.Context, blk cid.Cid) *msgPrecheck {
+	pcs := extractPrechecks(ctx)
+	if pcs == nil {
+		return nil
+	}
+
+	v, ok := pcs.LoadAndDelete(blk)
+	if !ok {
+		return nil
+	}
+	return v.(*msgPrecheck)
+}
+
+func recordSyncStage(ctx context.Context, stage string, start time.Time) {
+	_ = stats.RecordWithTags(ctx,
+		[]tag.Mutator{tag.Upsert(metrics.SyncStage, stage)},
+		metrics.SyncStageDuration.M(metrics.SinceInMilliseconds(start)))
+}
+
+// buildMsgMeta puts the message arrays of the block into a temporary
+// blockstore, returning it with the root of the arrays
+func buildMsgMeta(ctx context.Context, b *types.FullBlock) (bstore.Blockstore, cid.Cid, error) {
+	tmpbs := bstore.NewMemory()
+	tmpstore := blockadt.WrapStore(ctx, cbor.NewCborStore(tmpbs))
+
+	bmArr, err := blockadt.MakeEmptyArray(tmpstore, adt.DefaultMsgAmtBitwidth)
+	if err != nil {
+		return nil, cid.Undef, xerrors.Errorf("failed to make empty array: %w", err)
+	}
+	for i, m := range b.BlsMessages {
+		c, err := store.PutMessage(tmpbs, m)
+		if err != nil {
+			return nil, cid.Undef, xerrors.Errorf("failed to store message %s: %w", m.Cid(), err)
+		}
+
+		k := cbg.CborCid(c)
+		if err := bmArr.Set(uint64(i), &k); err != nil {
+			return nil, cid.Undef, xerrors.Errorf("failed to put bls message at index %d: %w", i, err)
+		}
+	}
+
+	smArr, err := blockadt.MakeEmptyArray(tmpstore, adt.DefaultMsgAmtBitwidth)
+	if err != nil {
+		return nil, cid.Undef, xerrors.Errorf("failed to make empty array: %w", err)
+	}
+	for i, m := range b.SecpkMessages {
+		c, err := store.PutMessage(tmpbs, m)
+		if err != nil {
+			return nil, cid.Undef, xerrors.Errorf("failed to store message %s: %w", m.Cid(), err)
+		}
+
+		k := cbg.CborCid(c)
+		if err := smArr.Set(uint64(i), &k); err != nil {
+			return nil, cid.Undef, xerrors.Errorf("failed to put secpk message at index %d: %w", i, err)
+		}
+	}
+
+	bmroot, err := bmArr.Root()
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	smroot, err := smArr.Root()
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	mrcid, err := tmpstore.Put(ctx, &types.MsgMeta{
+		BlsMessages:   bmroot,
+		SecpkMessages: smroot,
+	})
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	return tmpbs, mrcid, nil
+}
+
+// precheckMessages runs the checks of checkBlockMessages which don't need the
+// parent state: the message root, and the signatures of messages sent from
+// key addresses. Signatures of messages sent from ID addresses are left to
+// checkBlockMessages, as resolving the key needs the parent state.
+func (syncer *Syncer) precheckMessages(ctx context.Context, b *types.FullBlock) (*msgPrecheck, error) {
+	pc := &msgPrecheck{
+		secpk: map[int]struct{}{},
+	}
+
+	var err error
+	pc.msgs, pc.msgRoot, err = buildMsgMeta(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Header.Messages != pc.msgRoot {
+		return nil, xerrors.Errorf("messages didnt match message root in header")
+	}
+
+	keyed := true
+	var sigCids []cid.Cid
+	var pubks [][]byte
+	for _, m := range b.BlsMessages {
+		if m.From.Protocol() != address.BLS {
+			keyed = false
+			break
+		}
+		sigCids = append(sigCids, m.Cid())
+		pubks = append(pubks, m.From.Payload())
+	}
+	if keyed {
+		if err := syncer.verifyBlsAggregate(ctx, b.Header.BLSAggregate, sigCids, pubks); err != nil {
+			return nil, xerrors.Errorf("bls aggregate signature was invalid: %w", err)
+		}
+		pc.blsAggregate = true
+	}
+
+	for i, m := range b.SecpkMessages {
+		if m.Message.From.Protocol() != address.SECP256K1 {
+			continue
+		}
+		if err := sigs.Verify(&m.Signature, m.Message.From, m.Message.Cid().Bytes()); err != nil {
+			return nil, xerrors.Errorf("secpk message %s has invalid signature: %w", m.Cid(), err)
+		}
+		pc.secpk[i] = struct{}{}
+	}
+
+	return pc, nil
+}
+
+// pipelineTipSet is a tipset with its messages, queued for execution
+type pipelineTipSet struct {
+	fts *store.FullTipSet
+
+	// temporary store of fetched messages, nil if the messages were already
+	// in the chain store
+	bs   bstore.Blockstore
+	msgs *exchange.CompactedMessages
+
+	// closed when the message prechecks are done
+	prechecked chan struct{}
+}
+
+// precheckTipSet runs the message prechecks of the blocks of the tipset on
+// the worker pool, storing the results in prechecks. Failed prechecks are
+// not stored, the failures are reported by the full validation. The
+// prechecks are also tracked in pending, so that the caller can wait for
+// all of them to stop.
+func (syncer *Syncer) precheckTipSet(ctx context.Context, workers chan struct{}, pending *sync.WaitGroup, prechecks *sync.Map, fts *store.FullTipSet) chan struct{} {
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, b := range fts.Blocks {
+		b := b
+
+		wg.Add(1)
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			defer wg.Done()
+
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() {
+				<-workers
+			}()
+
+			if validated, err := syncer.store.IsBlockValidated(ctx, b.Cid()); err == nil && validated {
+				return
+			}
+
+			start := build.Clock.Now()
+			pc, err := syncer.precheckMessages(ctx, b)
+			if err != nil {
+				log.Debugw("message precheck failed", "block", b.Cid(), "height", b.Header.Height, "error", err)
+				return
+			}
+			recordSyncStage(ctx, syncStagePrecheck, start)
+
+			prechecks.Store(b.Cid(), pc)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return done
+}
+
+// fetchFullTipsets fills out the headers with messages, oldest first, and
+// queues them for execution after starting their message prechecks. Messages
+// are fetched from the network in batches, while the queued tipsets are
+// executed. It returns once the prechecks it started are done.
+func (syncer *Syncer) fetchFullTipsets(ctx context.Context, headers []*types.TipSet, prechecks *sync.Map, out chan<- *pipelineTipSet) error {
+	ss := extractSyncState(ctx)
+	workers := make(chan struct{}, SyncPrecheckWorkers)
+
+	var pending sync.WaitGroup
+	defer pending.Wait()
+
+	queue := func(fts *store.FullTipSet, bs bstore.Blockstore, msgs *exchange.CompactedMessages) error {
+		pts := &pipelineTipSet{
+			fts:        fts,
+			bs:         bs,
+			msgs:       msgs,
+			prechecked: syncer.precheckTipSet(ctx, workers, &pending, prechecks, fts),
+		}
+
+		select {
+		case out <- pts:
+			stats.Record(ctx, metrics.SyncPipelineQueued.M(int64(len(out))))
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for i := len(headers) - 1; i >= 0; {
+		fts, err := syncer.store.TryFillTipSet(headers[i])
+		if err != nil {
+			return err
+		}
+		if fts != nil {
+			if err := queue(fts, nil, nil); err != nil {
+				return err
+			}
+			i--
+			continue
+		}
+
+		batchSize := concurrentSyncRequests * syncRequestBatchSize
+		if i < batchSize {
+			batchSize = i + 1
+		}
+
+		ss.SetStage(api.StageFetchingMessages)
+		start := build.Clock.Now()
+		startOffset := i + 1 - batchSize
+		bstout, batchErr := syncer.fetchMessages(ctx, headers[startOffset:startOffset+batchSize], startOffset)
+		ss.SetStage(api.StageMessages)
+
+		if batchErr != nil {
+			return xerrors.Errorf("failed to fetch messages: %w", batchErr)
+		}
+		recordSyncStage(ctx, syncStageFetch, start)
+
+		for bsi := 0; bsi < len(bstout); bsi++ {
+			// temp storage so we don't persist data we dont want to
+			bs := bstore.NewMemory()
+			blks := cbor.NewCborStore(bs)
+
+			this := headers[i-bsi]
+			bstip := bstout[len(bstout)-(bsi+1)]
+			fts, err := zipTipSetAndMessages(blks, this, bstip.Bls, bstip.Secpk, bstip.BlsIncludes, bstip.SecpkIncludes)
+			if err != nil {
+				log.Warnw("zipping failed", "error", err, "bsi", bsi, "i", i,
+					"height", this.Height(),
+					"next-height", i+batchSize)
+				return xerrors.Errorf("message processing failed: %w", err)
+			}
+
+			if err := queue(fts, bs, bstip); err != nil {
+				return err
+			}
+		}
+
+		i -= batchSize
+	}
+
+	return nil
+}
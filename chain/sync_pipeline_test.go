@@ -0,0 +1,248 @@
+package chain
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/exchange"
+	"github.com/EpiK-Protocol/go-epik/chain/gen"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+)
+
+// mineMessageBlock mines a block on the current head of the generator with
+// a bls message from a funded bls account and a secpk message from the
+// banker, returning it with its parent tipset
+func mineMessageBlock(t *testing.T, cg *gen.ChainGen) (*types.FullBlock, *types.TipSet, address.Address) {
+	ctx := context.Background()
+
+	// the banker funds the bls receivers of the generator
+	for i := 0; i < 2; i++ {
+		_, err := cg.NextTipSet()
+		require.NoError(t, err)
+	}
+	base := cg.CurTipset.TipSet()
+
+	st, _, err := cg.StateManager().TipSetState(ctx, base)
+	require.NoError(t, err)
+
+	sign := func(msg types.Message) *types.SignedMessage {
+		sig, err := cg.Wallet().WalletSign(ctx, msg.From, msg.Cid().Bytes(), api.MsgMeta{})
+		require.NoError(t, err)
+		return &types.SignedMessage{Message: msg, Signature: *sig}
+	}
+	mkMsg := func(from address.Address) *types.SignedMessage {
+		act, err := cg.StateManager().LoadActorRaw(ctx, from, st)
+		require.NoError(t, err)
+		return sign(types.Message{
+			To:         cg.Banker(),
+			From:       from,
+			Nonce:      act.Nonce,
+			Value:      types.NewInt(1),
+			GasLimit:   100_000_000,
+			GasFeeCap:  types.NewInt(0),
+			GasPremium: types.NewInt(0),
+		})
+	}
+
+	keys, err := cg.Wallet().WalletList(ctx)
+	require.NoError(t, err)
+	var blsFrom address.Address
+	for _, k := range keys {
+		if k.Protocol() != address.BLS {
+			continue
+		}
+		if _, err := cg.StateManager().LoadActorRaw(ctx, k, st); err == nil {
+			blsFrom = k
+			break
+		}
+	}
+	require.NotEqual(t, address.Undef, blsFrom, "no funded bls account")
+
+	msgs := []*types.SignedMessage{mkMsg(blsFrom), mkMsg(cg.Banker())}
+	fts, err := cg.NextTipSetFromMinersWithMessages(base, cg.Miners, [][]*types.SignedMessage{msgs, msgs})
+	require.NoError(t, err)
+
+	b := fts.Blocks[0]
+	require.Len(t, b.BlsMessages, 1)
+	require.Len(t, b.SecpkMessages, 1)
+
+	return b, base, blsFrom
+}
+
+func TestPipelinedBlockMessages(t *testing.T) {
+	ctx := context.Background()
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+
+	valid, base, blsFrom := mineMessageBlock(t, cg)
+	syncer := &Syncer{store: cg.ChainStore(), sm: cg.StateManager()}
+
+	// copyBlock copies the header and messages of the valid block, and sets
+	// the message root to the messages after modify
+	copyBlock := func(modify func(b *types.FullBlock)) *types.FullBlock {
+		h := *valid.Header
+		b := &types.FullBlock{Header: &h}
+		for _, m := range valid.BlsMessages {
+			cm := *m
+			b.BlsMessages = append(b.BlsMessages, &cm)
+		}
+		for _, m := range valid.SecpkMessages {
+			cm := *m
+			cm.Signature.Data = append([]byte{}, m.Signature.Data...)
+			b.SecpkMessages = append(b.SecpkMessages, &cm)
+		}
+
+		modify(b)
+
+		_, root, err := buildMsgMeta(ctx, b)
+		require.NoError(t, err)
+		b.Header.Messages = root
+		return b
+	}
+
+	otherSig, err := cg.Wallet().WalletSign(ctx, blsFrom, []byte("not a message"), api.MsgMeta{})
+	require.NoError(t, err)
+
+	mismatched := copyBlock(func(*types.FullBlock) {})
+	mismatched.Header.Messages = base.Blocks()[0].Messages
+
+	for _, tc := range []struct {
+		name  string
+		block *types.FullBlock
+		valid bool
+	}{{
+		name:  "valid",
+		block: valid,
+		valid: true,
+	}, {
+		name: "bad secpk signature",
+		block: copyBlock(func(b *types.FullBlock) {
+			b.SecpkMessages[0].Signature.Data[5] ^= 0xff
+		}),
+	}, {
+		name: "bad bls aggregate",
+		block: copyBlock(func(b *types.FullBlock) {
+			b.Header.BLSAggregate = otherSig
+		}),
+	}, {
+		name:  "mismatched message root",
+		block: mismatched,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			seqErr := syncer.checkBlockMessages(ctx, tc.block, base)
+
+			prechecks := new(sync.Map)
+			pctx := context.WithValue(ctx, precheckKey{}, prechecks)
+			var pending sync.WaitGroup
+			<-syncer.precheckTipSet(pctx, make(chan struct{}, 1), &pending, prechecks, store.NewFullTipSet([]*types.FullBlock{tc.block}))
+
+			_, prechecked := prechecks.Load(tc.block.Cid())
+			pipeErr := syncer.checkBlockMessages(pctx, tc.block, base)
+
+			if tc.valid {
+				require.NoError(t, seqErr)
+				require.NoError(t, pipeErr)
+				require.True(t, prechecked)
+				return
+			}
+
+			require.Error(t, seqErr)
+			require.Error(t, pipeErr)
+			// invalid blocks are caught ahead of execution, and left to
+			// the full validation
+			require.False(t, prechecked)
+			_, err := syncer.precheckMessages(ctx, tc.block)
+			require.Error(t, err)
+		})
+	}
+}
+
+// blockingExchange blocks message requests until they are cancelled
+type blockingExchange struct {
+	exchange.Client
+
+	inflight int32
+	called   chan struct{}
+}
+
+func (e *blockingExchange) GetChainMessages(ctx context.Context, _ []*types.TipSet) ([]*exchange.CompactedMessages, error) {
+	atomic.AddInt32(&e.inflight, 1)
+	defer atomic.AddInt32(&e.inflight, -1)
+
+	select {
+	case e.called <- struct{}{}:
+	default:
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSyncPipelineCancel(t *testing.T) {
+	defer func(n int) {
+		SyncPrecheckWorkers = n
+	}(SyncPrecheckWorkers)
+	// no precheck worker is available, so execution waits on the prechecks
+	// of the first tipset
+	SyncPrecheckWorkers = 0
+
+	cg, err := gen.NewGenerator()
+	require.NoError(t, err)
+
+	// newest first, the oldest tipsets are in the chain store, the newer
+	// ones have their messages fetched from the network
+	var headers []*types.TipSet
+	for i := 0; i < 3; i++ {
+		mts, err := cg.NextTipSet()
+		require.NoError(t, err)
+		headers = append([]*types.TipSet{mts.TipSet.TipSet()}, headers...)
+	}
+	for i := 0; i < 3; i++ {
+		headers = append([]*types.TipSet{mock.TipSet(mock.MkBlock(headers[0], 1, uint64(i+1)))}, headers...)
+	}
+
+	ex := &blockingExchange{called: make(chan struct{}, 1)}
+	syncer := &Syncer{store: cg.ChainStore(), sm: cg.StateManager(), Exchange: ex}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var executed int32
+	done := make(chan error, 1)
+	go func() {
+		done <- syncer.iterFullTipsets(ctx, headers, func(context.Context, *store.FullTipSet) error {
+			atomic.AddInt32(&executed, 1)
+			return nil
+		})
+	}()
+
+	select {
+	case <-ex.called:
+	case <-time.After(10 * time.Second):
+		t.Fatal("messages were not fetched")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		require.True(t, xerrors.Is(err, context.Canceled), err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("pipeline did not stop")
+	}
+
+	// the fetch and precheck stages are done when iterFullTipsets returns
+	require.Zero(t, atomic.LoadInt32(&ex.inflight))
+	require.Zero(t, atomic.LoadInt32(&executed))
+}
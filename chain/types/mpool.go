@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
 )
 
 type MpoolConfig struct {
@@ -13,6 +14,37 @@ type MpoolConfig struct {
 	ReplaceByFeeRatio      float64
 	PruneCooldown          time.Duration
 	GasLimitOverestimation float64
+
+	// DenyRules reject messages matching any of the rules
+	DenyRules []MpoolMessageMatch
+	// MinPremiums reject messages matching a rule with a lower GasPremium
+	MinPremiums []MpoolMinPremium
+	// MaxPendingPerSender limits the number of pending messages of any
+	// sender, 0 keeps the default limits
+	MaxPendingPerSender int
+	// RejectBlockedExperts rejects messages sent to blocked expert actors
+	RejectBlockedExperts bool
+	// PolicyExemptAddrs are senders whose messages pushed through the API
+	// skip the policies, like the messages of the wallet keys of the node
+	PolicyExemptAddrs []address.Address
+
+	// LocalLane makes miner PoSt, expert and governance messages from local
+	// addresses be selected and republished before any other messages
+	LocalLane bool
+}
+
+// MpoolMessageMatch matches messages by their destination. Empty fields
+// match any message.
+type MpoolMessageMatch struct {
+	To []address.Address
+	// Actor is the destination actor type, e.g. "storageminer" or "expert"
+	Actor   string
+	Methods []abi.MethodNum
+}
+
+type MpoolMinPremium struct {
+	MpoolMessageMatch
+	MinGasPremium BigInt
 }
 
 func (mc *MpoolConfig) Clone() *MpoolConfig {
@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	logging "github.com/ipfs/go-log/v2"
@@ -97,6 +98,46 @@ func BackupCmd(repoFlag string, rt repo.RepoType, getApi BackupApiFn) *cli.Comma
 		return nil
 	}
 
+	var verifyCmd = &cli.Command{
+		Name:      "verify",
+		Usage:     "Verify a metadata backup, and the incremental backups applying on it",
+		ArgsUsage: "[backup file] [incremental backups...]",
+		Action: func(cctx *cli.Context) error {
+			if cctx.Args().Len() < 1 {
+				return xerrors.Errorf("expected at least 1 argument")
+			}
+
+			var files []io.Reader
+			for _, p := range cctx.Args().Slice() {
+				fpath, err := homedir.Expand(p)
+				if err != nil {
+					return xerrors.Errorf("expanding file path: %w", err)
+				}
+
+				f, err := os.Open(fpath)
+				if err != nil {
+					return xerrors.Errorf("opening backup file %s: %w", fpath, err)
+				}
+				defer f.Close() // nolint:errcheck
+
+				files = append(files, f)
+			}
+
+			res, err := backupds.Verify(files[0], files[1:]...)
+			if err != nil {
+				return xerrors.Errorf("verifying backup: %w", err)
+			}
+
+			fmt.Printf("Keys: %d\n", res.Keys)
+			fmt.Printf("Backup tuples: %d\n", res.Tuples)
+			fmt.Printf("Log entries: %d\n", res.Entries)
+			fmt.Printf("Checksum: %x\n", res.Checksum)
+			fmt.Printf("State hash: %x\n", res.StateHash)
+
+			return nil
+		},
+	}
+
 	return &cli.Command{
 		Name:  "backup",
 		Usage: "Create node metadata backup",
@@ -105,7 +146,12 @@ func BackupCmd(repoFlag string, rt repo.RepoType, getApi BackupApiFn) *cli.Comma
 Online backups:
 For security reasons, the daemon must be have EPIK_BACKUP_BASE_PATH env var set
 to a path where backup files are supposed to be saved, and the path specified in
-this command must be within this base path`,
+this command must be within this base path
+
+Scheduled backups:
+Full and incremental backups can be scheduled in the [Backup] section of the
+config. A full backup followed by its incremental backups is restored in order,
+use 'backup verify' to check a backup chain before restoring it`,
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:  "offline",
@@ -113,6 +159,9 @@ this command must be within this base path`,
 			},
 		},
 		ArgsUsage: "[backup file path]",
+		Subcommands: []*cli.Command{
+			verifyCmd,
+		},
 		Action: func(cctx *cli.Context) error {
 			if cctx.Args().Len() != 1 {
 				return xerrors.Errorf("expected 1 argument")
@@ -7,6 +7,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
@@ -14,8 +16,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/filecoin-project/go-address"
 	cborutil "github.com/filecoin-project/go-cbor-util"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -36,6 +40,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/actors"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
+	"github.com/EpiK-Protocol/go-epik/chain/store"
 	types "github.com/EpiK-Protocol/go-epik/chain/types"
 )
 
@@ -55,12 +60,14 @@ var chainCmd = &cli.Command{
 		chainGetCmd,
 		chainBisectCmd,
 		chainExportCmd,
+		chainVerifySnapshotCmd,
 		slashConsensusFault,
 		chainGasPriceCmd,
 		chainInspectUsage,
 		chainDecodeCmd,
 		chainEncodeCmd,
 		chainDisputeSetCmd,
+		chainSplitstoreCmd,
 	},
 }
 
@@ -1083,6 +1090,27 @@ var chainExportCmd = &cli.Command{
 		&cli.BoolFlag{
 			Name: "skip-old-msgs",
 		},
+		&cli.Int64Flag{
+			Name:  "from",
+			Usage: "only export the header chain down to this epoch",
+		},
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "export the chain from the tipset at this epoch, instead of the head",
+		},
+		&cli.StringSliceFlag{
+			Name:  "actors",
+			Usage: "only export the state of these actors",
+		},
+		&cli.Int64Flag{
+			Name:  "segment-size",
+			Usage: "size of the hashed segments in the manifest, in bytes",
+			Value: store.DefaultSnapshotSegmentSize,
+		},
+		&cli.BoolFlag{
+			Name:  "no-manifest",
+			Usage: "don't write a manifest with the snapshot hashes next to the output",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
@@ -1101,6 +1129,52 @@ var chainExportCmd = &cli.Command{
 			return fmt.Errorf("\"recent-stateroots\" has to be greater than %d", build.Finality)
 		}
 
+		if cctx.IsSet("tipset") && cctx.IsSet("to") {
+			return fmt.Errorf("\"tipset\" and \"to\" can't be used together")
+		}
+
+		cfg := lapi.ChainExportConfig{
+			From:        abi.ChainEpoch(cctx.Int64("from")),
+			RecentRoots: rsrs,
+			SkipOldMsgs: cctx.Bool("skip-old-msgs"),
+		}
+
+		for _, s := range cctx.StringSlice("actors") {
+			addr, err := address.NewFromString(s)
+			if err != nil {
+				return xerrors.Errorf("parsing actor address %q: %w", s, err)
+			}
+			cfg.Actors = append(cfg.Actors, addr)
+		}
+
+		if rsrs == 0 && cfg.SkipOldMsgs {
+			return fmt.Errorf("must pass recent stateroots along with skip-old-msgs")
+		}
+		if rsrs == 0 && cfg.From > 0 {
+			return fmt.Errorf("must pass recent stateroots along with from")
+		}
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+		if cctx.IsSet("to") {
+			ts, err = api.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(cctx.Int64("to")), types.EmptyTSK)
+			if err != nil {
+				return xerrors.Errorf("getting tipset at %d: %w", cctx.Int64("to"), err)
+			}
+		}
+		if ts == nil {
+			ts, err = api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if cfg.From > ts.Height() {
+			return fmt.Errorf("\"from\" (%d) is above the exported tipset at %d", cfg.From, ts.Height())
+		}
+
 		fi, err := os.Create(cctx.Args().First())
 		if err != nil {
 			return err
@@ -1112,36 +1186,155 @@ var chainExportCmd = &cli.Command{
 			}
 		}()
 
-		ts, err := LoadTipSet(ctx, cctx, api)
+		stream, err := api.ChainExportRange(ctx, ts.Key(), cfg)
 		if err != nil {
 			return err
 		}
 
-		skipold := cctx.Bool("skip-old-msgs")
+		hasher := store.NewSnapshotHasher(cctx.Int64("segment-size"))
+		w := io.MultiWriter(fi, hasher)
 
-		if rsrs == 0 && skipold {
-			return fmt.Errorf("must pass recent stateroots along with skip-old-msgs")
+		var last bool
+		for b := range stream {
+			last = len(b) == 0
+
+			_, err := w.Write(b)
+			if err != nil {
+				return err
+			}
 		}
 
-		stream, err := api.ChainExport(ctx, rsrs, skipold, ts.Key())
+		if !last {
+			return xerrors.Errorf("incomplete export (remote connection lost?)")
+		}
+
+		if cctx.Bool("no-manifest") {
+			return nil
+		}
+
+		manifest := store.SnapshotManifest{
+			Roots:       ts.Cids(),
+			Height:      ts.Height(),
+			From:        cfg.From,
+			RecentRoots: cfg.RecentRoots,
+			Actors:      cfg.Actors,
+		}
+		hasher.Finish(&manifest)
+
+		mb, err := json.MarshalIndent(&manifest, "", "  ")
+		if err != nil {
+			return xerrors.Errorf("marshaling manifest: %w", err)
+		}
+
+		if err := ioutil.WriteFile(cctx.Args().First()+manifestSuffix, mb, 0644); err != nil {
+			return xerrors.Errorf("writing manifest: %w", err)
+		}
+
+		return nil
+	},
+}
+
+const manifestSuffix = ".manifest.json"
+
+var chainVerifySnapshotCmd = &cli.Command{
+	Name:      "verify-snapshot",
+	Usage:     "check a chain snapshot without importing it",
+	ArgsUsage: "<snapshotPath>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "manifest",
+			Usage: "manifest written by 'chain export', defaults to the snapshot path with a .manifest.json suffix if it exists",
+		},
+		&cli.StringFlag{
+			Name:  "trusted-tipset",
+			Usage: "comma separated cids of a trusted tipset which the snapshot header chain must include",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return cli.ShowCommandHelp(cctx, cctx.Command.Name)
+		}
+		ctx := ReqContext(cctx)
+
+		fi, err := os.Open(cctx.Args().First())
 		if err != nil {
 			return err
 		}
+		defer fi.Close() //nolint:errcheck
 
-		var last bool
-		for b := range stream {
-			last = len(b) == 0
+		st, err := fi.Stat()
+		if err != nil {
+			return err
+		}
 
-			_, err := fi.Write(b)
+		var manifest *store.SnapshotManifest
+		mpath := cctx.String("manifest")
+		if mpath == "" {
+			if _, err := os.Stat(cctx.Args().First() + manifestSuffix); err == nil {
+				mpath = cctx.Args().First() + manifestSuffix
+			}
+		}
+		if mpath != "" {
+			mb, err := ioutil.ReadFile(mpath)
+			if err != nil {
+				return xerrors.Errorf("reading manifest: %w", err)
+			}
+			manifest = new(store.SnapshotManifest)
+			if err := json.Unmarshal(mb, manifest); err != nil {
+				return xerrors.Errorf("parsing manifest: %w", err)
+			}
+
+			bad, err := store.VerifySnapshotManifest(io.NewSectionReader(fi, 0, st.Size()), manifest)
+			for _, i := range bad {
+				seg := manifest.Segments[i]
+				fmt.Printf("segment %d (bytes %d-%d) doesn't match the manifest\n", i, seg.Offset, seg.Offset+seg.Size)
+			}
 			if err != nil {
 				return err
 			}
+			if len(bad) > 0 {
+				return xerrors.Errorf("%d segments don't match the manifest", len(bad))
+			}
+			fmt.Printf("manifest: %d segments ok\n", len(manifest.Segments))
 		}
 
-		if !last {
-			return xerrors.Errorf("incomplete export (remote connection lost?)")
+		var trusted types.TipSetKey
+		if cctx.IsSet("trusted-tipset") {
+			cids, err := ParseTipSetString(cctx.String("trusted-tipset"))
+			if err != nil {
+				return xerrors.Errorf("parsing trusted tipset: %w", err)
+			}
+			trusted = types.NewTipSetKey(cids...)
 		}
 
+		rep, err := store.VerifySnapshot(ctx, fi, st.Size(), trusted)
+		if rep != nil {
+			fmt.Printf("roots: %s\n", rep.Roots)
+			fmt.Printf("height: %d, lowest height: %d\n", rep.Height, rep.LowestHeight)
+			fmt.Printf("objects: %d, tipsets: %d, state roots: %d\n", rep.Objects, rep.Tipsets, rep.StateRoots)
+			if rep.MissingStateObjects > 0 {
+				fmt.Printf("missing state objects: %d\n", rep.MissingStateObjects)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if manifest != nil {
+			if types.NewTipSetKey(manifest.Roots...) != types.NewTipSetKey(rep.Roots...) {
+				return xerrors.Errorf("snapshot roots don't match the manifest")
+			}
+			if rep.LowestHeight > manifest.From {
+				return xerrors.Errorf("header chain ends at %d, manifest says %d", rep.LowestHeight, manifest.From)
+			}
+		}
+
+		// state of actors which weren't selected is expected to be missing
+		if rep.MissingStateObjects > 0 && (manifest == nil || len(manifest.Actors) == 0) {
+			return xerrors.Errorf("state roots in the snapshot are incomplete")
+		}
+
+		fmt.Println("snapshot ok")
 		return nil
 	},
 }
@@ -1456,3 +1649,141 @@ var chainEncodeParamsCmd = &cli.Command{
 		return nil
 	},
 }
+
+var chainSplitstoreCmd = &cli.Command{
+	Name:  "splitstore",
+	Usage: "Inspect and control the splitstore",
+	Subcommands: []*cli.Command{
+		chainSplitstoreStatusCmd,
+		chainSplitstoreCompactCmd,
+		chainSplitstoreGCCmd,
+		chainSplitstoreWarmupCmd,
+	},
+}
+
+var chainSplitstoreStatusCmd = &cli.Command{
+	Name:  "status",
+	Usage: "Print the splitstore state and compaction progress",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		st, err := api.ChainSplitstoreStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cctx.App.Writer, 8, 4, 2, ' ', 0)
+		size := func(s int64) string {
+			if s < 0 {
+				return "unknown"
+			}
+			return units.BytesSize(float64(s))
+		}
+
+		fmt.Fprintf(w, "Base epoch:\t%d\n", st.BaseEpoch)
+		fmt.Fprintf(w, "Warmup epoch:\t%d\n", st.WarmupEpoch)
+		fmt.Fprintf(w, "Compactions:\t%d\n", st.CompactionIndex)
+		fmt.Fprintf(w, "Mark set size:\t%d\n", st.MarkSetSize)
+		fmt.Fprintf(w, "Hotstore size:\t%s\n", size(st.HotSize))
+		fmt.Fprintf(w, "Coldstore size:\t%s\n", size(st.ColdSize))
+
+		if st.LastCompactionTime.IsZero() {
+			fmt.Fprintf(w, "Last compaction:\tnone since startup\n")
+		} else {
+			fmt.Fprintf(w, "Last compaction:\tepoch %d at %s (took %s)\n", st.LastCompactionEpoch,
+				st.LastCompactionTime.Format(time.RFC3339), st.LastCompactionTook.Truncate(time.Second))
+		}
+
+		switch {
+		case st.Stage != "":
+			fmt.Fprintf(w, "In progress:\t%s (for %s)\n", st.Stage, time.Since(st.StageStart).Truncate(time.Second))
+		case st.Compacting:
+			fmt.Fprintf(w, "In progress:\tyes\n")
+		default:
+			fmt.Fprintf(w, "In progress:\tno\n")
+		}
+
+		if st.LastError != "" {
+			fmt.Fprintf(w, "Last error:\t%s\n", st.LastError)
+		}
+
+		return w.Flush()
+	},
+}
+
+var chainSplitstoreCompactCmd = &cli.Command{
+	Name:  "compact",
+	Usage: "Start a splitstore compaction, regardless of the compaction threshold",
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "prune-cold",
+			Usage: "after compacting, delete coldstore objects not reachable from the last <epochs> epochs (universal coldstore only)",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if err := api.ChainSplitstoreCompact(ctx, abi.ChainEpoch(cctx.Int64("prune-cold"))); err != nil {
+			return err
+		}
+
+		fmt.Println("compaction started; check progress with 'epik chain splitstore status'")
+		return nil
+	},
+}
+
+var chainSplitstoreGCCmd = &cli.Command{
+	Name:  "gc",
+	Usage: "Start a garbage collection of the hotstore",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "full",
+			Usage: "perform a full (moving) garbage collection",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if err := api.ChainSplitstoreGC(ctx, cctx.Bool("full")); err != nil {
+			return err
+		}
+
+		fmt.Println("garbage collection started; check progress with 'epik chain splitstore status'")
+		return nil
+	},
+}
+
+var chainSplitstoreWarmupCmd = &cli.Command{
+	Name:  "warmup",
+	Usage: "Start a warmup of the hotstore from the current head",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		if err := api.ChainSplitstoreWarmup(ctx); err != nil {
+			return err
+		}
+
+		fmt.Println("warmup started; check progress with 'epik chain splitstore status'")
+		return nil
+	},
+}
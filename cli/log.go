@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
@@ -13,6 +15,7 @@ var logCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		logList,
 		logSetLevel,
+		logJournal,
 	},
 }
 
@@ -100,3 +103,86 @@ var logSetLevel = &cli.Command{
 		return nil
 	},
 }
+
+var logJournal = &cli.Command{
+	Name:  "journal",
+	Usage: "Print the events recorded by the journal",
+	Description: `Print the journal events, such as mpool republishes, window PoSts and
+   sealing state changes, oldest first.
+
+   Times are RFC3339 timestamps or durations before now, eg) --since 2h`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "system",
+			Usage: "only print the events of this system, eg) mpool, wdpost, storage",
+		},
+		&cli.StringFlag{
+			Name:  "event",
+			Usage: "only print events with this name",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only print the events recorded after this time",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "only print the events recorded before this time",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "only print the last events, 0 prints them all",
+			Value: 1000,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the events as newline-delimited JSON",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		since, err := parseJournalTime(cctx.String("since"))
+		if err != nil {
+			return xerrors.Errorf("parsing since: %w", err)
+		}
+		until, err := parseJournalTime(cctx.String("until"))
+		if err != nil {
+			return xerrors.Errorf("parsing until: %w", err)
+		}
+
+		evts, err := api.JournalQuery(ctx, cctx.String("system"), cctx.String("event"), since, until, cctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		for _, evt := range evts {
+			if cctx.Bool("json") {
+				b, err := json.Marshal(evt)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(b))
+				continue
+			}
+			fmt.Printf("%s\t%s:%s\t%s\n", evt.Timestamp.Format(time.RFC3339), evt.System, evt.Event, evt.Data)
+		}
+
+		return nil
+	},
+}
+
+// parseJournalTime parses an RFC3339 timestamp, or a duration before now.
+func parseJournalTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
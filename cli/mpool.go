@@ -3,7 +3,9 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	stdbig "math/big"
+	"os"
 	"sort"
 	"strconv"
 
@@ -34,6 +36,8 @@ var mpoolCmd = &cli.Command{
 		mpoolFindCmd,
 		mpoolConfig,
 		mpoolGasPerfCmd,
+		mpoolExportCmd,
+		mpoolImportCmd,
 	},
 }
 
@@ -637,6 +641,92 @@ var mpoolConfig = &cli.Command{
 	},
 }
 
+var mpoolExportCmd = &cli.Command{
+	Name:      "export",
+	Usage:     "export pending messages with their arrival time as JSON",
+	ArgsUsage: "[file]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() > 1 {
+			return cli.ShowCommandHelp(cctx, cctx.Command.Name)
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		msgs, err := api.MpoolExport(ctx)
+		if err != nil {
+			return err
+		}
+
+		bytes, err := json.MarshalIndent(msgs, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if !cctx.Args().Present() {
+			fmt.Println(string(bytes))
+			return nil
+		}
+
+		if err := ioutil.WriteFile(cctx.Args().First(), bytes, 0644); err != nil {
+			return xerrors.Errorf("writing export: %w", err)
+		}
+
+		fmt.Printf("exported %d messages\n", len(msgs))
+		return nil
+	},
+}
+
+var mpoolImportCmd = &cli.Command{
+	Name:      "import",
+	Usage:     "import pending messages exported with 'mpool export'",
+	ArgsUsage: "<file>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return cli.ShowCommandHelp(cctx, cctx.Command.Name)
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		var bytes []byte
+		if cctx.Args().First() == "-" {
+			bytes, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			bytes, err = ioutil.ReadFile(cctx.Args().First())
+		}
+		if err != nil {
+			return xerrors.Errorf("reading export: %w", err)
+		}
+
+		var msgs []lapi.MpoolPendingMessage
+		if err := json.Unmarshal(bytes, &msgs); err != nil {
+			return xerrors.Errorf("parsing export: %w", err)
+		}
+
+		res, err := api.MpoolImport(ctx, msgs)
+		if err != nil {
+			return err
+		}
+
+		for c, e := range res.Errors {
+			fmt.Printf("%s: %s\n", c, e)
+		}
+		fmt.Printf("added %d, skipped %d, failed %d\n", res.Added, res.Skipped, len(res.Errors))
+		return nil
+	},
+}
+
 var mpoolGasPerfCmd = &cli.Command{
 	Name:  "gas-perf",
 	Usage: "Check gas performance of messages in mempool",
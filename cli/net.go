@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/urfave/cli/v2"
@@ -21,6 +22,7 @@ import (
 	atypes "github.com/EpiK-Protocol/go-epik/api"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/lib/addrutil"
+	"github.com/EpiK-Protocol/go-epik/node/repo"
 )
 
 var netCmd = &cli.Command{
@@ -53,8 +55,16 @@ var NetPeers = &cli.Command{
 			Aliases: []string{"x"},
 			Usage:   "Print extended peer information in json",
 		},
+		&cli.BoolFlag{
+			Name:  "scores",
+			Usage: "Print chain exchange scores of peers, including banned peers we are not connected to (full node only)",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
+		if cctx.Bool("scores") {
+			return netPeerScores(cctx)
+		}
+
 		api, closer, err := GetAPI(cctx)
 		if err != nil {
 			return err
@@ -112,6 +122,70 @@ var NetPeers = &cli.Command{
 	},
 }
 
+func netPeerScores(cctx *cli.Context) error {
+	// chain exchange scores are only kept by full nodes, other nodes share
+	// this command through the common api
+	if t, ok := cctx.App.Metadata["repoType"]; ok && t != repo.FullNode {
+		return xerrors.Errorf("--scores is only supported by full nodes")
+	}
+
+	api, closer, err := GetFullNodeAPI(cctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+	ctx := ReqContext(cctx)
+
+	peers, err := api.NetPeers(ctx)
+	if err != nil {
+		return err
+	}
+	connected := make(map[peer.ID]struct{}, len(peers))
+	for _, p := range peers {
+		connected[p.ID] = struct{}{}
+	}
+
+	scores, err := api.SyncPeerScores(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	if cctx.Bool("extended") {
+		enc := json.NewEncoder(os.Stdout)
+		for _, s := range scores {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Peer\tScore\tConnected\tBans\tBanned Until\tPenalties\n")
+	for _, s := range scores {
+		_, conn := connected[s.ID]
+
+		banned := "-"
+		if s.Banned {
+			banned = s.BannedUntil.Format(time.Stamp)
+		}
+
+		var penalties []string
+		for reason, n := range s.Penalties {
+			penalties = append(penalties, fmt.Sprintf("%s:%d", reason, n))
+		}
+		sort.Strings(penalties)
+
+		fmt.Fprintf(tw, "%s\t%.0f\t%t\t%d\t%s\t%s\n", s.ID, s.Score, conn, s.Bans, banned, strings.Join(penalties, " "))
+	}
+
+	return tw.Flush()
+}
+
 var netScores = &cli.Command{
 	Name:  "scores",
 	Usage: "Print peers' pubsub scores",
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"text/tabwriter"
 	"time"
 
 	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
@@ -38,6 +39,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/blockstore"
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 )
@@ -66,7 +68,9 @@ var stateCmd = &cli.Command{
 		stateSectorSizeCmd,
 		stateReadStateCmd,
 		stateListMessagesCmd,
+		stateAddressHistoryCmd,
 		stateComputeStateCmd,
+		stateSimulateCmd,
 		stateCallCmd,
 		stateGetDealSetCmd,
 		stateWaitMsgCmd,
@@ -803,6 +807,62 @@ var stateReadStateCmd = &cli.Command{
 	},
 }
 
+var stateAddressHistoryCmd = &cli.Command{
+	Name:      "address-history",
+	Usage:     "list messages and transfers touching an address, newest first",
+	ArgsUsage: "[address]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "cursor",
+			Usage: "continue from the cursor printed by a previous call",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of entries to list",
+			Value: 50,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		if cctx.Args().Len() != 1 {
+			return ShowHelp(cctx, fmt.Errorf("must pass address"))
+		}
+
+		addr, err := address.NewFromString(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		hist, err := api.StateAddressHistory(ctx, addr, cctx.String("cursor"), cctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "Height\tType\tDir\tFrom\tTo\tValue\tMethod\tExit\tMessage")
+		for _, e := range hist.Entries {
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+				e.Height, e.Type, e.Direction, e.From, e.To, types.EPK(e.Value), e.Method, e.Receipt.ExitCode, e.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if hist.Cursor != "" {
+			fmt.Printf("\nmore entries available, continue with --cursor=%s\n", hist.Cursor)
+		}
+
+		return nil
+	},
+}
+
 var stateListMessagesCmd = &cli.Command{
 	Name:  "list-messages",
 	Usage: "list messages on chain matching given criteria",
@@ -916,6 +976,84 @@ var stateListMessagesCmd = &cli.Command{
 	},
 }
 
+var stateSimulateCmd = &cli.Command{
+	Name:      "simulate",
+	Usage:     "Apply synthetic messages on top of a tipset without persisting the state",
+	ArgsUsage: "[spec.json]",
+	Description: `The spec is a JSON object with the fields Height, Messages, Implicit and
+   Upgrades, as documented for the StateSimulate API method. Implicit messages
+   can be sent by any actor, including governor, multisig and system actors.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "show-trace",
+			Usage: "print out the full execution traces",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "generate json output",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return ShowHelp(cctx, fmt.Errorf("must specify the simulation spec"))
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		ts, err := LoadTipSet(ctx, cctx, api)
+		if err != nil {
+			return err
+		}
+		var tsk types.TipSetKey
+		if ts != nil {
+			tsk = ts.Key()
+		}
+
+		data, err := ioutil.ReadFile(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("reading spec: %w", err)
+		}
+		var spec lapi.SimulateSpec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return xerrors.Errorf("unmarshaling spec: %w", err)
+		}
+
+		out, err := api.StateSimulate(ctx, &spec, tsk)
+		if err != nil {
+			return err
+		}
+
+		if cctx.Bool("json") {
+			b, err := json.MarshalIndent(out, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		fmt.Println("simulated state cid: ", out.Root)
+		for _, ir := range out.Trace {
+			fmt.Printf("%s\t%s\t%s\t%d\texit %d", ir.Msg.From, ir.Msg.To, ir.Msg.Value, ir.Msg.Method, ir.MsgRct.ExitCode)
+			if ir.Error != "" {
+				fmt.Printf("\t%s", ir.Error)
+			}
+			fmt.Println()
+			if cctx.Bool("show-trace") {
+				printInternalExecutions("\t", ir.ExecutionTrace.Subcalls)
+			}
+		}
+		fmt.Println()
+		return statediff.Write(os.Stdout, out.Diff)
+	},
+}
+
 var stateComputeStateCmd = &cli.Command{
 	Name:  "compute-state",
 	Usage: "Perform state computations",
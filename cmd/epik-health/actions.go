@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+// Alert is the state of a check passed to the actions.
+type Alert struct {
+	Check string
+	// Failing is set once the check failed Threshold times in a row
+	Failing bool
+	// Failures is the number of consecutive failures of the check
+	Failures int
+	// Error is the error of the last run of the check, if it failed
+	Error string
+	Time  time.Time
+}
+
+// Action is run by the agent when a check fails.
+type Action interface {
+	Trigger(ctx context.Context, a Alert) error
+}
+
+type actionConstructor func(cfg ActionConfig) (Action, error)
+
+type actionType struct {
+	new actionConstructor
+	// everyRun actions are passed the state of the checks after each run,
+	// including the healthy ones
+	everyRun bool
+}
+
+var actionTypes = map[string]actionType{
+	"systemd":    {new: newSystemdAction},
+	"exec":       {new: newExecAction},
+	"webhook":    {new: newWebhookAction},
+	"prometheus": {new: newPrometheusAction, everyRun: true},
+}
+
+const (
+	defaultCooldown      = 10 * time.Minute
+	defaultActionTimeout = time.Minute
+)
+
+// action wraps an Action with the trigger policy of its config.
+type action struct {
+	cfg      ActionConfig
+	act      Action
+	everyRun bool
+
+	lk sync.Mutex
+	// last is the time the action was last triggered, by check
+	last map[string]time.Time
+}
+
+func newAction(cfg ActionConfig) (*action, error) {
+	at, ok := actionTypes[cfg.Type]
+	if !ok {
+		return nil, xerrors.Errorf("unknown action type %s", cfg.Type)
+	}
+	act, err := at.new(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("action %s: %w", cfg.Name, err)
+	}
+
+	if cfg.Cooldown == 0 {
+		cfg.Cooldown = config.Duration(defaultCooldown)
+	}
+	return &action{
+		cfg:      cfg,
+		act:      act,
+		everyRun: at.everyRun,
+		last:     map[string]time.Time{},
+	}, nil
+}
+
+// handle triggers the action if the alert calls for it; recovered is set when
+// the check stopped failing with this run.
+func (a *action) handle(ctx context.Context, alert Alert, recovered bool) {
+	if !a.shouldTrigger(alert, recovered) {
+		return
+	}
+
+	if err := a.act.Trigger(ctx, alert); err != nil {
+		log.Errorw("action failed", "action", a.cfg.Name, "check", alert.Check, "error", err)
+		return
+	}
+	if !a.everyRun {
+		log.Infow("action triggered", "action", a.cfg.Name, "check", alert.Check, "failing", alert.Failing)
+	}
+}
+
+func (a *action) shouldTrigger(alert Alert, recovered bool) bool {
+	if a.everyRun {
+		return true
+	}
+
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	if !alert.Failing {
+		delete(a.last, alert.Check)
+		return recovered && a.cfg.Resolved
+	}
+
+	if last, ok := a.last[alert.Check]; ok && alert.Time.Sub(last) < time.Duration(a.cfg.Cooldown) {
+		return false
+	}
+	a.last[alert.Check] = alert.Time
+	return true
+}
+
+// systemdAction restarts a systemd unit.
+type systemdAction struct {
+	unit string
+}
+
+func newSystemdAction(cfg ActionConfig) (Action, error) {
+	if cfg.Unit == "" {
+		return nil, xerrors.Errorf("Unit must be set")
+	}
+	return &systemdAction{unit: cfg.Unit}, nil
+}
+
+func (s *systemdAction) Trigger(ctx context.Context, _ Alert) error {
+	c, err := dbus.New()
+	if err != nil {
+		return xerrors.Errorf("connecting to systemd: %w", err)
+	}
+	defer c.Close()
+
+	statusCh := make(chan string, 1)
+	if _, err := c.TryRestartUnit(s.unit, "fail", statusCh); err != nil {
+		return xerrors.Errorf("restarting %s: %w", s.unit, err)
+	}
+
+	select {
+	case result := <-statusCh:
+		if result != "done" {
+			return xerrors.Errorf("restarting %s: %s", s.unit, result)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execAction runs a command, passing the alert in the environment.
+type execAction struct {
+	command []string
+	timeout time.Duration
+}
+
+func newExecAction(cfg ActionConfig) (Action, error) {
+	if len(cfg.Command) == 0 {
+		return nil, xerrors.Errorf("Command must be set")
+	}
+	return &execAction{command: cfg.Command, timeout: timeoutOrDefault(cfg)}, nil
+}
+
+func (e *execAction) Trigger(ctx context.Context, a Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"EPIK_HEALTH_CHECK="+a.Check,
+		fmt.Sprintf("EPIK_HEALTH_FAILING=%t", a.Failing),
+		fmt.Sprintf("EPIK_HEALTH_FAILURES=%d", a.Failures),
+		"EPIK_HEALTH_ERROR="+a.Error,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return xerrors.Errorf("running %s: %w; output: %s", e.command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// webhookAction posts the alert as JSON to a URL.
+type webhookAction struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookAction(cfg ActionConfig) (Action, error) {
+	if cfg.URL == "" {
+		return nil, xerrors.Errorf("URL must be set")
+	}
+	return &webhookAction{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: timeoutOrDefault(cfg)},
+	}, nil
+}
+
+func (w *webhookAction) Trigger(ctx context.Context, a Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(b))
+	if err != nil {
+		return xerrors.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("posting alert: %w", err)
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return xerrors.Errorf("posting alert: %s", resp.Status)
+	}
+	return nil
+}
+
+// prometheusAction writes the state of the checks to a file read by the
+// textfile collector of node_exporter.
+type prometheusAction struct {
+	path string
+
+	lk     sync.Mutex
+	alerts map[string]Alert
+}
+
+func newPrometheusAction(cfg ActionConfig) (Action, error) {
+	if cfg.Path == "" {
+		return nil, xerrors.Errorf("Path must be set")
+	}
+	return &prometheusAction{path: cfg.Path, alerts: map[string]Alert{}}, nil
+}
+
+func (p *prometheusAction) Trigger(_ context.Context, a Alert) error {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	p.alerts[a.Check] = a
+
+	checks := make([]string, 0, len(p.alerts))
+	for c := range p.alerts {
+		checks = append(checks, c)
+	}
+	sort.Strings(checks)
+
+	var buf bytes.Buffer
+	metric := func(name, help string, value func(Alert) string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, c := range checks {
+			fmt.Fprintf(&buf, "%s{check=%q} %s\n", name, c, value(p.alerts[c]))
+		}
+	}
+
+	metric("epik_health_check_failing", "Whether the check failed its threshold of consecutive runs", func(a Alert) string {
+		if a.Failing {
+			return "1"
+		}
+		return "0"
+	})
+	metric("epik_health_check_failures", "Consecutive failures of the check", func(a Alert) string {
+		return fmt.Sprint(a.Failures)
+	})
+	metric("epik_health_check_last_run_timestamp_seconds", "Time of the last run of the check", func(a Alert) string {
+		return fmt.Sprint(a.Time.Unix())
+	})
+
+	// written next to the file and renamed, the collector never reads a
+	// partial file
+	tmp, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return xerrors.Errorf("creating temp file: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return xerrors.Errorf("writing alert state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return xerrors.Errorf("closing alert state: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return xerrors.Errorf("setting alert state mode: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return xerrors.Errorf("renaming alert state: %w", err)
+	}
+	return nil
+}
+
+func timeoutOrDefault(cfg ActionConfig) time.Duration {
+	if cfg.Timeout == 0 {
+		return defaultActionTimeout
+	}
+	return time.Duration(cfg.Timeout)
+}
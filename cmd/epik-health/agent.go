@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-jsonrpc"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	lcli "github.com/EpiK-Protocol/go-epik/cli"
+)
+
+var agentCmd = &cli.Command{
+	Name:  "agent",
+	Usage: "Run the checks of a config file against the daemon and the miner, triggering actions on failures",
+	Description: `The agent runs each check of the config file at its interval. Once a check
+failed Threshold times in a row its actions are triggered, then again after
+the cooldown of each action while it keeps failing.
+
+Check types: head, sync-lag, peers, mpool, wdpost, transfers, disk
+Action types: systemd, exec, webhook, prometheus
+
+The miner API is only used when a check needs it (disk, wdpost without a Miner
+address, transfers with Node = "miner").`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "config",
+			Usage:    "path to the agent config (TOML)",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "api-timeout",
+			Value: int(build.BlockDelaySecs),
+			Usage: "timeout between API retries",
+		},
+		&cli.IntFlag{
+			Name:  "api-retries",
+			Value: 8,
+			Usage: "number of API retry attempts",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		cfg, err := loadAgentConfig(c.String("config"))
+		if err != nil {
+			return err
+		}
+		if len(cfg.Checks) == 0 {
+			return xerrors.Errorf("no checks configured")
+		}
+
+		apiRetries := c.Int("api-retries")
+		apiTimeout := time.Duration(c.Int("api-timeout")) * time.Second
+
+		var n nodes
+		full, closer, err := getFullNodeAPI(c, apiRetries, apiTimeout)
+		if err != nil {
+			return xerrors.Errorf("connecting to the daemon: %w", err)
+		}
+		defer closer()
+		n.full = full
+
+		for _, cc := range cfg.Checks {
+			if !needsMiner(cc) {
+				continue
+			}
+
+			miner, mcloser, err := getStorageMinerAPI(c, apiRetries, apiTimeout)
+			if err != nil {
+				return xerrors.Errorf("connecting to the miner: %w", err)
+			}
+			defer mcloser()
+			n.miner = miner
+			break
+		}
+
+		actions := map[string]*action{}
+		for _, ac := range cfg.Actions {
+			a, err := newAction(ac)
+			if err != nil {
+				return err
+			}
+			actions[ac.Name] = a
+		}
+
+		var runners []*checkRunner
+		for _, cc := range cfg.Checks {
+			check, err := newCheck(cc, &n)
+			if err != nil {
+				return err
+			}
+
+			r := &checkRunner{cfg: cc, check: check}
+			for _, name := range cc.Actions {
+				r.actions = append(r.actions, actions[name])
+			}
+			runners = append(runners, r)
+		}
+
+		ctx, cancel := context.WithCancel(lcli.ReqContext(c))
+		defer cancel()
+
+		sCh := make(chan os.Signal, 1)
+		signal.Notify(sCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			select {
+			case <-sCh:
+				log.Info("Stopping health agent")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		log.Infow("Running health checks", "checks", len(runners), "actions", len(actions))
+
+		var wg sync.WaitGroup
+		for _, r := range runners {
+			wg.Add(1)
+			go func(r *checkRunner) {
+				defer wg.Done()
+				r.run(ctx)
+			}(r)
+		}
+		wg.Wait()
+
+		return nil
+	},
+}
+
+// checkRunner runs a check and triggers its actions.
+type checkRunner struct {
+	cfg     CheckConfig
+	check   Check
+	actions []*action
+
+	// failures is the number of consecutive failures
+	failures int
+}
+
+func (r *checkRunner) run(ctx context.Context) {
+	interval := time.Duration(r.cfg.Interval)
+	ticker := build.Clock.Ticker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *checkRunner) runOnce(ctx context.Context) {
+	// API errors, including the ones of a node being restarted, count as
+	// failures of the check
+	cctx, cancel := context.WithTimeout(ctx, time.Duration(r.cfg.Interval))
+	err := r.check.Run(cctx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	wasFailing := r.failures >= r.cfg.Threshold
+
+	alert := Alert{
+		Check: r.cfg.Name,
+		Time:  build.Clock.Now(),
+	}
+	if err != nil {
+		r.failures++
+		alert.Error = err.Error()
+		log.Warnw("check failed", "check", r.cfg.Name, "failures", r.failures, "error", err)
+	} else {
+		r.failures = 0
+	}
+	alert.Failures = r.failures
+	alert.Failing = r.failures >= r.cfg.Threshold
+
+	recovered := wasFailing && !alert.Failing
+	if recovered {
+		log.Infow("check recovered", "check", r.cfg.Name)
+	}
+
+	for _, a := range r.actions {
+		a.handle(ctx, alert, recovered)
+	}
+}
+
+/*
+ * A thin wrapper around epik cli GetStorageMinerAPI
+ * Adds retry logic
+ */
+func getStorageMinerAPI(ctx *cli.Context, r int, t time.Duration) (api.StorageMiner, jsonrpc.ClientCloser, error) {
+	for i := 0; i < r; i++ {
+		api, closer, err := lcli.GetStorageMinerAPI(ctx)
+		if err != nil && i == (r-1) {
+			return nil, nil, err
+		}
+		if err != nil {
+			log.Warnf("Miner API connection failed. Retrying in %.0fs", t.Seconds())
+			time.Sleep(t)
+			continue
+		}
+		return api, closer, err
+	}
+	return nil, nil, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+type fakeCheck struct {
+	errs []error
+}
+
+func (f *fakeCheck) Run(context.Context) error {
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+type recordAction struct {
+	alerts []Alert
+}
+
+func (r *recordAction) Trigger(_ context.Context, a Alert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestCheckRunnerActions(t *testing.T) {
+	assert := assert.New(t)
+
+	mClock := clock.NewMock()
+	prev := build.Clock
+	build.Clock = mClock
+	defer func() { build.Clock = prev }()
+
+	fail := errors.New("fail")
+	check := &fakeCheck{errs: []error{fail, fail, fail, fail, nil, nil}}
+
+	rec := &recordAction{}
+	a := &action{
+		cfg: ActionConfig{
+			Name:     "rec",
+			Cooldown: config.Duration(10 * time.Minute),
+			Resolved: true,
+		},
+		act:  rec,
+		last: map[string]time.Time{},
+	}
+
+	r := &checkRunner{
+		cfg: CheckConfig{
+			Name:      "test",
+			Interval:  config.Duration(time.Minute),
+			Threshold: 2,
+		},
+		check:   check,
+		actions: []*action{a},
+	}
+
+	ctx := context.Background()
+
+	// below the threshold
+	r.runOnce(ctx)
+	assert.Empty(rec.alerts)
+
+	r.runOnce(ctx)
+	assert.Len(rec.alerts, 1)
+	assert.True(rec.alerts[0].Failing)
+	assert.Equal(2, rec.alerts[0].Failures)
+
+	// cooling down
+	mClock.Add(time.Minute)
+	r.runOnce(ctx)
+	assert.Len(rec.alerts, 1)
+
+	mClock.Add(10 * time.Minute)
+	r.runOnce(ctx)
+	assert.Len(rec.alerts, 2)
+	assert.Equal(4, rec.alerts[1].Failures)
+
+	r.runOnce(ctx)
+	assert.Len(rec.alerts, 3)
+	assert.False(rec.alerts[2].Failing)
+
+	// recovered once
+	r.runOnce(ctx)
+	assert.Len(rec.alerts, 3)
+}
+
+func TestPrometheusAction(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "epik-health-")
+	req.NoError(err)
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "health.prom")
+	act, err := newPrometheusAction(ActionConfig{Path: path})
+	req.NoError(err)
+
+	ctx := context.Background()
+	req.NoError(act.Trigger(ctx, Alert{Check: "sync-lag", Failures: 1, Time: time.Unix(100, 0)}))
+	req.NoError(act.Trigger(ctx, Alert{Check: "disk", Failing: true, Failures: 3, Time: time.Unix(200, 0)}))
+
+	b, err := ioutil.ReadFile(path)
+	req.NoError(err)
+	req.Contains(string(b), "epik_health_check_failing{check=\"disk\"} 1\nepik_health_check_failing{check=\"sync-lag\"} 0\n")
+	req.Contains(string(b), "epik_health_check_failures{check=\"disk\"} 3\n")
+	req.Contains(string(b), "epik_health_check_last_run_timestamp_seconds{check=\"sync-lag\"} 100\n")
+}
+
+func TestLoadAgentConfig(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "epik-health-")
+	req.NoError(err)
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "agent.toml")
+	req.NoError(ioutil.WriteFile(path, []byte(`
+Threshold = 5
+
+[[Checks]]
+Type = "peers"
+MinPeers = 10
+Interval = "1m"
+Actions = ["restart"]
+
+[[Checks]]
+Name = "miner-transfers"
+Type = "transfers"
+Node = "miner"
+Threshold = 2
+
+[[Actions]]
+Name = "restart"
+Type = "systemd"
+Unit = "epik-daemon.service"
+`), 0644))
+
+	cfg, err := loadAgentConfig(path)
+	req.NoError(err)
+	req.Len(cfg.Checks, 2)
+
+	req.Equal("peers", cfg.Checks[0].Name)
+	req.Equal(config.Duration(time.Minute), cfg.Checks[0].Interval)
+	req.Equal(5, cfg.Checks[0].Threshold)
+	req.True(needsMiner(cfg.Checks[1]))
+	req.Equal(2, cfg.Checks[1].Threshold)
+	req.Equal(cfg.Interval, cfg.Checks[1].Interval)
+
+	req.NoError(ioutil.WriteFile(path, []byte(`
+[[Checks]]
+Type = "peers"
+Actions = ["missing"]
+`), 0644))
+	_, err = loadAgentConfig(path)
+	req.Error(err)
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/dline"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// Check is a health check run periodically by the agent.
+type Check interface {
+	// Run returns an error describing the failure when the check fails
+	Run(ctx context.Context) error
+}
+
+// nodes are the APIs of the nodes watched by the agent, miner is nil when the
+// agent isn't watching a miner.
+type nodes struct {
+	full  api.FullNode
+	miner api.StorageMiner
+}
+
+type checkConstructor func(cfg CheckConfig, n *nodes) (Check, error)
+
+var checkTypes = map[string]checkConstructor{
+	"head":      newHeadCheck,
+	"sync-lag":  newSyncLagCheck,
+	"peers":     newPeersCheck,
+	"mpool":     newMpoolCheck,
+	"wdpost":    newWdPostCheck,
+	"transfers": newTransfersCheck,
+	"disk":      newDiskCheck,
+}
+
+// minerChecks are the check types watching the miner.
+var minerChecks = map[string]bool{
+	"disk": true,
+}
+
+// needsMiner returns whether the check needs the miner API.
+func needsMiner(cfg CheckConfig) bool {
+	return minerChecks[cfg.Type] || (cfg.Type == "transfers" && cfg.Node == "miner") ||
+		(cfg.Type == "wdpost" && cfg.Miner == "")
+}
+
+func newCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	cc, ok := checkTypes[cfg.Type]
+	if !ok {
+		return nil, xerrors.Errorf("unknown check type %s", cfg.Type)
+	}
+	if needsMiner(cfg) && n.miner == nil {
+		return nil, xerrors.Errorf("check %s needs the miner API", cfg.Name)
+	}
+	return cc(cfg, n)
+}
+
+// headCheck fails when the chain head didn't change since the previous run.
+type headCheck struct {
+	full api.FullNode
+	last types.TipSetKey
+}
+
+func newHeadCheck(_ CheckConfig, n *nodes) (Check, error) {
+	return &headCheck{full: n.full}, nil
+}
+
+func (c *headCheck) Run(ctx context.Context) error {
+	head, err := c.full.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	last := c.last
+	c.last = head.Key()
+	if last == head.Key() {
+		return xerrors.Errorf("chain head %d hasn't changed", head.Height())
+	}
+	return nil
+}
+
+// syncLagCheck fails when the head is more than MaxLag epochs behind the epoch
+// expected from the wall clock.
+type syncLagCheck struct {
+	full    api.FullNode
+	maxLag  abi.ChainEpoch
+	genesis uint64
+}
+
+func newSyncLagCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	if cfg.MaxLag == 0 {
+		cfg.MaxLag = 5
+	}
+	return &syncLagCheck{full: n.full, maxLag: abi.ChainEpoch(cfg.MaxLag)}, nil
+}
+
+func (c *syncLagCheck) Run(ctx context.Context) error {
+	if c.genesis == 0 {
+		gen, err := c.full.ChainGetGenesis(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting genesis: %w", err)
+		}
+		c.genesis = gen.MinTimestamp()
+	}
+
+	head, err := c.full.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	expected := abi.ChainEpoch((uint64(build.Clock.Now().Unix()) - c.genesis) / build.BlockDelaySecs)
+	if lag := expected - head.Height(); lag > c.maxLag {
+		return xerrors.Errorf("head %d is %d epochs behind the expected epoch %d", head.Height(), lag, expected)
+	}
+	return nil
+}
+
+// peersCheck fails when the daemon has fewer than MinPeers peers.
+type peersCheck struct {
+	full     api.FullNode
+	minPeers int
+}
+
+func newPeersCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	if cfg.MinPeers == 0 {
+		cfg.MinPeers = 1
+	}
+	return &peersCheck{full: n.full, minPeers: cfg.MinPeers}, nil
+}
+
+func (c *peersCheck) Run(ctx context.Context) error {
+	peers, err := c.full.NetPeers(ctx)
+	if err != nil {
+		return xerrors.Errorf("listing peers: %w", err)
+	}
+	if len(peers) < c.minPeers {
+		return xerrors.Errorf("%d peers connected, expected at least %d", len(peers), c.minPeers)
+	}
+	return nil
+}
+
+// mpoolCheck fails when the message pool holds more than MaxMessages pending
+// messages.
+type mpoolCheck struct {
+	full        api.FullNode
+	maxMessages int
+}
+
+func newMpoolCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	if cfg.MaxMessages <= 0 {
+		return nil, xerrors.Errorf("check %s: MaxMessages must be set", cfg.Name)
+	}
+	return &mpoolCheck{full: n.full, maxMessages: cfg.MaxMessages}, nil
+}
+
+func (c *mpoolCheck) Run(ctx context.Context) error {
+	pending, err := c.full.MpoolPending(ctx, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("getting pending messages: %w", err)
+	}
+	if len(pending) > c.maxMessages {
+		return xerrors.Errorf("%d pending messages, expected at most %d", len(pending), c.maxMessages)
+	}
+	return nil
+}
+
+// wdPostCheck fails when the miner didn't prove all the partitions with
+// active sectors of a deadline before it closed. Each deadline is checked once,
+// after it closes, against the state of its last epoch; every deadline that
+// closed since the last poll is checked, and the result is reported until the
+// next deadline is checked.
+type wdPostCheck struct {
+	full  api.FullNode
+	miner address.Address
+
+	// next is the first deadline that wasn't checked yet
+	next *dline.Info
+	// last is the result of the last checked deadlines
+	last error
+}
+
+func newWdPostCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	c := &wdPostCheck{full: n.full}
+	if cfg.Miner != "" {
+		maddr, err := address.NewFromString(cfg.Miner)
+		if err != nil {
+			return nil, xerrors.Errorf("check %s: parsing miner address: %w", cfg.Name, err)
+		}
+		c.miner = maddr
+	} else {
+		maddr, err := n.miner.ActorAddress(context.TODO())
+		if err != nil {
+			return nil, xerrors.Errorf("check %s: getting miner address: %w", cfg.Name, err)
+		}
+		c.miner = maddr
+	}
+	return c, nil
+}
+
+func (c *wdPostCheck) Run(ctx context.Context) error {
+	head, err := c.full.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	if c.next == nil {
+		di, err := c.full.StateMinerProvingDeadline(ctx, c.miner, head.Key())
+		if err != nil {
+			return xerrors.Errorf("getting proving deadline: %w", err)
+		}
+		c.next = di
+	}
+	if head.Height() < c.next.Close {
+		return c.last
+	}
+
+	// after a long outage, only the last proving period is checked
+	for c.next.Close+c.next.WPoStProvingPeriod <= head.Height() {
+		c.next = dline.NewInfo(c.next.PeriodStart+c.next.WPoStProvingPeriod, c.next.Index, c.next.CurrentEpoch+c.next.WPoStProvingPeriod,
+			c.next.WPoStPeriodDeadlines, c.next.WPoStProvingPeriod, c.next.WPoStChallengeWindow, c.next.WPoStChallengeLookback, c.next.FaultDeclarationCutoff)
+	}
+
+	var failed []string
+	for c.next.Close <= head.Height() {
+		missed, err := c.checkDeadline(ctx, c.next, head.Key())
+		if err != nil {
+			return err
+		}
+		if len(missed) > 0 {
+			failed = append(failed, fmt.Sprintf("deadline %d (open at %d) for partitions %s", c.next.Index, c.next.Open, strings.Join(missed, ",")))
+		}
+		c.next = nextDeadline(c.next)
+	}
+
+	c.last = nil
+	if len(failed) > 0 {
+		c.last = xerrors.Errorf("miner %s missed %s", c.miner, strings.Join(failed, "; "))
+	}
+	return c.last
+}
+
+// checkDeadline returns the partitions with active sectors the miner didn't
+// prove in the closed deadline di
+func (c *wdPostCheck) checkDeadline(ctx context.Context, di *dline.Info, head types.TipSetKey) ([]string, error) {
+	// the submissions are cleared when the deadline closes
+	ts, err := c.full.ChainGetTipSetByHeight(ctx, di.Close-1, head)
+	if err != nil {
+		return nil, xerrors.Errorf("getting tipset at the end of deadline %d: %w", di.Index, err)
+	}
+
+	dls, err := c.full.StateMinerDeadlines(ctx, c.miner, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting deadlines: %w", err)
+	}
+	if di.Index >= uint64(len(dls)) {
+		return nil, xerrors.Errorf("deadline %d out of range", di.Index)
+	}
+
+	parts, err := c.full.StateMinerPartitions(ctx, c.miner, di.Index, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting partitions of deadline %d: %w", di.Index, err)
+	}
+
+	var missed []string
+	for i, part := range parts {
+		active, err := part.ActiveSectors.Count()
+		if err != nil {
+			return nil, xerrors.Errorf("counting active sectors: %w", err)
+		}
+		if active == 0 {
+			continue
+		}
+
+		proven, err := dls[di.Index].PostSubmissions.IsSet(uint64(i))
+		if err != nil {
+			return nil, xerrors.Errorf("checking post submissions: %w", err)
+		}
+		if !proven {
+			missed = append(missed, fmt.Sprint(i))
+		}
+	}
+	return missed, nil
+}
+
+// nextDeadline returns the deadline following di, in the next proving period
+// after the last deadline
+func nextDeadline(di *dline.Info) *dline.Info {
+	start, idx := di.PeriodStart, di.Index+1
+	if idx >= di.WPoStPeriodDeadlines {
+		start, idx = start+di.WPoStProvingPeriod, 0
+	}
+	return dline.NewInfo(start, idx, di.Close, di.WPoStPeriodDeadlines, di.WPoStProvingPeriod, di.WPoStChallengeWindow, di.WPoStChallengeLookback, di.FaultDeclarationCutoff)
+}
+
+// transfersCheck fails when an ongoing data transfer, such as the retrievals
+// of the data stored by the miner, made no progress for StallTimeout.
+type transfersCheck struct {
+	list    func(context.Context) ([]api.DataTransferChannel, error)
+	timeout time.Duration
+
+	progress map[string]transferProgress
+}
+
+type transferProgress struct {
+	transferred uint64
+	since       time.Time
+}
+
+func newTransfersCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	c := &transfersCheck{
+		timeout:  time.Duration(cfg.StallTimeout),
+		progress: map[string]transferProgress{},
+	}
+	if c.timeout == 0 {
+		c.timeout = 30 * time.Minute
+	}
+
+	switch cfg.Node {
+	case "", "daemon":
+		c.list = n.full.ClientListDataTransfers
+	case "miner":
+		c.list = n.miner.MarketListDataTransfers
+	default:
+		return nil, xerrors.Errorf("check %s: unknown node %s", cfg.Name, cfg.Node)
+	}
+	return c, nil
+}
+
+func (c *transfersCheck) Run(ctx context.Context) error {
+	channels, err := c.list(ctx)
+	if err != nil {
+		return xerrors.Errorf("listing data transfers: %w", err)
+	}
+
+	now := build.Clock.Now()
+	seen := map[string]struct{}{}
+	var stalled []string
+	for _, ch := range channels {
+		if ch.Status != datatransfer.Ongoing {
+			continue
+		}
+
+		id := fmt.Sprintf("%s/%d", ch.OtherPeer, ch.TransferID)
+		seen[id] = struct{}{}
+
+		p, ok := c.progress[id]
+		if !ok || p.transferred != ch.Transferred {
+			c.progress[id] = transferProgress{transferred: ch.Transferred, since: now}
+			continue
+		}
+		if now.Sub(p.since) > c.timeout {
+			stalled = append(stalled, id)
+		}
+	}
+
+	for id := range c.progress {
+		if _, ok := seen[id]; !ok {
+			delete(c.progress, id)
+		}
+	}
+
+	if len(stalled) > 0 {
+		return xerrors.Errorf("%d data transfers stalled for more than %s: %s", len(stalled), c.timeout, strings.Join(stalled, ", "))
+	}
+	return nil
+}
+
+// diskCheck fails when a local storage path of the miner has less than
+// MinAvailable bytes or MinAvailablePercent of its capacity available.
+type diskCheck struct {
+	miner      api.StorageMiner
+	minBytes   int64
+	minPercent float64
+}
+
+func newDiskCheck(cfg CheckConfig, n *nodes) (Check, error) {
+	c := &diskCheck{miner: n.miner, minPercent: cfg.MinAvailablePercent}
+	if cfg.MinAvailable != "" {
+		b, err := units.RAMInBytes(cfg.MinAvailable)
+		if err != nil {
+			return nil, xerrors.Errorf("check %s: parsing MinAvailable: %w", cfg.Name, err)
+		}
+		c.minBytes = b
+	}
+	if c.minBytes == 0 && c.minPercent == 0 {
+		return nil, xerrors.Errorf("check %s: MinAvailable or MinAvailablePercent must be set", cfg.Name)
+	}
+	return c, nil
+}
+
+func (c *diskCheck) Run(ctx context.Context) error {
+	local, err := c.miner.StorageLocal(ctx)
+	if err != nil {
+		return xerrors.Errorf("listing storage paths: %w", err)
+	}
+
+	var low []string
+	for id, path := range local {
+		st, err := c.miner.StorageStat(ctx, id)
+		if err != nil {
+			return xerrors.Errorf("getting stats of %s: %w", path, err)
+		}
+
+		if st.Available < c.minBytes {
+			low = append(low, fmt.Sprintf("%s (%s available)", path, units.BytesSize(float64(st.Available))))
+			continue
+		}
+		if st.Capacity > 0 && float64(st.Available)*100/float64(st.Capacity) < c.minPercent {
+			low = append(low, fmt.Sprintf("%s (%.1f%% available)", path, float64(st.Available)*100/float64(st.Capacity)))
+		}
+	}
+
+	if len(low) > 0 {
+		return xerrors.Errorf("low disk space: %s", strings.Join(low, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/dline"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+// wdPostNode serves a miner with one partition of active sectors in every
+// deadline, proven in the deadlines set in proven
+type wdPostNode struct {
+	api.FullNode
+
+	height abi.ChainEpoch
+	proven map[uint64]bool
+}
+
+func (n *wdPostNode) tipset(h abi.ChainEpoch) *types.TipSet {
+	b := mock.MkBlock(nil, 1, 1)
+	b.Height = h
+	ts, err := types.NewTipSet([]*types.BlockHeader{b})
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+func (n *wdPostNode) ChainHead(context.Context) (*types.TipSet, error) {
+	return n.tipset(n.height), nil
+}
+
+func (n *wdPostNode) ChainGetTipSetByHeight(_ context.Context, h abi.ChainEpoch, _ types.TipSetKey) (*types.TipSet, error) {
+	return n.tipset(h), nil
+}
+
+func (n *wdPostNode) StateMinerProvingDeadline(context.Context, address.Address, types.TipSetKey) (*dline.Info, error) {
+	idx := uint64(n.height/miner.WPoStChallengeWindow) % miner.WPoStPeriodDeadlines
+	return dline.NewInfo(0, idx, n.height, miner.WPoStPeriodDeadlines, miner.WPoStProvingPeriod, miner.WPoStChallengeWindow, miner.WPoStChallengeLookback, miner.FaultDeclarationCutoff), nil
+}
+
+func (n *wdPostNode) StateMinerDeadlines(context.Context, address.Address, types.TipSetKey) ([]api.Deadline, error) {
+	dls := make([]api.Deadline, miner.WPoStPeriodDeadlines)
+	for i := range dls {
+		dls[i].PostSubmissions = bitfield.New()
+		if n.proven[uint64(i)] {
+			dls[i].PostSubmissions = bitfield.NewFromSet([]uint64{0})
+		}
+	}
+	return dls, nil
+}
+
+func (n *wdPostNode) StateMinerPartitions(context.Context, address.Address, uint64, types.TipSetKey) ([]api.Partition, error) {
+	return []api.Partition{{ActiveSectors: bitfield.NewFromSet([]uint64{1})}}, nil
+}
+
+func TestWdPostCheckKeepsFailure(t *testing.T) {
+	req := require.New(t)
+
+	node := &wdPostNode{height: 1, proven: map[uint64]bool{1: true}}
+	maddr, err := address.NewIDAddress(1000)
+	req.NoError(err)
+
+	rec := &recordAction{}
+	r := &checkRunner{
+		cfg: CheckConfig{
+			Name:      "wdpost",
+			Interval:  config.Duration(time.Minute),
+			Threshold: 1,
+		},
+		check: &wdPostCheck{full: node, miner: maddr},
+		actions: []*action{{
+			cfg:  ActionConfig{Name: "rec", Resolved: true},
+			act:  rec,
+			last: map[string]time.Time{},
+		}},
+	}
+
+	ctx := context.Background()
+	w := miner.WPoStChallengeWindow
+
+	// nothing to check before the first deadline closes
+	r.runOnce(ctx)
+	req.Zero(r.failures)
+
+	// deadline 0 closed without a proof
+	node.height = w + 1
+	r.runOnce(ctx)
+	req.Equal(1, r.failures)
+	req.Len(rec.alerts, 1)
+	req.True(rec.alerts[0].Failing)
+
+	// the miss is reported until the next deadline is checked
+	node.height = w + 2
+	r.runOnce(ctx)
+	req.Equal(2, r.failures)
+
+	node.height = 2*w - 1
+	r.runOnce(ctx)
+	req.Equal(3, r.failures)
+
+	// deadline 1 was proven
+	node.height = 2*w + 1
+	r.runOnce(ctx)
+	req.Zero(r.failures)
+	req.False(rec.alerts[len(rec.alerts)-1].Failing)
+}
+
+func TestWdPostCheckWalksDeadlines(t *testing.T) {
+	req := require.New(t)
+
+	node := &wdPostNode{height: 1, proven: map[uint64]bool{0: true, 2: true, 3: true}}
+	maddr, err := address.NewIDAddress(1000)
+	req.NoError(err)
+
+	c := &wdPostCheck{full: node, miner: maddr}
+	ctx := context.Background()
+	w := miner.WPoStChallengeWindow
+
+	req.NoError(c.Run(ctx))
+
+	// deadlines 0 to 2 closed between two polls, only 1 wasn't proven
+	node.height = 3*w + 1
+	err = c.Run(ctx)
+	req.Error(err)
+	req.Contains(err.Error(), "deadline 1 ")
+	req.NotContains(err.Error(), "deadline 0 ")
+	req.NotContains(err.Error(), "deadline 2 ")
+
+	// deadline 3 was proven
+	node.height = 4*w + 1
+	req.NoError(c.Run(ctx))
+}
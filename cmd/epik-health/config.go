@@ -0,0 +1,163 @@
+package main
+
+import (
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+// AgentConfig is the configuration of the health agent, loaded from a TOML
+// file:
+//
+//	[[Checks]]
+//	Type = "sync-lag"
+//	MaxLag = 5
+//	Threshold = 3
+//	Actions = ["restart-daemon", "alerts"]
+//
+//	[[Actions]]
+//	Name = "restart-daemon"
+//	Type = "systemd"
+//	Unit = "epik-daemon.service"
+//
+//	[[Actions]]
+//	Name = "alerts"
+//	Type = "prometheus"
+//	Path = "/var/lib/node_exporter/epik-health.prom"
+type AgentConfig struct {
+	// Interval is the default time between two runs of a check
+	Interval config.Duration
+	// Threshold is the default number of consecutive failures of a check
+	// before its actions are triggered
+	Threshold int
+
+	Checks  []CheckConfig
+	Actions []ActionConfig
+}
+
+// CheckConfig configures a check. The parameters only apply to the check
+// types documented next to them.
+type CheckConfig struct {
+	// Name identifies the check in the logs and alerts, defaults to the type
+	Name string
+	// Type is one of head, sync-lag, peers, mpool, wdpost, transfers, disk
+	Type string
+	// Node is the node checked by the transfers check, daemon or miner
+	Node string
+
+	// Interval overrides the default interval
+	Interval config.Duration
+	// Threshold overrides the default threshold
+	Threshold int
+	// Actions are the names of the actions triggered when the check fails
+	Actions []string
+
+	// MaxLag is the number of epochs the head can be behind the wall-clock
+	// epoch (sync-lag)
+	MaxLag int64
+	// MinPeers is the minimum number of connected peers (peers)
+	MinPeers int
+	// MaxMessages is the maximum number of pending messages (mpool)
+	MaxMessages int
+	// Miner is the miner to check, defaults to the miner the agent is
+	// connected to (wdpost)
+	Miner string
+	// StallTimeout is the time an ongoing data transfer can go without
+	// progress (transfers)
+	StallTimeout config.Duration
+	// MinAvailable is the minimum space available on each storage path, as a
+	// size like "100GiB" (disk)
+	MinAvailable string
+	// MinAvailablePercent is the minimum percentage of the capacity of each
+	// storage path available (disk)
+	MinAvailablePercent float64
+}
+
+// ActionConfig configures an action. The parameters only apply to the action
+// types documented next to them.
+type ActionConfig struct {
+	// Name is the name checks refer to the action with
+	Name string
+	// Type is one of systemd, exec, webhook, prometheus
+	Type string
+
+	// Cooldown is the minimum time between two triggers of the action while
+	// a check keeps failing; it doesn't apply to prometheus
+	Cooldown config.Duration
+	// Resolved also triggers the action when a failing check recovers
+	// (exec, webhook)
+	Resolved bool
+
+	// Unit is the systemd unit restarted (systemd)
+	Unit string
+	// Command is the command run, with the alert in the EPIK_HEALTH_*
+	// environment variables (exec)
+	Command []string
+	// URL receives the alert as a JSON POST (webhook)
+	URL string
+	// Timeout bounds the command or request (exec, webhook)
+	Timeout config.Duration
+	// Path is the file the state of the checks is written to, in the format of
+	// the node_exporter textfile collector (prometheus)
+	Path string
+}
+
+func defaultAgentConfig() *AgentConfig {
+	return &AgentConfig{
+		Interval:  config.Duration(time.Duration(build.BlockDelaySecs) * time.Second),
+		Threshold: 3,
+	}
+}
+
+// loadAgentConfig reads the agent configuration, checking that the checks
+// refer to declared actions.
+func loadAgentConfig(path string) (*AgentConfig, error) {
+	cfg := defaultAgentConfig()
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, xerrors.Errorf("decoding agent config: %w", err)
+	}
+
+	actions := map[string]struct{}{}
+	for i, a := range cfg.Actions {
+		if a.Name == "" {
+			return nil, xerrors.Errorf("action %d has no name", i)
+		}
+		if _, dup := actions[a.Name]; dup {
+			return nil, xerrors.Errorf("duplicate action %s", a.Name)
+		}
+		actions[a.Name] = struct{}{}
+	}
+
+	checks := map[string]struct{}{}
+	for i := range cfg.Checks {
+		c := &cfg.Checks[i]
+		if c.Type == "" {
+			return nil, xerrors.Errorf("check %d has no type", i)
+		}
+		if c.Name == "" {
+			c.Name = c.Type
+		}
+		if _, dup := checks[c.Name]; dup {
+			return nil, xerrors.Errorf("duplicate check %s, set distinct names", c.Name)
+		}
+		checks[c.Name] = struct{}{}
+
+		if c.Interval == 0 {
+			c.Interval = cfg.Interval
+		}
+		if c.Threshold == 0 {
+			c.Threshold = cfg.Threshold
+		}
+		for _, a := range c.Actions {
+			if _, ok := actions[a]; !ok {
+				return nil, xerrors.Errorf("check %s refers to unknown action %s", c.Name, a)
+			}
+		}
+	}
+
+	return cfg, nil
+}
@@ -31,11 +31,12 @@ func main() {
 
 	local := []*cli.Command{
 		watchHeadCmd,
+		agentCmd,
 	}
 
 	app := &cli.App{
 		Name:     "epik-health",
-		Usage:    "Tools for monitoring epik daemon health",
+		Usage:    "Tools for monitoring epik daemon and miner health",
 		Version:  build.UserVersion(),
 		Commands: local,
 		Flags: []cli.Flag{
@@ -44,6 +45,11 @@ func main() {
 				EnvVars: []string{"EPIK_PATH"},
 				Value:   "~/.epik", // TODO: Consider XDG_DATA_HOME
 			},
+			&cli.StringFlag{
+				Name:    "miner-repo",
+				EnvVars: []string{"EPIK_MINER_PATH", "EPIK_STORAGE_PATH"},
+				Value:   "~/.epikminer", // TODO: Consider XDG_DATA_HOME
+			},
 		},
 	}
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/journal"
 	"github.com/EpiK-Protocol/go-epik/node/modules/testing"
 	"github.com/google/uuid"
+	"github.com/ipfs/go-cid"
 	"github.com/mitchellh/go-homedir"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
@@ -23,6 +25,7 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	gov2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/govern"
 
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/gen"
@@ -38,6 +41,9 @@ var genesisCmd = &cli.Command{
 		genesisNewCmd,
 		genesisAddMinerCmd,
 		genesisAddMsigsCmd,
+		genesisAddExpertCmd,
+		genesisAddVoteCmd,
+		genesisAddGovernorCmd,
 		genesisCarCmd,
 	},
 }
@@ -369,3 +375,256 @@ var genesisCarCmd = &cli.Command{
 		return err
 	},
 }
+
+var genesisAddExpertCmd = &cli.Command{
+	Name:        "add-expert",
+	Description: "add genesis expert, with an account for its owner",
+	ArgsUsage:   "[genesis.json] [owner]",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "data",
+			Usage: "data imported into the expert, as `ROOT_CID:PIECE_CID:PADDED_SIZE`",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.New("seed genesis add-expert [genesis.json] [owner]")
+		}
+
+		owner, err := address.NewFromString(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing owner address: %w", err)
+		}
+		if owner.Protocol() != address.BLS && owner.Protocol() != address.SECP256K1 {
+			return xerrors.Errorf("expert owner must be a bls or secp256k1 address")
+		}
+
+		expert := genesis.Expert{Owner: owner}
+		for _, d := range cctx.StringSlice("data") {
+			parts := strings.Split(d, ":")
+			if len(parts) != 3 {
+				return xerrors.Errorf("invalid data %q, expected ROOT_CID:PIECE_CID:PADDED_SIZE", d)
+			}
+			root, err := cid.Decode(parts[0])
+			if err != nil {
+				return xerrors.Errorf("parsing root cid: %w", err)
+			}
+			piece, err := cid.Decode(parts[1])
+			if err != nil {
+				return xerrors.Errorf("parsing piece cid: %w", err)
+			}
+			size, err := strconv.ParseUint(parts[2], 10, 64)
+			if err != nil {
+				return xerrors.Errorf("parsing piece size: %w", err)
+			}
+			if err := abi.PaddedPieceSize(size).Validate(); err != nil {
+				return xerrors.Errorf("invalid piece size: %w", err)
+			}
+			expert.Datas = append(expert.Datas, genesis.ExpertData{
+				RootID:    root,
+				PieceID:   piece,
+				PieceSize: abi.PaddedPieceSize(size),
+			})
+		}
+
+		return editTemplate(cctx.Args().First(), func(template *genesis.Template) error {
+			for _, e := range template.Experts {
+				if e.Owner == owner {
+					return xerrors.Errorf("%s already owns a genesis expert", owner)
+				}
+			}
+
+			template.Experts = append(template.Experts, expert)
+			return addTemplateAccount(template, owner, big.Zero())
+		})
+	},
+}
+
+var genesisAddVoteCmd = &cli.Command{
+	Name:        "add-vote",
+	Description: "add genesis vote, with an account for the voter holding the voted amount",
+	ArgsUsage:   "[genesis.json] [voter] [expert] [amount (EPK)]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 4 {
+			return xerrors.New("seed genesis add-vote [genesis.json] [voter] [expert] [amount]")
+		}
+
+		voter, err := address.NewFromString(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing voter address: %w", err)
+		}
+		expert, err := address.NewFromString(cctx.Args().Get(2))
+		if err != nil {
+			return xerrors.Errorf("parsing expert address: %w", err)
+		}
+		amount, err := types.ParseEPK(cctx.Args().Get(3))
+		if err != nil {
+			return xerrors.Errorf("parsing amount: %w", err)
+		}
+		if !abi.TokenAmount(amount).GreaterThan(big.Zero()) {
+			return xerrors.Errorf("vote amount must be positive")
+		}
+
+		return editTemplate(cctx.Args().First(), func(template *genesis.Template) error {
+			if expert.Protocol() != address.ID {
+				found := false
+				for _, e := range template.Experts {
+					found = found || e.Owner == expert
+				}
+				if !found {
+					return xerrors.Errorf("%s doesn't own a genesis expert", expert)
+				}
+			}
+
+			template.Votes = append(template.Votes, genesis.Vote{
+				Voter:  voter,
+				Expert: expert,
+				Amount: abi.TokenAmount(amount),
+			})
+			return addTemplateAccount(template, voter, abi.TokenAmount(amount))
+		})
+	},
+}
+
+var genesisAddGovernorCmd = &cli.Command{
+	Name:        "add-governor",
+	Description: "add genesis governor, granted all authorities unless some are given",
+	ArgsUsage:   "[genesis.json] [address]",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "authority",
+			Usage: "authority granted, as `ACTOR[:METHOD,...]`, all governed methods of the actor when no method is given",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.New("seed genesis add-governor [genesis.json] [address]")
+		}
+
+		addr, err := address.NewFromString(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing governor address: %w", err)
+		}
+
+		governor := genesis.Governor{Address: addr}
+		for _, a := range cctx.StringSlice("authority") {
+			auth, err := parseAuthority(a)
+			if err != nil {
+				return err
+			}
+			governor.Authorities = append(governor.Authorities, auth)
+		}
+		governor.All = len(governor.Authorities) == 0
+
+		return editTemplate(cctx.Args().First(), func(template *genesis.Template) error {
+			for _, g := range template.Governors {
+				if g.Address == addr {
+					return xerrors.Errorf("%s is already a genesis governor", addr)
+				}
+			}
+
+			template.Governors = append(template.Governors, governor)
+			if addr.Protocol() == address.ID {
+				return nil
+			}
+			return addTemplateAccount(template, addr, big.Zero())
+		})
+	},
+}
+
+// parseAuthority parses an ACTOR[:METHOD,...] authority, ACTOR being the
+// name of a governed actor
+func parseAuthority(s string) (gov2.Authority, error) {
+	parts := strings.SplitN(s, ":", 2)
+
+	var auth gov2.Authority
+	for code := range gov2.GovernedActors {
+		if builtin.ActorNameByCode(code) == parts[0] {
+			auth.ActorCodeID = code
+			break
+		}
+	}
+	if !auth.ActorCodeID.Defined() {
+		var names []string
+		for code := range gov2.GovernedActors {
+			names = append(names, builtin.ActorNameByCode(code))
+		}
+		sort.Strings(names)
+		return auth, xerrors.Errorf("unknown governed actor %q, expected one of: %s", parts[0], strings.Join(names, ", "))
+	}
+
+	if len(parts) == 1 {
+		auth.All = true
+		return auth, nil
+	}
+
+	for _, m := range strings.Split(parts[1], ",") {
+		method, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			return auth, xerrors.Errorf("parsing method number %q: %w", m, err)
+		}
+		if _, ok := gov2.GovernedActors[auth.ActorCodeID][abi.MethodNum(method)]; !ok {
+			return auth, xerrors.Errorf("method %d of %s is not governed", method, parts[0])
+		}
+		auth.Methods = append(auth.Methods, abi.MethodNum(method))
+	}
+
+	return auth, nil
+}
+
+// editTemplate reads the genesis template in genf, applies edit to it and
+// writes it back
+func editTemplate(genf string, edit func(*genesis.Template) error) error {
+	genf, err := homedir.Expand(genf)
+	if err != nil {
+		return err
+	}
+
+	var template genesis.Template
+	genb, err := ioutil.ReadFile(genf)
+	if err != nil {
+		return xerrors.Errorf("read genesis template: %w", err)
+	}
+
+	if err := json.Unmarshal(genb, &template); err != nil {
+		return xerrors.Errorf("unmarshal genesis template: %w", err)
+	}
+
+	if err := edit(&template); err != nil {
+		return err
+	}
+
+	genb, err = json.MarshalIndent(&template, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(genf, genb, 0644)
+}
+
+// addTemplateAccount adds an account owned by owner to the template, or adds
+// balance to the existing one
+func addTemplateAccount(template *genesis.Template, owner address.Address, balance abi.TokenAmount) error {
+	for i, act := range template.Accounts {
+		if act.Type != genesis.TAccount {
+			continue
+		}
+
+		var meta genesis.AccountMeta
+		if err := json.Unmarshal(act.Meta, &meta); err != nil {
+			return xerrors.Errorf("unmarshal account meta: %w", err)
+		}
+		if meta.Owner == owner {
+			template.Accounts[i].Balance = big.Add(act.Balance, balance)
+			return nil
+		}
+	}
+
+	log.Infof("Giving %s an account with %s", owner, types.EPK(balance))
+	template.Accounts = append(template.Accounts, genesis.Actor{
+		Type:    genesis.TAccount,
+		Balance: balance,
+		Meta:    (&genesis.AccountMeta{Owner: owner}).ActorMeta(),
+	})
+	return nil
+}
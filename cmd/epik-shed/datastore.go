@@ -229,7 +229,12 @@ var datastoreBackupListCmd = &cli.Command{
 		defer f.Close() // nolint:errcheck
 
 		printKv := kvPrinter(cctx.Bool("top-level"), cctx.String("get-enc"))
-		_, err = backupds.ReadBackup(f, func(key datastore.Key, value []byte, _ bool) error {
+		_, err = backupds.ReadBackup(f, func(key datastore.Key, value []byte, log bool) error {
+			if log && value == nil {
+				// deleted by a log entry
+				fmt.Printf("%s (deleted)\n", key)
+				return nil
+			}
 			return printKv(key.String(), value)
 		})
 		if err != nil {
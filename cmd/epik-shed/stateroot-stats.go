@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"sort"
 
 	"github.com/multiformats/go-multihash"
@@ -10,7 +11,11 @@ import (
 	"github.com/ipfs/go-cid"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	lcli "github.com/EpiK-Protocol/go-epik/cli"
 )
@@ -20,6 +25,7 @@ var staterootCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		staterootDiffsCmd,
 		staterootStatCmd,
+		staterootStatediffCmd,
 	},
 }
 
@@ -216,3 +222,74 @@ var staterootStatCmd = &cli.Command{
 		return nil
 	},
 }
+
+var staterootStatediffCmd = &cli.Command{
+	Name:      "statediff",
+	Usage:     "print the typed differences between two state roots",
+	ArgsUsage: "[left-root] [right-root]",
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "epoch",
+			Usage: "epoch at which epoch dependent values are computed (defaults to the chain head)",
+			Value: -1,
+		},
+		&cli.StringSliceFlag{
+			Name:  "actor",
+			Usage: "only compare the given actors, instead of walking the whole state trees",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return lcli.ShowHelp(cctx, fmt.Errorf("must pass left and right state roots"))
+		}
+
+		left, err := cid.Decode(cctx.Args().Get(0))
+		if err != nil {
+			return fmt.Errorf("parsing left root: %w", err)
+		}
+		right, err := cid.Decode(cctx.Args().Get(1))
+		if err != nil {
+			return fmt.Errorf("parsing right root: %w", err)
+		}
+
+		api, closer, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		epoch := abi.ChainEpoch(cctx.Int64("epoch"))
+		if epoch < 0 {
+			head, err := api.ChainHead(ctx)
+			if err != nil {
+				return err
+			}
+			epoch = head.Height()
+		}
+
+		var addrs []address.Address
+		for _, s := range cctx.StringSlice("actor") {
+			a, err := address.NewFromString(s)
+			if err != nil {
+				return fmt.Errorf("parsing actor address %s: %w", s, err)
+			}
+			addrs = append(addrs, a)
+		}
+
+		bs := blockstore.NewAPIBlockstore(api)
+
+		var diffs []*statediff.ActorDiff
+		if len(addrs) > 0 {
+			diffs, err = statediff.DiffActors(ctx, bs, left, right, epoch, addrs...)
+		} else {
+			diffs, err = statediff.Diff(ctx, bs, left, right, epoch)
+		}
+		if err != nil {
+			return err
+		}
+
+		return statediff.Write(os.Stdout, diffs)
+	},
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	expert2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expert"
+	expertfund2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expertfund"
+	vote2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/vote"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expert"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/expertfund"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/retrieval"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// view gives the agents read access to the state they act upon.
+type view struct {
+	store adt.Store
+	tree  *state.StateTree
+	epoch abi.ChainEpoch
+}
+
+// act queues the scripted actions due by epoch, then the randomised ones.
+func (s *Simulation) act(ctx context.Context, tree *state.StateTree, epoch abi.ChainEpoch) error {
+	v := &view{
+		store: s.cg.ChainStore().ActorStore(ctx),
+		tree:  tree,
+		epoch: epoch,
+	}
+
+	if err := s.resolveExperts(v); err != nil {
+		return xerrors.Errorf("resolving experts: %w", err)
+	}
+
+	// null rounds skip epochs, scripted actions of skipped epochs run late
+	for e := s.acted + 1; e <= epoch; e++ {
+		for _, a := range s.script[e] {
+			if err := s.perform(v, s.agents[a.Agent], a.Kind, a.Target, a.Amount); err != nil {
+				return xerrors.Errorf("scripted %s of %s at %d: %w", a.Kind, a.Agent, a.Epoch, err)
+			}
+		}
+	}
+	s.acted = epoch
+
+	for _, kind := range actionKinds() {
+		rate := s.scn.Rates[kind]
+		if rate <= 0 {
+			continue
+		}
+
+		for _, a := range s.role(actionRoles[kind]) {
+			if s.rnd.Float64() >= rate {
+				continue
+			}
+			if err := s.perform(v, a, kind, "", ""); err != nil {
+				return xerrors.Errorf("%s of %s: %w", kind, a.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulation) role(role string) []*agent {
+	switch role {
+	case RoleExpert:
+		return s.experts
+	case RoleVoter:
+		return s.voters
+	case RoleClient:
+		return s.clients
+	case RoleMiner:
+		return s.miners
+	}
+	return nil
+}
+
+// perform queues the message of an action. Actions which make no sense in the
+// current state, such as claiming without rewards, are skipped; the others are
+// sent even if they may fail, failures are part of the outcomes.
+func (s *Simulation) perform(v *view, a *agent, kind, target, amount string) error {
+	var amt abi.TokenAmount
+	if amount != "" {
+		epk, err := types.ParseEPK(amount)
+		if err != nil {
+			return err
+		}
+		amt = abi.TokenAmount(epk)
+	}
+
+	bal, err := s.balance(v, a)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case ActApplyExpert:
+		if a.expert != address.Undef {
+			return nil
+		}
+		return s.send(kind, a, builtin2.ExpertFundActorAddr, expert.ExpertApplyCost, builtin2.MethodsExpertFunds.ApplyForExpert,
+			&expertfund2.ApplyForExpertParams{Owner: a.key})
+
+	case ActNominate:
+		// nominations come from the genesis expert
+		if a != s.experts[0] || a.expert == address.Undef {
+			return nil
+		}
+		nominee, err := s.pickExpert(v, target, true)
+		if err != nil || nominee == nil {
+			return err
+		}
+		return s.send(kind, a, a.expert, big.Zero(), builtin2.MethodsExpert.Nominate, &nominee.expert)
+
+	case ActImportData:
+		if a.expert == address.Undef {
+			return nil
+		}
+		commP := make([]byte, 32)
+		_, _ = s.rnd.Read(commP)
+		piece, err := commcid.PieceCommitmentV1ToCID(commP)
+		if err != nil {
+			return xerrors.Errorf("making piece cid: %w", err)
+		}
+		return s.send(kind, a, a.expert, big.Zero(), builtin2.MethodsExpert.ImportData, &expert2.BatchImportDataParams{
+			Datas: []expert2.ImportDataParams{{
+				RootID:    piece,
+				PieceID:   piece,
+				PieceSize: abi.PaddedPieceSize(2048),
+			}},
+		})
+
+	case ActClaim:
+		if a.expert == address.Undef {
+			return nil
+		}
+		if amt.Nil() {
+			st, err := s.expertFund(v)
+			if err != nil {
+				return err
+			}
+			rwd, err := st.Reward(v.epoch, a.expert)
+			if err != nil {
+				return xerrors.Errorf("loading rewards of %s: %w", a.expert, err)
+			}
+			if !rwd.UnlockedFunds.GreaterThan(big.Zero()) {
+				return nil
+			}
+			amt = rwd.UnlockedFunds
+		}
+		return s.send(kind, a, builtin2.ExpertFundActorAddr, big.Zero(), builtin2.MethodsExpertFunds.Claim,
+			&expertfund2.ClaimFundParams{Expert: a.expert, Amount: amt})
+
+	case ActVote:
+		candidate, err := s.pickExpert(v, target, false)
+		if err != nil || candidate == nil {
+			return err
+		}
+		if amt.Nil() {
+			amt = s.randomAmount(bal)
+		}
+		return s.send(kind, a, builtin2.VoteFundActorAddr, amt, builtin2.MethodsVote.Vote, &candidate.expert)
+
+	case ActRescind:
+		info, err := s.voterInfo(v, a)
+		if err != nil || info == nil {
+			return err
+		}
+		candidate, votes := s.pickCandidate(info, target)
+		if candidate == address.Undef {
+			return nil
+		}
+		if amt.Nil() {
+			amt = s.randomAmount(votes)
+		}
+		return s.send(kind, a, builtin2.VoteFundActorAddr, big.Zero(), builtin2.MethodsVote.Rescind,
+			&vote2.RescindParams{Candidate: candidate, Votes: amt})
+
+	case ActWithdrawVotes:
+		info, err := s.voterInfo(v, a)
+		if err != nil || info == nil {
+			return err
+		}
+		if info.UnlockedVotes.IsZero() && info.WithdrawableRewards.IsZero() {
+			return nil
+		}
+		return s.send(kind, a, builtin2.VoteFundActorAddr, big.Zero(), builtin2.MethodsVote.Withdraw, nil)
+
+	case ActPledge:
+		beneficiary := a.key
+		if target != "" {
+			beneficiary = s.agents[target].key
+		}
+		if amt.Nil() {
+			amt = s.randomAmount(bal)
+		}
+		return s.send(kind, a, builtin2.RetrievalFundActorAddr, amt, builtin2.MethodsRetrieval.Pledge,
+			&retrieval.PledgeParams{Address: beneficiary})
+
+	case ActApplyWithdraw:
+		st, err := s.retrievalFund(v)
+		if err != nil {
+			return err
+		}
+		ida, err := v.tree.LookupID(a.key)
+		if err != nil {
+			return xerrors.Errorf("resolving %s: %w", a.name, err)
+		}
+		pledges, err := st.PledgesInfo(ida)
+		if err != nil {
+			return xerrors.Errorf("loading pledges of %s: %w", a.name, err)
+		}
+		beneficiary, pledged := s.pickPledge(v, pledges, target)
+		if beneficiary == address.Undef {
+			return nil
+		}
+		if amt.Nil() {
+			amt = s.randomAmount(pledged)
+		}
+		return s.send(kind, a, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.ApplyForWithdraw,
+			&retrieval.WithdrawBalanceParams{Target: beneficiary, Amount: amt})
+
+	case ActWithdraw:
+		st, err := s.retrievalFund(v)
+		if err != nil {
+			return err
+		}
+		ida, err := v.tree.LookupID(a.key)
+		if err != nil {
+			return xerrors.Errorf("resolving %s: %w", a.name, err)
+		}
+		var locked retrieval.LockedState
+		found, err := st.LockedState(ida, &locked)
+		if err != nil {
+			return xerrors.Errorf("loading locked pledge of %s: %w", a.name, err)
+		}
+		period, err := st.LockedPeriod()
+		if err != nil {
+			return err
+		}
+		if !found || locked.Amount.IsZero() || v.epoch <= locked.ApplyEpoch+period {
+			return nil
+		}
+		if amt.Nil() {
+			amt = locked.Amount
+		}
+		return s.send(kind, a, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.WithdrawBalance, &amt)
+
+	case ActAddPledge:
+		if amt.Nil() {
+			amt = s.randomAmount(bal)
+		}
+		return s.send(kind, a, a.miner, amt, builtin2.MethodsMiner.AddPledge, nil)
+	}
+
+	return xerrors.Errorf("unknown action kind %s", kind)
+}
+
+// resolveExperts finds the expert actors of the expert agents which applied.
+func (s *Simulation) resolveExperts(v *view) error {
+	owners := make(map[address.Address]*agent)
+	for _, a := range s.experts {
+		if a.expert != address.Undef {
+			continue
+		}
+		ida, err := v.tree.LookupID(a.key)
+		if err != nil {
+			return xerrors.Errorf("resolving %s: %w", a.name, err)
+		}
+		// the owner may be recorded as either address
+		owners[ida] = a
+		owners[a.key] = a
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	st, err := s.expertFund(v)
+	if err != nil {
+		return err
+	}
+	all, err := st.ListAllExperts()
+	if err != nil {
+		return xerrors.Errorf("listing experts: %w", err)
+	}
+
+	for _, addr := range all {
+		est, err := s.expert(v, addr)
+		if err != nil {
+			return err
+		}
+		info, err := est.Info()
+		if err != nil {
+			return xerrors.Errorf("loading info of expert %s: %w", addr, err)
+		}
+		if a, ok := owners[info.Owner]; ok {
+			a.expert = addr
+			log.Infof("%s owns expert %s", a.name, addr)
+		}
+	}
+	return nil
+}
+
+// pickExpert returns the target expert agent, or a random expert agent which
+// applied, only among the registered ones if registered is set. It returns nil
+// when there is no such expert.
+func (s *Simulation) pickExpert(v *view, target string, registered bool) (*agent, error) {
+	if target != "" {
+		a := s.agents[target]
+		if a.expert == address.Undef {
+			return nil, nil
+		}
+		return a, nil
+	}
+
+	var candidates []*agent
+	for _, a := range s.experts {
+		if a.expert == address.Undef {
+			continue
+		}
+		if registered {
+			est, err := s.expert(v, a.expert)
+			if err != nil {
+				return nil, err
+			}
+			info, err := est.Info()
+			if err != nil {
+				return nil, xerrors.Errorf("loading info of expert %s: %w", a.expert, err)
+			}
+			if info.Status != expert2.ExpertStateRegistered {
+				continue
+			}
+		}
+		candidates = append(candidates, a)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	return candidates[s.rnd.Intn(len(candidates))], nil
+}
+
+// pickCandidate returns the target expert, or a random expert the voter voted
+// for, and the votes of the voter for it.
+func (s *Simulation) pickCandidate(info *vote.VoterInfo, target string) (address.Address, abi.TokenAmount) {
+	if target != "" {
+		a := s.agents[target]
+		votes, ok := info.Candidates[a.expert.String()]
+		if !ok {
+			return address.Undef, big.Zero()
+		}
+		return a.expert, votes
+	}
+
+	// sorted for the runs to be reproducible
+	keys := make([]string, 0, len(info.Candidates))
+	for k, votes := range info.Candidates {
+		if votes.GreaterThan(big.Zero()) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return address.Undef, big.Zero()
+	}
+	sort.Strings(keys)
+
+	k := keys[s.rnd.Intn(len(keys))]
+	addr, err := address.NewFromString(k)
+	if err != nil {
+		return address.Undef, big.Zero()
+	}
+	return addr, info.Candidates[k]
+}
+
+// pickPledge returns the target beneficiary, or a random beneficiary of the
+// pledges, and the pledged amount.
+func (s *Simulation) pickPledge(v *view, pledges map[address.Address]abi.TokenAmount, target string) (address.Address, abi.TokenAmount) {
+	var addrs []address.Address
+	for addr, amt := range pledges {
+		if amt.GreaterThan(big.Zero()) {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].String() < addrs[j].String()
+	})
+
+	if target != "" {
+		ida, err := v.tree.LookupID(s.agents[target].key)
+		if err != nil {
+			return address.Undef, big.Zero()
+		}
+		for _, addr := range addrs {
+			if addr == ida || addr == s.agents[target].key {
+				return addr, pledges[addr]
+			}
+		}
+		return address.Undef, big.Zero()
+	}
+
+	if len(addrs) == 0 {
+		return address.Undef, big.Zero()
+	}
+	addr := addrs[s.rnd.Intn(len(addrs))]
+	return addr, pledges[addr]
+}
+
+// randomAmount returns between 1% and 10% of max.
+func (s *Simulation) randomAmount(max abi.TokenAmount) abi.TokenAmount {
+	pct := int64(1 + s.rnd.Intn(10))
+	return big.Div(big.Mul(max, big.NewInt(pct)), big.NewInt(100))
+}
+
+func (s *Simulation) balance(v *view, a *agent) (abi.TokenAmount, error) {
+	act, err := v.tree.GetActor(a.key)
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("loading actor of %s: %w", a.name, err)
+	}
+	return act.Balance, nil
+}
+
+func (s *Simulation) voterInfo(v *view, a *agent) (*vote.VoterInfo, error) {
+	act, err := v.tree.GetActor(builtin2.VoteFundActorAddr)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vote fund actor: %w", err)
+	}
+	st, err := vote.Load(v.store, act)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vote fund state: %w", err)
+	}
+
+	ida, err := v.tree.LookupID(a.key)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving %s: %w", a.name, err)
+	}
+	info, err := st.VoterInfo(ida, v.epoch, act.Balance)
+	if err != nil {
+		// voters which never voted have no info
+		return nil, nil
+	}
+	return info, nil
+}
+
+func (s *Simulation) expertFund(v *view) (expertfund.State, error) {
+	act, err := v.tree.GetActor(builtin2.ExpertFundActorAddr)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert fund actor: %w", err)
+	}
+	return expertfund.Load(v.store, act)
+}
+
+func (s *Simulation) retrievalFund(v *view) (retrieval.State, error) {
+	act, err := v.tree.GetActor(builtin2.RetrievalFundActorAddr)
+	if err != nil {
+		return nil, xerrors.Errorf("loading retrieval fund actor: %w", err)
+	}
+	return retrieval.Load(v.store, act)
+}
+
+func (s *Simulation) expert(v *view, addr address.Address) (expert.State, error) {
+	act, err := v.tree.GetActor(addr)
+	if err != nil {
+		return nil, xerrors.Errorf("loading expert actor %s: %w", addr, err)
+	}
+	return expert.Load(v.store, act)
+}
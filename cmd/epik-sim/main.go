@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/policy"
+	lcli "github.com/EpiK-Protocol/go-epik/cli"
+	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/bls"
+	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/secp"
+)
+
+var log = logging.Logger("epik-sim")
+
+func init() {
+	// the generated chain uses 2KiB presealed sectors
+	policy.SetSupportedProofTypes(abi.RegisteredSealProof_StackedDrg2KiBV1)
+	policy.SetConsensusMinerMinPower(abi.NewStoragePower(2048))
+}
+
+func main() {
+	logging.SetLogLevel("*", "WARN")
+	logging.SetLogLevel("epik-sim", "INFO")
+
+	app := &cli.App{
+		Name:  "epik-sim",
+		Usage: "Simulate the EpiK economics on an in-process chain",
+		Description: "epik-sim mines a local chain with mock proofs, on which experts, voters, retrieval " +
+			"clients and miners act according to a scenario, and writes a time series of the rewards, " +
+			"vote tallies, expert fund rewards and circulating supply. Retrieval spending depends on " +
+			"off-chain retrieval deals and is not simulated.",
+		Version:  build.UserVersion(),
+		Commands: []*cli.Command{runCmd, scenarioCmd},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Errorf("%+v", err)
+		os.Exit(1)
+	}
+}
+
+var runCmd = &cli.Command{
+	Name:      "run",
+	Usage:     "run a simulation",
+	ArgsUsage: "[scenario.json]",
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "epochs",
+			Usage: "number of epochs to simulate, overrides the scenario",
+		},
+		&cli.Int64Flag{
+			Name:  "seed",
+			Usage: "seed of the randomised behaviours, overrides the scenario",
+		},
+		&cli.IntFlag{
+			Name:  "experts",
+			Usage: "number of experts, overrides the scenario",
+		},
+		&cli.IntFlag{
+			Name:  "voters",
+			Usage: "number of voters, overrides the scenario",
+		},
+		&cli.IntFlag{
+			Name:  "clients",
+			Usage: "number of retrieval clients, overrides the scenario",
+		},
+		&cli.Int64Flag{
+			Name:  "sample-every",
+			Usage: "number of epochs between samples",
+			Value: 10,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: csv or json",
+			Value: "csv",
+		},
+		&cli.StringFlag{
+			Name:      "out",
+			Aliases:   []string{"o"},
+			Usage:     "file to write the time series to, stdout when empty",
+			TakesFile: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := lcli.ReqContext(cctx)
+
+		scn := DefaultScenario()
+		if cctx.Args().Present() {
+			var err error
+			if scn, err = LoadScenario(cctx.Args().First()); err != nil {
+				return err
+			}
+		}
+		if cctx.IsSet("epochs") {
+			scn.Epochs = abi.ChainEpoch(cctx.Int64("epochs"))
+		}
+		if cctx.IsSet("seed") {
+			scn.Seed = cctx.Int64("seed")
+		}
+		if cctx.IsSet("experts") {
+			scn.Experts = cctx.Int("experts")
+		}
+		if cctx.IsSet("voters") {
+			scn.Voters = cctx.Int("voters")
+		}
+		if cctx.IsSet("clients") {
+			scn.Clients = cctx.Int("clients")
+		}
+
+		out := os.Stdout
+		if p := cctx.String("out"); p != "" {
+			f, err := os.Create(p)
+			if err != nil {
+				return xerrors.Errorf("creating output file: %w", err)
+			}
+			defer f.Close() //nolint:errcheck
+			out = f
+		}
+
+		w, err := NewSampleWriter(out, cctx.String("format"))
+		if err != nil {
+			return err
+		}
+
+		sim, err := NewSimulation(ctx, scn)
+		if err != nil {
+			return err
+		}
+
+		var logged abi.ChainEpoch
+		err = sim.Run(ctx, abi.ChainEpoch(cctx.Int64("sample-every")), func(s *Sample) error {
+			if err := w.Write(s); err != nil {
+				return xerrors.Errorf("writing sample: %w", err)
+			}
+			if s.Epoch-logged >= 1000 {
+				log.Infof("simulated %d/%d epochs", s.Epoch, scn.Epochs)
+				logged = s.Epoch
+			}
+			return nil
+		})
+		if ferr := w.Flush(); ferr != nil && err == nil {
+			err = xerrors.Errorf("flushing samples: %w", ferr)
+		}
+		if err != nil {
+			return err
+		}
+
+		kinds := make([]string, 0, len(sim.Outcomes))
+		for kind := range sim.Outcomes {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			o := sim.Outcomes[kind]
+			log.Infof("%s: %d messages, %d failed", kind, o.Sent, o.Failed)
+		}
+		return nil
+	},
+}
+
+var scenarioCmd = &cli.Command{
+	Name:  "scenario",
+	Usage: "print the default scenario, as a starting point for scenario files",
+	Action: func(cctx *cli.Context) error {
+		return printScenario(cctx.App.Writer, DefaultScenario())
+	},
+}
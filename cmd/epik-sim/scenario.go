@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/genesis"
+)
+
+// Agent roles. Miners are the genesis miners of the generated chain, the other
+// agents are accounts funded at genesis. expert/0 owns the genesis expert.
+const (
+	RoleExpert = "expert"
+	RoleVoter  = "voter"
+	RoleClient = "client"
+	RoleMiner  = "miner"
+)
+
+// Action kinds, each performed by agents of a single role.
+const (
+	ActApplyExpert   = "apply-expert"
+	ActNominate      = "nominate"
+	ActImportData    = "import-data"
+	ActClaim         = "claim"
+	ActVote          = "vote"
+	ActRescind       = "rescind"
+	ActWithdrawVotes = "withdraw-votes"
+	ActPledge        = "pledge"
+	ActApplyWithdraw = "apply-withdraw"
+	ActWithdraw      = "withdraw"
+	ActAddPledge     = "add-pledge"
+)
+
+var actionRoles = map[string]string{
+	ActApplyExpert:   RoleExpert,
+	ActNominate:      RoleExpert,
+	ActImportData:    RoleExpert,
+	ActClaim:         RoleExpert,
+	ActVote:          RoleVoter,
+	ActRescind:       RoleVoter,
+	ActWithdrawVotes: RoleVoter,
+	ActPledge:        RoleClient,
+	ActApplyWithdraw: RoleClient,
+	ActWithdraw:      RoleClient,
+	ActAddPledge:     RoleMiner,
+}
+
+// Scenario describes the agents of a simulation and how they behave.
+type Scenario struct {
+	// Epochs is the number of epochs to simulate
+	Epochs abi.ChainEpoch
+	// Seed seeds the randomised behaviours; a scenario and a seed always
+	// produce the same time series
+	Seed int64
+
+	Experts int
+	Voters  int
+	Clients int
+
+	// AgentBalance is the genesis balance of each expert, voter and client
+	AgentBalance string
+
+	// GovParams overrides governed parameters at genesis, to compare their
+	// effect on the same behaviours
+	GovParams *genesis.GovParams `json:",omitempty"`
+
+	// Rates are the probabilities, per agent and epoch, of the randomised
+	// behaviours; zero disables a behaviour
+	Rates map[string]float64
+
+	// Actions are scripted actions, performed on top of the randomised ones
+	Actions []Action `json:",omitempty"`
+}
+
+// Action is a scripted action.
+type Action struct {
+	Epoch abi.ChainEpoch
+	// Agent is the name of the acting agent, such as "voter/3"
+	Agent string
+	Kind  string
+	// Target is the agent voted for, rescinded from, nominated or pledged
+	// for, picked at random when empty
+	Target string `json:",omitempty"`
+	// Amount is picked at random when empty
+	Amount string `json:",omitempty"`
+}
+
+// DefaultScenario returns a small scenario exercising all the behaviours over
+// a simulated day.
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Epochs:       builtin2.EpochsInDay,
+		Seed:         1,
+		Experts:      4,
+		Voters:       8,
+		Clients:      4,
+		AgentBalance: "10000",
+		Rates: map[string]float64{
+			ActApplyExpert:   0.05,
+			ActNominate:      0.2,
+			ActImportData:    0.02,
+			ActClaim:         0.01,
+			ActVote:          0.01,
+			ActRescind:       0.002,
+			ActWithdrawVotes: 0.005,
+			ActPledge:        0.01,
+			ActApplyWithdraw: 0.002,
+			ActWithdraw:      0.01,
+			ActAddPledge:     0.001,
+		},
+	}
+}
+
+// LoadScenario reads a JSON scenario. Fields missing from the file keep the
+// values of DefaultScenario.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("reading scenario: %w", err)
+	}
+
+	scn := DefaultScenario()
+	if err := json.Unmarshal(b, scn); err != nil {
+		return nil, xerrors.Errorf("unmarshaling scenario: %w", err)
+	}
+	return scn, nil
+}
+
+func printScenario(w io.Writer, scn *Scenario) error {
+	b, err := json.MarshalIndent(scn, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// Validate checks the scenario, including the agents and kinds of the scripted
+// actions. Miners aren't checked, their number depends on the generated chain.
+func (s *Scenario) Validate() error {
+	if s.Epochs <= 0 {
+		return xerrors.Errorf("epochs must be positive")
+	}
+	if s.Experts < 1 {
+		return xerrors.Errorf("at least one expert is required, expert/0 owns the genesis expert")
+	}
+	if s.Voters < 0 || s.Clients < 0 {
+		return xerrors.Errorf("negative number of agents")
+	}
+	if _, err := types.ParseEPK(s.AgentBalance); err != nil {
+		return xerrors.Errorf("parsing agent balance: %w", err)
+	}
+
+	for kind, rate := range s.Rates {
+		if _, ok := actionRoles[kind]; !ok {
+			return xerrors.Errorf("unknown action kind %q, expected one of %s", kind, strings.Join(actionKinds(), ", "))
+		}
+		if rate < 0 || rate > 1 {
+			return xerrors.Errorf("rate of %s must be between 0 and 1", kind)
+		}
+	}
+
+	for i, a := range s.Actions {
+		role, ok := actionRoles[a.Kind]
+		if !ok {
+			return xerrors.Errorf("action %d: unknown kind %q, expected one of %s", i, a.Kind, strings.Join(actionKinds(), ", "))
+		}
+		if a.Epoch <= 0 || a.Epoch > s.Epochs {
+			return xerrors.Errorf("action %d: epoch %d out of the simulated range", i, a.Epoch)
+		}
+		if err := s.checkAgent(a.Agent, role); err != nil {
+			return xerrors.Errorf("action %d: %w", i, err)
+		}
+		if a.Target != "" {
+			targetRole := RoleExpert
+			if a.Kind == ActPledge {
+				targetRole = RoleClient
+			}
+			if err := s.checkAgent(a.Target, targetRole); err != nil {
+				return xerrors.Errorf("action %d: target: %w", i, err)
+			}
+		}
+		if a.Amount != "" {
+			if _, err := types.ParseEPK(a.Amount); err != nil {
+				return xerrors.Errorf("action %d: parsing amount: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Scenario) checkAgent(name, role string) error {
+	r, idx, err := parseAgentName(name)
+	if err != nil {
+		return err
+	}
+	if r != role {
+		return xerrors.Errorf("agent %s must be a %s", name, role)
+	}
+
+	var count int
+	switch role {
+	case RoleExpert:
+		count = s.Experts
+	case RoleVoter:
+		count = s.Voters
+	case RoleClient:
+		count = s.Clients
+	case RoleMiner:
+		return nil
+	}
+	if idx >= count {
+		return xerrors.Errorf("agent %s doesn't exist, the scenario has %d %ss", name, count, role)
+	}
+	return nil
+}
+
+func parseAgentName(name string) (string, int, error) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return "", 0, xerrors.Errorf("invalid agent name %q, expected ROLE/INDEX", name)
+	}
+	idx, err := strconv.Atoi(parts[1])
+	if err != nil || idx < 0 {
+		return "", 0, xerrors.Errorf("invalid agent index in %q", name)
+	}
+	switch parts[0] {
+	case RoleExpert, RoleVoter, RoleClient, RoleMiner:
+	default:
+		return "", 0, xerrors.Errorf("invalid agent role in %q", name)
+	}
+	return parts[0], idx, nil
+}
+
+func actionKinds() []string {
+	out := make([]string, 0, len(actionRoles))
+	for k := range actionRoles {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadScenario(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	err := ioutil.WriteFile(path, []byte(`{
+		"Voters": 2,
+		"Rates": {"vote": 0.5},
+		"Actions": [{"Epoch": 10, "Agent": "voter/1", "Kind": "vote", "Target": "expert/0", "Amount": "100"}]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scn, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scn.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	def := DefaultScenario()
+	if scn.Voters != 2 || scn.Experts != def.Experts {
+		t.Errorf("expected 2 voters and the default experts, got %d and %d", scn.Voters, scn.Experts)
+	}
+	if scn.Rates[ActVote] != 0.5 || scn.Rates[ActPledge] != def.Rates[ActPledge] {
+		t.Errorf("expected the vote rate to be overridden and the others kept, got %v", scn.Rates)
+	}
+}
+
+func TestValidateScenario(t *testing.T) {
+	for name, a := range map[string]Action{
+		"unknown kind":   {Epoch: 1, Agent: "voter/0", Kind: "dance"},
+		"wrong role":     {Epoch: 1, Agent: "client/0", Kind: ActVote},
+		"missing agent":  {Epoch: 1, Agent: "voter/8", Kind: ActVote},
+		"bad name":       {Epoch: 1, Agent: "voter", Kind: ActVote},
+		"out of range":   {Epoch: 0, Agent: "voter/0", Kind: ActVote},
+		"wrong target":   {Epoch: 1, Agent: "voter/0", Kind: ActVote, Target: "client/0"},
+		"invalid amount": {Epoch: 1, Agent: "voter/0", Kind: ActVote, Amount: "lots"},
+	} {
+		scn := DefaultScenario()
+		scn.Actions = []Action{a}
+		if err := scn.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	scn := DefaultScenario()
+	scn.Rates["dance"] = 0.1
+	if err := scn.Validate(); err == nil {
+		t.Error("expected an error for an unknown rate")
+	}
+}
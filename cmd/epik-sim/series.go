@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	expert2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expert"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/reward"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// Sample is a point of the time series produced by a simulation.
+type Sample struct {
+	Epoch abi.ChainEpoch
+
+	// cumulated rewards, as reported by StateTotalMinedDetail
+	TotalExpertReward       abi.TokenAmount
+	TotalVoteReward         abi.TokenAmount
+	TotalKnowledgeReward    abi.TokenAmount
+	TotalRetrievalReward    abi.TokenAmount
+	TotalStoragePowerReward abi.TokenAmount
+
+	// vote tally
+	TotalVotes      abi.TokenAmount
+	VotedCandidates int
+
+	// expert fund
+	Experts               int
+	QualifiedExperts      int
+	ExpertLockedRewards   abi.TokenAmount
+	ExpertUnlockedRewards abi.TokenAmount
+
+	// retrieval fund
+	RetrievalCollateral    abi.TokenAmount
+	RetrievalPendingReward abi.TokenAmount
+
+	// circulating supply
+	EpkVested      abi.TokenAmount
+	EpkMined       abi.TokenAmount
+	EpkBurnt       abi.TokenAmount
+	EpkLocked      abi.TokenAmount
+	EpkCirculating abi.TokenAmount
+
+	// messages sent by the agents since the previous sample
+	Messages       int
+	FailedMessages int
+}
+
+func (s *Simulation) sample(ctx context.Context, tree *state.StateTree, epoch abi.ChainEpoch) (*Sample, error) {
+	v := &view{
+		store: s.cg.ChainStore().ActorStore(ctx),
+		tree:  tree,
+		epoch: epoch,
+	}
+	out := &Sample{
+		Epoch:          epoch,
+		Messages:       s.window.Sent,
+		FailedMessages: s.window.Failed,
+	}
+	s.window = Outcome{}
+
+	ract, err := tree.GetActor(reward.Address)
+	if err != nil {
+		return nil, xerrors.Errorf("loading reward actor: %w", err)
+	}
+	rst, err := reward.Load(v.store, ract)
+	if err != nil {
+		return nil, xerrors.Errorf("loading reward state: %w", err)
+	}
+	mined, err := rst.TotalMinedDetail()
+	if err != nil {
+		return nil, xerrors.Errorf("loading mined details: %w", err)
+	}
+	out.TotalExpertReward = mined.TotalExpertReward
+	out.TotalVoteReward = mined.TotalVoteReward
+	out.TotalKnowledgeReward = mined.TotalKnowledgeReward
+	out.TotalRetrievalReward = mined.TotalRetrievalReward
+	out.TotalStoragePowerReward = mined.TotalStoragePowerReward
+
+	vact, err := tree.GetActor(builtin2.VoteFundActorAddr)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vote fund actor: %w", err)
+	}
+	vst, err := vote.Load(v.store, vact)
+	if err != nil {
+		return nil, xerrors.Errorf("loading vote fund state: %w", err)
+	}
+	tally, err := vst.Tally()
+	if err != nil {
+		return nil, xerrors.Errorf("loading tally: %w", err)
+	}
+	out.TotalVotes = tally.TotalVotes
+	for _, votes := range tally.Candidates {
+		if votes.GreaterThan(big.Zero()) {
+			out.VotedCandidates++
+		}
+	}
+
+	efst, err := s.expertFund(v)
+	if err != nil {
+		return nil, err
+	}
+	experts, err := efst.ListAllExperts()
+	if err != nil {
+		return nil, xerrors.Errorf("listing experts: %w", err)
+	}
+	out.Experts = len(experts)
+	out.ExpertLockedRewards, out.ExpertUnlockedRewards = big.Zero(), big.Zero()
+	for _, addr := range experts {
+		est, err := s.expert(v, addr)
+		if err != nil {
+			return nil, err
+		}
+		info, err := est.Info()
+		if err != nil {
+			return nil, xerrors.Errorf("loading info of expert %s: %w", addr, err)
+		}
+		if info.Status == expert2.ExpertStateQualified {
+			out.QualifiedExperts++
+		}
+
+		rwd, err := efst.Reward(epoch, addr)
+		if err != nil {
+			return nil, xerrors.Errorf("loading rewards of expert %s: %w", addr, err)
+		}
+		out.ExpertLockedRewards = big.Add(out.ExpertLockedRewards, rwd.LockedFunds)
+		out.ExpertUnlockedRewards = big.Add(out.ExpertUnlockedRewards, rwd.UnlockedFunds)
+	}
+
+	rfst, err := s.retrievalFund(v)
+	if err != nil {
+		return nil, err
+	}
+	if out.RetrievalCollateral, err = rfst.TotalCollateral(); err != nil {
+		return nil, xerrors.Errorf("loading retrieval collateral: %w", err)
+	}
+	if out.RetrievalPendingReward, err = rfst.PendingReward(); err != nil {
+		return nil, xerrors.Errorf("loading retrieval pending reward: %w", err)
+	}
+
+	circ, err := s.sm.GetVMCirculatingSupplyDetailed(ctx, epoch, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("computing circulating supply: %w", err)
+	}
+	out.EpkVested = circ.EpkVested
+	out.EpkMined = circ.EpkMined
+	out.EpkBurnt = circ.EpkBurnt
+	out.EpkLocked = circ.EpkLocked
+	out.EpkCirculating = circ.EpkCirculating
+
+	return out, nil
+}
+
+// SampleWriter writes the samples of a simulation.
+type SampleWriter interface {
+	Write(*Sample) error
+	Flush() error
+}
+
+// NewSampleWriter returns a writer for the csv or json (one object per line)
+// formats.
+func NewSampleWriter(w io.Writer, format string) (SampleWriter, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, xerrors.Errorf("unknown output format %q, expected csv or json", format)
+}
+
+var csvHeader = []string{
+	"epoch",
+	"total_expert_reward", "total_vote_reward", "total_knowledge_reward", "total_retrieval_reward", "total_storage_power_reward",
+	"total_votes", "voted_candidates",
+	"experts", "qualified_experts", "expert_locked_rewards", "expert_unlocked_rewards",
+	"retrieval_collateral", "retrieval_pending_reward",
+	"epk_vested", "epk_mined", "epk_burnt", "epk_locked", "epk_circulating",
+	"messages", "failed_messages",
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(s *Sample) error {
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+
+	// amounts are written in EPK, to be plotted as they are
+	epk := func(amt abi.TokenAmount) string {
+		return types.EPK(amt).Unitless()
+	}
+	return c.w.Write([]string{
+		fmt.Sprint(s.Epoch),
+		epk(s.TotalExpertReward), epk(s.TotalVoteReward), epk(s.TotalKnowledgeReward), epk(s.TotalRetrievalReward), epk(s.TotalStoragePowerReward),
+		epk(s.TotalVotes), fmt.Sprint(s.VotedCandidates),
+		fmt.Sprint(s.Experts), fmt.Sprint(s.QualifiedExperts), epk(s.ExpertLockedRewards), epk(s.ExpertUnlockedRewards),
+		epk(s.RetrievalCollateral), epk(s.RetrievalPendingReward),
+		epk(s.EpkVested), epk(s.EpkMined), epk(s.EpkBurnt), epk(s.EpkLocked), epk(s.EpkCirculating),
+		fmt.Sprint(s.Messages), fmt.Sprint(s.FailedMessages),
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonWriter) Write(s *Sample) error {
+	return j.enc.Encode(s)
+}
+
+func (j *jsonWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	blockadt "github.com/filecoin-project/specs-actors/v2/actors/util/adt"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/actors"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/adt"
+	"github.com/EpiK-Protocol/go-epik/chain/gen"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/wallet"
+	"github.com/EpiK-Protocol/go-epik/genesis"
+)
+
+const (
+	// maxMsgsPerEpoch bounds the messages included in a tipset, so that their
+	// gas limits fit in a block; extra messages wait for the next epochs
+	maxMsgsPerEpoch = 100
+	msgGasLimit     = build.BlockGasLimit / maxMsgsPerEpoch
+)
+
+type agent struct {
+	name string
+	role string
+	// key is the wallet address of the agent, the worker key for miners
+	key address.Address
+	// miner is the miner actor of miner agents
+	miner address.Address
+	// expert is the expert actor of expert agents, once they applied
+	expert address.Address
+}
+
+// Outcome counts the messages of an action kind.
+type Outcome struct {
+	Sent   int
+	Failed int
+}
+
+// Simulation runs a scenario on an in-process chain with mock proofs.
+type Simulation struct {
+	scn *Scenario
+	cg  *gen.ChainGen
+	sm  *stmgr.StateManager
+	rnd *rand.Rand
+
+	agents  map[string]*agent
+	experts []*agent
+	voters  []*agent
+	clients []*agent
+	miners  []*agent
+
+	script map[abi.ChainEpoch][]Action
+	// acted is the last epoch the agents acted at
+	acted abi.ChainEpoch
+
+	// queue holds the messages waiting to be included, nonces are set when
+	// they are included
+	queue  []*pendingMsg
+	nonces map[address.Address]uint64
+	next   []*types.SignedMessage
+
+	// sent maps the messages of the last tipset to their action kind
+	sent map[cid.Cid]string
+
+	// Outcomes counts the messages of each action kind over the whole run
+	Outcomes map[string]*Outcome
+	// window counts the messages since the last sample
+	window Outcome
+}
+
+type pendingMsg struct {
+	kind string
+	from *agent
+	msg  *types.Message
+}
+
+// NewSimulation creates the agents of the scenario and the genesis of the
+// chain funding them.
+func NewSimulation(ctx context.Context, scn *Scenario) (*Simulation, error) {
+	if err := scn.Validate(); err != nil {
+		return nil, xerrors.Errorf("invalid scenario: %w", err)
+	}
+
+	balance, err := types.ParseEPK(scn.AgentBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Simulation{
+		scn:      scn,
+		rnd:      rand.New(rand.NewSource(scn.Seed)),
+		agents:   make(map[string]*agent),
+		script:   make(map[abi.ChainEpoch][]Action),
+		nonces:   make(map[address.Address]uint64),
+		sent:     make(map[cid.Cid]string),
+		Outcomes: make(map[string]*Outcome),
+	}
+	for _, a := range scn.Actions {
+		s.script[a.Epoch] = append(s.script[a.Epoch], a)
+	}
+
+	cg, err := gen.NewGeneratorWithTemplate(1, func(w *wallet.LocalWallet, tpl *genesis.Template) error {
+		roles := []struct {
+			role  string
+			count int
+			out   *[]*agent
+		}{
+			{RoleExpert, scn.Experts, &s.experts},
+			{RoleVoter, scn.Voters, &s.voters},
+			{RoleClient, scn.Clients, &s.clients},
+		}
+		for _, r := range roles {
+			for i := 0; i < r.count; i++ {
+				key, err := w.WalletNew(ctx, types.KTSecp256k1)
+				if err != nil {
+					return xerrors.Errorf("creating agent key: %w", err)
+				}
+
+				a := &agent{name: fmt.Sprintf("%s/%d", r.role, i), role: r.role, key: key}
+				*r.out = append(*r.out, a)
+				s.agents[a.name] = a
+
+				act := genesis.Actor{
+					Type:    genesis.TAccount,
+					Balance: abi.TokenAmount(balance),
+					Meta:    (&genesis.AccountMeta{Owner: key}).ActorMeta(),
+				}
+				if a.name == "expert/0" {
+					tpl.DefaultExpertActor = act
+					continue
+				}
+				tpl.Accounts = append(tpl.Accounts, act)
+			}
+		}
+
+		tpl.GovParams = scn.GovParams
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("creating chain generator: %w", err)
+	}
+	cg.GetMessages = func(*gen.ChainGen) ([]*types.SignedMessage, error) {
+		return s.next, nil
+	}
+
+	s.cg = cg
+	s.sm = cg.StateManager()
+
+	gts := cg.CurTipset.TipSet()
+	for i, maddr := range cg.Miners {
+		worker, err := stmgr.GetMinerWorkerRaw(ctx, s.sm, gts.ParentState(), maddr)
+		if err != nil {
+			return nil, xerrors.Errorf("getting worker of %s: %w", maddr, err)
+		}
+		key, err := s.sm.ResolveToKeyAddress(ctx, worker, gts)
+		if err != nil {
+			return nil, xerrors.Errorf("resolving worker of %s: %w", maddr, err)
+		}
+
+		a := &agent{name: fmt.Sprintf("%s/%d", RoleMiner, i), role: RoleMiner, key: key, miner: maddr}
+		s.miners = append(s.miners, a)
+		s.agents[a.name] = a
+	}
+
+	for _, a := range scn.Actions {
+		if _, ok := s.agents[a.Agent]; !ok {
+			return nil, xerrors.Errorf("scripted action at epoch %d: unknown agent %s", a.Epoch, a.Agent)
+		}
+	}
+
+	return s, nil
+}
+
+// Run simulates the scenario, passing a sample to sink every sampleEvery
+// epochs and at the last epoch.
+func (s *Simulation) Run(ctx context.Context, sampleEvery abi.ChainEpoch, sink func(*Sample) error) error {
+	if sampleEvery <= 0 {
+		sampleEvery = 1
+	}
+
+	var lastSample abi.ChainEpoch = -1
+	for {
+		ts := s.cg.CurTipset.TipSet()
+
+		root, rec, err := s.sm.TipSetState(ctx, ts)
+		if err != nil {
+			return xerrors.Errorf("computing state of tipset %d: %w", ts.Height(), err)
+		}
+		tree, err := state.LoadStateTree(cbor.NewCborStore(s.cg.ChainStore().StateBlockstore()), root)
+		if err != nil {
+			return xerrors.Errorf("loading state tree: %w", err)
+		}
+
+		if err := s.tally(ctx, ts, rec); err != nil {
+			return xerrors.Errorf("counting message outcomes at %d: %w", ts.Height(), err)
+		}
+
+		done := ts.Height() >= s.scn.Epochs
+		if ts.Height()-lastSample >= sampleEvery || done {
+			smp, err := s.sample(ctx, tree, ts.Height())
+			if err != nil {
+				return xerrors.Errorf("sampling epoch %d: %w", ts.Height(), err)
+			}
+			if err := sink(smp); err != nil {
+				return err
+			}
+			lastSample = ts.Height()
+		}
+		if done {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// messages are included in the next tipset, whose epoch may be later
+		// with null rounds
+		if err := s.act(ctx, tree, ts.Height()+1); err != nil {
+			return xerrors.Errorf("acting at epoch %d: %w", ts.Height()+1, err)
+		}
+		if err := s.prepare(ctx, tree); err != nil {
+			return xerrors.Errorf("preparing messages: %w", err)
+		}
+
+		if _, err := s.cg.NextTipSet(); err != nil {
+			return xerrors.Errorf("mining tipset after %d: %w", ts.Height(), err)
+		}
+	}
+}
+
+// send queues a message of an agent.
+func (s *Simulation) send(kind string, from *agent, to address.Address, value abi.TokenAmount, method abi.MethodNum, params cbg.CBORMarshaler) error {
+	var enc []byte
+	if params != nil {
+		var aerr error
+		if enc, aerr = actors.SerializeParams(params); aerr != nil {
+			return xerrors.Errorf("serializing %s params: %w", kind, aerr)
+		}
+	}
+
+	s.queue = append(s.queue, &pendingMsg{
+		kind: kind,
+		from: from,
+		msg: &types.Message{
+			From:       from.key,
+			To:         to,
+			Value:      value,
+			Method:     method,
+			Params:     enc,
+			GasLimit:   msgGasLimit,
+			GasFeeCap:  types.NewInt(0),
+			GasPremium: types.NewInt(0),
+		},
+	})
+	return nil
+}
+
+// prepare signs the queued messages to include in the next tipset.
+func (s *Simulation) prepare(ctx context.Context, tree *state.StateTree) error {
+	s.next = s.next[:0]
+	s.sent = make(map[cid.Cid]string)
+	for k := range s.nonces {
+		delete(s.nonces, k)
+	}
+
+	n := len(s.queue)
+	if n > maxMsgsPerEpoch {
+		n = maxMsgsPerEpoch
+	}
+
+	for _, p := range s.queue[:n] {
+		nonce, ok := s.nonces[p.from.key]
+		if !ok {
+			act, err := tree.GetActor(p.from.key)
+			if err != nil {
+				return xerrors.Errorf("loading actor of %s: %w", p.from.name, err)
+			}
+			nonce = act.Nonce
+		}
+		s.nonces[p.from.key] = nonce + 1
+
+		p.msg.Nonce = nonce
+		sig, err := s.cg.Wallet().WalletSign(ctx, p.from.key, p.msg.Cid().Bytes(), api.MsgMeta{
+			Type: api.MTUnknown,
+		})
+		if err != nil {
+			return xerrors.Errorf("signing message of %s: %w", p.from.name, err)
+		}
+
+		s.next = append(s.next, &types.SignedMessage{Message: *p.msg, Signature: *sig})
+		s.sent[p.msg.Cid()] = p.kind
+		s.outcome(p.kind).Sent++
+		s.window.Sent++
+	}
+
+	s.queue = s.queue[n:]
+	return nil
+}
+
+// tally counts the failed messages sent by the agents in ts.
+func (s *Simulation) tally(ctx context.Context, ts *types.TipSet, rec cid.Cid) error {
+	if len(s.sent) == 0 {
+		return nil
+	}
+
+	msgs, err := s.cg.ChainStore().MessagesForTipset(ts)
+	if err != nil {
+		return xerrors.Errorf("loading messages: %w", err)
+	}
+	rcpts, err := blockadt.AsArray(s.cg.ChainStore().ActorStore(ctx), rec, adt.DefaultMsgAmtBitwidth)
+	if err != nil {
+		return xerrors.Errorf("loading receipts: %w", err)
+	}
+
+	for i, m := range msgs {
+		kind, ok := s.sent[m.VMMessage().Cid()]
+		if !ok {
+			continue
+		}
+
+		var r types.MessageReceipt
+		found, err := rcpts.Get(uint64(i), &r)
+		if err != nil {
+			return xerrors.Errorf("loading receipt %d: %w", i, err)
+		}
+		if !found || r.ExitCode.IsSuccess() {
+			continue
+		}
+
+		log.Debugf("%s message %s failed with exit code %d", kind, m.Cid(), r.ExitCode)
+		s.outcome(kind).Failed++
+		s.window.Failed++
+	}
+	return nil
+}
+
+func (s *Simulation) outcome(kind string) *Outcome {
+	o, ok := s.Outcomes[kind]
+	if !ok {
+		o = new(Outcome)
+		s.Outcomes[kind] = o
+	}
+	return o
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+func TestSimulationSmoke(t *testing.T) {
+	ctx := context.Background()
+
+	scn := DefaultScenario()
+	scn.Epochs = 6
+	scn.Experts = 1
+	scn.Voters = 2
+	scn.Clients = 1
+	// only the scripted vote, so that the series is deterministic
+	scn.Rates = map[string]float64{}
+	scn.Actions = []Action{{Epoch: 2, Agent: "voter/0", Kind: ActVote, Target: "expert/0", Amount: "100"}}
+
+	sim, err := NewSimulation(ctx, scn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	w, err := NewSampleWriter(&out, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var samples []*Sample
+	err = sim.Run(ctx, 2, func(s *Sample) error {
+		samples = append(samples, s)
+		return w.Write(s)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) < 2 {
+		t.Fatalf("expected several samples, got %d", len(samples))
+	}
+	last := samples[len(samples)-1]
+	if last.Epoch != scn.Epochs {
+		t.Errorf("expected the last sample at epoch %d, got %d", scn.Epochs, last.Epoch)
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Epoch <= samples[i-1].Epoch {
+			t.Errorf("sample epochs are not increasing: %d after %d", samples[i].Epoch, samples[i-1].Epoch)
+		}
+		if samples[i].EpkMined.LessThan(samples[i-1].EpkMined) {
+			t.Errorf("mined supply decreased at epoch %d", samples[i].Epoch)
+		}
+	}
+	if !last.EpkMined.GreaterThan(big.Zero()) || !last.TotalStoragePowerReward.GreaterThan(big.Zero()) {
+		t.Errorf("expected block rewards to be mined, got %s mined and %s storage power reward", last.EpkMined, last.TotalStoragePowerReward)
+	}
+	if last.Experts != 1 {
+		t.Errorf("expected the genesis expert, got %d experts", last.Experts)
+	}
+
+	sent, failed := 0, 0
+	for _, s := range samples {
+		sent += s.Messages
+		failed += s.FailedMessages
+	}
+	o := sim.Outcomes[ActVote]
+	if o == nil || o.Sent != 1 || sent != 1 || failed != o.Failed {
+		t.Fatalf("expected the scripted vote to be sent once, got %+v and %d messages, %d failed", o, sent, failed)
+	}
+	// the tally reflects the outcome of the vote
+	voted := abi.TokenAmount(types.MustParseEPK("100"))
+	if o.Failed != 0 {
+		voted = big.Zero()
+	}
+	if !last.TotalVotes.Equals(voted) {
+		t.Errorf("expected %s of votes, got %s", types.EPK(voted), types.EPK(last.TotalVotes))
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(samples)+1 {
+		t.Fatalf("expected a header and %d rows, got %d rows", len(samples), len(rows))
+	}
+	for _, r := range rows {
+		if len(r) != len(csvHeader) {
+			t.Fatalf("expected %d columns, got %d", len(csvHeader), len(r))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 
@@ -45,11 +46,11 @@ var initRestoreCmd = &cli.Command{
 			Usage: "storage paths config (storage.json)",
 		},
 	},
-	ArgsUsage: "[backupFile]",
+	ArgsUsage: "[backupFile] [incrementalBackups...]",
 	Action: func(cctx *cli.Context) error {
 		log.Info("Initializing epik miner using a backup")
-		if cctx.Args().Len() != 1 {
-			return xerrors.Errorf("expected 1 argument")
+		if cctx.Args().Len() < 1 {
+			return xerrors.Errorf("expected at least 1 argument")
 		}
 
 		log.Info("Trying to connect to full node RPC")
@@ -95,6 +96,29 @@ var initRestoreCmd = &cli.Command{
 		}
 		defer f.Close() // nolint:errcheck
 
+		size := st.Size()
+		var incrs []io.Reader
+		for _, p := range cctx.Args().Tail() {
+			ip, err := homedir.Expand(p)
+			if err != nil {
+				return xerrors.Errorf("expand incremental backup path: %w", err)
+			}
+
+			ist, err := os.Stat(ip)
+			if err != nil {
+				return xerrors.Errorf("stat incremental backup file (%s): %w", ip, err)
+			}
+			size += ist.Size()
+
+			inf, err := os.Open(ip)
+			if err != nil {
+				return xerrors.Errorf("opening incremental backup file: %w", err)
+			}
+			defer inf.Close() // nolint:errcheck
+
+			incrs = append(incrs, inf)
+		}
+
 		log.Info("Checking if repo exists")
 
 		repoPath := cctx.String(FlagMinerRepo)
@@ -197,15 +221,19 @@ var initRestoreCmd = &cli.Command{
 			return err
 		}
 
-		bar := pb.New64(st.Size())
+		bar := pb.New64(size)
 		br := bar.NewProxyReader(f)
 		bar.ShowTimeLeft = true
 		bar.ShowPercent = true
 		bar.ShowSpeed = true
 		bar.Units = pb.U_BYTES
 
+		for i, inf := range incrs {
+			incrs[i] = bar.NewProxyReader(inf)
+		}
+
 		bar.Start()
-		err = backupds.RestoreInto(br, mds)
+		err = backupds.RestoreChainInto(mds, br, incrs...)
 		bar.Finish()
 
 		if err != nil {
@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
 	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
@@ -35,6 +38,7 @@ var provingCmd = &cli.Command{
 		provingDeadlineInfoCmd,
 		provingFaultsCmd,
 		provingCheckProvableCmd,
+		provingSimulateCmd,
 	},
 }
 
@@ -491,3 +495,129 @@ var provingCheckProvableCmd = &cli.Command{
 		return tw.Flush()
 	},
 }
+
+var provingSimulateCmd = &cli.Command{
+	Name:  "simulate",
+	Usage: "Run WindowPoSt for a deadline without submitting it, and report risks",
+	Description: `Runs the full WindowPoSt flow for the next instance of the deadline against
+the current chain state: sector checks, partition batching, proof generation and
+message gas estimation. Challenges are generated from mock randomness, nothing is
+sent to the chain.
+
+Gas can only be estimated for the currently open deadline.`,
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:     "deadline",
+			Usage:    "deadline index to simulate",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		color.NoColor = !cctx.Bool("color")
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.ReqContext(cctx)
+
+		sim, err := nodeApi.ProvingSimulate(ctx, cctx.Uint64("deadline"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Deadline:      %d\n", sim.Deadline)
+		fmt.Printf("Opens:         %s\n", lcli.EpochTime(sim.CurrentEpoch, sim.Open))
+		fmt.Printf("Fault Cutoff:  %s\n", lcli.EpochTime(sim.CurrentEpoch, sim.FaultCutoff))
+		fmt.Printf("Prove Time:    %s\n\n", sim.ProveTime.Truncate(time.Millisecond))
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "Partition\tLive\tFaulty\tRecovering\tUnprovable\tRecoverable\tStatus")
+
+		var recommendations []string
+		cutoffPassed := sim.CurrentEpoch >= sim.FaultCutoff
+
+		for _, p := range sim.Partitions {
+			unprovable, err := p.Unprovable.Count()
+			if err != nil {
+				return err
+			}
+			recoverable, err := p.Recoverable.Count()
+			if err != nil {
+				return err
+			}
+
+			status := color.GreenString("ok")
+			if unprovable > 0 {
+				status = color.RedString("at risk")
+
+				if cutoffPassed {
+					recommendations = append(recommendations, fmt.Sprintf("partition %d: %d sectors can't be proven and the fault cutoff has passed, they will be skipped in the proof and penalized", p.Index, unprovable))
+				} else {
+					recommendations = append(recommendations, fmt.Sprintf("partition %d: %d sectors can't be proven, restore their storage or declare them faulty before epoch %d", p.Index, unprovable, sim.FaultCutoff))
+				}
+			}
+			if recoverable > 0 {
+				recommendations = append(recommendations, fmt.Sprintf("partition %d: %d faulty sectors are provable again, declare recoveries before epoch %d", p.Index, recoverable, sim.FaultCutoff))
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%s\n", p.Index, p.Live, p.Faulty, p.Recovering, unprovable, recoverable, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Println()
+
+		tw = tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "Message\tPartitions\tSkipped\tParams Size\tGas Limit\tMax Fee")
+		for i, m := range sim.Messages {
+			parts := make([]string, len(m.Partitions))
+			for j, p := range m.Partitions {
+				parts[j] = fmt.Sprint(p)
+			}
+
+			fee := types.EPK(m.Fee).Short()
+			if !m.GasEstimated {
+				// fixed gas limit per partition
+				fee += "*"
+			}
+			if m.GasLimit == 0 {
+				fee = color.YellowString("n/a")
+			} else if m.Fee.GreaterThan(sim.MaxFee) {
+				fee = color.RedString("%s", fee)
+				recommendations = append(recommendations, fmt.Sprintf("message %d: expected fee %s is above MaxWindowPoStGasFee (%s), raise the limit in the Fees config section", i, types.EPK(m.Fee), types.EPK(sim.MaxFee)))
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%d\t%s\n", i, strings.Join(parts, ","), m.Skipped, m.ParamsSize, m.GasLimit, fee)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for i, m := range sim.Messages {
+			if !m.GasEstimated {
+				fmt.Printf("* message %d: gas not estimated (%s), assuming a fixed gas limit per partition\n", i, m.GasError)
+			}
+		}
+
+		window := time.Duration(miner.WPoStChallengeWindow) * time.Duration(build.BlockDelaySecs) * time.Second
+		if sim.ProveTime > window {
+			recommendations = append(recommendations, fmt.Sprintf("proving took %s, longer than the %s challenge window", sim.ProveTime.Truncate(time.Second), window))
+		}
+
+		fmt.Println()
+		if len(recommendations) == 0 {
+			fmt.Println(color.GreenString("No risks found"))
+			return nil
+		}
+
+		fmt.Println("Recommendations:")
+		for _, r := range recommendations {
+			fmt.Printf("  - %s\n", r)
+		}
+
+		return nil
+	},
+}
@@ -6,8 +6,11 @@ import (
 	"text/tabwriter"
 
 	"github.com/docker/go-units"
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/ipfs/go-cid"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	lcli "github.com/EpiK-Protocol/go-epik/cli"
@@ -21,6 +24,7 @@ var retrievalDealsCmd = &cli.Command{
 		retrievalDealsListCmd,
 		retrievalSetAskCmd,
 		retrievalGetAskCmd,
+		retrievalQuoteCmd,
 	},
 }
 
@@ -243,3 +247,69 @@ var retrievalGetAskCmd = &cli.Command{
 
 	},
 }
+
+var retrievalQuoteCmd = &cli.Command{
+	Name:      "quote",
+	Usage:     "Show the retrieval terms the pricing policy gives for retrieving data",
+	ArgsUsage: "[payloadCid]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "piece-cid",
+			Usage: "piece containing the payload",
+		},
+		&cli.StringFlag{
+			Name:  "client",
+			Usage: "quote for a specific client wallet",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("expected 1 argument")
+		}
+
+		ctx := lcli.DaemonContext(cctx)
+
+		api, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		payload, err := cid.Parse(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing payload cid: %w", err)
+		}
+
+		var piece *cid.Cid
+		if cctx.IsSet("piece-cid") {
+			c, err := cid.Parse(cctx.String("piece-cid"))
+			if err != nil {
+				return xerrors.Errorf("parsing piece cid: %w", err)
+			}
+			piece = &c
+		}
+
+		client := address.Undef
+		if cctx.IsSet("client") {
+			client, err = address.NewFromString(cctx.String("client"))
+			if err != nil {
+				return xerrors.Errorf("parsing client address: %w", err)
+			}
+		}
+
+		ask, err := api.MarketRetrievalQuote(ctx, payload, piece, client)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Price per Byte\tUnseal Price\tPayment Interval\tPayment Interval Increase\n")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			types.EPK(ask.PricePerByte),
+			types.EPK(ask.UnsealPrice),
+			units.BytesSize(float64(ask.PaymentInterval)),
+			units.BytesSize(float64(ask.PaymentIntervalIncrease)),
+		)
+		return w.Flush()
+	},
+}
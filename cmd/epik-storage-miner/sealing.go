@@ -15,6 +15,7 @@ import (
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/sealtasks"
 	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
 
 	"github.com/EpiK-Protocol/go-epik/chain/types"
@@ -29,6 +30,7 @@ var sealingCmd = &cli.Command{
 		sealingWorkersCmd,
 		sealingSchedDiagCmd,
 		sealingAbortCmd,
+		sealingHistoryCmd,
 	},
 }
 
@@ -305,3 +307,187 @@ var sealingAbortCmd = &cli.Command{
 		return nodeApi.SealingAbort(ctx, job.ID)
 	},
 }
+
+var sealingHistoryCmd = &cli.Command{
+	Name:  "history",
+	Usage: "show finished sealing jobs and aggregate timings",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "since",
+			Usage: "only show jobs which finished within this time window",
+			Value: 24 * time.Hour,
+		},
+		&cli.StringFlag{
+			Name:  "task",
+			Usage: "only show jobs of this task type (e.g. PC1, C2)",
+		},
+		&cli.StringFlag{
+			Name:  "host",
+			Usage: "only show jobs executed on workers with this hostname",
+		},
+		&cli.StringFlag{
+			Name:  "worker",
+			Usage: "only show jobs executed by the worker with this ID prefix",
+		},
+		&cli.BoolFlag{
+			Name:  "list",
+			Usage: "list individual jobs, not only aggregates",
+		},
+		&cli.BoolFlag{
+			Name:  "by-worker",
+			Usage: "aggregate timings per worker, in addition to per task",
+		},
+		&cli.BoolFlag{Name: "color"},
+	},
+	Action: func(cctx *cli.Context) error {
+		color.NoColor = !cctx.Bool("color")
+
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := lcli.ReqContext(cctx)
+
+		filter := storiface.WorkerJobFilter{
+			Since:  time.Now().Add(-cctx.Duration("since")),
+			Host:   cctx.String("host"),
+			Worker: cctx.String("worker"),
+		}
+		if cctx.IsSet("task") {
+			tt, ok := sealtasks.ParseTaskType(cctx.String("task"))
+			if !ok {
+				return xerrors.Errorf("unknown task type '%s'", cctx.String("task"))
+			}
+			filter.Task = tt
+		}
+
+		jobs, err := nodeApi.SealingJobHistory(ctx, filter)
+		if err != nil {
+			return xerrors.Errorf("getting job history: %w", err)
+		}
+
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].End.Before(jobs[j].End)
+		})
+
+		if cctx.Bool("list") {
+			tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintf(tw, "ID\tSector\tWorker\tHostname\tTask\tFinished\tTook\tResult\n")
+
+			for _, j := range jobs {
+				res := color.GreenString("ok")
+				if j.Error != "" {
+					res = color.RedString(j.Error)
+				}
+
+				_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					hex.EncodeToString(j.ID.ID[:4]),
+					j.Sector.Number,
+					hex.EncodeToString(j.Worker[:4]),
+					j.Hostname,
+					j.Task.Short(),
+					j.End.Format(time.Stamp),
+					j.Duration().Truncate(time.Second),
+					res)
+			}
+
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+
+		type aggKey struct {
+			task     sealtasks.TaskType
+			hostname string
+		}
+
+		groups := map[aggKey][]storiface.WorkerJobRecord{}
+		for _, j := range jobs {
+			k := aggKey{task: j.Task}
+			if cctx.Bool("by-worker") {
+				k.hostname = j.Hostname
+			}
+			groups[k] = append(groups[k], j)
+		}
+
+		keys := make([]aggKey, 0, len(groups))
+		for k := range groups {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].task != keys[j].task {
+				return keys[i].task.Less(keys[j].task)
+			}
+			return keys[i].hostname < keys[j].hostname
+		})
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		if cctx.Bool("by-worker") {
+			_, _ = fmt.Fprint(tw, "Hostname\t")
+		}
+		_, _ = fmt.Fprintf(tw, "Task\tJobs\tFailed\tP50\tP90\tP99\tMax\tThroughput\n")
+
+		for _, k := range keys {
+			group := groups[k]
+
+			var failed int
+			var bytes uint64
+			var busy time.Duration
+			durs := make([]time.Duration, 0, len(group))
+			for _, j := range group {
+				if j.Error != "" {
+					failed++
+					continue
+				}
+				durs = append(durs, j.Duration())
+				bytes += j.Bytes
+				busy += j.Duration()
+			}
+			sort.Slice(durs, func(i, j int) bool {
+				return durs[i] < durs[j]
+			})
+
+			failStr := fmt.Sprint(failed)
+			if failed > 0 {
+				failStr = color.RedString("%d", failed)
+			}
+
+			tput := "n/a"
+			if busy > 0 {
+				tput = types.SizeStr(types.NewInt(uint64(float64(bytes)/busy.Seconds()))) + "/s"
+			}
+
+			if cctx.Bool("by-worker") {
+				_, _ = fmt.Fprintf(tw, "%s\t", k.hostname)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				k.task.Short(),
+				len(group),
+				failStr,
+				durationPercentile(durs, 50),
+				durationPercentile(durs, 90),
+				durationPercentile(durs, 99),
+				durationPercentile(durs, 100),
+				tput)
+		}
+
+		return tw.Flush()
+	},
+}
+
+// durationPercentile returns the p-th percentile of the sorted durations
+func durationPercentile(sorted []time.Duration, p int) string {
+	if len(sorted) == 0 {
+		return "n/a"
+	}
+
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx].Truncate(time.Second).String()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-address"
+)
+
+type nodeDetail struct {
+	NodeSummary
+	History []HeadEntry
+}
+
+type minerDetail struct {
+	Miner   address.Address
+	History []MinerBlock
+}
+
+func apiRouter(f *fleet) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/nodes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, f.nodeList())
+	}).Methods("GET")
+	r.HandleFunc("/api/nodes/{peer}", func(w http.ResponseWriter, r *http.Request) {
+		p, err := peer.Decode(mux.Vars(r)["peer"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, history, ok := f.node(p)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, nodeDetail{NodeSummary: s, History: history})
+	}).Methods("GET")
+	r.HandleFunc("/api/miners", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, f.minerList())
+	}).Methods("GET")
+	r.HandleFunc("/api/miners/{miner}", func(w http.ResponseWriter, r *http.Request) {
+		a, err := address.NewFromString(mux.Vars(r)["miner"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		history, ok := f.miner(a)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, minerDetail{Miner: a, History: history})
+	}).Methods("GET")
+	r.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, f.alertList())
+	}).Methods("GET")
+	return r
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnw("writing response", "error", err)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// headNotif is the head notification published by the nodes running with
+// Metrics.HeadNotifs, see chain/metrics
+type headNotif struct {
+	Cids   []cid.Cid
+	Blocks []*types.BlockHeader
+	Height abi.ChainEpoch
+	Weight types.BigInt
+	Time   uint64
+	Nonce  uint64
+
+	NodeName  string
+	PeerCount int
+}
+
+// Node statuses
+const (
+	StatusOK = "ok"
+	// StatusBehind is a node more than the lag threshold behind the heaviest
+	// head reported
+	StatusBehind = "behind"
+	// StatusForked is a node at the height of the heaviest head reported, or
+	// above, with a lighter head
+	StatusForked = "forked"
+	// StatusSilent is a node which didn't report a head for the silence
+	// timeout
+	StatusSilent = "silent"
+)
+
+// HeadEntry is a head reported by a node.
+type HeadEntry struct {
+	Height    abi.ChainEpoch
+	Weight    types.BigInt
+	Cids      []cid.Cid
+	PeerCount int
+	Time      time.Time
+}
+
+// NodeSummary is the latest state of a node.
+type NodeSummary struct {
+	Peer     peer.ID
+	Nickname string
+	Status   string
+	Head     HeadEntry
+}
+
+// MinerBlock is a block of a miner, seen in the heads reported by the nodes.
+type MinerBlock struct {
+	Height     abi.ChainEpoch
+	Block      cid.Cid
+	Time       time.Time
+	ReportedBy peer.ID
+}
+
+// MinerSummary is the latest state of a miner.
+type MinerSummary struct {
+	Miner address.Address
+	// Blocks is the number of blocks of the miner seen since the townhall
+	// started
+	Blocks int
+	Last   MinerBlock
+}
+
+// Alert is a change of the status of a node.
+type Alert struct {
+	Peer       peer.ID
+	Nickname   string
+	Status     string
+	Previous   string
+	Height     abi.ChainEpoch
+	BestHeight abi.ChainEpoch
+	Time       time.Time
+}
+
+// fleetNode is a node which reported at least one head, its history is never
+// empty.
+type fleetNode struct {
+	nickname string
+	nonce    uint64
+	status   string
+	history  []HeadEntry
+}
+
+type fleetMiner struct {
+	blocks  int
+	history []MinerBlock
+	// seen holds the blocks in history, which is in the order the blocks
+	// were received rather than by height
+	seen map[cid.Cid]struct{}
+}
+
+// fleet keeps a bounded history of the heads reported by each node and of the
+// blocks of each miner, and tracks the status of the nodes. Nodes and miners
+// are forgotten once they're not seen for the expiry.
+type fleet struct {
+	historySize int
+	lag         abi.ChainEpoch
+	silence     time.Duration
+	expiry      time.Duration
+	onAlert     func(Alert)
+
+	lk     sync.Mutex
+	nodes  map[peer.ID]*fleetNode
+	miners map[address.Address]*fleetMiner
+	alerts []Alert
+}
+
+func newFleet(historySize int, lag abi.ChainEpoch, silence, expiry time.Duration, onAlert func(Alert)) *fleet {
+	if onAlert == nil {
+		onAlert = func(Alert) {}
+	}
+	return &fleet{
+		historySize: historySize,
+		lag:         lag,
+		silence:     silence,
+		expiry:      expiry,
+		onAlert:     onAlert,
+		nodes:       map[peer.ID]*fleetNode{},
+		miners:      map[address.Address]*fleetMiner{},
+	}
+}
+
+// add records a head notification received from a node.
+func (f *fleet) add(from peer.ID, m *headNotif, now time.Time) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	// the nonce starts from the time the node started, a lower nonce is a
+	// delayed or replayed notification
+	n, ok := f.nodes[from]
+	if ok && m.Nonce <= n.nonce {
+		return
+	}
+	if !ok {
+		n = &fleetNode{}
+		f.nodes[from] = n
+	}
+	n.nonce = m.Nonce
+	n.nickname = m.NodeName
+
+	n.history = append(n.history, HeadEntry{
+		Height:    m.Height,
+		Weight:    m.Weight,
+		Cids:      m.Cids,
+		PeerCount: m.PeerCount,
+		Time:      now,
+	})
+	if over := len(n.history) - f.historySize; over > 0 {
+		n.history = append(n.history[:0], n.history[over:]...)
+	}
+
+	for _, b := range m.Blocks {
+		f.addBlock(from, b, now)
+	}
+
+	f.evaluate(now)
+}
+
+func (f *fleet) addBlock(from peer.ID, b *types.BlockHeader, now time.Time) {
+	mi, ok := f.miners[b.Miner]
+	if !ok {
+		mi = &fleetMiner{seen: map[cid.Cid]struct{}{}}
+		f.miners[b.Miner] = mi
+	}
+
+	// the same block is reported by every node in sync
+	c := b.Cid()
+	if _, ok := mi.seen[c]; ok {
+		return
+	}
+	mi.seen[c] = struct{}{}
+
+	mi.blocks++
+	mi.history = append(mi.history, MinerBlock{
+		Height:     b.Height,
+		Block:      c,
+		Time:       now,
+		ReportedBy: from,
+	})
+	if over := len(mi.history) - f.historySize; over > 0 {
+		for _, mb := range mi.history[:over] {
+			delete(mi.seen, mb.Block)
+		}
+		mi.history = append(mi.history[:0], mi.history[over:]...)
+	}
+}
+
+// check updates the status of the nodes, used to notice the silent ones, and
+// forgets the nodes and miners not seen for the expiry.
+func (f *fleet) check(now time.Time) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	f.expire(now)
+	f.evaluate(now)
+}
+
+func (f *fleet) expire(now time.Time) {
+	for p, n := range f.nodes {
+		if now.Sub(n.history[len(n.history)-1].Time) > f.expiry {
+			delete(f.nodes, p)
+		}
+	}
+	for a, mi := range f.miners {
+		if now.Sub(mi.history[len(mi.history)-1].Time) > f.expiry {
+			delete(f.miners, a)
+		}
+	}
+}
+
+func (f *fleet) evaluate(now time.Time) {
+	var best *HeadEntry
+	for _, n := range f.nodes {
+		head := &n.history[len(n.history)-1]
+		if now.Sub(head.Time) > f.silence {
+			continue
+		}
+		if best == nil || head.Weight.GreaterThan(best.Weight) ||
+			(head.Weight.Equals(best.Weight) && head.Height > best.Height) {
+			best = head
+		}
+	}
+
+	for p, n := range f.nodes {
+		head := n.history[len(n.history)-1]
+
+		status := StatusOK
+		switch {
+		case now.Sub(head.Time) > f.silence:
+			status = StatusSilent
+		case best == nil:
+			// all the nodes are silent
+		case best.Height-head.Height > f.lag:
+			status = StatusBehind
+		case head.Height >= best.Height && head.Weight.LessThan(best.Weight):
+			status = StatusForked
+		}
+
+		if status == n.status || (n.status == "" && status == StatusOK) {
+			n.status = status
+			continue
+		}
+
+		a := Alert{
+			Peer:     p,
+			Nickname: n.nickname,
+			Status:   status,
+			Previous: n.status,
+			Height:   head.Height,
+			Time:     now,
+		}
+		if best != nil {
+			a.BestHeight = best.Height
+		}
+		n.status = status
+
+		f.alerts = append(f.alerts, a)
+		if over := len(f.alerts) - f.historySize; over > 0 {
+			f.alerts = append(f.alerts[:0], f.alerts[over:]...)
+		}
+		f.onAlert(a)
+	}
+}
+
+func (f *fleet) nodeList() []NodeSummary {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	out := make([]NodeSummary, 0, len(f.nodes))
+	for p, n := range f.nodes {
+		out = append(out, n.summary(p))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Nickname != out[j].Nickname {
+			return out[i].Nickname < out[j].Nickname
+		}
+		return out[i].Peer < out[j].Peer
+	})
+	return out
+}
+
+func (f *fleet) node(p peer.ID) (NodeSummary, []HeadEntry, bool) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	n, ok := f.nodes[p]
+	if !ok {
+		return NodeSummary{}, nil, false
+	}
+	return n.summary(p), append([]HeadEntry(nil), n.history...), true
+}
+
+func (n *fleetNode) summary(p peer.ID) NodeSummary {
+	return NodeSummary{
+		Peer:     p,
+		Nickname: n.nickname,
+		Status:   n.status,
+		Head:     n.history[len(n.history)-1],
+	}
+}
+
+func (f *fleet) minerList() []MinerSummary {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	out := make([]MinerSummary, 0, len(f.miners))
+	for a, mi := range f.miners {
+		out = append(out, MinerSummary{
+			Miner:  a,
+			Blocks: mi.blocks,
+			Last:   mi.history[len(mi.history)-1],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Miner.String() < out[j].Miner.String()
+	})
+	return out
+}
+
+func (f *fleet) miner(a address.Address) ([]MinerBlock, bool) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	mi, ok := f.miners[a]
+	if !ok {
+		return nil, false
+	}
+	return append([]MinerBlock(nil), mi.history...), true
+}
+
+func (f *fleet) alertList() []Alert {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	return append([]Alert(nil), f.alerts...)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+)
+
+func TestFleet(t *testing.T) {
+	req := require.New(t)
+
+	var alerts []Alert
+	f := newFleet(3, 5, time.Minute, time.Hour, func(a Alert) {
+		alerts = append(alerts, a)
+	})
+
+	a, b := peer.ID("a"), peer.ID("b")
+	miner := mock.Address(1000)
+	now := time.Unix(1000, 0)
+
+	var nonce uint64
+	notif := func(height abi.ChainEpoch, weight int64) *headNotif {
+		nonce++
+		blk := mock.MkBlock(nil, uint64(weight), uint64(height))
+		blk.Miner = miner
+		blk.Height = height
+		return &headNotif{
+			Blocks: []*types.BlockHeader{blk},
+			Height: height,
+			Weight: types.NewInt(uint64(weight)),
+			Nonce:  nonce,
+		}
+	}
+
+	for h := abi.ChainEpoch(1); h <= 5; h++ {
+		f.add(a, notif(h, int64(h)), now)
+	}
+	_, history, ok := f.node(a)
+	req.True(ok)
+	req.Len(history, 3)
+	req.Equal(abi.ChainEpoch(3), history[0].Height)
+
+	// replayed
+	old := notif(100, 100)
+	f.add(a, notif(6, 6), now)
+	f.add(a, old, now)
+	s, _, _ := f.node(a)
+	req.Equal(abi.ChainEpoch(6), s.Head.Height)
+
+	blocks, ok := f.miner(miner)
+	req.True(ok)
+	req.Len(blocks, 3)
+	req.Equal(6, f.minerList()[0].Blocks)
+
+	f.add(b, notif(6, 6), now)
+	req.Equal(6, f.minerList()[0].Blocks)
+	req.Empty(alerts)
+
+	// b forks at the same height
+	f.add(b, notif(7, 6), now)
+	f.add(a, notif(7, 8), now)
+	req.Len(alerts, 1)
+	req.Equal(StatusForked, alerts[0].Status)
+	req.Equal(b, alerts[0].Peer)
+
+	f.add(a, notif(20, 30), now)
+	req.Len(alerts, 2)
+	req.Equal(StatusBehind, alerts[1].Status)
+	req.Equal(StatusForked, alerts[1].Previous)
+
+	f.add(b, notif(20, 30), now.Add(30*time.Second))
+	req.Len(alerts, 3)
+	req.Equal(StatusOK, alerts[2].Status)
+
+	f.check(now.Add(90 * time.Second))
+	req.Len(alerts, 4)
+	req.Equal(a, alerts[3].Peer)
+	req.Equal(StatusSilent, alerts[3].Status)
+	req.Len(f.alertList(), 3)
+
+	// silent nodes and miners without blocks are forgotten after the expiry
+	f.add(b, &headNotif{Height: 21, Weight: types.NewInt(31), Nonce: nonce + 1}, now.Add(time.Hour))
+	f.check(now.Add(time.Hour + time.Minute))
+	_, _, ok = f.node(a)
+	req.False(ok)
+	_, _, ok = f.node(b)
+	req.True(ok)
+	_, ok = f.miner(miner)
+	req.False(ok)
+	req.Empty(f.minerList())
+}
+
+func TestFleetNonce(t *testing.T) {
+	req := require.New(t)
+
+	f := newFleet(3, 5, time.Minute, time.Hour, nil)
+	a := peer.ID("a")
+	now := time.Unix(1000, 0)
+
+	// a first notification with a zero nonce is recorded
+	f.add(a, &headNotif{Height: 1, Weight: types.NewInt(1)}, now)
+	s, _, ok := f.node(a)
+	req.True(ok)
+	req.Equal(abi.ChainEpoch(1), s.Head.Height)
+
+	f.add(a, &headNotif{Height: 2, Weight: types.NewInt(2)}, now)
+	s, _, _ = f.node(a)
+	req.Equal(abi.ChainEpoch(1), s.Head.Height)
+
+	f.add(a, &headNotif{Height: 2, Weight: types.NewInt(2), Nonce: 1}, now)
+	s, _, _ = f.node(a)
+	req.Equal(abi.ChainEpoch(2), s.Head.Height)
+	req.Len(f.nodeList(), 1)
+}
+
+func TestFleetBlocksOutOfOrder(t *testing.T) {
+	req := require.New(t)
+
+	f := newFleet(3, 5, time.Minute, time.Hour, nil)
+	a, b := peer.ID("a"), peer.ID("b")
+	miner := mock.Address(1000)
+	now := time.Unix(1000, 0)
+
+	block := func(height abi.ChainEpoch) *types.BlockHeader {
+		blk := mock.MkBlock(nil, uint64(height), uint64(height))
+		blk.Miner = miner
+		blk.Height = height
+		return blk
+	}
+	notif := func(nonce uint64, blks ...*types.BlockHeader) *headNotif {
+		return &headNotif{
+			Blocks: blks,
+			Height: blks[0].Height,
+			Weight: types.NewInt(uint64(blks[0].Height)),
+			Nonce:  nonce,
+		}
+	}
+
+	// b is behind, it reports the block of a after an older block
+	b10 := block(10)
+	f.add(a, notif(1, b10), now)
+	f.add(b, notif(1, block(5)), now)
+	f.add(b, notif(2, b10), now)
+
+	blocks, ok := f.miner(miner)
+	req.True(ok)
+	req.Len(blocks, 2)
+	req.Equal(2, f.minerList()[0].Blocks)
+}
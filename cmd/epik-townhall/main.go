@@ -6,19 +6,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	rice "github.com/GeertJohan/go.rice"
 	"github.com/gorilla/websocket"
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipld/go-car"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/EpiK-Protocol/go-epik/blockstore"
 	"github.com/EpiK-Protocol/go-epik/build"
 )
 
+var log = logging.Logger("townhall")
+
 var topic = "/epk/headnotifs/"
 
 func init() {
@@ -50,9 +58,62 @@ var upgrader = websocket.Upgrader{
 }
 
 func main() {
+	app := &cli.App{
+		Name:    "epik-townhall",
+		Usage:   "Follow the heads reported by the nodes and the blocks of the miners",
+		Version: build.UserVersion(),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "address to serve the dashboard and the API on",
+				Value: "0.0.0.0:2975",
+			},
+			&cli.IntFlag{
+				Name:  "history",
+				Usage: "number of heads kept for each node, and of blocks for each miner",
+				Value: 1000,
+			},
+			&cli.IntFlag{
+				Name:  "lag-threshold",
+				Usage: "number of epochs a node can be behind the heaviest head before it is reported as behind",
+				Value: 5,
+			},
+			&cli.DurationFlag{
+				Name:  "silence-timeout",
+				Usage: "time without head notifications after which a node is reported as silent",
+				Value: 5 * time.Minute,
+			},
+			&cli.DurationFlag{
+				Name:  "expiry",
+				Usage: "time without head notifications or blocks after which a node or miner is forgotten",
+				Value: 24 * time.Hour,
+			},
+			&cli.StringFlag{
+				Name:  "webhook",
+				Usage: "url the node status changes are posted to as JSON",
+			},
+		},
+		Action: run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Errorw("exit in error", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(cctx *cli.Context) error {
 	if topic == "" {
-		fmt.Println("FATAL: No genesis found")
-		return
+		return xerrors.New("no genesis found")
+	}
+	if cctx.Int("history") < 1 {
+		return xerrors.New("--history must be at least 1")
+	}
+	if cctx.Duration("silence-timeout") <= 0 {
+		return xerrors.New("--silence-timeout must be positive")
+	}
+	if cctx.Duration("expiry") < cctx.Duration("silence-timeout") {
+		return xerrors.New("--expiry must be at least --silence-timeout")
 	}
 
 	ctx := context.Background()
@@ -62,29 +123,88 @@ func main() {
 		libp2p.Defaults,
 	)
 	if err != nil {
-		panic(err)
+		return xerrors.Errorf("creating libp2p host: %w", err)
 	}
 	ps, err := pubsub.NewGossipSub(ctx, host)
 	if err != nil {
-		panic(err)
+		return xerrors.Errorf("creating gossipsub: %w", err)
 	}
 
 	pi, err := build.BuiltinBootstrap()
 	if err != nil {
-		panic(err)
+		return xerrors.Errorf("getting bootstrap peers: %w", err)
 	}
 
 	if err := host.Connect(ctx, pi[0]); err != nil {
-		panic(err)
+		return xerrors.Errorf("connecting to bootstrap peer: %w", err)
 	}
 
-	http.HandleFunc("/sub", handler(ps))
-	http.Handle("/", http.FileServer(rice.MustFindBox("townhall/build").HTTPBox()))
+	var onAlert func(Alert)
+	if hook := cctx.String("webhook"); hook != "" {
+		onAlert = func(a Alert) {
+			go postAlert(hook, a)
+		}
+	}
+	f := newFleet(cctx.Int("history"), abi.ChainEpoch(cctx.Int("lag-threshold")), cctx.Duration("silence-timeout"), cctx.Duration("expiry"), onAlert)
 
-	fmt.Println("listening on http://localhost:2975")
+	sub, err := ps.Subscribe(topic) //nolint
+	if err != nil {
+		return xerrors.Errorf("subscribing to head notifications: %w", err)
+	}
+	go ingest(ctx, sub, f)
 
-	if err := http.ListenAndServe("0.0.0.0:2975", nil); err != nil {
-		panic(err)
+	go func() {
+		for now := range time.Tick(cctx.Duration("silence-timeout") / 4) {
+			f.check(now)
+		}
+	}()
+
+	r := apiRouter(f)
+	r.HandleFunc("/sub", handler(ps))
+	r.PathPrefix("/").Handler(http.FileServer(rice.MustFindBox("townhall/build").HTTPBox()))
+
+	fmt.Printf("listening on http://%s\n", cctx.String("listen"))
+
+	return http.ListenAndServe(cctx.String("listen"), r)
+}
+
+func ingest(ctx context.Context, sub *pubsub.Subscription, f *fleet) {
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			log.Errorw("reading head notifications", "error", err)
+			return
+		}
+
+		var m headNotif
+		if err := json.Unmarshal(msg.Data, &m); err != nil {
+			log.Warnw("decoding head notification", "from", peer.ID(msg.From), "error", err)
+			continue
+		}
+		f.add(peer.ID(msg.From), &m, time.Now())
+	}
+}
+
+// alertClient posts the alerts, a webhook which doesn't answer must not keep
+// goroutines around
+var alertClient = &http.Client{Timeout: 30 * time.Second}
+
+func postAlert(url string, a Alert) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		log.Errorw("encoding alert", "error", err)
+		return
+	}
+
+	resp, err := alertClient.Post(url, "application/json", bytes.NewReader(b)) //nolint:gosec
+	if err != nil {
+		log.Warnw("posting alert", "error", err)
+		return
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		log.Warnw("posting alert", "status", resp.Status)
 	}
 }
 
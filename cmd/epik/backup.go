@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"io"
 	"os"
 
 	dstore "github.com/ipfs/go-datastore"
@@ -40,6 +41,29 @@ func restore(cctx *cli.Context, r repo.Repo) error {
 	}
 	defer f.Close() // nolint:errcheck
 
+	size := st.Size()
+	var incrs []*os.File
+	for _, p := range cctx.StringSlice("restore-incremental") {
+		ip, err := homedir.Expand(p)
+		if err != nil {
+			return xerrors.Errorf("expand incremental backup path: %w", err)
+		}
+
+		ist, err := os.Stat(ip)
+		if err != nil {
+			return xerrors.Errorf("stat incremental backup file (%s): %w", ip, err)
+		}
+		size += ist.Size()
+
+		inf, err := os.Open(ip)
+		if err != nil {
+			return xerrors.Errorf("opening incremental backup file: %w", err)
+		}
+		defer inf.Close() // nolint:errcheck
+
+		incrs = append(incrs, inf)
+	}
+
 	lr, err := r.Lock(repo.FullNode)
 	if err != nil {
 		return err
@@ -93,15 +117,20 @@ func restore(cctx *cli.Context, r repo.Repo) error {
 		return err
 	}
 
-	bar := pb.New64(st.Size())
+	bar := pb.New64(size)
 	br := bar.NewProxyReader(f)
 	bar.ShowTimeLeft = true
 	bar.ShowPercent = true
 	bar.ShowSpeed = true
 	bar.Units = pb.U_BYTES
 
+	ibrs := make([]io.Reader, len(incrs))
+	for i, inf := range incrs {
+		ibrs[i] = bar.NewProxyReader(inf)
+	}
+
 	bar.Start()
-	err = backupds.RestoreInto(br, mds)
+	err = backupds.RestoreChainInto(mds, br, ibrs...)
 	bar.Finish()
 
 	if err != nil {
@@ -153,6 +153,10 @@ var DaemonCmd = &cli.Command{
 			Name:  "restore",
 			Usage: "restore from backup file",
 		},
+		&cli.StringSliceFlag{
+			Name:  "restore-incremental",
+			Usage: "incremental backups to apply, in order, on top of the restored backup",
+		},
 		&cli.PathFlag{
 			Name:  "restore-config",
 			Usage: "config file to use when restoring from backup",
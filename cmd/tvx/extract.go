@@ -28,6 +28,7 @@ type extractOpts struct {
 	precursor          string
 	ignoreSanityChecks bool
 	squash             bool
+	statediff          bool
 }
 
 var extractFlags extractOpts
@@ -100,6 +101,11 @@ var extractCmd = &cli.Command{
 			Value:       false,
 			Destination: &extractFlags.ignoreSanityChecks,
 		},
+		&cli.BoolFlag{
+			Name:        "statediff",
+			Usage:       "display a statediff of the precondition and postcondition states of an extracted message",
+			Destination: &extractFlags.statediff,
+		},
 		&cli.BoolFlag{
 			Name:        "squash",
 			Usage:       "when extracting a tipset range, squash all tipsets into a single vector",
@@ -15,6 +15,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
 	init_ "github.com/EpiK-Protocol/go-epik/chain/actors/builtin/init"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/reward"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/chain/vm"
 	"github.com/EpiK-Protocol/go-epik/conformance"
@@ -231,6 +232,15 @@ func doExtractMessage(opts extractOpts) error {
 		log.Println(color.YellowString("skipping receipts comparison; we got back a nil receipt from lotus"))
 	}
 
+	if opts.statediff {
+		addrs := append(statediff.TraceActors(applyret.ExecutionTrace), reward.Address, builtin.BurntFundsActorAddr)
+		diffs, err := statediff.DiffActors(ctx, pst.Blockstore, preroot, postroot, execTs.Height(), addrs...)
+		if err != nil {
+			return fmt.Errorf("failed to statediff: %w", err)
+		}
+		log.Print(statediff.String(diffs))
+	}
+
 	log.Println("generating vector")
 	msgBytes, err := msg.Serialize()
 	if err != nil {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/filecoin-project/test-vectors/schema"
+	"github.com/urfave/cli/v2"
+
+	"github.com/EpiK-Protocol/go-epik/conformance/builders"
+)
+
+var generateFlags struct {
+	out   string
+	match string
+}
+
+var generateCmd = &cli.Command{
+	Name: "generate",
+	Description: "generate message-class test vectors for the EpiK actors (experts, votes, " +
+		"retrieval pledges, expert fund and govern) on top of a genesis state, without a network",
+	Action: runGenerate,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "out",
+			Aliases:     []string{"o"},
+			Usage:       "directory to write the test vectors to",
+			TakesFile:   true,
+			Required:    true,
+			Destination: &generateFlags.out,
+		},
+		&cli.StringFlag{
+			Name:        "match",
+			Usage:       "only generate the vectors whose id matches this glob pattern",
+			Value:       "*",
+			Destination: &generateFlags.match,
+		},
+	},
+}
+
+func runGenerate(_ *cli.Context) error {
+	ctx := context.Background()
+
+	var vectors []*schema.TestVector
+	for _, g := range builders.EpikGenerators {
+		ok, err := filepath.Match(generateFlags.match, g.ID)
+		if err != nil {
+			return fmt.Errorf("invalid match pattern: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		vector, err := g.Generate(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate vector %s: %w", g.ID, err)
+		}
+
+		for i, r := range vector.Post.Receipts {
+			log.Printf("%s: message %d exited with %d", g.ID, i, r.ExitCode)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	if len(vectors) == 0 {
+		return fmt.Errorf("no generator matches %q", generateFlags.match)
+	}
+
+	if err := writeVectors(generateFlags.out, vectors...); err != nil {
+		return err
+	}
+
+	log.Printf(color.GreenString("generated %d vectors in %s"), len(vectors), generateFlags.out)
+	return nil
+}
@@ -36,7 +36,7 @@ var repoFlag = cli.StringFlag{
 func main() {
 	app := &cli.App{
 		Name: "tvx",
-		Description: `tvx is a tool for extracting and executing test vectors. It has four subcommands.
+		Description: `tvx is a tool for extracting and executing test vectors. It has five subcommands.
 
    tvx extract extracts a test vector from a live network. It requires access to
    a Filecoin client that exposes the standard JSON-RPC API endpoint. Only
@@ -52,6 +52,9 @@ func main() {
    epoch, reporting the result on stderr and writing a test vector on stdout
    or into the specified file.
 
+   tvx generate builds message class test vectors for the EpiK actors on top
+   of a genesis state, without a network.
+
    SETTING THE JSON-RPC API ENDPOINT
 
    You can set the JSON-RPC API endpoint through one of the following methods.
@@ -75,6 +78,7 @@ func main() {
 			execCmd,
 			extractManyCmd,
 			simulateCmd,
+			generateCmd,
 		},
 	}
 
@@ -8,14 +8,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os/exec"
 
 	"github.com/fatih/color"
 	"github.com/filecoin-project/go-state-types/abi"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
 	"github.com/filecoin-project/test-vectors/schema"
 	"github.com/urfave/cli/v2"
 
 	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/conformance"
 )
@@ -212,24 +213,14 @@ func runSimulateCmd(_ *cli.Context) error {
 		return nil
 	}
 
-	if simulateFlags.out == "" {
-		log.Print("omitting statediff in non-file mode")
-		return nil
-	}
-
-	// check if statediff is installed; if not, skip.
-	if err := exec.Command("statediff", "--help").Run(); err != nil {
-		log.Printf("could not perform statediff on generated vector; command not found (%s)", err)
-		log.Printf("install statediff with:")
-		log.Printf("$ GOMODULE111=off go get github.com/filecoin-project/statediff/cmd/statediff")
-		return err
-	}
-
-	stdiff, err := exec.Command("statediff", "vector", "--file", simulateFlags.out).CombinedOutput()
+	// only diff the actors touched by the message, the trees are fetched
+	// through the API.
+	addrs := append(statediff.TraceActors(applyret.ExecutionTrace), builtin2.BurntFundsActorAddr, builtin2.RewardActorAddr)
+	diffs, err := statediff.DiffActors(ctx, stores.Blockstore, preroot, postroot, epoch, addrs...)
 	if err != nil {
 		return fmt.Errorf("failed to statediff: %w", err)
 	}
 
-	log.Print(string(stdiff))
+	log.Print(statediff.String(diffs))
 	return nil
 }
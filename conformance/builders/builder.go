@@ -0,0 +1,365 @@
+package builders
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipld/go-car"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/test-vectors/schema"
+
+	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/chain/actors"
+	"github.com/EpiK-Protocol/go-epik/chain/gen"
+	genesis2 "github.com/EpiK-Protocol/go-epik/chain/gen/genesis"
+	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/vm"
+	"github.com/EpiK-Protocol/go-epik/conformance"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/ffiwrapper"
+	"github.com/EpiK-Protocol/go-epik/genesis"
+	"github.com/EpiK-Protocol/go-epik/journal"
+	"github.com/EpiK-Protocol/go-epik/storage/mockstorage"
+)
+
+// Codename is the protocol codename set on generated vectors, which are all
+// applied on top of a genesis state.
+const Codename = "genesis"
+
+var (
+	// DefaultAccountBalance is the balance of every template account created by
+	// NewTemplate.
+	DefaultAccountBalance = types.FromEpk(100_000)
+
+	// DefaultGasLimit is the gas limit of the messages applied by a builder.
+	DefaultGasLimit = build.BlockGasLimit / 10
+)
+
+// NewAccountAddress returns a deterministic secp256k1 address for name. The
+// conformance driver doesn't verify signatures, so no key is needed.
+func NewAccountAddress(name string) address.Address {
+	addr, err := address.NewSecp256k1Address([]byte("conformance/" + name))
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+var (
+	// MinerWorker owns the genesis miner and is its worker.
+	MinerWorker = NewAccountAddress("miner")
+
+	// GenesisMiner is the miner created at genesis, after the genesis expert.
+	GenesisMiner = genesis2.MinerAddress(1)
+
+	// minerBalance pays the retrieval and mining pledges of the genesis miner.
+	minerBalance = types.FromEpk(20_000_000)
+)
+
+// NewTemplate returns a genesis template with the default singleton actors,
+// GenesisMiner with one presealed sector, and an account funded with
+// DefaultAccountBalance for each of the supplied owners.
+func NewTemplate(owners ...address.Address) (genesis.Template, error) {
+	// genesis doesn't check signatures, so the random key of the preseal is
+	// replaced by MinerWorker to keep the genesis state deterministic
+	genm, _, err := mockstorage.PreSeal(abi.RegisteredSealProof_StackedDrg2KiBV1, GenesisMiner, 1)
+	if err != nil {
+		return genesis.Template{}, xerrors.Errorf("presealing genesis miner: %w", err)
+	}
+	genm.Owner, genm.Worker, genm.Coinbase = MinerWorker, MinerWorker, MinerWorker
+	for _, ps := range genm.Sectors {
+		ps.Deal.Client = MinerWorker
+	}
+
+	tpl := genesis.Template{
+		Accounts: []genesis.Actor{{
+			Type:    genesis.TAccount,
+			Balance: minerBalance,
+			Meta:    (&genesis.AccountMeta{Owner: MinerWorker}).ActorMeta(),
+		}},
+		Miners:      []genesis.Miner{*genm},
+		NetworkName: "conformance",
+
+		TeamAccountActor:        gen.DefaultTeamAccountActor,
+		FoundationAccountActor:  gen.DefaultFoundationAccountActor,
+		InvestorAccountActor:    gen.DefaultInvestorAccountActor,
+		DefaultGovernorActor:    gen.DefaultGovernorActor,
+		DefaultExpertActor:      gen.DefaultExpertActor,
+		DefaultKgFundPayeeActor: gen.DefaultKgFundPayeeActor,
+	}
+
+	for _, owner := range owners {
+		tpl.Accounts = append(tpl.Accounts, genesis.Actor{
+			Type:    genesis.TAccount,
+			Balance: DefaultAccountBalance,
+			Meta:    (&genesis.AccountMeta{Owner: owner}).ActorMeta(),
+		})
+	}
+
+	return tpl, nil
+}
+
+// MessageVectorBuilder builds a message-class test vector by applying messages
+// through the conformance driver on top of the state of a genesis template,
+// without any network access. Receipts and the post state root are recorded
+// as the vector postconditions.
+//
+// The first error encountered is kept and returned by Finish; messages applied
+// after an error are ignored.
+type MessageVectorBuilder struct {
+	ctx    context.Context
+	bs     blockstore.Blockstore
+	driver *conformance.Driver
+
+	preroot cid.Cid
+	root    cid.Cid
+	epoch   abi.ChainEpoch
+	// offset is the epoch offset of the next applied message
+	offset int64
+
+	nonces   map[address.Address]uint64
+	messages []schema.Message
+	receipts []*schema.Receipt
+
+	err error
+}
+
+// NewMessageVectorBuilder creates the genesis state of tpl and returns a
+// builder applying messages on top of it, starting at epoch 1.
+func NewMessageVectorBuilder(ctx context.Context, tpl genesis.Template) (*MessageVectorBuilder, error) {
+	bs := blockstore.NewMemorySync()
+
+	b, err := genesis2.MakeGenesisBlock(ctx, journal.NilJournal(), bs, vm.Syscalls(ffiwrapper.ProofVerifier), tpl)
+	if err != nil {
+		return nil, xerrors.Errorf("make genesis block: %w", err)
+	}
+
+	return &MessageVectorBuilder{
+		ctx:    ctx,
+		bs:     bs,
+		driver: conformance.NewDriver(ctx, schema.Selector{}, conformance.DriverOpts{DisableVMFlush: true}),
+
+		preroot: b.Genesis.ParentStateRoot,
+		root:    b.Genesis.ParentStateRoot,
+		epoch:   1,
+
+		nonces: make(map[address.Address]uint64),
+	}, nil
+}
+
+// Advance moves the epoch of the next applied message forward.
+func (b *MessageVectorBuilder) Advance(epochs abi.ChainEpoch) {
+	b.offset += int64(epochs)
+}
+
+// Epoch returns the epoch at which the next message is applied.
+func (b *MessageVectorBuilder) Epoch() abi.ChainEpoch {
+	return b.epoch + abi.ChainEpoch(b.offset)
+}
+
+// Send applies a message from one actor to another, encoding params if not
+// nil. See Apply.
+func (b *MessageVectorBuilder) Send(from, to address.Address, value abi.TokenAmount, method abi.MethodNum, params cbg.CBORMarshaler) *vm.ApplyRet {
+	var enc []byte
+	if params != nil {
+		var aerr error
+		if enc, aerr = actors.SerializeParams(params); aerr != nil {
+			b.fail(xerrors.Errorf("serializing params of method %d: %w", method, aerr))
+			return nil
+		}
+	}
+
+	return b.Apply(&types.Message{
+		From:   from,
+		To:     to,
+		Value:  value,
+		Method: method,
+		Params: enc,
+	})
+}
+
+// Apply sets the nonce and gas of msg, applies it and records it along with
+// its receipt. Failed messages are recorded as well, the returned receipt
+// holds their exit code. It returns nil if the message couldn't be applied.
+func (b *MessageVectorBuilder) Apply(msg *types.Message) *vm.ApplyRet {
+	if b.err != nil {
+		return nil
+	}
+
+	msg.Version = 0
+	msg.Nonce = b.nonces[msg.From]
+	msg.GasLimit = DefaultGasLimit
+	msg.GasFeeCap = conformance.DefaultBaseFee
+	msg.GasPremium = big.Zero()
+	if msg.Value.Nil() {
+		msg.Value = big.Zero()
+	}
+
+	msgb, err := msg.Serialize()
+	if err != nil {
+		b.fail(xerrors.Errorf("serializing message: %w", err))
+		return nil
+	}
+
+	ret, root, err := b.driver.ExecuteMessage(b.bs, conformance.ExecuteMessageParams{
+		Preroot:    b.root,
+		Epoch:      b.Epoch(),
+		Message:    msg,
+		BaseFee:    conformance.DefaultBaseFee,
+		CircSupply: conformance.DefaultCirculatingSupply,
+	})
+	if err != nil {
+		b.fail(xerrors.Errorf("applying message %d: %w", len(b.messages), err))
+		return nil
+	}
+
+	m := schema.Message{Bytes: msgb}
+	if b.offset != 0 {
+		offset := b.offset
+		m.EpochOffset = &offset
+		b.epoch += abi.ChainEpoch(b.offset)
+		b.offset = 0
+	}
+
+	b.root = root
+	b.nonces[msg.From]++
+	b.messages = append(b.messages, m)
+	b.receipts = append(b.receipts, &schema.Receipt{
+		ExitCode:    int64(ret.ExitCode),
+		ReturnValue: ret.Return,
+		GasUsed:     ret.GasUsed,
+	})
+	return ret
+}
+
+// Decode unmarshals the return value of a successful message into out.
+func (b *MessageVectorBuilder) Decode(ret *vm.ApplyRet, out cbg.CBORUnmarshaler) {
+	if b.err != nil {
+		return
+	}
+	if ret == nil || !ret.ExitCode.IsSuccess() {
+		b.fail(xerrors.Errorf("can't decode the return of a failed message"))
+		return
+	}
+	if err := out.UnmarshalCBOR(bytes.NewReader(ret.Return)); err != nil {
+		b.fail(xerrors.Errorf("unmarshaling return value: %w", err))
+	}
+}
+
+// LookupID returns the ID address of addr in the current state.
+func (b *MessageVectorBuilder) LookupID(addr address.Address) address.Address {
+	if b.err != nil {
+		return address.Undef
+	}
+
+	st, err := state.LoadStateTree(cbor.NewCborStore(b.bs), b.root)
+	if err != nil {
+		b.fail(xerrors.Errorf("loading state tree: %w", err))
+		return address.Undef
+	}
+
+	ida, err := st.LookupID(addr)
+	if err != nil {
+		b.fail(xerrors.Errorf("looking up id of %s: %w", addr, err))
+		return address.Undef
+	}
+	return ida
+}
+
+// ExpectExitCodes fails the vector unless the messages applied so far exited
+// with the expected codes, in order.
+func (b *MessageVectorBuilder) ExpectExitCodes(expected []exitcode.ExitCode) {
+	if b.err != nil {
+		return
+	}
+	if len(expected) != len(b.receipts) {
+		b.fail(xerrors.Errorf("expected %d exit codes, applied %d messages", len(expected), len(b.receipts)))
+		return
+	}
+	for i, r := range b.receipts {
+		if exitcode.ExitCode(r.ExitCode) != expected[i] {
+			b.fail(xerrors.Errorf("message %d exited with %d, expected %d", i, r.ExitCode, expected[i]))
+			return
+		}
+	}
+}
+
+func (b *MessageVectorBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Finish returns the test vector, with a CAR holding the pre and post states.
+func (b *MessageVectorBuilder) Finish(id, comment string) (*schema.TestVector, error) {
+	if b.err != nil {
+		return nil, xerrors.Errorf("building vector %s: %w", id, b.err)
+	}
+	if len(b.messages) == 0 {
+		return nil, xerrors.Errorf("building vector %s: no message applied", id)
+	}
+
+	out := new(bytes.Buffer)
+	gw := gzip.NewWriter(out)
+	dags := merkledag.NewDAGService(blockservice.New(b.bs, offline.Exchange(b.bs)))
+	if err := car.WriteCar(b.ctx, dags, []cid.Cid{b.preroot, b.root}, gw); err != nil {
+		return nil, xerrors.Errorf("writing vector car: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	// the first message is applied at the variant epoch
+	epoch := int64(1)
+	if off := b.messages[0].EpochOffset; off != nil {
+		epoch += *off
+		b.messages[0].EpochOffset = nil
+	}
+
+	nv := stmgr.NewStateManager(nil).GetNtwkVersion(b.ctx, abi.ChainEpoch(epoch))
+
+	return &schema.TestVector{
+		Class: schema.ClassMessage,
+		Meta: &schema.Metadata{
+			ID:      id,
+			Comment: comment,
+			Gen: []schema.GenerationData{
+				{Source: "github.com/EpiK-Protocol/go-epik", Version: build.UserVersion()}},
+		},
+		Selector: schema.Selector{
+			schema.SelectorMinProtocolVersion: Codename,
+		},
+		CAR: out.Bytes(),
+		Pre: &schema.Preconditions{
+			Variants: []schema.Variant{
+				{ID: Codename, Epoch: epoch, NetworkVersion: uint(nv)},
+			},
+			CircSupply: conformance.DefaultCirculatingSupply.Int,
+			BaseFee:    conformance.DefaultBaseFee.Int,
+			StateTree: &schema.StateTree{
+				RootCID: b.preroot,
+			},
+		},
+		ApplyMessages: b.messages,
+		Post: &schema.Postconditions{
+			StateTree: &schema.StateTree{
+				RootCID: b.root,
+			},
+			Receipts: b.receipts,
+		},
+	}, nil
+}
@@ -0,0 +1,446 @@
+package builders
+
+import (
+	"context"
+	"encoding/json"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/test-vectors/schema"
+
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	expert2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/expert"
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/expertfund"
+	gov2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/govern"
+	retrieval2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/retrieval"
+	vote2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/vote"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/multisig"
+	genesis2 "github.com/EpiK-Protocol/go-epik/chain/gen/genesis"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/genesis"
+)
+
+// Accounts funded at genesis in the EpiK vectors.
+var (
+	Owner    = NewAccountAddress("owner")
+	Voter    = NewAccountAddress("voter")
+	Pledger  = NewAccountAddress("pledger")
+	Stranger = NewAccountAddress("stranger")
+)
+
+var (
+	// DefaultExpert is the expert created at genesis.
+	DefaultExpert = mustIDAddress(genesis2.MinerStart)
+
+	// unknownActor is an ID address no actor is created at.
+	unknownActor = mustIDAddress(9999)
+)
+
+// Generator builds one message-class test vector.
+type Generator struct {
+	ID      string
+	Comment string
+	// Exits are the expected exit codes of the messages applied by Build, in
+	// order
+	Exits []exitcode.ExitCode
+	Build func(b *MessageVectorBuilder, tpl *genesis.Template)
+}
+
+// Generate builds the vector on top of the genesis state of a template
+// funding Owner, Voter, Pledger and Stranger.
+func (g Generator) Generate(ctx context.Context) (*schema.TestVector, error) {
+	tpl, err := NewTemplate(Owner, Voter, Pledger, Stranger)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := NewMessageVectorBuilder(ctx, tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Build(b, &tpl)
+	b.ExpectExitCodes(g.Exits)
+	return b.Finish(g.ID, g.Comment)
+}
+
+// EpikGenerators cover the messages of the expert, expert fund, vote fund,
+// retrieval fund and govern actors, including failing ones.
+var EpikGenerators = []Generator{
+	// expert registration and data import
+	{
+		ID:      "epik-expert-apply",
+		Comment: "apply for an expert, paying the apply cost",
+		Exits:   []exitcode.ExitCode{exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			applyForExpert(b, Owner)
+		},
+	},
+	{
+		ID:      "epik-expert-apply-without-cost",
+		Comment: "apply for an expert without paying the apply cost, fails",
+		Exits:   []exitcode.ExitCode{exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			b.Send(Owner, builtin2.ExpertFundActorAddr, big.Zero(), builtin2.MethodsExpertFunds.ApplyForExpert,
+				&expertfund.ApplyForExpertParams{Owner: Owner})
+		},
+	},
+	{
+		ID:      "epik-expert-import-data",
+		Comment: "import data into an expert, then import the same data again which fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			expert := applyForExpert(b, Owner)
+			importData(b, Owner, expert, 0, 1)
+			importData(b, Owner, expert, 1)
+		},
+	},
+	{
+		ID:      "epik-expert-import-data-not-owner",
+		Comment: "import data into an expert from another account, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.SysErrForbidden},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			expert := applyForExpert(b, Owner)
+			importData(b, Stranger, expert, 0)
+		},
+	},
+
+	// nomination
+	{
+		ID:      "epik-expert-nominate",
+		Comment: "nominate a new expert by the owner of the genesis expert",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, tpl *genesis.Template) {
+			owner := fund(b, accountOwner(b, tpl.DefaultExpertActor))
+			expert := applyForExpert(b, Owner)
+			b.Send(owner, DefaultExpert, big.Zero(), builtin2.MethodsExpert.Nominate, &expert)
+		},
+	},
+	{
+		ID:      "epik-expert-nominate-not-owner",
+		Comment: "nominate a new expert from an account which doesn't own the nominating expert, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.SysErrForbidden},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			expert := applyForExpert(b, Owner)
+			b.Send(Stranger, DefaultExpert, big.Zero(), builtin2.MethodsExpert.Nominate, &expert)
+		},
+	},
+
+	// votes
+	{
+		ID:      "epik-vote-send",
+		Comment: "vote for the genesis expert",
+		Exits:   []exitcode.ExitCode{exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, DefaultExpert, types.FromEpk(100))
+		},
+	},
+	{
+		ID:      "epik-vote-send-unknown-expert",
+		Comment: "vote for an expert which doesn't exist, fails",
+		Exits:   []exitcode.ExitCode{exitcode.SysErrInvalidReceiver},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, unknownActor, types.FromEpk(100))
+		},
+	},
+	{
+		ID:      "epik-vote-rescind",
+		Comment: "vote for the genesis expert, then rescind half of the votes",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, DefaultExpert, types.FromEpk(100))
+			rescind(b, Voter, DefaultExpert, types.FromEpk(50))
+		},
+	},
+	{
+		ID:      "epik-vote-rescind-too-many",
+		Comment: "rescind more votes than were cast, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, DefaultExpert, types.FromEpk(10))
+			rescind(b, Voter, DefaultExpert, types.FromEpk(20))
+		},
+	},
+	{
+		ID:      "epik-vote-withdraw",
+		Comment: "rescind all votes, then withdraw them once unlocked",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, DefaultExpert, types.FromEpk(100))
+			rescind(b, Voter, DefaultExpert, types.FromEpk(100))
+			b.Advance(vote2.RescindingUnlockDelay + 1)
+			b.Send(Voter, builtin2.VoteFundActorAddr, big.Zero(), builtin2.MethodsVote.Withdraw, nil)
+		},
+	},
+	{
+		ID:      "epik-vote-withdraw-locked",
+		Comment: "rescind all votes, then withdraw them before they are unlocked",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			vote(b, Voter, DefaultExpert, types.FromEpk(100))
+			rescind(b, Voter, DefaultExpert, types.FromEpk(100))
+			b.Send(Voter, builtin2.VoteFundActorAddr, big.Zero(), builtin2.MethodsVote.Withdraw, nil)
+		},
+	},
+
+	// retrieval pledges
+	{
+		ID:      "epik-retrieval-pledge",
+		Comment: "pledge to the retrieval fund for the pledger and another account",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			pledge(b, Pledger, Stranger, types.FromEpk(10))
+		},
+	},
+	{
+		ID:      "epik-retrieval-pledge-zero",
+		Comment: "pledge nothing to the retrieval fund, fails",
+		Exits:   []exitcode.ExitCode{exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, big.Zero())
+		},
+	},
+	{
+		ID:      "epik-retrieval-bind",
+		Comment: "bind a pledge to the genesis miner",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			b.Send(Pledger, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.BindMiners,
+				&retrieval2.BindMinersParams{Pledger: Pledger, Miners: []address.Address{GenesisMiner}})
+		},
+	},
+	{
+		ID:      "epik-retrieval-bind-unknown-miner",
+		Comment: "bind a pledge to a miner which doesn't exist, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			b.Send(Pledger, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.BindMiners,
+				&retrieval2.BindMinersParams{Pledger: Pledger, Miners: []address.Address{unknownActor}})
+		},
+	},
+	{
+		ID:      "epik-retrieval-apply-withdraw",
+		Comment: "apply for withdrawing half of a pledge",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			applyForWithdraw(b, Pledger, Pledger, types.FromEpk(50))
+		},
+	},
+	{
+		ID:      "epik-retrieval-withdraw",
+		Comment: "withdraw half of a pledge once the lock period is over",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			applyForWithdraw(b, Pledger, Pledger, types.FromEpk(50))
+			b.Advance(retrieval2.RetrievalLockPeriod + 1)
+			withdraw(b, Pledger, types.FromEpk(50))
+		},
+	},
+	{
+		ID:      "epik-retrieval-withdraw-without-apply",
+		Comment: "withdraw a pledge without applying for it first, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			pledge(b, Pledger, Pledger, types.FromEpk(100))
+			withdraw(b, Pledger, types.FromEpk(50))
+		},
+	},
+
+	// expert fund claims
+	{
+		ID: "epik-expertfund-claim",
+		Comment: "fund the expert fund, whose only share is the genesis data stored by the genesis miner, " +
+			"settle the rewards of the genesis expert by importing data, then claim them once vested",
+		Exits: []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, tpl *genesis.Template) {
+			owner := fund(b, accountOwner(b, tpl.DefaultExpertActor))
+			b.Send(Stranger, builtin2.ExpertFundActorAddr, types.FromEpk(1_000), builtin2.MethodSend, nil)
+			importData(b, owner, DefaultExpert, 7)
+			b.Advance(expertfund.RewardVestingDelay + 1)
+			claim(b, owner, DefaultExpert, types.FromEpk(1))
+		},
+	},
+	{
+		ID:      "epik-expertfund-claim-without-rewards",
+		Comment: "claim rewards of the genesis expert before any is vested, fails",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.ErrIllegalArgument},
+		Build: func(b *MessageVectorBuilder, tpl *genesis.Template) {
+			owner := fund(b, accountOwner(b, tpl.DefaultExpertActor))
+			claim(b, owner, DefaultExpert, types.FromEpk(1))
+		},
+	},
+	{
+		ID:      "epik-expertfund-claim-not-owner",
+		Comment: "claim rewards of the genesis expert from another account, fails",
+		Exits:   []exitcode.ExitCode{exitcode.SysErrForbidden},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			claim(b, Stranger, DefaultExpert, types.FromEpk(1))
+		},
+	},
+
+	// govern authorities
+	{
+		ID:      "epik-govern-grant-revoke",
+		Comment: "grant all authorities to an account through the supervisor multisig, then revoke them",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, tpl *genesis.Template) {
+			signer := fund(b, multisigSigner(b, tpl.FoundationAccountActor))
+			params := &gov2.GrantOrRevokeParams{Governor: b.LookupID(Stranger), All: true}
+			supervise(b, signer, builtin2.MethodsGovern.Grant, params)
+			supervise(b, signer, builtin2.MethodsGovern.Revoke, params)
+		},
+	},
+	{
+		ID:      "epik-govern-grant-not-supervisor",
+		Comment: "grant authorities from an account which isn't the supervisor, fails",
+		Exits:   []exitcode.ExitCode{exitcode.SysErrForbidden},
+		Build: func(b *MessageVectorBuilder, _ *genesis.Template) {
+			b.Send(Stranger, builtin2.GovernActorAddr, big.Zero(), builtin2.MethodsGovern.Grant,
+				&gov2.GrantOrRevokeParams{Governor: b.LookupID(Stranger), All: true})
+		},
+	},
+	{
+		ID:      "epik-govern-authority-enforced",
+		Comment: "change the expert fund thresholds without authority, which fails, then with a granted authority",
+		Exits:   []exitcode.ExitCode{exitcode.Ok, exitcode.SysErrForbidden, exitcode.Ok, exitcode.Ok},
+		Build: func(b *MessageVectorBuilder, tpl *genesis.Template) {
+			signer := fund(b, multisigSigner(b, tpl.FoundationAccountActor))
+			thresholds := &expertfund.ChangeThresholdParams{DataStoreThreshold: 1}
+
+			b.Send(Stranger, builtin2.ExpertFundActorAddr, big.Zero(), builtin2.MethodsExpertFunds.ChangeThreshold, thresholds)
+			supervise(b, signer, builtin2.MethodsGovern.Grant, &gov2.GrantOrRevokeParams{
+				Governor: b.LookupID(Stranger),
+				Authorities: []gov2.Authority{{
+					ActorCodeID: builtin2.ExpertFundActorCodeID,
+					Methods:     []abi.MethodNum{builtin2.MethodsExpertFunds.ChangeThreshold},
+				}},
+			})
+			b.Send(Stranger, builtin2.ExpertFundActorAddr, big.Zero(), builtin2.MethodsExpertFunds.ChangeThreshold, thresholds)
+		},
+	},
+}
+
+// applyForExpert creates an expert owned by owner and returns its ID address.
+func applyForExpert(b *MessageVectorBuilder, owner address.Address) address.Address {
+	ret := b.Send(owner, builtin2.ExpertFundActorAddr, expert2.ExpertApplyCost, builtin2.MethodsExpertFunds.ApplyForExpert,
+		&expertfund.ApplyForExpertParams{Owner: owner})
+
+	var out expertfund.ApplyForExpertReturn
+	b.Decode(ret, &out)
+	return out.IDAddress
+}
+
+// importData imports the pieces with the given seeds into expert.
+func importData(b *MessageVectorBuilder, from, expert address.Address, seeds ...byte) {
+	params := &expert2.BatchImportDataParams{}
+	for _, seed := range seeds {
+		commP := make([]byte, 32)
+		commP[0] = seed + 1
+		piece, err := commcid.PieceCommitmentV1ToCID(commP)
+		if err != nil {
+			b.fail(xerrors.Errorf("making piece cid: %w", err))
+			return
+		}
+		params.Datas = append(params.Datas, expert2.ImportDataParams{
+			RootID:    piece,
+			PieceID:   piece,
+			PieceSize: abi.PaddedPieceSize(2048),
+		})
+	}
+	b.Send(from, expert, big.Zero(), builtin2.MethodsExpert.ImportData, params)
+}
+
+func vote(b *MessageVectorBuilder, voter, expert address.Address, amount abi.TokenAmount) {
+	b.Send(voter, builtin2.VoteFundActorAddr, amount, builtin2.MethodsVote.Vote, &expert)
+}
+
+func rescind(b *MessageVectorBuilder, voter, expert address.Address, amount abi.TokenAmount) {
+	b.Send(voter, builtin2.VoteFundActorAddr, big.Zero(), builtin2.MethodsVote.Rescind,
+		&vote2.RescindParams{Candidate: expert, Votes: amount})
+}
+
+func pledge(b *MessageVectorBuilder, pledger, target address.Address, amount abi.TokenAmount) {
+	b.Send(pledger, builtin2.RetrievalFundActorAddr, amount, builtin2.MethodsRetrieval.Pledge,
+		&retrieval2.PledgeParams{Address: target})
+}
+
+func applyForWithdraw(b *MessageVectorBuilder, pledger, target address.Address, amount abi.TokenAmount) {
+	b.Send(pledger, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.ApplyForWithdraw,
+		&retrieval2.WithdrawBalanceParams{Target: target, Amount: amount})
+}
+
+func withdraw(b *MessageVectorBuilder, pledger address.Address, amount abi.TokenAmount) {
+	b.Send(pledger, builtin2.RetrievalFundActorAddr, big.Zero(), builtin2.MethodsRetrieval.WithdrawBalance, &amount)
+}
+
+func claim(b *MessageVectorBuilder, from, expert address.Address, amount abi.TokenAmount) {
+	b.Send(from, builtin2.ExpertFundActorAddr, big.Zero(), builtin2.MethodsExpertFunds.Claim,
+		&expertfund.ClaimFundParams{Expert: expert, Amount: amount})
+}
+
+// supervise proposes a govern message through the foundation multisig, the
+// govern supervisor, from one of its signers. The multisigs of the default
+// templates have a threshold of 1, so the proposal is executed right away.
+func supervise(b *MessageVectorBuilder, signer address.Address, method abi.MethodNum, params *gov2.GrantOrRevokeParams) {
+	enc, aerr := actors.SerializeParams(params)
+	if aerr != nil {
+		b.fail(xerrors.Errorf("serializing govern params: %w", aerr))
+		return
+	}
+
+	msg, err := multisig.Message(actors.Version3, signer).Propose(builtin.FoundationIDAddress, builtin2.GovernActorAddr, big.Zero(), method, enc)
+	if err != nil {
+		b.fail(xerrors.Errorf("making propose message: %w", err))
+		return
+	}
+	b.Apply(msg)
+}
+
+// fund transfers some funds to addr from Stranger, to pay for the gas of the
+// messages sent by genesis accounts created without balance.
+func fund(b *MessageVectorBuilder, addr address.Address) address.Address {
+	b.Send(Stranger, addr, types.FromEpk(1_000), builtin2.MethodSend, nil)
+	return addr
+}
+
+func accountOwner(b *MessageVectorBuilder, act genesis.Actor) address.Address {
+	var meta genesis.AccountMeta
+	if err := json.Unmarshal(act.Meta, &meta); err != nil {
+		b.fail(xerrors.Errorf("unmarshaling account meta: %w", err))
+	}
+	return meta.Owner
+}
+
+func multisigSigner(b *MessageVectorBuilder, act genesis.Actor) address.Address {
+	var meta genesis.MultisigMeta
+	if err := json.Unmarshal(act.Meta, &meta); err != nil {
+		b.fail(xerrors.Errorf("unmarshaling multisig meta: %w", err))
+		return address.Undef
+	}
+	if len(meta.Signers) == 0 {
+		b.fail(xerrors.Errorf("multisig without signers"))
+		return address.Undef
+	}
+	return meta.Signers[0]
+}
+
+func mustIDAddress(id uint64) address.Address {
+	addr, err := address.NewIDAddress(id)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
@@ -0,0 +1,47 @@
+package builders
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/exitcode"
+
+	"github.com/EpiK-Protocol/go-epik/conformance"
+)
+
+func TestEpikGenerators(t *testing.T) {
+	ctx := context.Background()
+
+	ids := make(map[string]struct{})
+	for _, g := range EpikGenerators {
+		g := g
+		if _, ok := ids[g.ID]; ok {
+			t.Fatalf("duplicate generator id %s", g.ID)
+		}
+		ids[g.ID] = struct{}{}
+
+		t.Run(g.ID, func(t *testing.T) {
+			vector, err := g.Generate(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(vector.Post.Receipts) != len(g.Exits) {
+				t.Fatalf("expected %d receipts, got %d", len(g.Exits), len(vector.Post.Receipts))
+			}
+			for i, r := range vector.Post.Receipts {
+				if exitcode.ExitCode(r.ExitCode) != g.Exits[i] {
+					t.Fatalf("message %d exited with %d, expected %d", i, r.ExitCode, g.Exits[i])
+				}
+			}
+
+			// the generated vector must replay to the same receipts and post root.
+			for _, variant := range vector.Pre.Variants {
+				variant := variant
+				if _, err := conformance.ExecuteMessageVector(t, vector, &variant); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
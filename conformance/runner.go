@@ -6,9 +6,6 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
 	"strconv"
 
 	"github.com/fatih/color"
@@ -16,17 +13,14 @@ import (
 	"github.com/filecoin-project/go-state-types/exitcode"
 	"github.com/hashicorp/go-multierror"
 	blocks "github.com/ipfs/go-block-format"
-	"github.com/ipfs/go-blockservice"
 	"github.com/ipfs/go-cid"
 	ds "github.com/ipfs/go-datastore"
-	offline "github.com/ipfs/go-ipfs-exchange-offline"
-	format "github.com/ipfs/go-ipld-format"
-	"github.com/ipfs/go-merkledag"
 	"github.com/ipld/go-car"
 
 	"github.com/filecoin-project/test-vectors/schema"
 
 	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/chain/vm"
 )
@@ -196,28 +190,6 @@ func AssertMsgResult(r Reporter, expected *schema.Receipt, actual *vm.ApplyRet,
 }
 
 func dumpThreeWayStateDiff(r Reporter, vector *schema.TestVector, bs blockstore.Blockstore, actual cid.Cid) []string {
-	// check if statediff exists; if not, skip.
-	if err := exec.Command("statediff", "--help").Run(); err != nil {
-		r.Log("could not dump 3-way state tree diff upon test failure: statediff command not found")
-		r.Log("install statediff with:")
-		r.Log("$ git clone https://github.com/filecoin-project/statediff.git")
-		r.Log("$ cd statediff")
-		r.Log("$ go generate ./...")
-		r.Log("$ go install ./cmd/statediff")
-		return nil
-	}
-
-	tmpCar, err := writeStateToTempCAR(bs,
-		vector.Pre.StateTree.RootCID,
-		vector.Post.StateTree.RootCID,
-		actual,
-	)
-	if err != nil {
-		r.Fatalf("failed to write temporary state CAR: %s", err)
-		return nil
-	}
-	defer os.RemoveAll(tmpCar) //nolint:errcheck
-
 	color.NoColor = false // enable colouring.
 
 	var (
@@ -229,13 +201,18 @@ func dumpThreeWayStateDiff(r Reporter, vector *schema.TestVector, bs blockstore.
 		d3 = color.New(color.FgGreen, color.Bold).Sprint("[Δ3]")
 	)
 
+	var epoch abi.ChainEpoch
+	if len(vector.Pre.Variants) > 0 {
+		epoch = abi.ChainEpoch(vector.Pre.Variants[0].Epoch)
+	}
+
 	diff := func(left, right cid.Cid) string {
-		cmd := exec.Command("statediff", "car", "--file", tmpCar, left.String(), right.String())
-		b, err := cmd.CombinedOutput()
+		diffs, err := statediff.Diff(context.Background(), bs, left, right, epoch)
 		if err != nil {
-			r.Fatalf("statediff failed: %s", err)
+			// vector state trees may be incomplete.
+			return fmt.Sprintf("statediff failed: %s", err)
 		}
-		return string(b)
+		return statediff.String(diffs)
 	}
 
 	bold := color.New(color.Bold).SprintfFunc()
@@ -268,43 +245,6 @@ func dumpThreeWayStateDiff(r Reporter, vector *schema.TestVector, bs blockstore.
 	return []string{diffA, diffB, diffC}
 }
 
-// writeStateToTempCAR writes the provided roots to a temporary CAR that'll be
-// cleaned up via t.Cleanup(). It returns the full path of the temp file.
-func writeStateToTempCAR(bs blockstore.Blockstore, roots ...cid.Cid) (string, error) {
-	tmp, err := ioutil.TempFile("", "epik-tests-*.car")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp file to dump CAR for diffing: %w", err)
-	}
-
-	carWalkFn := func(nd format.Node) (out []*format.Link, err error) {
-		for _, link := range nd.Links() {
-			if link.Cid.Prefix().Codec == cid.FilCommitmentSealed || link.Cid.Prefix().Codec == cid.FilCommitmentUnsealed {
-				continue
-			}
-			// ignore things we don't have, the state tree is incomplete.
-			if has, err := bs.Has(link.Cid); err != nil {
-				return nil, err
-			} else if has {
-				out = append(out, link)
-			}
-		}
-		return out, nil
-	}
-
-	var (
-		offl    = offline.Exchange(bs)
-		blkserv = blockservice.New(bs, offl)
-		dserv   = merkledag.NewDAGService(blkserv)
-	)
-
-	err = car.WriteCarWithWalker(context.Background(), dserv, roots, tmp, carWalkFn)
-	if err != nil {
-		return "", fmt.Errorf("failed to dump CAR for diffing: %w", err)
-	}
-	_ = tmp.Close()
-	return tmp.Name(), nil
-}
-
 func LoadBlockstore(vectorCAR schema.Base64EncodedBytes) (blockstore.Blockstore, error) {
 	bs := blockstore.Blockstore(blockstore.NewMemory())
 
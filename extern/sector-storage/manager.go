@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
@@ -28,6 +29,7 @@ import (
 var log = logging.Logger("advmgr")
 
 var ErrNoWorkers = errors.New("no suitable workers found")
+var ErrSectorInUse = errors.New("sector files are in use")
 
 type URLs []string
 
@@ -98,6 +100,10 @@ type SealerConfig struct {
 	AllowPreCommit2 bool
 	AllowCommit     bool
 	AllowUnseal     bool
+
+	// How many days records of finished sealing jobs are kept in the
+	// job history, 0 disables the history
+	JobHistoryRetentionDays uint64
 }
 
 type StorageAuth http.Header
@@ -105,7 +111,7 @@ type StorageAuth http.Header
 type WorkerStateStore *statestore.StateStore
 type ManagerStateStore *statestore.StateStore
 
-func New(ctx context.Context, ls stores.LocalStorage, si stores.SectorIndex, sc SealerConfig, urls URLs, sa StorageAuth, wss WorkerStateStore, mss ManagerStateStore) (*Manager, error) {
+func New(ctx context.Context, ls stores.LocalStorage, si stores.SectorIndex, sc SealerConfig, urls URLs, sa StorageAuth, wss WorkerStateStore, mss ManagerStateStore, hds JobHistoryStore) (*Manager, error) {
 	lstor, err := stores.NewLocal(ctx, ls, si, urls)
 	if err != nil {
 		return nil, err
@@ -136,6 +142,8 @@ func New(ctx context.Context, ls stores.LocalStorage, si stores.SectorIndex, sc
 		waitRes:    map[WorkID]chan struct{}{},
 	}
 
+	m.sched.workTracker.history = newJobHistory(hds, time.Duration(sc.JobHistoryRetentionDays)*24*time.Hour)
+
 	m.setupWorkTracker()
 
 	go m.sched.runSched()
@@ -608,6 +616,28 @@ func (m *Manager) ReleaseUnsealed(ctx context.Context, sector storage.SectorRef,
 	return nil
 }
 
+// RemoveUnsealed removes all unsealed copies of the sector. ErrSectorInUse
+// is returned without removing anything when the sector files are locked,
+// e.g. by a running retrieval.
+func (m *Manager) RemoveUnsealed(ctx context.Context, sector storage.SectorRef) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	locked, err := m.index.StorageTryLock(ctx, sector.ID, storiface.FTNone, storiface.FTUnsealed)
+	if err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+	if !locked {
+		return ErrSectorInUse
+	}
+
+	if err := m.storage.Remove(ctx, sector.ID, storiface.FTUnsealed, true); err != nil {
+		return xerrors.Errorf("removing sector (unsealed): %w", err)
+	}
+
+	return nil
+}
+
 func (m *Manager) Remove(ctx context.Context, sector storage.SectorRef) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -357,7 +357,7 @@ func (m *Manager) returnResult(ctx context.Context, callID storiface.CallID, r i
 		res.err = cerr
 	}
 
-	m.sched.workTracker.onDone(ctx, callID)
+	m.sched.workTracker.onDone(ctx, callID, cerr)
 
 	m.workLk.Lock()
 	defer m.workLk.Unlock()
@@ -0,0 +1,183 @@
+package sectorstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+)
+
+// JobHistoryStore is the datastore finished job records are persisted in
+type JobHistoryStore datastore.Batching
+
+const jobHistoryPruneInterval = time.Hour
+
+// jobHistory keeps records of finished worker calls, keyed by the time they
+// finished, so that old records can be dropped cheaply
+type jobHistory struct {
+	ds        datastore.Batching
+	retention time.Duration
+
+	lk        sync.Mutex
+	lastPrune time.Time
+}
+
+func newJobHistory(ds datastore.Batching, retention time.Duration) *jobHistory {
+	if ds == nil || retention <= 0 {
+		return nil
+	}
+
+	return &jobHistory{
+		ds:        ds,
+		retention: retention,
+	}
+}
+
+func jobHistoryKey(r storiface.WorkerJobRecord) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("/%020d/%s", r.End.UnixNano(), r.ID.ID))
+}
+
+func (h *jobHistory) record(r storiface.WorkerJobRecord) {
+	if h == nil {
+		return
+	}
+
+	b, err := json.Marshal(&r)
+	if err != nil {
+		log.Errorf("marshaling job history record: %+v", err)
+		return
+	}
+
+	if err := h.ds.Put(jobHistoryKey(r), b); err != nil {
+		log.Errorf("storing job history record: %+v", err)
+		return
+	}
+
+	h.lk.Lock()
+	prune := time.Since(h.lastPrune) > jobHistoryPruneInterval
+	if prune {
+		h.lastPrune = time.Now()
+	}
+	h.lk.Unlock()
+
+	if prune {
+		go func() {
+			if err := h.prune(time.Now().Add(-h.retention)); err != nil {
+				log.Errorf("pruning job history: %+v", err)
+			}
+		}()
+	}
+}
+
+// prune removes all records which finished before the given time
+func (h *jobHistory) prune(before time.Time) error {
+	res, err := h.ds.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return xerrors.Errorf("querying job history: %w", err)
+	}
+	defer res.Close() // nolint
+
+	b, err := h.ds.Batch()
+	if err != nil {
+		return xerrors.Errorf("creating batch: %w", err)
+	}
+
+	var removed int
+	for r := range res.Next() {
+		if r.Error != nil {
+			return xerrors.Errorf("iterating job history: %w", r.Error)
+		}
+
+		end, err := jobHistoryKeyTime(r.Key)
+		if err != nil {
+			log.Warnf("bad job history key %s: %s", r.Key, err)
+			continue
+		}
+		if !end.Before(before) {
+			continue
+		}
+
+		if err := b.Delete(datastore.NewKey(r.Key)); err != nil {
+			return xerrors.Errorf("deleting job history record: %w", err)
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Infow("pruned sealing job history", "removed", removed, "before", before)
+	}
+
+	return b.Commit()
+}
+
+func (h *jobHistory) list(filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error) {
+	if h == nil {
+		return nil, xerrors.Errorf("sealing job history is disabled")
+	}
+
+	res, err := h.ds.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("querying job history: %w", err)
+	}
+	defer res.Close() // nolint
+
+	out := make([]storiface.WorkerJobRecord, 0)
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("iterating job history: %w", r.Error)
+		}
+
+		if !filter.Since.IsZero() {
+			end, err := jobHistoryKeyTime(r.Key)
+			if err == nil && end.Before(filter.Since) {
+				continue
+			}
+		}
+
+		var rec storiface.WorkerJobRecord
+		if err := json.Unmarshal(r.Value, &rec); err != nil {
+			log.Warnf("decoding job history record %s: %s", r.Key, err)
+			continue
+		}
+
+		if filter.Task != "" && rec.Task != filter.Task {
+			continue
+		}
+		if filter.Host != "" && rec.Hostname != filter.Host {
+			continue
+		}
+		if filter.Worker != "" && !strings.HasPrefix(rec.Worker.String(), filter.Worker) {
+			continue
+		}
+
+		out = append(out, rec)
+	}
+
+	return out, nil
+}
+
+func jobHistoryKeyTime(k string) (time.Time, error) {
+	parts := datastore.NewKey(k).List()
+	if len(parts) != 2 {
+		return time.Time{}, xerrors.Errorf("expected 2 key parts, got %d", len(parts))
+	}
+
+	ns, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, ns), nil
+}
+
+func (m *Manager) JobHistory(filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error) {
+	return m.sched.workTracker.history.list(filter)
+}
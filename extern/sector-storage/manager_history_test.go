@@ -0,0 +1,91 @@
+package sectorstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/sealtasks"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+)
+
+func TestJobHistory(t *testing.T) {
+	h := newJobHistory(dssync.MutexWrap(datastore.NewMapDatastore()), time.Hour)
+	h.lastPrune = time.Now() // don't prune in the background
+
+	now := time.Now()
+	w1 := uuid.MustParse("a1b2c3d4-0000-4000-8000-000000000001")
+	w2 := uuid.MustParse("b1b2c3d4-0000-4000-8000-000000000002")
+
+	rec := func(task sealtasks.TaskType, worker uuid.UUID, host string, end time.Time, errStr string) {
+		h.record(storiface.WorkerJobRecord{
+			ID:       storiface.CallID{Sector: abi.SectorID{Miner: 1000, Number: 1}, ID: uuid.New()},
+			Sector:   abi.SectorID{Miner: 1000, Number: 1},
+			Task:     task,
+			Worker:   worker,
+			Hostname: host,
+			Start:    end.Add(-time.Minute),
+			End:      end,
+			Error:    errStr,
+		})
+	}
+
+	rec(sealtasks.TTPreCommit1, w1, "a", now.Add(-3*time.Hour), "")
+	rec(sealtasks.TTPreCommit1, w1, "a", now.Add(-30*time.Minute), "")
+	rec(sealtasks.TTPreCommit2, w2, "b", now.Add(-20*time.Minute), "failed")
+	rec(sealtasks.TTCommit2, w2, "b", now.Add(-10*time.Minute), "")
+
+	all, err := h.list(storiface.WorkerJobFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+
+	recent, err := h.list(storiface.WorkerJobFilter{Since: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+
+	pc1, err := h.list(storiface.WorkerJobFilter{Task: sealtasks.TTPreCommit1})
+	require.NoError(t, err)
+	require.Len(t, pc1, 2)
+
+	byHost, err := h.list(storiface.WorkerJobFilter{Host: "b"})
+	require.NoError(t, err)
+	require.Len(t, byHost, 2)
+
+	// hostnames aren't matched by prefix, nor worker IDs by hostname
+	none, err := h.list(storiface.WorkerJobFilter{Host: "a1"})
+	require.NoError(t, err)
+	require.Empty(t, none)
+
+	byID, err := h.list(storiface.WorkerJobFilter{Worker: "a1"})
+	require.NoError(t, err)
+	require.Len(t, byID, 2)
+	for _, r := range byID {
+		require.Equal(t, w1, r.Worker)
+	}
+
+	both, err := h.list(storiface.WorkerJobFilter{Host: "b", Worker: "a1"})
+	require.NoError(t, err)
+	require.Empty(t, both)
+
+	require.NoError(t, h.prune(now.Add(-time.Hour)))
+
+	all, err = h.list(storiface.WorkerJobFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+}
+
+func TestJobHistoryDisabled(t *testing.T) {
+	h := newJobHistory(nil, time.Hour)
+	require.Nil(t, h)
+
+	h.record(storiface.WorkerJobRecord{}) // nil history must ignore records
+
+	_, err := h.list(storiface.WorkerJobFilter{})
+	require.Error(t, err)
+}
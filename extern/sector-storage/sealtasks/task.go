@@ -1,5 +1,7 @@
 package sealtasks
 
+import "strings"
+
 type TaskType string
 
 const (
@@ -61,3 +63,14 @@ func (a TaskType) Short() string {
 
 	return n
 }
+
+// ParseTaskType accepts either a full task type, or its short name (e.g. PC1)
+func ParseTaskType(s string) (TaskType, bool) {
+	for tt, short := range shortNames {
+		if s == string(tt) || strings.EqualFold(s, short) {
+			return tt, true
+		}
+	}
+
+	return "", false
+}
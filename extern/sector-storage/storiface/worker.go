@@ -64,6 +64,37 @@ type WorkerJob struct {
 	Hostname string `json:",omitempty"` // optional, set for ret-wait jobs
 }
 
+// WorkerJobRecord describes a finished (or failed to start) worker call, as
+// kept in the manager job history
+type WorkerJobRecord struct {
+	ID       CallID
+	Sector   abi.SectorID
+	Task     sealtasks.TaskType
+	Worker   uuid.UUID
+	Hostname string
+
+	Start time.Time
+	End   time.Time
+	Bytes uint64 // amount of sector / piece data the task operated on
+
+	Error string `json:",omitempty"` // empty when the call succeeded
+}
+
+func (r WorkerJobRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// WorkerJobFilter selects records returned from the job history. Zero values
+// match everything.
+type WorkerJobFilter struct {
+	Since time.Time
+	Task  sealtasks.TaskType
+	// Host matches the worker hostname exactly
+	Host string
+	// Worker matches a prefix of the worker UUID
+	Worker string
+}
+
 type CallID struct {
 	Sector abi.SectorID
 	ID     uuid.UUID
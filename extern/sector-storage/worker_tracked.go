@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/ipfs/go-cid"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
@@ -22,6 +23,7 @@ type trackedWork struct {
 	job            storiface.WorkerJob
 	worker         WorkerID
 	workerHostname string
+	bytes          uint64
 }
 
 type workTracker struct {
@@ -30,20 +32,31 @@ type workTracker struct {
 	done    map[storiface.CallID]struct{}
 	running map[storiface.CallID]trackedWork
 
-	// TODO: done, aggregate stats, queue stats, scheduler feedback
+	history *jobHistory // nil when job history is disabled
+
+	// TODO: queue stats, scheduler feedback
 }
 
-func (wt *workTracker) onDone(ctx context.Context, callID storiface.CallID) {
+func (wt *workTracker) onDone(ctx context.Context, callID storiface.CallID, cerr *storiface.CallError) {
 	wt.lk.Lock()
-	defer wt.lk.Unlock()
 
 	t, ok := wt.running[callID]
 	if !ok {
 		wt.done[callID] = struct{}{}
+		wt.lk.Unlock()
 
 		stats.Record(ctx, metrics.WorkerUntrackedCallsReturned.M(1))
 		return
 	}
+	delete(wt.running, callID)
+	wt.lk.Unlock()
+
+	// the history is written to the datastore, don't hold up other calls
+	var errStr string
+	if cerr != nil {
+		errStr = cerr.Error()
+	}
+	wt.history.record(t.record(time.Now(), errStr))
 
 	took := metrics.SinceInMilliseconds(t.job.Start)
 
@@ -53,13 +66,24 @@ func (wt *workTracker) onDone(ctx context.Context, callID storiface.CallID) {
 		tag.Upsert(metrics.WorkerHostname, t.workerHostname),
 	)
 	stats.Record(ctx, metrics.WorkerCallsReturnedCount.M(1), metrics.WorkerCallsReturnedDuration.M(took))
-
-	delete(wt.running, callID)
 }
 
-func (wt *workTracker) track(ctx context.Context, wid WorkerID, wi storiface.WorkerInfo, sid storage.SectorRef, task sealtasks.TaskType) func(storiface.CallID, error) (storiface.CallID, error) {
+func (wt *workTracker) track(ctx context.Context, wid WorkerID, wi storiface.WorkerInfo, sid storage.SectorRef, task sealtasks.TaskType, bytes uint64) func(storiface.CallID, error) (storiface.CallID, error) {
 	return func(callID storiface.CallID, err error) (storiface.CallID, error) {
 		if err != nil {
+			now := time.Now()
+			wt.history.record(trackedWork{
+				job: storiface.WorkerJob{
+					ID:     callID,
+					Sector: sid.ID,
+					Task:   task,
+					Start:  now,
+				},
+				worker:         wid,
+				workerHostname: wi.Hostname,
+				bytes:          bytes,
+			}.record(now, err.Error()))
+
 			return callID, err
 		}
 
@@ -81,6 +105,7 @@ func (wt *workTracker) track(ctx context.Context, wid WorkerID, wi storiface.Wor
 			},
 			worker:         wid,
 			workerHostname: wi.Hostname,
+			bytes:          bytes,
 		}
 
 		ctx, _ = tag.New(
@@ -94,6 +119,20 @@ func (wt *workTracker) track(ctx context.Context, wid WorkerID, wi storiface.Wor
 	}
 }
 
+func (t trackedWork) record(end time.Time, errStr string) storiface.WorkerJobRecord {
+	return storiface.WorkerJobRecord{
+		ID:       t.job.ID,
+		Sector:   t.job.Sector,
+		Task:     t.job.Task,
+		Worker:   uuid.UUID(t.worker),
+		Hostname: t.workerHostname,
+		Start:    t.job.Start,
+		End:      end,
+		Bytes:    t.bytes,
+		Error:    errStr,
+	}
+}
+
 func (wt *workTracker) worker(wid WorkerID, wi storiface.WorkerInfo, w Worker) Worker {
 	return &trackedWorker{
 		Worker:     w,
@@ -125,39 +164,47 @@ type trackedWorker struct {
 }
 
 func (t *trackedWorker) SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTPreCommit1)(t.Worker.SealPreCommit1(ctx, sector, ticket, pieces))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTPreCommit1, sectorBytes(sector))(t.Worker.SealPreCommit1(ctx, sector, ticket, pieces))
 }
 
 func (t *trackedWorker) SealPreCommit2(ctx context.Context, sector storage.SectorRef, pc1o storage.PreCommit1Out) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTPreCommit2)(t.Worker.SealPreCommit2(ctx, sector, pc1o))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTPreCommit2, sectorBytes(sector))(t.Worker.SealPreCommit2(ctx, sector, pc1o))
 }
 
 func (t *trackedWorker) SealCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, seed abi.InteractiveSealRandomness, pieces []abi.PieceInfo, cids storage.SectorCids) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTCommit1)(t.Worker.SealCommit1(ctx, sector, ticket, seed, pieces, cids))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTCommit1, sectorBytes(sector))(t.Worker.SealCommit1(ctx, sector, ticket, seed, pieces, cids))
 }
 
 func (t *trackedWorker) SealCommit2(ctx context.Context, sector storage.SectorRef, c1o storage.Commit1Out) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTCommit2)(t.Worker.SealCommit2(ctx, sector, c1o))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTCommit2, sectorBytes(sector))(t.Worker.SealCommit2(ctx, sector, c1o))
 }
 
 func (t *trackedWorker) FinalizeSector(ctx context.Context, sector storage.SectorRef, keepUnsealed []storage.Range) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTFinalize)(t.Worker.FinalizeSector(ctx, sector, keepUnsealed))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTFinalize, sectorBytes(sector))(t.Worker.FinalizeSector(ctx, sector, keepUnsealed))
 }
 
 func (t *trackedWorker) AddPiece(ctx context.Context, sector storage.SectorRef, pieceSizes []abi.UnpaddedPieceSize, newPieceSize abi.UnpaddedPieceSize, pieceData storage.Data) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTAddPiece)(t.Worker.AddPiece(ctx, sector, pieceSizes, newPieceSize, pieceData))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, sector, sealtasks.TTAddPiece, uint64(newPieceSize.Padded()))(t.Worker.AddPiece(ctx, sector, pieceSizes, newPieceSize, pieceData))
 }
 
 func (t *trackedWorker) Fetch(ctx context.Context, s storage.SectorRef, ft storiface.SectorFileType, ptype storiface.PathType, am storiface.AcquireMode) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, s, sealtasks.TTFetch)(t.Worker.Fetch(ctx, s, ft, ptype, am))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, s, sealtasks.TTFetch, sectorBytes(s))(t.Worker.Fetch(ctx, s, ft, ptype, am))
 }
 
 func (t *trackedWorker) UnsealPiece(ctx context.Context, id storage.SectorRef, index storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, randomness abi.SealRandomness, cid cid.Cid) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, id, sealtasks.TTUnseal)(t.Worker.UnsealPiece(ctx, id, index, size, randomness, cid))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, id, sealtasks.TTUnseal, uint64(size.Padded()))(t.Worker.UnsealPiece(ctx, id, index, size, randomness, cid))
 }
 
 func (t *trackedWorker) ReadPiece(ctx context.Context, writer io.Writer, id storage.SectorRef, index storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (storiface.CallID, error) {
-	return t.tracker.track(ctx, t.wid, t.workerInfo, id, sealtasks.TTReadUnsealed)(t.Worker.ReadPiece(ctx, writer, id, index, size))
+	return t.tracker.track(ctx, t.wid, t.workerInfo, id, sealtasks.TTReadUnsealed, uint64(size.Padded()))(t.Worker.ReadPiece(ctx, writer, id, index, size))
+}
+
+func sectorBytes(sector storage.SectorRef) uint64 {
+	ssize, err := sector.ProofType.SectorSize()
+	if err != nil {
+		return 0
+	}
+	return uint64(ssize)
 }
 
 var _ Worker = &trackedWorker{}
@@ -11,6 +11,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	govern2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/govern"
 	market2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 )
 
@@ -98,6 +99,60 @@ type Actor struct {
 	Meta json.RawMessage
 }
 
+// Expert is an expert created at genesis, in addition to the default expert
+type Expert struct {
+	// Owner must be the owner of a template account
+	Owner address.Address
+	Datas []ExpertData
+}
+
+// ExpertData is imported into an expert at genesis
+type ExpertData struct {
+	RootID    cid.Cid
+	PieceID   cid.Cid
+	PieceSize abi.PaddedPieceSize
+}
+
+// Vote is cast for an expert at genesis
+type Vote struct {
+	// Voter must be the owner of a template account, which pays Amount
+	Voter address.Address
+	// Expert is the owner of a template expert, or an expert ID address
+	Expert address.Address
+	Amount abi.TokenAmount
+}
+
+// RetrievalPledge is pledged to the retrieval fund at genesis
+type RetrievalPledge struct {
+	// Pledger must be the owner of a template account, which pays Amount
+	Pledger address.Address
+	// Target is the address pledged for
+	Target address.Address
+	// Miners are the template miners bound to the pledge
+	Miners []address.Address
+	Amount abi.TokenAmount
+}
+
+// Governor is granted authorities at genesis, in addition to the default
+// governor
+type Governor struct {
+	// Address is the owner of a template account, or an ID address
+	Address address.Address
+	// All grants all authorities, otherwise Authorities are granted
+	All         bool
+	Authorities []govern2.Authority `json:",omitempty"`
+}
+
+// GovParams overrides governed parameters at genesis, zero values keep the
+// defaults
+type GovParams struct {
+	MinersPoStRatio      uint64
+	MinersPledgePeriod   abi.ChainEpoch
+	MarketInitialQuota   int64
+	ExpertDataThreshold  uint64
+	ExpertDailyThreshold uint64
+}
+
 type Template struct {
 	Accounts []Actor
 	Miners   []Miner
@@ -111,4 +166,12 @@ type Template struct {
 	DefaultGovernorActor    Actor
 	DefaultExpertActor      Actor
 	DefaultKgFundPayeeActor Actor
+
+	// Experts, Votes, RetrievalPledges, Governors and GovParams seed the
+	// state of the fund and govern actors once the miners are created
+	Experts          []Expert          `json:",omitempty"`
+	Votes            []Vote            `json:",omitempty"`
+	RetrievalPledges []RetrievalPledge `json:",omitempty"`
+	Governors        []Governor        `json:",omitempty"`
+	GovParams        *GovParams        `json:",omitempty"`
 }
@@ -1,91 +1,81 @@
 package journal
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"golang.org/x/xerrors"
 
 	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/repo"
 )
 
 const RFC3339nocolon = "2006-01-02T150405Z0700"
 
-// fsJournal is a basic journal backed by files on a filesystem.
-type fsJournal struct {
-	EventTypeRegistry
-
-	dir       string
-	sizeLimit int64
-
-	fi    *os.File
-	fSize int64
+const (
+	journalFilePrefix = "epik-journal-"
+	journalFileSuffix = ".ndjson"
+)
 
-	incoming chan *Event
+// fileSink is a sink backed by NDJSON files on a filesystem, rotated by size
+// and age.
+type fileSink struct {
+	dir string
+	cfg config.Journal
 
-	closing chan struct{}
-	closed  chan struct{}
+	fi     *os.File
+	fSize  int64
+	opened time.Time
 }
 
+var _ QuerySink = (*fileSink)(nil)
+
 // OpenFSJournal constructs a rolling filesystem journal, with a default
-// per-file size limit of 1GiB.
+// per-file size limit of 1GiB and a week of retention.
 func OpenFSJournal(lr repo.LockedRepo, disabled DisabledEvents) (Journal, error) {
+	return OpenJournal(lr, disabled, config.Journal{
+		Sinks:       []string{"file"},
+		MaxFileSize: 1 << 30,
+		Retention:   config.Duration(7 * 24 * time.Hour),
+	}, DefaultRegistry)
+}
+
+func openFileSink(lr repo.LockedRepo, cfg config.Journal) (Sink, error) {
 	dir := filepath.Join(lr.Path(), "journal")
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to mk directory %s for file journal: %w", dir, err)
 	}
 
-	f := &fsJournal{
-		EventTypeRegistry: NewEventTypeRegistry(disabled),
-		dir:               dir,
-		sizeLimit:         1 << 30,
-		incoming:          make(chan *Event, 32),
-		closing:           make(chan struct{}),
-		closed:            make(chan struct{}),
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = 1 << 30
+	}
+	f := &fileSink{
+		dir: dir,
+		cfg: cfg,
 	}
 
 	if err := f.rollJournalFile(); err != nil {
 		return nil, err
 	}
 
-	go f.runLoop()
-
 	return f, nil
 }
 
-func (f *fsJournal) RecordEvent(evtType EventType, supplier func() interface{}) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Warnf("recovered from panic while recording journal event; type=%s, err=%v", evtType, r)
+func (f *fileSink) Write(evt *Event) error {
+	if f.cfg.MaxFileAge > 0 && build.Clock.Since(f.opened) >= time.Duration(f.cfg.MaxFileAge) {
+		if err := f.rollJournalFile(); err != nil {
+			return err
 		}
-	}()
-
-	if !evtType.Enabled() {
-		return
 	}
 
-	je := &Event{
-		EventType: evtType,
-		Timestamp: build.Clock.Now(),
-		Data:      supplier(),
-	}
-	select {
-	case f.incoming <- je:
-	case <-f.closing:
-		log.Warnw("journal closed but tried to log event", "event", je)
-	}
-}
-
-func (f *fsJournal) Close() error {
-	close(f.closing)
-	<-f.closed
-	return nil
-}
-
-func (f *fsJournal) putEvent(evt *Event) error {
 	b, err := json.Marshal(evt)
 	if err != nil {
 		return err
@@ -97,40 +87,195 @@ func (f *fsJournal) putEvent(evt *Event) error {
 
 	f.fSize += int64(n)
 
-	if f.fSize >= f.sizeLimit {
+	if f.fSize >= f.cfg.MaxFileSize {
 		_ = f.rollJournalFile()
 	}
 
 	return nil
 }
 
-func (f *fsJournal) rollJournalFile() error {
+func (f *fileSink) Close() error {
+	return f.fi.Close()
+}
+
+func (f *fileSink) rollJournalFile() error {
 	if f.fi != nil {
 		_ = f.fi.Close()
 	}
 
-	nfi, err := os.Create(filepath.Join(f.dir, fmt.Sprintf("epik-journal-%s.ndjson", build.Clock.Now().Format(RFC3339nocolon))))
+	now := build.Clock.Now()
+	name := filepath.Join(f.dir, journalFilePrefix+now.Format(RFC3339nocolon)+journalFileSuffix)
+
+	// files rolled within the same second share their name, append rather
+	// than truncate
+	nfi, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return xerrors.Errorf("failed to open journal file: %w", err)
 	}
+	st, err := nfi.Stat()
+	if err != nil {
+		_ = nfi.Close()
+		return xerrors.Errorf("failed to stat journal file: %w", err)
+	}
 
 	f.fi = nfi
-	f.fSize = 0
+	f.fSize = st.Size()
+	f.opened = now
+
+	if err := f.prune(); err != nil {
+		log.Warnw("failed to prune journal files", "error", err)
+	}
+	return nil
+}
+
+// prune removes the rotated files past the retention period, and the oldest
+// files beyond MaxFiles.
+func (f *fileSink) prune() error {
+	if f.cfg.Retention <= 0 && f.cfg.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := listJournalFiles(f.dir)
+	if err != nil {
+		return err
+	}
+
+	now := build.Clock.Now()
+	for i, jf := range files {
+		if jf.path == f.fi.Name() || i == len(files)-1 {
+			continue
+		}
+
+		// a file was last written when the next one was opened
+		expired := f.cfg.Retention > 0 && now.Sub(files[i+1].start) > time.Duration(f.cfg.Retention)
+		excess := f.cfg.MaxFiles > 0 && len(files)-i > f.cfg.MaxFiles
+		if !expired && !excess {
+			continue
+		}
+
+		if err := os.Remove(jf.path); err != nil && !os.IsNotExist(err) {
+			return xerrors.Errorf("removing %s: %w", jf.path, err)
+		}
+	}
 	return nil
 }
 
-func (f *fsJournal) runLoop() {
-	defer close(f.closed)
+// Query reads back the events of the journal files overlapping the filtered
+// period. Files are read line by line, newest first, so that limited queries
+// only keep and read what they return.
+func (f *fileSink) Query(flt Filter) ([]*Event, error) {
+	files, err := listJournalFiles(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Event
+	for i := len(files) - 1; i >= 0; i-- {
+		jf := files[i]
+		if !flt.Until.IsZero() && jf.start.After(flt.Until) {
+			continue
+		}
+		// file names have a precision of a second, older files end earlier
+		if !flt.Since.IsZero() && i+1 < len(files) && files[i+1].start.Add(time.Second).Before(flt.Since) {
+			break
+		}
+
+		last := 0
+		if flt.Limit > 0 {
+			last = flt.Limit - len(out)
+		}
+		evts, err := readJournalFile(jf.path, flt, last)
+		if err != nil {
+			return nil, xerrors.Errorf("reading %s: %w", jf.path, err)
+		}
+		out = append(evts, out...)
+
+		if flt.Limit > 0 && len(out) >= flt.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+type journalFile struct {
+	path  string
+	start time.Time
+}
+
+// listJournalFiles returns the journal files in dir, oldest first.
+func listJournalFiles(dir string) ([]journalFile, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, journalFilePrefix+"*"+journalFileSuffix))
+	if err != nil {
+		return nil, xerrors.Errorf("listing journal files: %w", err)
+	}
 
+	out := make([]journalFile, 0, len(matches))
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), journalFilePrefix), journalFileSuffix)
+		start, err := time.Parse(RFC3339nocolon, ts)
+		if err != nil {
+			log.Debugw("skipping journal file with unexpected name", "file", m)
+			continue
+		}
+		out = append(out, journalFile{path: m, start: start})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].start.Before(out[j].start)
+	})
+	return out, nil
+}
+
+// readJournalFile returns the events of a journal file passing the filter, only
+// the last ones when last isn't zero, with their data as raw JSON.
+func readJournalFile(path string, flt Filter, last int) ([]*Event, error) {
+	fi, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// pruned since it was listed
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close() //nolint:errcheck
+
+	var out []*Event
+	r := bufio.NewReader(fi)
 	for {
-		select {
-		case je := <-f.incoming:
-			if err := f.putEvent(je); err != nil {
-				log.Errorw("failed to write out journal event", "event", je, "err", err)
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// the last line may still be being written
+			if last > 0 && len(out) > last {
+				out = out[len(out)-last:]
 			}
-		case <-f.closing:
-			_ = f.fi.Close()
-			return
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var raw struct {
+			System    string
+			Event     string
+			Timestamp time.Time
+			Data      json.RawMessage
+		}
+		if err := json.Unmarshal(line, &raw); err != nil {
+			log.Debugw("skipping malformed journal entry", "file", path, "error", err)
+			continue
+		}
+
+		evt := &Event{
+			EventType: EventType{System: raw.System, Event: raw.Event},
+			Timestamp: raw.Timestamp,
+			Data:      raw.Data,
+		}
+		if flt.Match(evt) {
+			out = append(out, evt)
+		}
+		// drop the older events once there are twice as many as needed
+		if last > 0 && len(out) >= 2*last {
+			out = append(out[:0], out[len(out)-last:]...)
 		}
 	}
 }
@@ -13,4 +13,6 @@ func (n *nilJournal) RegisterEventType(_, _ string) EventType { return EventType
 
 func (n *nilJournal) RecordEvent(_ EventType, _ func() interface{}) {}
 
+func (n *nilJournal) Query(_ Filter) ([]*Event, error) { return nil, nil }
+
 func (n *nilJournal) Close() error { return nil }
@@ -0,0 +1,69 @@
+package journal
+
+import (
+	"sync"
+
+	"github.com/EpiK-Protocol/go-epik/node/config"
+	"github.com/EpiK-Protocol/go-epik/node/repo"
+)
+
+const defaultRingSize = 4096
+
+// ringSink keeps the most recent events in memory.
+type ringSink struct {
+	lk   sync.Mutex
+	evts []*Event
+	next int
+	full bool
+}
+
+var _ QuerySink = (*ringSink)(nil)
+
+// NewRingSink returns a sink keeping the last size events in memory.
+func NewRingSink(size int) QuerySink {
+	if size <= 0 {
+		size = defaultRingSize
+	}
+	return &ringSink{evts: make([]*Event, size)}
+}
+
+func openRingSink(_ repo.LockedRepo, cfg config.Journal) (Sink, error) {
+	return NewRingSink(cfg.RingSize), nil
+}
+
+func (r *ringSink) Write(evt *Event) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.evts[r.next] = evt
+	r.next = (r.next + 1) % len(r.evts)
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+func (r *ringSink) Query(flt Filter) ([]*Event, error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	ordered := r.evts[:r.next]
+	if r.full {
+		ordered = append(append([]*Event{}, r.evts[r.next:]...), r.evts[:r.next]...)
+	}
+
+	var out []*Event
+	for _, evt := range ordered {
+		if flt.Match(evt) {
+			out = append(out, evt)
+		}
+	}
+	if flt.Limit > 0 && len(out) > flt.Limit {
+		out = out[len(out)-flt.Limit:]
+	}
+	return out, nil
+}
+
+func (r *ringSink) Close() error {
+	return nil
+}
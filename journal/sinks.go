@@ -0,0 +1,181 @@
+package journal
+
+import (
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+	"github.com/EpiK-Protocol/go-epik/node/repo"
+)
+
+// Sink is a destination of journal events. Writes are made from a single
+// goroutine and must not block for long, as they hold up the journal.
+type Sink interface {
+	Write(*Event) error
+	Close() error
+}
+
+// QuerySink is a sink the events can be read back from.
+type QuerySink interface {
+	Sink
+
+	// Query returns the events matching the filter, oldest first. It may be
+	// called concurrently with Write.
+	Query(Filter) ([]*Event, error)
+}
+
+// SinkConstructor opens a sink for the journal of a repo.
+type SinkConstructor func(lr repo.LockedRepo, cfg config.Journal) (Sink, error)
+
+// Registry maps sink names to their constructors. Plugins register their sinks
+// on DefaultRegistry before the node is constructed, and are enabled by adding
+// their names to the Journal.Sinks config.
+type Registry struct {
+	lk    sync.Mutex
+	sinks map[string]SinkConstructor
+}
+
+// DefaultRegistry holds the file, memory and socket sinks.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("file", openFileSink)
+	r.Register("memory", openRingSink)
+	r.Register("socket", openSocketSink)
+	return r
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sinks: make(map[string]SinkConstructor)}
+}
+
+// Register adds a sink, replacing any sink registered with the same name.
+func (r *Registry) Register(name string, ctor SinkConstructor) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.sinks[name] = ctor
+}
+
+// Open opens the sinks listed in the config, in order.
+func (r *Registry) Open(lr repo.LockedRepo, cfg config.Journal) ([]Sink, error) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	var out []Sink
+	for _, name := range cfg.Sinks {
+		ctor, ok := r.sinks[name]
+		if !ok {
+			closeSinks(out)
+			return nil, xerrors.Errorf("unknown journal sink %q", name)
+		}
+		s, err := ctor(lr, cfg)
+		if err != nil {
+			closeSinks(out)
+			return nil, xerrors.Errorf("opening journal sink %s: %w", name, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func closeSinks(sinks []Sink) {
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			log.Warnw("failed to close journal sink", "error", err)
+		}
+	}
+}
+
+// sinkJournal is a journal writing events to a set of sinks.
+type sinkJournal struct {
+	EventTypeRegistry
+
+	sinks []Sink
+
+	incoming chan *Event
+
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+// OpenJournal constructs a journal writing to the sinks listed in the config,
+// as registered in reg.
+func OpenJournal(lr repo.LockedRepo, disabled DisabledEvents, cfg config.Journal, reg *Registry) (Journal, error) {
+	sinks, err := reg.Open(lr, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &sinkJournal{
+		EventTypeRegistry: NewEventTypeRegistry(disabled),
+		sinks:             sinks,
+		incoming:          make(chan *Event, 32),
+		closing:           make(chan struct{}),
+		closed:            make(chan struct{}),
+	}
+
+	go j.runLoop()
+
+	return j, nil
+}
+
+func (j *sinkJournal) RecordEvent(evtType EventType, supplier func() interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Warnf("recovered from panic while recording journal event; type=%s, err=%v", evtType, r)
+		}
+	}()
+
+	if !evtType.Enabled() {
+		return
+	}
+
+	je := &Event{
+		EventType: evtType,
+		Timestamp: build.Clock.Now(),
+		Data:      supplier(),
+	}
+	select {
+	case j.incoming <- je:
+	case <-j.closing:
+		log.Warnw("journal closed but tried to log event", "event", je)
+	}
+}
+
+// Query reads the events back from the first sink supporting queries.
+func (j *sinkJournal) Query(flt Filter) ([]*Event, error) {
+	for _, s := range j.sinks {
+		if qs, ok := s.(QuerySink); ok {
+			return qs.Query(flt)
+		}
+	}
+	return nil, xerrors.Errorf("no journal sink supports queries, enable the file or memory sink")
+}
+
+func (j *sinkJournal) Close() error {
+	close(j.closing)
+	<-j.closed
+	return nil
+}
+
+func (j *sinkJournal) runLoop() {
+	defer close(j.closed)
+
+	for {
+		select {
+		case je := <-j.incoming:
+			for _, s := range j.sinks {
+				if err := s.Write(je); err != nil {
+					log.Errorw("failed to write out journal event", "event", je, "err", err)
+				}
+			}
+		case <-j.closing:
+			closeSinks(j.sinks)
+			return
+		}
+	}
+}
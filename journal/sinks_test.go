@@ -0,0 +1,175 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+func TestRingSink(t *testing.T) {
+	req := require.New(t)
+
+	s := NewRingSink(3)
+	base := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		req.NoError(s.Write(&Event{
+			EventType: EventType{System: "mpool", Event: "repub"},
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Data:      i,
+		}))
+	}
+
+	evts, err := s.Query(Filter{})
+	req.NoError(err)
+	req.Len(evts, 3)
+	for i, evt := range evts {
+		req.Equal(i+2, evt.Data)
+	}
+
+	evts, err = s.Query(Filter{Since: base.Add(3 * time.Second), Until: base.Add(3 * time.Second)})
+	req.NoError(err)
+	req.Len(evts, 1)
+	req.Equal(3, evts[0].Data)
+
+	evts, err = s.Query(Filter{System: "wdpost"})
+	req.NoError(err)
+	req.Empty(evts)
+
+	evts, err = s.Query(Filter{Limit: 2})
+	req.NoError(err)
+	req.Len(evts, 2)
+	req.Equal(3, evts[0].Data)
+	req.Equal(4, evts[1].Data)
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	req := require.New(t)
+
+	mClock := clock.NewMock()
+	mClock.Set(time.Unix(1000, 0))
+	prev := build.Clock
+	build.Clock = mClock
+	defer func() { build.Clock = prev }()
+
+	dir, err := ioutil.TempDir("", "epik-journal-")
+	req.NoError(err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	f := &fileSink{
+		dir: dir,
+		cfg: config.Journal{
+			MaxFileSize: 1 << 20,
+			MaxFileAge:  config.Duration(time.Minute),
+			MaxFiles:    3,
+		},
+	}
+	req.NoError(f.rollJournalFile())
+	defer f.Close() //nolint:errcheck
+
+	write := func(event string) {
+		req.NoError(f.Write(&Event{
+			EventType: EventType{System: "sealing", Event: event},
+			Timestamp: mClock.Now(),
+			Data:      map[string]int{"sector": 1},
+		}))
+	}
+
+	for i := 0; i < 5; i++ {
+		write("precommit")
+		write("commit")
+		mClock.Add(time.Minute)
+	}
+
+	files, err := listJournalFiles(dir)
+	req.NoError(err)
+	req.Len(files, 3)
+
+	// the files of the first two minutes were pruned
+	evts, err := f.Query(Filter{Event: "commit"})
+	req.NoError(err)
+	req.Len(evts, 3)
+	req.JSONEq(`{"sector":1}`, string(evts[0].Data.(json.RawMessage)))
+
+	evts, err = f.Query(Filter{Since: mClock.Now().Add(-time.Minute)})
+	req.NoError(err)
+	req.Len(evts, 2)
+
+	// limited queries return the last events, across files
+	for i := 0; i < 3; i++ {
+		write("commit")
+	}
+	evts, err = f.Query(Filter{Event: "commit", Limit: 4})
+	req.NoError(err)
+	req.Len(evts, 4)
+	req.True(evts[0].Timestamp.Equal(mClock.Now().Add(-time.Minute)))
+	for _, evt := range evts[1:] {
+		req.True(evt.Timestamp.Equal(mClock.Now()))
+	}
+}
+
+func TestSocketSinkSlowClient(t *testing.T) {
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "epik-journal-")
+	req.NoError(err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	sink, err := openSocketSink(nil, config.Journal{SocketPath: filepath.Join(dir, "journal.sock")})
+	req.NoError(err)
+	defer sink.Close() //nolint:errcheck
+	s := sink.(*socketSink)
+
+	conns := func() int {
+		s.lk.Lock()
+		defer s.lk.Unlock()
+		return len(s.conns)
+	}
+
+	// a client which never reads
+	slow, err := net.Dial("unix", filepath.Join(dir, "journal.sock"))
+	req.NoError(err)
+	defer slow.Close() //nolint:errcheck
+
+	reader, err := net.Dial("unix", filepath.Join(dir, "journal.sock"))
+	req.NoError(err)
+	defer reader.Close() //nolint:errcheck
+	var read int64
+	go func() {
+		sc := bufio.NewScanner(reader)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			atomic.AddInt64(&read, 1)
+		}
+	}()
+
+	req.Eventually(func() bool { return conns() == 2 }, 10*time.Second, 10*time.Millisecond)
+
+	// far more than the socket buffers of the slow client hold, in bursts the
+	// reader keeps up with
+	data := strings.Repeat("x", 4096)
+	for round := 1; round <= 20; round++ {
+		start := time.Now()
+		for i := 0; i < socketClientBuffer/2; i++ {
+			req.NoError(s.Write(&Event{EventType: EventType{System: "test", Event: "burst"}, Timestamp: time.Now(), Data: data}))
+		}
+		req.Less(int64(time.Since(start)), int64(socketWriteTimeout), "writes waited on a client")
+
+		want := int64(round * socketClientBuffer / 2)
+		req.Eventually(func() bool { return atomic.LoadInt64(&read) == want }, 10*time.Second, 10*time.Millisecond)
+	}
+
+	req.Eventually(func() bool { return conns() == 1 }, 10*time.Second, 10*time.Millisecond)
+}
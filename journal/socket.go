@@ -0,0 +1,138 @@
+package journal
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/EpiK-Protocol/go-epik/node/config"
+	"github.com/EpiK-Protocol/go-epik/node/repo"
+)
+
+// socketWriteTimeout bounds the time a reader of the socket sink has to read an
+// event; slower readers are disconnected.
+const socketWriteTimeout = time.Second
+
+// socketClientBuffer is the number of events queued for a reader of the socket
+// sink. Readers falling further behind are disconnected, so that the journal
+// never waits on them.
+const socketClientBuffer = 256
+
+// socketSink streams the events as newline-delimited JSON to the clients of a
+// local unix socket.
+type socketSink struct {
+	lst net.Listener
+
+	lk    sync.Mutex
+	conns map[net.Conn]chan []byte
+}
+
+func openSocketSink(lr repo.LockedRepo, cfg config.Journal) (Sink, error) {
+	path := cfg.SocketPath
+	if path == "" {
+		path = "journal.sock"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(lr.Path(), path)
+	}
+
+	// the repo is locked, a socket left there belongs to a previous run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, xerrors.Errorf("removing stale journal socket: %w", err)
+	}
+	lst, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, xerrors.Errorf("listening on journal socket: %w", err)
+	}
+
+	s := &socketSink{
+		lst:   lst,
+		conns: make(map[net.Conn]chan []byte),
+	}
+	go s.accept()
+
+	return s, nil
+}
+
+func (s *socketSink) accept() {
+	for {
+		c, err := s.lst.Accept()
+		if err != nil {
+			// closed
+			return
+		}
+
+		out := make(chan []byte, socketClientBuffer)
+		s.lk.Lock()
+		s.conns[c] = out
+		s.lk.Unlock()
+
+		go s.serve(c, out)
+	}
+}
+
+// serve writes the events queued for a client until it is dropped.
+func (s *socketSink) serve(c net.Conn, out <-chan []byte) {
+	for b := range out {
+		_ = c.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if _, err := c.Write(b); err != nil {
+			log.Debugw("dropping journal socket client", "error", err)
+			s.lk.Lock()
+			s.drop(c)
+			s.lk.Unlock()
+			return
+		}
+	}
+}
+
+// drop disconnects a client. Must be called with s.lk held.
+func (s *socketSink) drop(c net.Conn) {
+	out, ok := s.conns[c]
+	if !ok {
+		return
+	}
+	delete(s.conns, c)
+	close(out)
+	_ = c.Close()
+}
+
+func (s *socketSink) Write(evt *Event) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if len(s.conns) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	for c, out := range s.conns {
+		select {
+		case out <- b:
+		default:
+			log.Debugw("dropping journal socket client falling behind", "queued", len(out))
+			s.drop(c)
+		}
+	}
+	return nil
+}
+
+func (s *socketSink) Close() error {
+	err := s.lst.Close()
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	for c := range s.conns {
+		s.drop(c)
+	}
+	return err
+}
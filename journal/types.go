@@ -87,6 +87,9 @@ type Journal interface {
 	// Implementations MUST recover from panics raised by the supplier function.
 	RecordEvent(evtType EventType, supplier func() interface{})
 
+	// Query returns the recorded events matching the filter, oldest first.
+	Query(Filter) ([]*Event, error)
+
 	// Close closes this journal for further writing.
 	Close() error
 }
@@ -100,3 +103,33 @@ type Event struct {
 	Timestamp time.Time
 	Data      interface{}
 }
+
+// Filter selects journal events. Empty fields match all events.
+type Filter struct {
+	System string
+	Event  string
+
+	// Since and Until bound the timestamps of the events, inclusively
+	Since time.Time
+	Until time.Time
+
+	// Limit keeps the last Limit matching events, zero keeps them all
+	Limit int
+}
+
+// Match returns whether the event passes the filter.
+func (f Filter) Match(evt *Event) bool {
+	if f.System != "" && f.System != evt.System {
+		return false
+	}
+	if f.Event != "" && f.Event != evt.Event {
+		return false
+	}
+	if !f.Since.IsZero() && evt.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && evt.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
@@ -28,12 +28,19 @@ func (t *Entry) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Value))); err != nil {
-		return err
-	}
+	// deletions are encoded with a null value
+	if t.Deleted {
+		if _, err := w.Write(cbg.CborNull); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Value))); err != nil {
+			return err
+		}
 
-	if _, err := w.Write(t.Value[:]); err != nil {
-		return err
+		if _, err := w.Write(t.Value[:]); err != nil {
+			return err
+		}
 	}
 
 	// t.Timestamp (int64) (int64)
@@ -87,21 +94,31 @@ func (t *Entry) UnmarshalCBOR(r io.Reader) error {
 	}
 	// t.Value ([]uint8) (slice)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	b, err := br.ReadByte()
 	if err != nil {
 		return err
 	}
+	if b == cbg.CborNull[0] {
+		t.Deleted = true
+	} else {
+		if err := br.UnreadByte(); err != nil {
+			return err
+		}
 
-	if maj != cbg.MajByteString {
-		return fmt.Errorf("expected byte array")
-	}
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+
+		if maj != cbg.MajByteString {
+			return fmt.Errorf("expected byte array")
+		}
 
-	if extra > 0 {
 		t.Value = make([]uint8, extra)
-	}
 
-	if _, err := io.ReadFull(br, t.Value[:]); err != nil {
-		return err
+		if _, err := io.ReadFull(br, t.Value[:]); err != nil {
+			return err
+		}
 	}
 	// t.Timestamp (int64) (int64)
 	{
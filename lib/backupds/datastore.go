@@ -25,12 +25,19 @@ type Datastore struct {
 	backupLk sync.RWMutex
 
 	log             chan Entry
+	marks           chan chan logMark
 	closing, closed chan struct{}
+
+	// logHead is the head written when the log was opened
+	logHead string
 }
 
 type Entry struct {
 	Key, Value []byte
 	Timestamp  int64
+
+	// Deleted marks the deletion of Key, Value is then empty
+	Deleted bool
 }
 
 func Wrap(child datastore.Batching, logdir string) (*Datastore, error) {
@@ -41,6 +48,7 @@ func Wrap(child datastore.Batching, logdir string) (*Datastore, error) {
 	if logdir != NoLogdir {
 		ds.closing, ds.closed = make(chan struct{}), make(chan struct{})
 		ds.log = make(chan Entry)
+		ds.marks = make(chan chan logMark)
 
 		if err := ds.startLog(logdir); err != nil {
 			return nil, err
@@ -53,10 +61,37 @@ func Wrap(child datastore.Batching, logdir string) (*Datastore, error) {
 // Writes a datastore dump into the provided writer as
 // [array(*) of [key, value] tuples, checksum]
 func (d *Datastore) Backup(out io.Writer) error {
+	d.backupLk.Lock()
+	defer d.backupLk.Unlock()
+
+	_, err := d.dump(out)
+	return err
+}
+
+// BackupFull writes a backup like Backup does, and makes it the base of the
+// next incremental backup.
+func (d *Datastore) BackupFull(out io.Writer) error {
+	if d.log == nil {
+		return xerrors.Errorf("incremental backups require the metadata log")
+	}
+
+	d.backupLk.Lock()
+	defer d.backupLk.Unlock()
+
+	sum, err := d.dump(out)
+	if err != nil {
+		return err
+	}
+	return d.recordBackup(sum)
+}
+
+// dump writes the backup, with the write lock held or before the log is
+// started.
+func (d *Datastore) dump(out io.Writer) ([]byte, error) {
 	scratch := make([]byte, 9)
 
 	if err := cbg.WriteMajorTypeHeaderBuf(scratch, out, cbg.MajArray, 2); err != nil {
-		return xerrors.Errorf("writing tuple header: %w", err)
+		return nil, xerrors.Errorf("writing tuple header: %w", err)
 	}
 
 	hasher := sha256.New()
@@ -66,18 +101,15 @@ func (d *Datastore) Backup(out io.Writer) error {
 	{
 		// write indefinite length array header
 		if _, err := hout.Write([]byte{0x9f}); err != nil {
-			return xerrors.Errorf("writing header: %w", err)
+			return nil, xerrors.Errorf("writing header: %w", err)
 		}
 
-		d.backupLk.Lock()
-		defer d.backupLk.Unlock()
-
 		log.Info("Starting datastore backup")
 		defer log.Info("Datastore backup done")
 
 		qr, err := d.child.Query(query.Query{})
 		if err != nil {
-			return xerrors.Errorf("query: %w", err)
+			return nil, xerrors.Errorf("query: %w", err)
 		}
 		defer func() {
 			if err := qr.Close(); err != nil {
@@ -87,47 +119,49 @@ func (d *Datastore) Backup(out io.Writer) error {
 		}()
 
 		for result := range qr.Next() {
+			if result.Error != nil {
+				return nil, xerrors.Errorf("query result: %w", result.Error)
+			}
+
 			if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajArray, 2); err != nil {
-				return xerrors.Errorf("writing tuple header: %w", err)
+				return nil, xerrors.Errorf("writing tuple header: %w", err)
 			}
 
 			if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajByteString, uint64(len([]byte(result.Key)))); err != nil {
-				return xerrors.Errorf("writing key header: %w", err)
+				return nil, xerrors.Errorf("writing key header: %w", err)
 			}
 
 			if _, err := hout.Write([]byte(result.Key)[:]); err != nil {
-				return xerrors.Errorf("writing key: %w", err)
+				return nil, xerrors.Errorf("writing key: %w", err)
 			}
 
 			if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajByteString, uint64(len(result.Value))); err != nil {
-				return xerrors.Errorf("writing value header: %w", err)
+				return nil, xerrors.Errorf("writing value header: %w", err)
 			}
 
 			if _, err := hout.Write(result.Value[:]); err != nil {
-				return xerrors.Errorf("writing value: %w", err)
+				return nil, xerrors.Errorf("writing value: %w", err)
 			}
 		}
 
 		// array break
 		if _, err := hout.Write([]byte{0xff}); err != nil {
-			return xerrors.Errorf("writing array 'break': %w", err)
+			return nil, xerrors.Errorf("writing array 'break': %w", err)
 		}
 	}
 
 	// Write the checksum
-	{
-		sum := hasher.Sum(nil)
+	sum := hasher.Sum(nil)
 
-		if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajByteString, uint64(len(sum))); err != nil {
-			return xerrors.Errorf("writing checksum header: %w", err)
-		}
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajByteString, uint64(len(sum))); err != nil {
+		return nil, xerrors.Errorf("writing checksum header: %w", err)
+	}
 
-		if _, err := hout.Write(sum[:]); err != nil {
-			return xerrors.Errorf("writing checksum: %w", err)
-		}
+	if _, err := hout.Write(sum[:]); err != nil {
+		return nil, xerrors.Errorf("writing checksum: %w", err)
 	}
 
-	return nil
+	return sum, nil
 }
 
 // proxy
@@ -167,6 +201,14 @@ func (d *Datastore) Delete(key datastore.Key) error {
 	d.backupLk.RLock()
 	defer d.backupLk.RUnlock()
 
+	if d.log != nil {
+		d.log <- Entry{
+			Key:       []byte(key.String()),
+			Timestamp: time.Now().Unix(),
+			Deleted:   true,
+		}
+	}
+
 	return d.child.Delete(key)
 }
 
@@ -228,6 +270,14 @@ func (b *bbatch) Put(key datastore.Key, value []byte) error {
 }
 
 func (b *bbatch) Delete(key datastore.Key) error {
+	if b.d.log != nil {
+		b.d.log <- Entry{
+			Key:       []byte(key.String()),
+			Timestamp: time.Now().Unix(),
+			Deleted:   true,
+		}
+	}
+
 	return b.b.Delete(key)
 }
 
@@ -0,0 +1,396 @@
+package backupds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+)
+
+var backupHeadKey = datastore.NewKey("/backupds/backup/head")
+
+// ErrNoBase is returned by BackupIncremental when BackupFull wasn't called
+// since the log was opened.
+var ErrNoBase = xerrors.New("no backup to base an incremental backup on since the log was opened")
+
+// backupHead records the last backup, full or incremental, and the position of
+// the log when it was taken.
+type backupHead struct {
+	LogHead string
+	Offset  int64
+	Sum     []byte
+	Time    int64
+}
+
+// BackupIncremental writes the log entries since the previous backup taken by
+// BackupFull or BackupIncremental, into the provided writer as
+// [base checksum, array(*) of log entries, checksum]
+//
+// The base checksum is the checksum of the previous backup, incremental backups
+// are restored in order on top of the full backup they derive from.
+func (d *Datastore) BackupIncremental(out io.Writer) error {
+	d.backupLk.Lock()
+	defer d.backupLk.Unlock()
+
+	head, m, err := d.incrementalBase()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(m.file)
+	if err != nil {
+		return xerrors.Errorf("opening log: %w", err)
+	}
+	defer f.Close() // nolint:errcheck
+
+	if _, err := f.Seek(head.Offset, io.SeekStart); err != nil {
+		return xerrors.Errorf("seeking log to the last backup: %w", err)
+	}
+
+	log.Infow("Starting incremental datastore backup", "logBytes", m.offset-head.Offset)
+	defer log.Info("Incremental datastore backup done")
+
+	scratch := make([]byte, 9)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, out, cbg.MajArray, 3); err != nil {
+		return xerrors.Errorf("writing tuple header: %w", err)
+	}
+
+	hasher := sha256.New()
+	hout := io.MultiWriter(hasher, out)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, hout, cbg.MajByteString, uint64(len(head.Sum))); err != nil {
+		return xerrors.Errorf("writing base checksum header: %w", err)
+	}
+	if _, err := hout.Write(head.Sum); err != nil {
+		return xerrors.Errorf("writing base checksum: %w", err)
+	}
+
+	// the log entries are copied as they are
+	if _, err := hout.Write([]byte{0x9f}); err != nil {
+		return xerrors.Errorf("writing header: %w", err)
+	}
+	if _, err := io.CopyN(hout, f, m.offset-head.Offset); err != nil {
+		return xerrors.Errorf("copying log entries: %w", err)
+	}
+	if _, err := hout.Write([]byte{0xff}); err != nil {
+		return xerrors.Errorf("writing array 'break': %w", err)
+	}
+
+	sum := hasher.Sum(nil)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, out, cbg.MajByteString, uint64(len(sum))); err != nil {
+		return xerrors.Errorf("writing checksum header: %w", err)
+	}
+	if _, err := out.Write(sum); err != nil {
+		return xerrors.Errorf("writing checksum: %w", err)
+	}
+
+	return d.putBackupHead(sum, m)
+}
+
+// changedSinceBackup returns whether entries were logged since the previous
+// backup, or ErrNoBase.
+func (d *Datastore) changedSinceBackup() (bool, error) {
+	d.backupLk.Lock()
+	defer d.backupLk.Unlock()
+
+	head, m, err := d.incrementalBase()
+	if err != nil {
+		return false, err
+	}
+	return m.offset > head.Offset, nil
+}
+
+// incrementalBase returns the previous backup and the current position of the
+// log, or ErrNoBase. Must be called with backupLk held.
+func (d *Datastore) incrementalBase() (backupHead, logMark, error) {
+	if d.log == nil {
+		return backupHead{}, logMark{}, xerrors.Errorf("incremental backups require the metadata log")
+	}
+
+	hb, err := d.child.Get(backupHeadKey)
+	if err == datastore.ErrNotFound {
+		return backupHead{}, logMark{}, ErrNoBase
+	}
+	if err != nil {
+		return backupHead{}, logMark{}, xerrors.Errorf("getting last backup: %w", err)
+	}
+	var head backupHead
+	if err := json.Unmarshal(hb, &head); err != nil {
+		return backupHead{}, logMark{}, xerrors.Errorf("unmarshaling last backup: %w", err)
+	}
+	if head.LogHead != d.logHead {
+		return backupHead{}, logMark{}, ErrNoBase
+	}
+
+	m, err := d.markLog()
+	if err != nil {
+		return backupHead{}, logMark{}, err
+	}
+	return head, m, nil
+}
+
+// recordBackup makes the backup with the given checksum the base of the next
+// incremental backup.
+func (d *Datastore) recordBackup(sum []byte) error {
+	m, err := d.markLog()
+	if err != nil {
+		return err
+	}
+	return d.putBackupHead(sum, m)
+}
+
+func (d *Datastore) putBackupHead(sum []byte, m logMark) error {
+	b, err := json.Marshal(&backupHead{
+		LogHead: d.logHead,
+		Offset:  m.offset,
+		Sum:     sum,
+		Time:    time.Now().Unix(),
+	})
+	if err != nil {
+		return xerrors.Errorf("marshaling backup head: %w", err)
+	}
+
+	// not logged, the head is only meaningful to this log
+	if err := d.child.Put(backupHeadKey, b); err != nil {
+		return xerrors.Errorf("writing backup head: %w", err)
+	}
+	return nil
+}
+
+// markLog returns the current position of the log, once the pending entries
+// are written.
+func (d *Datastore) markLog() (logMark, error) {
+	req := make(chan logMark, 1)
+	select {
+	case d.marks <- req:
+	case <-d.closed:
+		return logMark{}, xerrors.Errorf("log closed")
+	}
+
+	m := <-req
+	if m.file == "" {
+		return logMark{}, xerrors.Errorf("failed to get log position")
+	}
+	return m, nil
+}
+
+// ReadIncremental reads an incremental backup, calling cb for each log entry,
+// with a nil value for deletions. It returns the checksum of the backup the
+// incremental backup applies on, and its own checksum.
+func ReadIncremental(r io.Reader, cb func(key datastore.Key, value []byte) error) (base []byte, sum []byte, err error) {
+	scratch := make([]byte, 1)
+
+	// read array[3](
+	if _, err := io.ReadFull(r, scratch); err != nil {
+		return nil, nil, xerrors.Errorf("reading array header: %w", err)
+	}
+	if scratch[0] != 0x83 {
+		return nil, nil, xerrors.Errorf("expected array(3) header byte 0x83, got %x", scratch[0])
+	}
+
+	hasher := sha256.New()
+	hr := io.TeeReader(r, hasher)
+
+	base, err = cbg.ReadByteArray(hr, 32)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading base checksum: %w", err)
+	}
+
+	// read array[*](
+	if _, err := io.ReadFull(hr, scratch); err != nil {
+		return nil, nil, xerrors.Errorf("reading array header: %w", err)
+	}
+	if scratch[0] != 0x9f {
+		return nil, nil, xerrors.Errorf("expected indefinite length array header byte 0x9f, got %x", scratch[0])
+	}
+
+	var ent Entry
+	bp := cbg.GetPeeker(hr)
+	for {
+		b, err := bp.ReadByte()
+		if err != nil {
+			return nil, nil, xerrors.Errorf("reading log entry: %w", err)
+		}
+
+		// close array[*]
+		if b == 0xff {
+			break
+		}
+		if err := bp.UnreadByte(); err != nil {
+			return nil, nil, xerrors.Errorf("unread log byte: %w", err)
+		}
+
+		if err := ent.UnmarshalCBOR(bp); err != nil {
+			return nil, nil, xerrors.Errorf("unmarshaling log entry: %w", err)
+		}
+
+		value := ent.Value
+		if ent.Deleted {
+			value = nil
+		}
+		if err := cb(datastore.NewKey(string(ent.Key)), value); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sum = hasher.Sum(nil)
+
+	expSum, err := cbg.ReadByteArray(r, 32)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("reading expected checksum: %w", err)
+	}
+	if !bytes.Equal(sum, expSum) {
+		return nil, nil, xerrors.Errorf("checksum didn't match; expected %x, got %x", expSum, sum)
+	}
+
+	return base, sum, nil
+}
+
+// RestoreChainInto restores a backup, or a log file, followed by the
+// incremental backups derived from it, in order. Nothing is written to dest
+// unless the whole chain is read successfully.
+func RestoreChainInto(dest datastore.Batching, full io.Reader, incrementals ...io.Reader) error {
+	_, err := restoreChain(dest, full, incrementals, false)
+	return err
+}
+
+type chainStats struct {
+	// tuples is the number of key-value tuples of the base backup
+	tuples int
+	// entries is the number of log entries, of the base and incremental
+	// backups
+	entries int
+	sum     []byte
+}
+
+func restoreChain(dest datastore.Batching, full io.Reader, incrementals []io.Reader, checkDups bool) (*chainStats, error) {
+	batch, err := dest.Batch()
+	if err != nil {
+		return nil, xerrors.Errorf("creating batch: %w", err)
+	}
+
+	var st chainStats
+	seen := make(map[datastore.Key]struct{})
+	apply := func(key datastore.Key, value []byte, log bool) error {
+		if log {
+			st.entries++
+		} else {
+			st.tuples++
+			if checkDups {
+				if _, dup := seen[key]; dup {
+					return xerrors.Errorf("duplicate key %s in backup", key)
+				}
+				seen[key] = struct{}{}
+			}
+		}
+
+		if log && value == nil {
+			if err := batch.Delete(key); err != nil {
+				return xerrors.Errorf("delete key: %w", err)
+			}
+			return nil
+		}
+		if err := batch.Put(key, value); err != nil {
+			return xerrors.Errorf("put key: %w", err)
+		}
+		return nil
+	}
+
+	st.sum, _, err = readBackup(full, apply)
+	if err != nil {
+		return nil, xerrors.Errorf("reading backup: %w", err)
+	}
+
+	for i, r := range incrementals {
+		base, sum, err := ReadIncremental(r, func(key datastore.Key, value []byte) error {
+			return apply(key, value, true)
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("reading incremental backup %d: %w", i, err)
+		}
+		if !bytes.Equal(base, st.sum) {
+			return nil, xerrors.Errorf("incremental backup %d applies on backup %x, not on the previous backup %x", i, base, st.sum)
+		}
+		st.sum = sum
+	}
+
+	if err := batch.Commit(); err != nil {
+		return nil, xerrors.Errorf("committing batch: %w", err)
+	}
+
+	return &st, nil
+}
+
+// VerifyResult describes a verified backup chain.
+type VerifyResult struct {
+	// Keys is the number of keys in the restored datastore
+	Keys int
+	// Tuples is the number of key-value tuples of the full backup
+	Tuples int
+	// Entries is the number of log entries replayed
+	Entries int
+
+	// Checksum is the checksum of the last backup of the chain
+	Checksum []byte
+	// StateHash hashes the restored keys and values, it matches for backups
+	// of the same metadata
+	StateHash []byte
+}
+
+// Verify restores a backup chain into a memory datastore, checking the
+// checksums, that the incremental backups follow each other, and that the
+// keys of the full backup are unique.
+func Verify(full io.Reader, incrementals ...io.Reader) (*VerifyResult, error) {
+	mds := datastore.NewMapDatastore()
+
+	st, err := restoreChain(mds, full, incrementals, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := mds.Query(query.Query{Orders: []query.Order{query.OrderByKey{}}})
+	if err != nil {
+		return nil, xerrors.Errorf("querying restored datastore: %w", err)
+	}
+	defer res.Close() // nolint:errcheck
+
+	out := &VerifyResult{
+		Tuples:   st.tuples,
+		Entries:  st.entries,
+		Checksum: st.sum,
+	}
+
+	hasher := sha256.New()
+	scratch := make([]byte, 9)
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("reading restored datastore: %w", r.Error)
+		}
+		out.Keys++
+
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, hasher, cbg.MajByteString, uint64(len(r.Key))); err != nil {
+			return nil, err
+		}
+		_, _ = hasher.Write([]byte(r.Key))
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, hasher, cbg.MajByteString, uint64(len(r.Value))); err != nil {
+			return nil, err
+		}
+		_, _ = hasher.Write(r.Value)
+	}
+	out.StateHash = hasher.Sum(nil)
+
+	if st.entries == 0 && out.Keys != st.tuples {
+		return nil, xerrors.Errorf("backup has %d key-value tuples but restores %d keys", st.tuples, out.Keys)
+	}
+
+	return out, nil
+}
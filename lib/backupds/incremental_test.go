@@ -0,0 +1,128 @@
+package backupds
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func TestIncrementalChain(t *testing.T) {
+	req := require.New(t)
+
+	logdir, err := ioutil.TempDir("", "backupds-")
+	req.NoError(err)
+	defer os.RemoveAll(logdir) // nolint:errcheck
+
+	ds, err := Wrap(dssync.MutexWrap(datastore.NewMapDatastore()), logdir)
+	req.NoError(err)
+	defer ds.CloseLog() // nolint:errcheck
+
+	var incr bytes.Buffer
+	req.Equal(ErrNoBase, ds.BackupIncremental(&incr))
+
+	req.NoError(ds.Put(datastore.NewKey("/sectors/1"), []byte("packing")))
+	req.NoError(ds.Put(datastore.NewKey("/sectors/2"), []byte("packing")))
+
+	var full bytes.Buffer
+	req.NoError(ds.BackupFull(&full))
+
+	req.NoError(ds.Put(datastore.NewKey("/sectors/1"), []byte("proving")))
+	req.NoError(ds.Delete(datastore.NewKey("/sectors/2")))
+
+	var incr1 bytes.Buffer
+	req.NoError(ds.BackupIncremental(&incr1))
+
+	req.NoError(ds.Put(datastore.NewKey("/sectors/3"), []byte("packing")))
+
+	var incr2 bytes.Buffer
+	req.NoError(ds.BackupIncremental(&incr2))
+
+	// incremental backups only apply in order
+	_, err = Verify(bytes.NewReader(full.Bytes()), bytes.NewReader(incr2.Bytes()))
+	req.Error(err)
+
+	res, err := Verify(bytes.NewReader(full.Bytes()), bytes.NewReader(incr1.Bytes()), bytes.NewReader(incr2.Bytes()))
+	req.NoError(err)
+	req.Equal(3, res.Entries)
+
+	dest := datastore.NewMapDatastore()
+	req.NoError(RestoreChainInto(dest, bytes.NewReader(full.Bytes()), bytes.NewReader(incr1.Bytes()), bytes.NewReader(incr2.Bytes())))
+
+	v, err := dest.Get(datastore.NewKey("/sectors/1"))
+	req.NoError(err)
+	req.Equal([]byte("proving"), v)
+
+	_, err = dest.Get(datastore.NewKey("/sectors/2"))
+	req.Equal(datastore.ErrNotFound, err)
+
+	v, err = dest.Get(datastore.NewKey("/sectors/3"))
+	req.NoError(err)
+	req.Equal([]byte("packing"), v)
+}
+
+func TestScheduledIncrementalSkipsEmpty(t *testing.T) {
+	req := require.New(t)
+
+	logdir, err := ioutil.TempDir("", "backupds-")
+	req.NoError(err)
+	defer os.RemoveAll(logdir) // nolint:errcheck
+	dir, err := ioutil.TempDir("", "backupds-scheduled-")
+	req.NoError(err)
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ds, err := Wrap(dssync.MutexWrap(datastore.NewMapDatastore()), logdir)
+	req.NoError(err)
+	defer ds.CloseLog() // nolint:errcheck
+
+	req.True(xerrors.Is(ds.writeScheduled(dir, true), ErrNoBase))
+
+	req.NoError(ds.Put(datastore.NewKey("/sectors/1"), []byte("packing")))
+	req.NoError(ds.writeScheduled(dir, false))
+
+	// nothing changed since the full backup
+	req.NoError(ds.writeScheduled(dir, true))
+	files, err := ListBackups(dir)
+	req.NoError(err)
+	req.Len(files, 1)
+
+	req.NoError(ds.Put(datastore.NewKey("/sectors/1"), []byte("proving")))
+	req.NoError(ds.writeScheduled(dir, true))
+	files, err = ListBackups(dir)
+	req.NoError(err)
+	req.Len(files, 2)
+	req.True(files[1].Incremental)
+}
+
+func TestLogVersion(t *testing.T) {
+	req := require.New(t)
+
+	logdir, err := ioutil.TempDir("", "backupds-")
+	req.NoError(err)
+	defer os.RemoveAll(logdir) // nolint:errcheck
+
+	child := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	ds, err := Wrap(child, logdir)
+	req.NoError(err)
+	req.NoError(ds.Put(datastore.NewKey("/sectors/1"), []byte("packing")))
+	req.NoError(ds.Delete(datastore.NewKey("/sectors/1")))
+	req.NoError(ds.CloseLog())
+
+	lh, err := child.Get(loghead)
+	req.NoError(err)
+	req.True(strings.HasSuffix(string(lh), fmt.Sprintf(";%d", logVersion)))
+
+	// logs written by newer versions are refused
+	req.NoError(child.Put(loghead, []byte(strings.TrimSuffix(string(lh), fmt.Sprint(logVersion))+fmt.Sprint(logVersion+1))))
+	_, err = Wrap(child, logdir)
+	req.Error(err)
+	req.Contains(err.Error(), "newer than the supported version")
+}
@@ -16,7 +16,14 @@ import (
 	"github.com/ipfs/go-datastore"
 )
 
-var loghead = datastore.NewKey("/backupds/log/head") // string([logfile base name];[uuid];[unix ts])
+var loghead = datastore.NewKey("/backupds/log/head") // string([logfile base name];[uuid];[unix ts];[log version])
+
+// logVersion is the version of the log entries. Version 2 logs deletions with a
+// null value, which binaries reading version 1 logs fail to decode; their log
+// heads had no version, so that these binaries refuse to open newer logs.
+// Downgrading requires removing the log directory, a new log is then started
+// from the datastore.
+const logVersion = 2
 
 func (d *Datastore) startLog(logdir string) error {
 	if err := os.MkdirAll(logdir, 0755); err != nil && !os.IsExist(err) {
@@ -61,7 +68,7 @@ func (d *Datastore) startLog(logdir string) error {
 		}
 	}
 
-	if err := l.writeLogHead(latest, d.child); err != nil {
+	if d.logHead, err = l.writeLogHead(latest, d.child); err != nil {
 		return xerrors.Errorf("writing new log head: %w", err)
 	}
 
@@ -84,6 +91,12 @@ func (d *Datastore) runLog(l *logfile) {
 			if err := l.file.Sync(); err != nil {
 				log.Errorw("failed to sync log", "error", err)
 			}
+		case req := <-d.marks:
+			m, err := l.mark()
+			if err != nil {
+				log.Errorw("failed to get log position", "error", err)
+			}
+			req <- m
 		case <-d.closing:
 			if err := l.Close(); err != nil {
 				log.Errorw("failed to close log", "error", err)
@@ -108,7 +121,7 @@ func (d *Datastore) createLog(logdir string) (*logfile, string, error) {
 		return nil, "", err
 	}
 
-	if err := d.Backup(f); err != nil {
+	if _, err := d.dump(f); err != nil {
 		return nil, "", xerrors.Errorf("writing log base: %w", err)
 	}
 	if err := f.Sync(); err != nil {
@@ -129,8 +142,19 @@ func (d *Datastore) openLog(p string) (*logfile, string, error) {
 	}
 
 	lhp := strings.Split(string(lh), ";")
-	if len(lhp) != 3 {
-		return nil, "", xerrors.Errorf("expected loghead to have 3 parts")
+	if len(lhp) != 3 && len(lhp) != 4 {
+		return nil, "", xerrors.Errorf("expected loghead to have 3 or 4 parts")
+	}
+	// logs without a version only hold version 1 entries, which are a subset
+	// of the current ones
+	if len(lhp) == 4 {
+		v, err := strconv.Atoi(lhp[3])
+		if err != nil {
+			return nil, "", xerrors.Errorf("parsing log version: %w", err)
+		}
+		if v > logVersion {
+			return nil, "", xerrors.Errorf("log version %d is newer than the supported version %d", v, logVersion)
+		}
 	}
 
 	if lhp[0] != filepath.Base(p) {
@@ -211,8 +235,8 @@ func (d *Datastore) openLog(p string) (*logfile, string, error) {
 	}, filepath.Base(p), nil
 }
 
-func (l *logfile) writeLogHead(logname string, ds datastore.Batching) error {
-	lval := []byte(fmt.Sprintf("%s;%s;%d", logname, uuid.New(), time.Now().Unix()))
+func (l *logfile) writeLogHead(logname string, ds datastore.Batching) (string, error) {
+	lval := []byte(fmt.Sprintf("%s;%s;%d;%d", logname, uuid.New(), time.Now().Unix(), logVersion))
 
 	err := l.writeEntry(&Entry{
 		Key:       loghead.Bytes(),
@@ -220,16 +244,31 @@ func (l *logfile) writeLogHead(logname string, ds datastore.Batching) error {
 		Timestamp: time.Now().Unix(),
 	})
 	if err != nil {
-		return xerrors.Errorf("writing loghead to the log: %w", err)
+		return "", xerrors.Errorf("writing loghead to the log: %w", err)
 	}
 
 	if err := ds.Put(loghead, lval); err != nil {
-		return xerrors.Errorf("writing loghead to the datastore: %w", err)
+		return "", xerrors.Errorf("writing loghead to the datastore: %w", err)
 	}
 
 	log.Infow("new log head", "loghead", string(lval))
 
-	return nil
+	return string(lval), nil
+}
+
+// logMark is a position in the log. The file is empty if the position
+// couldn't be read.
+type logMark struct {
+	file   string
+	offset int64
+}
+
+func (l *logfile) mark() (logMark, error) {
+	at, err := l.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return logMark{}, xerrors.Errorf("get current logfile offset: %w", err)
+	}
+	return logMark{file: l.file.Name(), offset: at}, nil
 }
 
 func (l *logfile) writeEntry(e *Entry) error {
@@ -11,16 +11,24 @@ import (
 	"golang.org/x/xerrors"
 )
 
+// ReadBackup reads a backup or a log file, calling cb for each key, and for
+// each log entry with a nil value for deletions. It returns false when the log
+// is truncated and EPIK_ALLOW_TRUNCATED_LOG is set.
 func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool) error) (bool, error) {
+	_, clean, err := readBackup(r, cb)
+	return clean, err
+}
+
+func readBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool) error) ([]byte, bool, error) {
 	scratch := make([]byte, 9)
 
 	// read array[2](
 	if _, err := r.Read(scratch[:1]); err != nil {
-		return false, xerrors.Errorf("reading array header: %w", err)
+		return nil, false, xerrors.Errorf("reading array header: %w", err)
 	}
 
 	if scratch[0] != 0x82 {
-		return false, xerrors.Errorf("expected array(2) header byte 0x82, got %x", scratch[0])
+		return nil, false, xerrors.Errorf("expected array(2) header byte 0x82, got %x", scratch[0])
 	}
 
 	hasher := sha256.New()
@@ -28,16 +36,16 @@ func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool)
 
 	// read array[*](
 	if _, err := hr.Read(scratch[:1]); err != nil {
-		return false, xerrors.Errorf("reading array header: %w", err)
+		return nil, false, xerrors.Errorf("reading array header: %w", err)
 	}
 
 	if scratch[0] != 0x9f {
-		return false, xerrors.Errorf("expected indefinite length array header byte 0x9f, got %x", scratch[0])
+		return nil, false, xerrors.Errorf("expected indefinite length array header byte 0x9f, got %x", scratch[0])
 	}
 
 	for {
 		if _, err := hr.Read(scratch[:1]); err != nil {
-			return false, xerrors.Errorf("reading tuple header: %w", err)
+			return nil, false, xerrors.Errorf("reading tuple header: %w", err)
 		}
 
 		// close array[*]
@@ -47,22 +55,22 @@ func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool)
 
 		// read array[2](key:[]byte, value:[]byte)
 		if scratch[0] != 0x82 {
-			return false, xerrors.Errorf("expected array(2) header 0x82, got %x", scratch[0])
+			return nil, false, xerrors.Errorf("expected array(2) header 0x82, got %x", scratch[0])
 		}
 
 		keyb, err := cbg.ReadByteArray(hr, 1<<40)
 		if err != nil {
-			return false, xerrors.Errorf("reading key: %w", err)
+			return nil, false, xerrors.Errorf("reading key: %w", err)
 		}
 		key := datastore.NewKey(string(keyb))
 
 		value, err := cbg.ReadByteArray(hr, 1<<40)
 		if err != nil {
-			return false, xerrors.Errorf("reading value: %w", err)
+			return nil, false, xerrors.Errorf("reading value: %w", err)
 		}
 
 		if err := cb(key, value, false); err != nil {
-			return false, err
+			return nil, false, err
 		}
 	}
 
@@ -71,11 +79,11 @@ func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool)
 	// read the [32]byte checksum
 	expSum, err := cbg.ReadByteArray(r, 32)
 	if err != nil {
-		return false, xerrors.Errorf("reading expected checksum: %w", err)
+		return nil, false, xerrors.Errorf("reading expected checksum: %w", err)
 	}
 
 	if !bytes.Equal(sum, expSum) {
-		return false, xerrors.Errorf("checksum didn't match; expected %x, got %x", expSum, sum)
+		return nil, false, xerrors.Errorf("checksum didn't match; expected %x, got %x", expSum, sum)
 	}
 
 	// read the log, set of Entry-ies
@@ -86,13 +94,13 @@ func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool)
 		_, err := bp.ReadByte()
 		switch err {
 		case io.EOF, io.ErrUnexpectedEOF:
-			return true, nil
+			return sum, true, nil
 		case nil:
 		default:
-			return false, xerrors.Errorf("peek log: %w", err)
+			return nil, false, xerrors.Errorf("peek log: %w", err)
 		}
 		if err := bp.UnreadByte(); err != nil {
-			return false, xerrors.Errorf("unread log byte: %w", err)
+			return nil, false, xerrors.Errorf("unread log byte: %w", err)
 		}
 
 		if err := ent.UnmarshalCBOR(bp); err != nil {
@@ -100,43 +108,28 @@ func ReadBackup(r io.Reader, cb func(key datastore.Key, value []byte, log bool)
 			case io.EOF, io.ErrUnexpectedEOF:
 				if os.Getenv("EPIK_ALLOW_TRUNCATED_LOG") == "1" {
 					log.Errorw("log entry potentially truncated")
-					return false, nil
+					return sum, false, nil
 				} else {
-					return false, xerrors.Errorf("log entry potentially truncated, set EPIK_ALLOW_TRUNCATED_LOG=1 to proceed: %w", err)
+					return nil, false, xerrors.Errorf("log entry potentially truncated, set EPIK_ALLOW_TRUNCATED_LOG=1 to proceed: %w", err)
 				}
 			default:
-				return false, xerrors.Errorf("unmarshaling log entry: %w", err)
+				return nil, false, xerrors.Errorf("unmarshaling log entry: %w", err)
 			}
 		}
 
 		key := datastore.NewKey(string(ent.Key))
 
-		if err := cb(key, ent.Value, true); err != nil {
-			return false, err
+		value := ent.Value
+		if ent.Deleted {
+			value = nil
+		}
+		if err := cb(key, value, true); err != nil {
+			return nil, false, err
 		}
 	}
 }
 
+// RestoreInto restores a backup or a log file into dest.
 func RestoreInto(r io.Reader, dest datastore.Batching) error {
-	batch, err := dest.Batch()
-	if err != nil {
-		return xerrors.Errorf("creating batch: %w", err)
-	}
-
-	_, err = ReadBackup(r, func(key datastore.Key, value []byte, _ bool) error {
-		if err := batch.Put(key, value); err != nil {
-			return xerrors.Errorf("put key: %w", err)
-		}
-
-		return nil
-	})
-	if err != nil {
-		return xerrors.Errorf("reading backup: %w", err)
-	}
-
-	if err := batch.Commit(); err != nil {
-		return xerrors.Errorf("committing batch: %w", err)
-	}
-
-	return nil
+	return RestoreChainInto(dest, r)
 }
@@ -0,0 +1,244 @@
+package backupds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	fullSuffix        = ".full.cbor"
+	incrementalSuffix = ".incr.cbor"
+
+	// retryInterval is the time before retrying a failed scheduled backup
+	retryInterval = 5 * time.Minute
+)
+
+// Schedule configures periodic backups.
+type Schedule struct {
+	// Dir is the directory the backups are written to
+	Dir string
+	// Full is the time between full backups
+	Full time.Duration
+	// Incremental is the time between incremental backups, zero disables
+	// them
+	Incremental time.Duration
+	// Keep is the number of full backups kept, along with the incremental
+	// backups derived from them; zero keeps them all
+	Keep int
+}
+
+// BackupFile is a backup written by a schedule.
+type BackupFile struct {
+	Path        string
+	Time        time.Time
+	Incremental bool
+}
+
+// ListBackups returns the backups written by a schedule in dir, oldest first.
+// A full backup and the incremental backups following it form a chain to
+// restore with RestoreChainInto.
+func ListBackups(dir string) ([]BackupFile, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, xerrors.Errorf("reading backup dir: %w", err)
+	}
+
+	var out []BackupFile
+	for _, fi := range files {
+		name := fi.Name()
+
+		var incr bool
+		var ts string
+		switch {
+		case strings.HasSuffix(name, fullSuffix):
+			ts = strings.TrimSuffix(name, fullSuffix)
+		case strings.HasSuffix(name, incrementalSuffix):
+			ts, incr = strings.TrimSuffix(name, incrementalSuffix), true
+		default:
+			continue
+		}
+
+		ns, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			log.Warnw("backup file with unexpected name", "file", name)
+			continue
+		}
+
+		out = append(out, BackupFile{
+			Path:        filepath.Join(dir, name),
+			Time:        time.Unix(0, ns),
+			Incremental: incr,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+// RunSchedule takes the scheduled backups until the context is canceled.
+func (d *Datastore) RunSchedule(ctx context.Context, s Schedule) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		log.Errorw("creating backup dir, scheduled backups disabled", "dir", s.Dir, "error", err)
+		return
+	}
+
+	var lastFull, last time.Time
+	files, err := ListBackups(s.Dir)
+	if err != nil {
+		log.Errorw("listing backups", "error", err)
+	}
+	for _, f := range files {
+		if !f.Incremental {
+			lastFull = f.Time
+		}
+		last = f.Time
+	}
+
+	// retry is set after a failure, the next backup is then a full backup
+	var retry bool
+	for {
+		nextFull := lastFull.Add(s.Full)
+		next := nextFull
+		if retry {
+			next = last.Add(retryInterval)
+		} else if s.Incremental > 0 && last.Add(s.Incremental).Before(next) {
+			next = last.Add(s.Incremental)
+		}
+
+		select {
+		case <-time.After(time.Until(next)):
+		case <-ctx.Done():
+			return
+		}
+
+		full := retry || !time.Now().Before(nextFull)
+		if !full {
+			err := d.writeScheduled(s.Dir, true)
+			if xerrors.Is(err, ErrNoBase) {
+				log.Infow("no base for an incremental backup, taking a full backup")
+				full = true
+			} else if err != nil {
+				// the chain may be broken, start a new one
+				log.Errorw("scheduled incremental backup failed, taking a full backup next", "error", err)
+				last, retry = time.Now(), true
+				continue
+			}
+		}
+		if full {
+			if err := d.writeScheduled(s.Dir, false); err != nil {
+				log.Errorw("scheduled full backup failed", "error", err)
+				last, retry = time.Now(), true
+				continue
+			}
+			lastFull = time.Now()
+		}
+		last, retry = time.Now(), false
+
+		if err := pruneBackups(s.Dir, s.Keep); err != nil {
+			log.Errorw("pruning backups", "error", err)
+		}
+	}
+}
+
+// writeScheduled writes a backup to a temporary file, renamed once complete.
+// Incremental backups are skipped when nothing changed since the previous one.
+func (d *Datastore) writeScheduled(dir string, incremental bool) error {
+	if incremental {
+		changed, err := d.changedSinceBackup()
+		if err != nil {
+			return err
+		}
+		if !changed {
+			log.Debugw("nothing changed since the last backup, skipping the incremental backup")
+			return nil
+		}
+	}
+
+	suffix := fullSuffix
+	if incremental {
+		suffix = incrementalSuffix
+	}
+	p := filepath.Join(dir, fmt.Sprintf("%d%s", time.Now().UnixNano(), suffix))
+
+	f, err := os.OpenFile(p+".tmp", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return xerrors.Errorf("creating backup file: %w", err)
+	}
+
+	write := d.BackupFull
+	if incremental {
+		write = d.BackupIncremental
+	}
+	err = writeAndSync(f, write)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = xerrors.Errorf("closing backup file: %w", cerr)
+	}
+	if err != nil {
+		_ = os.Remove(p + ".tmp")
+		return err
+	}
+
+	if err := os.Rename(p+".tmp", p); err != nil {
+		return xerrors.Errorf("renaming backup file: %w", err)
+	}
+
+	log.Infow("scheduled backup written", "file", p)
+	return nil
+}
+
+func writeAndSync(f *os.File, write func(io.Writer) error) error {
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return xerrors.Errorf("syncing backup file: %w", err)
+	}
+	return nil
+}
+
+// pruneBackups removes the backups older than the keep-th most recent full
+// backup.
+func pruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	files, err := ListBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	var fulls []time.Time
+	for _, f := range files {
+		if !f.Incremental {
+			fulls = append(fulls, f.Time)
+		}
+	}
+	if len(fulls) <= keep {
+		return nil
+	}
+
+	oldest := fulls[len(fulls)-keep]
+	for _, f := range files {
+		if !f.Time.Before(oldest) {
+			break
+		}
+		if err := os.Remove(f.Path); err != nil {
+			return xerrors.Errorf("removing %s: %w", f.Path, err)
+		}
+		log.Infow("removed old backup", "file", f.Path)
+	}
+	return nil
+}
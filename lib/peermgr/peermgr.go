@@ -29,6 +29,8 @@ const (
 	MinFilPeers = 12
 )
 
+const goodBootstrapTag = "good-bootstrap"
+
 type MaybePeerMgr struct {
 	fx.In
 
@@ -134,6 +136,31 @@ func (pmgr *PeerMgr) SetPeerLatency(p peer.ID, latency time.Duration) {
 
 }
 
+// SetGoodPeer marks a peer as serving us well, or not anymore. Connections
+// to good bootstrap peers are protected from the connection manager, so that
+// we keep a reliable source of chain data. Other peers are left to the
+// connection manager.
+func (pmgr *PeerMgr) SetGoodPeer(p peer.ID, good bool) {
+	if !pmgr.isBootstrapper(p) {
+		return
+	}
+
+	if good {
+		pmgr.h.ConnManager().Protect(p, goodBootstrapTag)
+	} else {
+		pmgr.h.ConnManager().Unprotect(p, goodBootstrapTag)
+	}
+}
+
+func (pmgr *PeerMgr) isBootstrapper(p peer.ID) bool {
+	for _, bsp := range pmgr.bootstrappers {
+		if bsp.ID == p {
+			return true
+		}
+	}
+	return false
+}
+
 func (pmgr *PeerMgr) Disconnect(p peer.ID) {
 	disconnected := false
 
@@ -0,0 +1,247 @@
+package dealfilter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+var log = logging.Logger("dealfilter")
+
+// RuleFilter is an in-process deal filter evaluating the rules from
+// config.DealFilterRules
+type RuleFilter struct {
+	full api.FullNode
+
+	clientAllow, clientDeny map[address.Address]struct{}
+	peerAllow, peerDeny     map[peer.ID]struct{}
+	expertAllow             map[address.Address]struct{}
+
+	requireExpert       bool
+	minPieceSize        abi.PaddedPieceSize
+	maxPieceSize        abi.PaddedPieceSize
+	minPricePerGiBEpoch abi.TokenAmount
+	minStartDelay       abi.ChainEpoch
+	maxStartDelay       abi.ChainEpoch
+	clientDailyQuota    uint64
+
+	quotaLk  sync.Mutex
+	quotaDay string
+	quota    map[address.Address]uint64 // bytes accepted per client on quotaDay
+}
+
+func NewRuleFilter(cfg config.DealFilterRules) func(full api.FullNode) (*RuleFilter, error) {
+	return func(full api.FullNode) (*RuleFilter, error) {
+		rf := &RuleFilter{
+			full: full,
+
+			requireExpert:       cfg.RequireExpertData,
+			minPieceSize:        abi.PaddedPieceSize(cfg.MinPieceSize),
+			maxPieceSize:        abi.PaddedPieceSize(cfg.MaxPieceSize),
+			minPricePerGiBEpoch: big.Zero(),
+			minStartDelay:       abi.ChainEpoch(cfg.MinStartEpochDelay),
+			maxStartDelay:       abi.ChainEpoch(cfg.MaxStartEpochDelay),
+			clientDailyQuota:    cfg.ClientDailyQuota,
+
+			quota: map[address.Address]uint64{},
+		}
+
+		if cfg.MinPricePerGiBEpoch.Int != nil {
+			rf.minPricePerGiBEpoch = abi.TokenAmount(cfg.MinPricePerGiBEpoch)
+		}
+
+		var err error
+		if rf.clientAllow, err = parseAddrs(cfg.ClientAllowlist); err != nil {
+			return nil, xerrors.Errorf("parsing client allowlist: %w", err)
+		}
+		if rf.clientDeny, err = parseAddrs(cfg.ClientDenylist); err != nil {
+			return nil, xerrors.Errorf("parsing client denylist: %w", err)
+		}
+		if rf.expertAllow, err = parseAddrs(cfg.ExpertAllowlist); err != nil {
+			return nil, xerrors.Errorf("parsing expert allowlist: %w", err)
+		}
+		if rf.peerAllow, err = parsePeers(cfg.PeerAllowlist); err != nil {
+			return nil, xerrors.Errorf("parsing peer allowlist: %w", err)
+		}
+		if rf.peerDeny, err = parsePeers(cfg.PeerDenylist); err != nil {
+			return nil, xerrors.Errorf("parsing peer denylist: %w", err)
+		}
+
+		return rf, nil
+	}
+}
+
+func (rf *RuleFilter) StorageDealFilter(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+	prop := deal.Proposal
+
+	client, err := rf.full.StateLookupID(ctx, prop.Client, types.EmptyTSK)
+	if err != nil {
+		// the client may not be on chain yet, match by the proposal address
+		client = prop.Client
+	}
+
+	if inAddrSet(rf.clientDeny, prop.Client, client) {
+		return false, fmt.Sprintf("client %s is not allowed to make deals with this miner", prop.Client), nil
+	}
+	if len(rf.clientAllow) > 0 && !inAddrSet(rf.clientAllow, prop.Client, client) {
+		return false, fmt.Sprintf("client %s is not allowed to make deals with this miner", prop.Client), nil
+	}
+
+	if rf.minPieceSize > 0 && prop.PieceSize < rf.minPieceSize {
+		return false, fmt.Sprintf("piece size %d is below the minimum of %d", prop.PieceSize, rf.minPieceSize), nil
+	}
+	if rf.maxPieceSize > 0 && prop.PieceSize > rf.maxPieceSize {
+		return false, fmt.Sprintf("piece size %d is above the maximum of %d", prop.PieceSize, rf.maxPieceSize), nil
+	}
+
+	if !rf.minPricePerGiBEpoch.IsZero() {
+		minPrice := big.Div(big.Mul(rf.minPricePerGiBEpoch, big.NewIntUnsigned(uint64(prop.PieceSize))), big.NewInt(1<<30))
+		if prop.StoragePricePerEpoch.LessThan(minPrice) {
+			return false, fmt.Sprintf("storage price per epoch %s is below the minimum of %s", types.EPK(prop.StoragePricePerEpoch), types.EPK(minPrice)), nil
+		}
+	}
+
+	if rf.minStartDelay > 0 || rf.maxStartDelay > 0 {
+		head, err := rf.full.ChainHead(ctx)
+		if err != nil {
+			return false, "miner error", xerrors.Errorf("getting chain head: %w", err)
+		}
+
+		if rf.minStartDelay > 0 && prop.StartEpoch < head.Height()+rf.minStartDelay {
+			return false, fmt.Sprintf("deal start epoch %d is too early, must be at least %d", prop.StartEpoch, head.Height()+rf.minStartDelay), nil
+		}
+		if rf.maxStartDelay > 0 && prop.StartEpoch > head.Height()+rf.maxStartDelay {
+			return false, fmt.Sprintf("deal start epoch %d is too far in the future, must be at most %d", prop.StartEpoch, head.Height()+rf.maxStartDelay), nil
+		}
+	}
+
+	if ok, reason, err := rf.checkExpert(ctx, prop.PieceCID.String(), func() (*api.ExpertFileInfo, error) {
+		return rf.full.StateExpertFileInfo(ctx, prop.PieceCID, types.EmptyTSK)
+	}); !ok {
+		return ok, reason, err
+	}
+
+	if !rf.takeQuota(client, uint64(prop.PieceSize)) {
+		return false, fmt.Sprintf("client %s exceeded the daily deal quota of %s", prop.Client, types.SizeStr(types.NewInt(rf.clientDailyQuota))), nil
+	}
+
+	return true, "", nil
+}
+
+func (rf *RuleFilter) RetrievalDealFilter(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+	if _, denied := rf.peerDeny[deal.Receiver]; denied {
+		return false, fmt.Sprintf("peer %s is not allowed to retrieve from this miner", deal.Receiver), nil
+	}
+	if _, allowed := rf.peerAllow[deal.Receiver]; len(rf.peerAllow) > 0 && !allowed {
+		return false, fmt.Sprintf("peer %s is not allowed to retrieve from this miner", deal.Receiver), nil
+	}
+
+	if deal.PieceCID != nil {
+		pieceCid := *deal.PieceCID
+		if ok, reason, err := rf.checkExpert(ctx, pieceCid.String(), func() (*api.ExpertFileInfo, error) {
+			return rf.full.StateExpertFileInfo(ctx, pieceCid, types.EmptyTSK)
+		}); !ok {
+			return ok, reason, err
+		}
+	}
+
+	return true, "", nil
+}
+
+func (rf *RuleFilter) checkExpert(ctx context.Context, piece string, info func() (*api.ExpertFileInfo, error)) (bool, string, error) {
+	if !rf.requireExpert && len(rf.expertAllow) == 0 {
+		return true, "", nil
+	}
+
+	fi, err := info()
+	if err != nil {
+		log.Warnf("looking up expert of piece %s: %s", piece, err)
+		return false, fmt.Sprintf("piece %s is not registered by an expert", piece), nil
+	}
+
+	if len(rf.expertAllow) > 0 {
+		expert, err := rf.full.StateLookupID(ctx, fi.Expert, types.EmptyTSK)
+		if err != nil {
+			expert = fi.Expert
+		}
+
+		if !inAddrSet(rf.expertAllow, fi.Expert, expert) {
+			return false, fmt.Sprintf("data of expert %s is not accepted by this miner", fi.Expert), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// takeQuota accounts size bytes against the client quota for the current
+// day, returning false when the quota would be exceeded. Quota usage is kept
+// in memory, and starts from zero after a restart.
+func (rf *RuleFilter) takeQuota(client address.Address, size uint64) bool {
+	if rf.clientDailyQuota == 0 {
+		return true
+	}
+
+	rf.quotaLk.Lock()
+	defer rf.quotaLk.Unlock()
+
+	day := time.Now().UTC().Format("2006-01-02")
+	if day != rf.quotaDay {
+		rf.quotaDay = day
+		rf.quota = map[address.Address]uint64{}
+	}
+
+	if rf.quota[client]+size > rf.clientDailyQuota {
+		return false
+	}
+
+	rf.quota[client] += size
+	return true
+}
+
+func inAddrSet(set map[address.Address]struct{}, addrs ...address.Address) bool {
+	for _, a := range addrs {
+		if _, ok := set[a]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAddrs(in []string) (map[address.Address]struct{}, error) {
+	out := make(map[address.Address]struct{}, len(in))
+	for _, s := range in {
+		a, err := address.NewFromString(s)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing address %s: %w", s, err)
+		}
+		out[a] = struct{}{}
+	}
+	return out, nil
+}
+
+func parsePeers(in []string) (map[peer.ID]struct{}, error) {
+	out := make(map[peer.ID]struct{}, len(in))
+	for _, s := range in {
+		p, err := peer.Decode(s)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing peer ID %s: %w", s, err)
+		}
+		out[p] = struct{}{}
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package dealfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/chain/types/mock"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+type ruleFullNode struct {
+	api.FullNode
+	head abi.ChainEpoch
+	// experts are the experts which registered each piece
+	experts map[cid.Cid]address.Address
+}
+
+func (n *ruleFullNode) StateLookupID(_ context.Context, a address.Address, _ types.TipSetKey) (address.Address, error) {
+	if a.Protocol() != address.ID {
+		return address.Undef, xerrors.New("not found")
+	}
+	return a, nil
+}
+
+func (n *ruleFullNode) StateExpertFileInfo(_ context.Context, piece cid.Cid, _ types.TipSetKey) (*api.ExpertFileInfo, error) {
+	expert, ok := n.experts[piece]
+	if !ok {
+		return nil, xerrors.New("not found")
+	}
+	return &api.ExpertFileInfo{Expert: expert, PieceID: piece}, nil
+}
+
+func (n *ruleFullNode) ChainHead(context.Context) (*types.TipSet, error) {
+	blk := mock.MkBlock(nil, 1, 1)
+	blk.Height = n.head
+	return mock.TipSet(blk), nil
+}
+
+func TestRuleStorageDealFilter(t *testing.T) {
+	allowed, denied, other := mock.Address(1000), mock.Address(1001), mock.Address(1002)
+
+	deal := func(client address.Address, size abi.PaddedPieceSize, price int64, start abi.ChainEpoch) storagemarket.MinerDeal {
+		return storagemarket.MinerDeal{
+			ClientDealProposal: market.ClientDealProposal{
+				Proposal: market.DealProposal{
+					PieceCID:             mock.MkBlock(nil, 1, 1).Cid(),
+					PieceSize:            size,
+					Client:               client,
+					StartEpoch:           start,
+					StoragePricePerEpoch: big.NewInt(price),
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		rules  config.DealFilterRules
+		deal   storagemarket.MinerDeal
+		accept bool
+	}{{
+		name:   "default accepts",
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: true,
+	}, {
+		name:   "client denied",
+		rules:  config.DealFilterRules{ClientDenylist: []string{denied.String()}},
+		deal:   deal(denied, 1<<30, 0, 0),
+		accept: false,
+	}, {
+		name:   "client not denied",
+		rules:  config.DealFilterRules{ClientDenylist: []string{denied.String()}},
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: true,
+	}, {
+		name:   "client allowed",
+		rules:  config.DealFilterRules{ClientAllowlist: []string{allowed.String()}},
+		deal:   deal(allowed, 1<<30, 0, 0),
+		accept: true,
+	}, {
+		name:   "client not allowed",
+		rules:  config.DealFilterRules{ClientAllowlist: []string{allowed.String()}},
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: false,
+	}, {
+		name:   "deny wins over allow",
+		rules:  config.DealFilterRules{ClientAllowlist: []string{allowed.String()}, ClientDenylist: []string{allowed.String()}},
+		deal:   deal(allowed, 1<<30, 0, 0),
+		accept: false,
+	}, {
+		name:   "piece too small",
+		rules:  config.DealFilterRules{MinPieceSize: 2 << 30},
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: false,
+	}, {
+		name:   "piece too large",
+		rules:  config.DealFilterRules{MaxPieceSize: 512 << 20},
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: false,
+	}, {
+		name:   "piece size in range",
+		rules:  config.DealFilterRules{MinPieceSize: 512 << 20, MaxPieceSize: 2 << 30},
+		deal:   deal(other, 1<<30, 0, 0),
+		accept: true,
+	}, {
+		name:   "price too low",
+		rules:  config.DealFilterRules{MinPricePerGiBEpoch: types.EPK(big.NewInt(100))},
+		deal:   deal(other, 2<<30, 150, 0),
+		accept: false,
+	}, {
+		name:   "price high enough",
+		rules:  config.DealFilterRules{MinPricePerGiBEpoch: types.EPK(big.NewInt(100))},
+		deal:   deal(other, 2<<30, 200, 0),
+		accept: true,
+	}, {
+		name:   "start too early",
+		rules:  config.DealFilterRules{MinStartEpochDelay: 50},
+		deal:   deal(other, 1<<30, 0, 120),
+		accept: false,
+	}, {
+		name:   "start too late",
+		rules:  config.DealFilterRules{MaxStartEpochDelay: 50},
+		deal:   deal(other, 1<<30, 0, 200),
+		accept: false,
+	}, {
+		name:   "start in window",
+		rules:  config.DealFilterRules{MinStartEpochDelay: 10, MaxStartEpochDelay: 50},
+		deal:   deal(other, 1<<30, 0, 120),
+		accept: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rf, err := NewRuleFilter(tc.rules)(&ruleFullNode{head: 100})
+			require.NoError(t, err)
+
+			accept, reason, err := rf.StorageDealFilter(context.Background(), tc.deal)
+			require.NoError(t, err)
+			require.Equal(t, tc.accept, accept, reason)
+			if !accept {
+				require.NotEmpty(t, reason)
+			}
+		})
+	}
+}
+
+func TestRuleFilterClientQuota(t *testing.T) {
+	rf, err := NewRuleFilter(config.DealFilterRules{ClientDailyQuota: 3 << 30})(&ruleFullNode{})
+	require.NoError(t, err)
+
+	client := mock.Address(1000)
+	d := storagemarket.MinerDeal{
+		ClientDealProposal: market.ClientDealProposal{
+			Proposal: market.DealProposal{
+				PieceCID:             mock.MkBlock(nil, 1, 1).Cid(),
+				PieceSize:            1 << 30,
+				Client:               client,
+				StoragePricePerEpoch: big.Zero(),
+			},
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		accept, _, err := rf.StorageDealFilter(context.Background(), d)
+		require.NoError(t, err)
+		require.True(t, accept)
+	}
+
+	accept, _, err := rf.StorageDealFilter(context.Background(), d)
+	require.NoError(t, err)
+	require.False(t, accept)
+
+	d.Proposal.Client = mock.Address(1001)
+	accept, _, err = rf.StorageDealFilter(context.Background(), d)
+	require.NoError(t, err)
+	require.True(t, accept)
+}
+
+func TestNewRuleFilterInvalid(t *testing.T) {
+	_, err := NewRuleFilter(config.DealFilterRules{ClientDenylist: []string{"not an address"}})(&ruleFullNode{})
+	require.Error(t, err)
+}
+
+func TestRuleFilterExpert(t *testing.T) {
+	allowed, other := mock.Address(2000), mock.Address(2001)
+	allowedPiece := mock.MkBlock(nil, 1, 1).Cid()
+	otherPiece := mock.MkBlock(nil, 1, 2).Cid()
+	unregistered := mock.MkBlock(nil, 1, 3).Cid()
+
+	full := &ruleFullNode{experts: map[cid.Cid]address.Address{
+		allowedPiece: allowed,
+		otherPiece:   other,
+	}}
+
+	storageDeal := func(piece cid.Cid) storagemarket.MinerDeal {
+		return storagemarket.MinerDeal{
+			ClientDealProposal: market.ClientDealProposal{
+				Proposal: market.DealProposal{
+					PieceCID:             piece,
+					PieceSize:            1 << 30,
+					Client:               mock.Address(1000),
+					StoragePricePerEpoch: big.Zero(),
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		rules  config.DealFilterRules
+		piece  cid.Cid
+		accept bool
+	}{{
+		name:   "expert data accepted",
+		rules:  config.DealFilterRules{RequireExpertData: true},
+		piece:  otherPiece,
+		accept: true,
+	}, {
+		name:   "data without expert rejected",
+		rules:  config.DealFilterRules{RequireExpertData: true},
+		piece:  unregistered,
+		accept: false,
+	}, {
+		name:   "allowed expert",
+		rules:  config.DealFilterRules{ExpertAllowlist: []string{allowed.String()}},
+		piece:  allowedPiece,
+		accept: true,
+	}, {
+		name:   "expert not allowed",
+		rules:  config.DealFilterRules{ExpertAllowlist: []string{allowed.String()}},
+		piece:  otherPiece,
+		accept: false,
+	}, {
+		name:   "allowlist requires an expert",
+		rules:  config.DealFilterRules{ExpertAllowlist: []string{allowed.String()}},
+		piece:  unregistered,
+		accept: false,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rf, err := NewRuleFilter(tc.rules)(full)
+			require.NoError(t, err)
+
+			accept, reason, err := rf.StorageDealFilter(context.Background(), storageDeal(tc.piece))
+			require.NoError(t, err)
+			require.Equal(t, tc.accept, accept, reason)
+
+			piece := tc.piece
+			accept, reason, err = rf.RetrievalDealFilter(context.Background(), retrievalmarket.ProviderDealState{PieceCID: &piece})
+			require.NoError(t, err)
+			require.Equal(t, tc.accept, accept, reason)
+			if !accept {
+				require.NotEmpty(t, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package dealfilter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/EpiK-Protocol/go-epik/node/config"
+	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
+)
+
+// WebhookResponse is the response expected from a deal filter webhook
+type WebhookResponse struct {
+	Accept bool
+	Reason string
+}
+
+func WebhookStorageDealFilter(cfg config.DealFilterWebhook) dtypes.StorageDealFilter {
+	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		d := struct {
+			storagemarket.MinerDeal
+			DealType string
+		}{
+			MinerDeal: deal,
+			DealType:  "storage",
+		}
+		return runWebhookFilter(ctx, cfg, d)
+	}
+}
+
+func WebhookRetrievalDealFilter(cfg config.DealFilterWebhook) dtypes.RetrievalDealFilter {
+	return func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+		d := struct {
+			retrievalmarket.ProviderDealState
+			DealType string
+		}{
+			ProviderDealState: deal,
+			DealType:          "retrieval",
+		}
+		return runWebhookFilter(ctx, cfg, d)
+	}
+}
+
+// runWebhookFilter POSTs the deal as JSON (same format as passed to the cli
+// filter) to the webhook, and expects a WebhookResponse back
+func runWebhookFilter(ctx context.Context, cfg config.DealFilterWebhook, deal interface{}) (bool, string, error) {
+	res, err := callWebhook(ctx, cfg, deal)
+	if err != nil {
+		if cfg.FailOpen {
+			log.Warnf("deal filter webhook failed, accepting deal (fail-open): %s", err)
+			return true, "", nil
+		}
+
+		log.Warnf("deal filter webhook failed, rejecting deal: %s", err)
+		return false, "filter webhook error", nil
+	}
+
+	return res.Accept, res.Reason, nil
+}
+
+func callWebhook(ctx context.Context, cfg config.DealFilterWebhook, deal interface{}) (*WebhookResponse, error) {
+	j, err := json.Marshal(deal)
+	if err != nil {
+		return nil, xerrors.Errorf("marshaling deal: %w", err)
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout))
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(j))
+	if err != nil {
+		return nil, xerrors.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("calling webhook: %w", err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, xerrors.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var out WebhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, xerrors.Errorf("decoding webhook response: %w", err)
+	}
+
+	if !out.Accept && out.Reason == "" {
+		out.Reason = "rejected by deal filter webhook"
+	}
+
+	return &out, nil
+}
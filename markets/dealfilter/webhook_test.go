@@ -0,0 +1,60 @@
+package dealfilter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+func TestWebhookDealFilter(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d struct {
+			DealType string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&d))
+		require.Equal(t, "storage", d.DealType)
+
+		_ = json.NewEncoder(w).Encode(WebhookResponse{Accept: false, Reason: "no thanks"})
+	}))
+	defer srv.Close()
+
+	filter := WebhookStorageDealFilter(config.DealFilterWebhook{URL: srv.URL})
+	accept, reason, err := filter(ctx, storagemarket.MinerDeal{})
+	require.NoError(t, err)
+	require.False(t, accept)
+	require.Equal(t, "no thanks", reason)
+}
+
+func TestWebhookDealFilterFailPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	cfg := config.DealFilterWebhook{
+		URL:     srv.URL,
+		Timeout: config.Duration(50 * time.Millisecond),
+	}
+
+	accept, _, err := WebhookStorageDealFilter(cfg)(ctx, storagemarket.MinerDeal{})
+	require.NoError(t, err)
+	require.False(t, accept, "fail-closed webhook should reject deals on timeout")
+
+	cfg.FailOpen = true
+	accept, _, err = WebhookStorageDealFilter(cfg)(ctx, storagemarket.MinerDeal{})
+	require.NoError(t, err)
+	require.True(t, accept, "fail-open webhook should accept deals on timeout")
+}
@@ -1,6 +1,12 @@
 package markets
 
 import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 
@@ -27,6 +33,24 @@ type RetrievalProviderEvt struct {
 	Deal  retrievalmarket.ProviderDealState
 }
 
+type StorageDealFilterEvt struct {
+	ProposalCid cid.Cid
+	Client      address.Address
+	PieceCid    cid.Cid
+	Accept      bool
+	Reason      string
+	Error       string `json:",omitempty"`
+}
+
+type RetrievalDealFilterEvt struct {
+	DealID     retrievalmarket.DealID
+	Receiver   peer.ID
+	PayloadCid cid.Cid
+	Accept     bool
+	Reason     string
+	Error      string `json:",omitempty"`
+}
+
 // StorageClientJournaler records journal events from the storage client.
 func StorageClientJournaler(j journal.Journal, evtType journal.EventType) func(event storagemarket.ClientEvent, deal storagemarket.ClientDeal) {
 	return func(event storagemarket.ClientEvent, deal storagemarket.ClientDeal) {
@@ -74,3 +98,45 @@ func RetrievalProviderJournaler(j journal.Journal, evtType journal.EventType) fu
 		})
 	}
 }
+
+// StorageDealFilterJournaler records the decisions of a storage deal filter.
+func StorageDealFilterJournaler(j journal.Journal, evtType journal.EventType, filter func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error)) func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+	return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		accept, reason, err := filter(ctx, deal)
+		j.RecordEvent(evtType, func() interface{} {
+			evt := StorageDealFilterEvt{
+				ProposalCid: deal.ProposalCid,
+				Client:      deal.Proposal.Client,
+				PieceCid:    deal.Proposal.PieceCID,
+				Accept:      accept,
+				Reason:      reason,
+			}
+			if err != nil {
+				evt.Error = err.Error()
+			}
+			return evt
+		})
+		return accept, reason, err
+	}
+}
+
+// RetrievalDealFilterJournaler records the decisions of a retrieval deal filter.
+func RetrievalDealFilterJournaler(j journal.Journal, evtType journal.EventType, filter func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error)) func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+	return func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+		accept, reason, err := filter(ctx, deal)
+		j.RecordEvent(evtType, func() interface{} {
+			evt := RetrievalDealFilterEvt{
+				DealID:     deal.ID,
+				Receiver:   deal.Receiver,
+				PayloadCid: deal.PayloadCID,
+				Accept:     accept,
+				Reason:     reason,
+			}
+			if err != nil {
+				evt.Error = err.Error()
+			}
+			return evt
+		})
+		return accept, reason, err
+	}
+}
@@ -0,0 +1,222 @@
+package retrievaladapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/stores"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+var log = logging.Logger("retrievaladapter")
+
+// PricingInput describes a retrieval a price is requested for
+type PricingInput struct {
+	PayloadCID cid.Cid
+	PieceCID   cid.Cid // cid.Undef when the piece isn't known
+
+	// Client is the client wallet, address.Undef when it isn't known
+	Client address.Address
+	// AnyClient asks for the lowest terms any client could get when the
+	// client isn't known, used to check proposals which don't identify the
+	// client wallet
+	AnyClient bool
+
+	// Unsealed is set when an unsealed copy of the piece is available, so
+	// retrieving it doesn't require unsealing
+	Unsealed bool
+
+	// Ask is the global retrieval ask of the miner, used as the base price
+	Ask retrievalmarket.Ask
+}
+
+// PricingFunc returns the retrieval terms the miner is willing to serve a
+// retrieval for
+type PricingFunc func(ctx context.Context, in PricingInput) (retrievalmarket.Ask, error)
+
+// DefaultPricing serves all retrievals with the global retrieval ask
+func DefaultPricing(ctx context.Context, in PricingInput) (retrievalmarket.Ask, error) {
+	return in.Ask, nil
+}
+
+// NewPricingPolicy returns a PricingFunc implementing the policy from
+// config.RetrievalPricing. Prices are picked from the most specific setting:
+// piece price, then the price for the expert owning the data, then the
+// global ask. Clients with retrieval pledges bound to the miner get the bound
+// client discount on top.
+func NewPricingPolicy(cfg config.RetrievalPricing, maddr address.Address, full api.FullNode) (PricingFunc, error) {
+	piecePrices := map[cid.Cid]abi.TokenAmount{}
+	for k, v := range cfg.PiecePricePerByte {
+		c, err := cid.Parse(k)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing piece cid %s: %w", k, err)
+		}
+		piecePrices[c] = abi.TokenAmount(v)
+	}
+
+	expertPrices := map[address.Address]abi.TokenAmount{}
+	for k, v := range cfg.ExpertPricePerByte {
+		a, err := address.NewFromString(k)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing expert address %s: %w", k, err)
+		}
+		expertPrices[a] = abi.TokenAmount(v)
+	}
+
+	if cfg.BoundClientDiscount > 100 {
+		return nil, xerrors.Errorf("bound client discount must be a percentage, got %d", cfg.BoundClientDiscount)
+	}
+
+	return func(ctx context.Context, in PricingInput) (retrievalmarket.Ask, error) {
+		out := in.Ask
+
+		if in.PieceCID.Defined() {
+			if p, ok := piecePrices[in.PieceCID]; ok {
+				out.PricePerByte = p
+			} else if len(expertPrices) > 0 {
+				fi, err := full.StateExpertFileInfo(ctx, in.PieceCID, types.EmptyTSK)
+				if err == nil {
+					if p, ok := expertPrices[fi.Expert]; ok {
+						out.PricePerByte = p
+					} else if ida, err := full.StateLookupID(ctx, fi.Expert, types.EmptyTSK); err == nil {
+						if p, ok := expertPrices[ida]; ok {
+							out.PricePerByte = p
+						}
+					}
+				}
+			}
+		}
+
+		if cfg.BoundClientDiscount > 0 {
+			bound := in.Client == address.Undef && in.AnyClient
+			if in.Client != address.Undef {
+				var err error
+				bound, err = isBoundClient(ctx, full, maddr, in.Client)
+				if err != nil {
+					log.Warnf("checking retrieval pledge of client %s: %s", in.Client, err)
+				}
+			}
+			if bound {
+				out.PricePerByte = applyDiscount(out.PricePerByte, cfg.BoundClientDiscount)
+				out.UnsealPrice = applyDiscount(out.UnsealPrice, cfg.BoundClientDiscount)
+			}
+		}
+
+		if in.Unsealed && cfg.WaiveUnsealPriceIfUnsealed {
+			out.UnsealPrice = big.Zero()
+		}
+
+		return out, nil
+	}, nil
+}
+
+func applyDiscount(price abi.TokenAmount, percent uint64) abi.TokenAmount {
+	return big.Div(big.Mul(price, big.NewIntUnsigned(100-percent)), big.NewInt(100))
+}
+
+// isBoundClient checks whether the client has a retrieval pledge bound to the miner
+func isBoundClient(ctx context.Context, full api.FullNode, maddr address.Address, client address.Address) (bool, error) {
+	st, err := full.StateRetrievalPledge(ctx, client, types.EmptyTSK)
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range st.BindMiners {
+		if m == maddr {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewPricingInput collects pricing input for a retrieval of the payload,
+// looking up the piece and whether it has an unsealed copy
+func NewPricingInput(ctx context.Context, ps piecestore.PieceStore, index stores.SectorIndex, maddr address.Address, payload cid.Cid, piece *cid.Cid, client address.Address, ask retrievalmarket.Ask) PricingInput {
+	in := PricingInput{
+		PayloadCID: payload,
+		PieceCID:   cid.Undef,
+		Client:     client,
+		Ask:        ask,
+	}
+
+	if piece != nil {
+		in.PieceCID = *piece
+	} else if ci, err := ps.GetCIDInfo(payload); err == nil && len(ci.PieceBlockLocations) > 0 {
+		in.PieceCID = ci.PieceBlockLocations[0].PieceCID
+	}
+
+	if in.PieceCID.Defined() {
+		unsealed, err := HasUnsealedCopy(ctx, ps, index, maddr, in.PieceCID)
+		if err != nil {
+			log.Warnf("checking for unsealed copy of piece %s: %s", in.PieceCID, err)
+		}
+		in.Unsealed = unsealed
+	}
+
+	return in
+}
+
+// PricingDealFilter rejects retrieval deals which were proposed with terms
+// below the price returned by the pricing function for the deal
+func PricingDealFilter(pricing PricingFunc, ask func() *retrievalmarket.Ask, ps piecestore.PieceStore, index stores.SectorIndex, maddr address.Address) func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+	return func(ctx context.Context, deal retrievalmarket.ProviderDealState) (bool, string, error) {
+		// The proposal doesn't identify the client wallet, so it is checked
+		// against the lowest terms a bound client would be quoted
+		in := NewPricingInput(ctx, ps, index, maddr, deal.PayloadCID, deal.PieceCID, address.Undef, *ask())
+		in.AnyClient = true
+
+		price, err := pricing(ctx, in)
+		if err != nil {
+			return false, "miner error", xerrors.Errorf("computing retrieval price: %w", err)
+		}
+
+		if deal.PricePerByte.LessThan(price.PricePerByte) {
+			return false, fmt.Sprintf("retrieval price per byte for this data is %s", types.EPK(price.PricePerByte)), nil
+		}
+		if deal.UnsealPrice.LessThan(price.UnsealPrice) {
+			return false, fmt.Sprintf("unseal price for this data is %s", types.EPK(price.UnsealPrice)), nil
+		}
+
+		return true, "", nil
+	}
+}
+
+// HasUnsealedCopy checks whether any sector holding the piece has an
+// unsealed copy in storage
+func HasUnsealedCopy(ctx context.Context, ps piecestore.PieceStore, index stores.SectorIndex, maddr address.Address, pieceCid cid.Cid) (bool, error) {
+	mid, err := address.IDFromAddress(maddr)
+	if err != nil {
+		return false, err
+	}
+
+	pi, err := ps.GetPieceInfo(pieceCid)
+	if err != nil {
+		return false, xerrors.Errorf("getting piece info: %w", err)
+	}
+
+	for _, d := range pi.Deals {
+		sid := abi.SectorID{Miner: abi.ActorID(mid), Number: d.SectorID}
+		si, err := index.StorageFindSector(ctx, sid, storiface.FTUnsealed, 0, false)
+		if err != nil {
+			return false, xerrors.Errorf("finding unsealed sector %d: %w", d.SectorID, err)
+		}
+		if len(si) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,173 @@
+package retrievaladapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	rmnet "github.com/filecoin-project/go-fil-markets/retrievalmarket/network"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+func TestPricingPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	piece, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+
+	pricing, err := NewPricingPolicy(config.RetrievalPricing{
+		Enable: true,
+		PiecePricePerByte: map[string]types.EPK{
+			piece.String(): types.EPK(abi.NewTokenAmount(5)),
+		},
+		WaiveUnsealPriceIfUnsealed: true,
+	}, address.Undef, nil)
+	require.NoError(t, err)
+
+	ask := retrievalmarket.Ask{
+		PricePerByte: abi.NewTokenAmount(1),
+		UnsealPrice:  abi.NewTokenAmount(100),
+	}
+
+	out, err := pricing(ctx, PricingInput{PieceCID: cid.Undef, Ask: ask})
+	require.NoError(t, err)
+	require.Equal(t, ask, out, "unknown piece should get the global ask")
+
+	out, err = pricing(ctx, PricingInput{PieceCID: piece, Ask: ask})
+	require.NoError(t, err)
+	require.True(t, out.PricePerByte.Equals(abi.NewTokenAmount(5)))
+	require.True(t, out.UnsealPrice.Equals(abi.NewTokenAmount(100)))
+
+	out, err = pricing(ctx, PricingInput{PieceCID: piece, Unsealed: true, Ask: ask})
+	require.NoError(t, err)
+	require.True(t, out.UnsealPrice.IsZero())
+}
+
+// pledgeNode serves the miners the retrieval pledges of clients are bound to
+type pledgeNode struct {
+	api.FullNode
+	bound map[address.Address][]address.Address
+}
+
+func (n *pledgeNode) StateRetrievalPledge(_ context.Context, client address.Address, _ types.TipSetKey) (*api.RetrievalState, error) {
+	miners, ok := n.bound[client]
+	if !ok {
+		return nil, xerrors.New("no retrieval pledge")
+	}
+	return &api.RetrievalState{BindMiners: miners}, nil
+}
+
+func TestPricingBoundClient(t *testing.T) {
+	ctx := context.Background()
+
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	otherMiner, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	bound, err := address.NewIDAddress(2000)
+	require.NoError(t, err)
+	boundElsewhere, err := address.NewIDAddress(2001)
+	require.NoError(t, err)
+	unpledged, err := address.NewIDAddress(2002)
+	require.NoError(t, err)
+
+	pricing, err := NewPricingPolicy(config.RetrievalPricing{
+		Enable:              true,
+		BoundClientDiscount: 25,
+	}, maddr, &pledgeNode{bound: map[address.Address][]address.Address{
+		bound:          {otherMiner, maddr},
+		boundElsewhere: {otherMiner},
+	}})
+	require.NoError(t, err)
+
+	ask := retrievalmarket.Ask{
+		PricePerByte: abi.NewTokenAmount(100),
+		UnsealPrice:  abi.NewTokenAmount(40),
+	}
+
+	out, err := pricing(ctx, PricingInput{Client: bound, Ask: ask})
+	require.NoError(t, err)
+	require.True(t, out.PricePerByte.Equals(abi.NewTokenAmount(75)))
+	require.True(t, out.UnsealPrice.Equals(abi.NewTokenAmount(30)))
+
+	for _, client := range []address.Address{boundElsewhere, unpledged, address.Undef} {
+		out, err = pricing(ctx, PricingInput{Client: client, Ask: ask})
+		require.NoError(t, err)
+		require.Equal(t, ask, out, "client %s should get the global ask", client)
+	}
+
+	// proposals don't identify the client, they are checked against the
+	// terms of bound clients
+	out, err = pricing(ctx, PricingInput{AnyClient: true, Ask: ask})
+	require.NoError(t, err)
+	require.True(t, out.PricePerByte.Equals(abi.NewTokenAmount(75)))
+
+	_, err = NewPricingPolicy(config.RetrievalPricing{BoundClientDiscount: 101}, maddr, nil)
+	require.Error(t, err)
+}
+
+type fakeQueryStream struct {
+	rmnet.RetrievalQueryStream
+
+	query retrievalmarket.Query
+	resp  *retrievalmarket.QueryResponse
+}
+
+func (s *fakeQueryStream) ReadQuery() (retrievalmarket.Query, error) {
+	return s.query, nil
+}
+
+func (s *fakeQueryStream) WriteQueryResponse(resp retrievalmarket.QueryResponse) error {
+	s.resp = &resp
+	return nil
+}
+
+func TestPricingQueryStream(t *testing.T) {
+	piece, err := cid.Parse("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+
+	fs := &fakeQueryStream{query: retrievalmarket.Query{
+		PayloadCID:  piece,
+		QueryParams: retrievalmarket.QueryParams{PieceCID: &piece},
+	}}
+	s := &pricingQueryStream{
+		RetrievalQueryStream: fs,
+		quote: func(ctx context.Context, q retrievalmarket.Query) (retrievalmarket.Ask, error) {
+			require.Equal(t, piece, *q.PieceCID)
+			return retrievalmarket.Ask{
+				PricePerByte:            abi.NewTokenAmount(5),
+				UnsealPrice:             abi.NewTokenAmount(7),
+				PaymentInterval:         10,
+				PaymentIntervalIncrease: 20,
+			}, nil
+		},
+	}
+
+	_, err = s.ReadQuery()
+	require.NoError(t, err)
+	require.NoError(t, s.WriteQueryResponse(retrievalmarket.QueryResponse{
+		Status:          retrievalmarket.QueryResponseAvailable,
+		MinPricePerByte: abi.NewTokenAmount(1),
+		UnsealPrice:     abi.NewTokenAmount(1),
+	}))
+	require.True(t, fs.resp.MinPricePerByte.Equals(abi.NewTokenAmount(5)))
+	require.True(t, fs.resp.UnsealPrice.Equals(abi.NewTokenAmount(7)))
+	require.Equal(t, uint64(10), fs.resp.MaxPaymentInterval)
+	require.Equal(t, uint64(20), fs.resp.MaxPaymentIntervalIncrease)
+
+	// unavailable payloads are passed through
+	require.NoError(t, s.WriteQueryResponse(retrievalmarket.QueryResponse{
+		Status:          retrievalmarket.QueryResponseUnavailable,
+		MinPricePerByte: abi.NewTokenAmount(1),
+	}))
+	require.True(t, fs.resp.MinPricePerByte.Equals(abi.NewTokenAmount(1)))
+}
@@ -0,0 +1,87 @@
+package retrievaladapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	rmnet "github.com/filecoin-project/go-fil-markets/retrievalmarket/network"
+
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/stores"
+)
+
+const queryPricingTimeout = 30 * time.Second
+
+// PricingNetwork wraps the retrieval market network of the provider so that
+// the terms in query responses come from the pricing function instead of the
+// global retrieval ask. Clients propose deals with the terms from the query
+// response, so they match what PricingDealFilter accepts.
+func PricingNetwork(net rmnet.RetrievalMarketNetwork, pricing PricingFunc, ask func() *retrievalmarket.Ask, ps piecestore.PieceStore, index stores.SectorIndex, maddr address.Address) rmnet.RetrievalMarketNetwork {
+	return &pricingNetwork{
+		RetrievalMarketNetwork: net,
+		quote: func(ctx context.Context, q retrievalmarket.Query) (retrievalmarket.Ask, error) {
+			// queries don't identify the client, they get the public terms
+			return pricing(ctx, NewPricingInput(ctx, ps, index, maddr, q.PayloadCID, q.PieceCID, address.Undef, *ask()))
+		},
+	}
+}
+
+type quoteFunc func(ctx context.Context, q retrievalmarket.Query) (retrievalmarket.Ask, error)
+
+type pricingNetwork struct {
+	rmnet.RetrievalMarketNetwork
+	quote quoteFunc
+}
+
+func (n *pricingNetwork) SetDelegate(r rmnet.RetrievalReceiver) error {
+	return n.RetrievalMarketNetwork.SetDelegate(&pricingReceiver{RetrievalReceiver: r, quote: n.quote})
+}
+
+type pricingReceiver struct {
+	rmnet.RetrievalReceiver
+	quote quoteFunc
+}
+
+func (r *pricingReceiver) HandleQueryStream(s rmnet.RetrievalQueryStream) {
+	r.RetrievalReceiver.HandleQueryStream(&pricingQueryStream{RetrievalQueryStream: s, quote: r.quote})
+}
+
+// pricingQueryStream remembers the query read by the provider, and rewrites
+// the terms of the response written for it
+type pricingQueryStream struct {
+	rmnet.RetrievalQueryStream
+	quote quoteFunc
+
+	query *retrievalmarket.Query
+}
+
+func (s *pricingQueryStream) ReadQuery() (retrievalmarket.Query, error) {
+	q, err := s.RetrievalQueryStream.ReadQuery()
+	if err == nil {
+		s.query = &q
+	}
+	return q, err
+}
+
+func (s *pricingQueryStream) WriteQueryResponse(resp retrievalmarket.QueryResponse) error {
+	if s.query != nil && resp.Status == retrievalmarket.QueryResponseAvailable {
+		ctx, cancel := context.WithTimeout(context.Background(), queryPricingTimeout)
+		defer cancel()
+
+		ask, err := s.quote(ctx, *s.query)
+		if err != nil {
+			log.Errorf("computing retrieval price for query of %s: %s", s.query.PayloadCID, err)
+			resp.Status = retrievalmarket.QueryResponseError
+			resp.Message = "failed to compute retrieval price"
+		} else {
+			resp.MinPricePerByte = ask.PricePerByte
+			resp.UnsealPrice = ask.UnsealPrice
+			resp.MaxPaymentInterval = ask.PaymentInterval
+			resp.MaxPaymentIntervalIncrease = ask.PaymentIntervalIncrease
+		}
+	}
+
+	return s.RetrievalQueryStream.WriteQueryResponse(resp)
+}
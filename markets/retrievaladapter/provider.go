@@ -9,6 +9,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	sectorstorage "github.com/EpiK-Protocol/go-epik/extern/sector-storage"
 	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+	"github.com/EpiK-Protocol/go-epik/markets/unsealcache"
 	"github.com/EpiK-Protocol/go-epik/storage"
 
 	"github.com/filecoin-project/go-address"
@@ -24,12 +25,13 @@ type retrievalProviderNode struct {
 	miner  *storage.Miner
 	sealer sectorstorage.SectorManager
 	full   api.FullNode
+	cache  *unsealcache.Cache // nil when the unsealed cache is disabled
 }
 
 // NewRetrievalProviderNode returns a new node adapter for a retrieval provider that talks to the
 // epik Node
-func NewRetrievalProviderNode(miner *storage.Miner, sealer sectorstorage.SectorManager, full api.FullNode) retrievalmarket.RetrievalProviderNode {
-	return &retrievalProviderNode{miner, sealer, full}
+func NewRetrievalProviderNode(miner *storage.Miner, sealer sectorstorage.SectorManager, full api.FullNode, cache *unsealcache.Cache) retrievalmarket.RetrievalProviderNode {
+	return &retrievalProviderNode{miner, sealer, full, cache}
 }
 
 func (rpn *retrievalProviderNode) GetMinerWorkerAddress(ctx context.Context, miner address.Address, tok shared.TipSetToken) (address.Address, error) {
@@ -61,6 +63,11 @@ func (rpn *retrievalProviderNode) UnsealSector(ctx context.Context, sectorID abi
 		ProofType: si.SectorType,
 	}
 
+	done := func(error) {}
+	if rpn.cache != nil {
+		done = rpn.cache.Retrieval(ctx, ref)
+	}
+
 	r, w := io.Pipe()
 	go func() {
 		var commD cid.Cid
@@ -69,6 +76,7 @@ func (rpn *retrievalProviderNode) UnsealSector(ctx context.Context, sectorID abi
 		}
 		err := rpn.sealer.ReadPiece(ctx, w, ref, storiface.UnpaddedByteIndex(offset), length, si.TicketValue, commD)
 		_ = w.CloseWithError(err)
+		done(err)
 	}()
 
 	return r, nil
@@ -0,0 +1,599 @@
+package unsealcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/piecestore"
+	"github.com/filecoin-project/go-state-types/abi"
+	specstorage "github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+	sectorstorage "github.com/EpiK-Protocol/go-epik/extern/sector-storage"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/stores"
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+	sealing "github.com/EpiK-Protocol/go-epik/extern/storage-sealing"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+var log = logging.Logger("unsealcache")
+
+// Sealer is the part of the sector manager used to create and remove
+// unsealed copies
+type Sealer interface {
+	ReadPiece(context.Context, io.Writer, specstorage.SectorRef, storiface.UnpaddedByteIndex, abi.UnpaddedPieceSize, abi.SealRandomness, cid.Cid) error
+	RemoveUnsealed(context.Context, specstorage.SectorRef) error
+}
+
+// SectorInfoFunc returns sealing info of a sector of the miner
+type SectorInfoFunc func(abi.SectorNumber) (sealing.SectorInfo, error)
+
+// Entry holds retrieval demand statistics of a sector, and whether the cache
+// keeps an unsealed copy of it
+type Entry struct {
+	Sector     abi.SectorNumber
+	Retrievals uint64
+	LastAccess time.Time
+
+	// Cached is set when the unsealed copy of the sector is managed by the
+	// cache. Unsealed copies created by the sealing pipeline are never cached.
+	Cached    bool
+	Prewarmed bool
+	Size      uint64
+}
+
+// Cache keeps unsealed copies of frequently retrieved sectors within a byte
+// budget, so that hot data doesn't need to be unsealed for each retrieval
+type Cache struct {
+	cfg     config.UnsealedCacheConfig
+	paths   map[stores.ID]struct{}
+	experts []address.Address
+	miner   abi.ActorID
+
+	ds         datastore.Batching
+	sealer     Sealer
+	index      stores.SectorIndex
+	sectorInfo SectorInfoFunc
+	ps         piecestore.PieceStore
+	full       api.FullNode
+
+	lk           sync.Mutex
+	entries      map[abi.SectorNumber]*Entry
+	pieceExperts map[cid.Cid]address.Address
+
+	// ctx is the lifecycle context of the cache, it's cancelled by Stop
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	kick    chan struct{}
+	done    chan struct{}
+}
+
+func New(cfg config.UnsealedCacheConfig, ds datastore.Batching, maddr address.Address, sealer Sealer, index stores.SectorIndex, sectorInfo SectorInfoFunc, ps piecestore.PieceStore, full api.FullNode) (*Cache, error) {
+	mid, err := address.IDFromAddress(maddr)
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner id: %w", err)
+	}
+
+	switch cfg.Policy {
+	case "", config.UnsealedCachePolicyLRU, config.UnsealedCachePolicyLFU:
+	default:
+		return nil, xerrors.Errorf("unknown unsealed cache policy %q", cfg.Policy)
+	}
+
+	c := &Cache{
+		cfg:   cfg,
+		paths: map[stores.ID]struct{}{},
+		miner: abi.ActorID(mid),
+
+		ds:         ds,
+		sealer:     sealer,
+		index:      index,
+		sectorInfo: sectorInfo,
+		ps:         ps,
+		full:       full,
+
+		entries:      map[abi.SectorNumber]*Entry{},
+		pieceExperts: map[cid.Cid]address.Address{},
+
+		kick: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	for _, p := range cfg.Paths {
+		c.paths[stores.ID(p)] = struct{}{}
+	}
+
+	for _, s := range cfg.PrewarmExperts {
+		a, err := address.NewFromString(s)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing expert address %s: %w", s, err)
+		}
+		c.experts = append(c.experts, a)
+	}
+
+	if err := c.load(); err != nil {
+		return nil, xerrors.Errorf("loading unsealed cache entries: %w", err)
+	}
+
+	return c, nil
+}
+
+// Start starts pruning and prewarming the cache in the background, until
+// the context is cancelled or Stop is called.
+func (c *Cache) Start(ctx context.Context) {
+	c.lk.Lock()
+	c.started = true
+	c.lk.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.cancel()
+		case <-c.ctx.Done():
+		}
+	}()
+
+	go c.run()
+}
+
+func (c *Cache) run() {
+	defer close(c.done)
+
+	interval := time.Duration(c.cfg.Interval)
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			c.prune(c.ctx)
+			c.prewarm(c.ctx)
+		case <-c.kick:
+			c.prune(c.ctx)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cache) Stop(ctx context.Context) error {
+	c.cancel()
+
+	c.lk.Lock()
+	started := c.started
+	c.lk.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Retrieval records a retrieval reading from the sector. The returned
+// function must be called with the result of the read; when the read
+// unsealed the sector, the cache decides whether to keep the unsealed copy.
+func (c *Cache) Retrieval(ctx context.Context, sector specstorage.SectorRef) func(error) {
+	unsealed, err := c.hasUnsealed(ctx, sector.ID)
+	if err != nil {
+		// don't take ownership of copies we can't tell were created by us
+		log.Warnf("checking for unsealed copy of sector %d: %s", sector.ID.Number, err)
+		unsealed = true
+	}
+
+	c.lk.Lock()
+	e := c.entry(sector.ID.Number)
+	e.Retrievals++
+	e.LastAccess = time.Now()
+	c.persist(e)
+	c.lk.Unlock()
+
+	return func(err error) {
+		if err != nil || unsealed {
+			return
+		}
+
+		c.adopt(c.ctx, sector, false)
+	}
+}
+
+// Entries returns all sectors the cache has statistics for
+func (c *Cache) Entries() []Entry {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	out := make([]Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Sector < out[j].Sector
+	})
+	return out
+}
+
+// adopt decides whether to keep an unsealed copy of the sector which was
+// just created for a retrieval or by prewarming
+func (c *Cache) adopt(ctx context.Context, sector specstorage.SectorRef, prewarmed bool) {
+	ssize, err := sector.ProofType.SectorSize()
+	if err != nil {
+		log.Errorf("getting sector size: %s", err)
+		return
+	}
+
+	c.lk.Lock()
+	e := c.entry(sector.ID.Number)
+	keep := prewarmed || e.Retrievals >= c.cfg.MinRetrievals
+	c.lk.Unlock()
+
+	if keep {
+		keep, err = c.onCachePath(ctx, sector.ID)
+		if err != nil {
+			log.Warnf("finding unsealed copy of sector %d: %s", sector.ID.Number, err)
+		}
+	}
+
+	if !keep {
+		err := c.sealer.RemoveUnsealed(ctx, sector)
+		if err == nil {
+			return
+		}
+		if !xerrors.Is(err, sectorstorage.ErrSectorInUse) {
+			log.Errorf("removing unsealed copy of sector %d: %s", sector.ID.Number, err)
+			return
+		}
+		// still being read, track it so that a later prune can evict it
+	}
+
+	c.lk.Lock()
+	e.Cached = true
+	e.Prewarmed = e.Prewarmed || prewarmed
+	e.Size = uint64(ssize)
+	c.persist(e)
+	c.lk.Unlock()
+
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// prune evicts unsealed copies which aren't hot enough to be kept, and the
+// least valuable copies according to the policy when over budget
+func (c *Cache) prune(ctx context.Context) {
+	c.lk.Lock()
+	var cached []Entry
+	var total uint64
+	for _, e := range c.entries {
+		if e.Cached {
+			cached = append(cached, *e)
+			total += e.Size
+		}
+	}
+	c.lk.Unlock()
+
+	hot := func(e Entry) bool {
+		return e.Prewarmed || e.Retrievals >= c.cfg.MinRetrievals
+	}
+
+	// copies which aren't hot go first, regardless of the policy
+	sortForEviction(cached, c.cfg.Policy)
+	sort.SliceStable(cached, func(i, j int) bool {
+		return !hot(cached[i]) && hot(cached[j])
+	})
+
+	for _, e := range cached {
+		if hot(e) && total <= c.cfg.MaxBytes {
+			continue
+		}
+
+		if err := c.evict(ctx, e.Sector); err != nil {
+			if xerrors.Is(err, sectorstorage.ErrSectorInUse) {
+				log.Debugf("not evicting sector %d: %s", e.Sector, err)
+			} else {
+				log.Errorf("evicting unsealed copy of sector %d: %s", e.Sector, err)
+			}
+			continue
+		}
+
+		total -= e.Size
+	}
+}
+
+// sortForEviction orders entries so that the ones to evict first come first
+func sortForEviction(entries []Entry, policy string) {
+	sort.Slice(entries, func(i, j int) bool {
+		if policy == config.UnsealedCachePolicyLFU && entries[i].Retrievals != entries[j].Retrievals {
+			return entries[i].Retrievals < entries[j].Retrievals
+		}
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+}
+
+func (c *Cache) evict(ctx context.Context, sector abi.SectorNumber) error {
+	ref, _, err := c.sectorRef(sector)
+	if err != nil {
+		return err
+	}
+
+	if err := c.sealer.RemoveUnsealed(ctx, ref); err != nil {
+		return err
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	e := c.entry(sector)
+	e.Cached = false
+	e.Prewarmed = false
+	e.Size = 0
+	c.persist(e)
+
+	log.Infow("evicted unsealed copy", "sector", sector)
+	return nil
+}
+
+// prewarm unseals sectors holding data of popular experts, as long as they
+// fit in the free budget
+func (c *Cache) prewarm(ctx context.Context) {
+	for _, expert := range c.prewarmExperts(ctx) {
+		datas, err := c.full.StateExpertDatas(ctx, expert, nil, false, types.EmptyTSK)
+		if err != nil {
+			log.Warnf("listing data of expert %s: %s", expert, err)
+			continue
+		}
+
+		for _, d := range datas {
+			piece, err := cid.Parse(d.PieceID)
+			if err != nil {
+				continue
+			}
+
+			pi, err := c.ps.GetPieceInfo(piece)
+			if err != nil {
+				// not stored by this miner
+				continue
+			}
+
+			for _, deal := range pi.Deals {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := c.warm(ctx, deal); err != nil {
+					log.Warnf("prewarming sector %d for piece %s: %s", deal.SectorID, piece, err)
+				}
+			}
+		}
+	}
+}
+
+func (c *Cache) warm(ctx context.Context, deal piecestore.DealInfo) error {
+	ref, si, err := c.sectorRef(deal.SectorID)
+	if err != nil {
+		return err
+	}
+
+	ssize, err := ref.ProofType.SectorSize()
+	if err != nil {
+		return err
+	}
+
+	c.lk.Lock()
+	var used uint64
+	for _, e := range c.entries {
+		if e.Cached {
+			used += e.Size
+		}
+	}
+	cached := c.entries[deal.SectorID] != nil && c.entries[deal.SectorID].Cached
+	c.lk.Unlock()
+
+	if cached || used+uint64(ssize) > c.cfg.MaxBytes {
+		return nil
+	}
+
+	unsealed, err := c.hasUnsealed(ctx, ref.ID)
+	if err != nil || unsealed {
+		return err
+	}
+
+	if si.CommD == nil {
+		return xerrors.Errorf("sector doesn't have CommD")
+	}
+
+	log.Infow("prewarming unsealed copy", "sector", deal.SectorID)
+
+	err = c.sealer.ReadPiece(ctx, ioutil.Discard, ref, storiface.UnpaddedByteIndex(deal.Offset.Unpadded()), deal.Length.Unpadded(), si.TicketValue, *si.CommD)
+	if err != nil {
+		return xerrors.Errorf("unsealing: %w", err)
+	}
+
+	c.adopt(ctx, ref, true)
+	return nil
+}
+
+// prewarmExperts returns the configured experts, followed by the experts
+// whose data was retrieved the most
+func (c *Cache) prewarmExperts(ctx context.Context) []address.Address {
+	out := append([]address.Address{}, c.experts...)
+	if c.cfg.PrewarmTopExperts <= 0 {
+		return out
+	}
+
+	c.lk.Lock()
+	demand := make(map[abi.SectorNumber]uint64, len(c.entries))
+	for _, e := range c.entries {
+		demand[e.Sector] = e.Retrievals
+	}
+	c.lk.Unlock()
+
+	byExpert := map[address.Address]uint64{}
+	for sector, n := range demand {
+		si, err := c.sectorInfo(sector)
+		if err != nil {
+			continue
+		}
+
+		for _, p := range si.Pieces {
+			if p.DealInfo == nil {
+				continue
+			}
+
+			expert, err := c.pieceExpert(ctx, p.Piece.PieceCID)
+			if err != nil {
+				continue
+			}
+			byExpert[expert] += n
+		}
+	}
+
+	top := make([]address.Address, 0, len(byExpert))
+	for a := range byExpert {
+		top = append(top, a)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return byExpert[top[i]] > byExpert[top[j]]
+	})
+	if len(top) > c.cfg.PrewarmTopExperts {
+		top = top[:c.cfg.PrewarmTopExperts]
+	}
+
+	return append(out, top...)
+}
+
+func (c *Cache) pieceExpert(ctx context.Context, piece cid.Cid) (address.Address, error) {
+	c.lk.Lock()
+	a, ok := c.pieceExperts[piece]
+	c.lk.Unlock()
+	if ok {
+		return a, nil
+	}
+
+	fi, err := c.full.StateExpertFileInfo(ctx, piece, types.EmptyTSK)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	c.lk.Lock()
+	c.pieceExperts[piece] = fi.Expert
+	c.lk.Unlock()
+
+	return fi.Expert, nil
+}
+
+func (c *Cache) sectorRef(sector abi.SectorNumber) (specstorage.SectorRef, sealing.SectorInfo, error) {
+	si, err := c.sectorInfo(sector)
+	if err != nil {
+		return specstorage.SectorRef{}, si, xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	return specstorage.SectorRef{
+		ID: abi.SectorID{
+			Miner:  c.miner,
+			Number: sector,
+		},
+		ProofType: si.SectorType,
+	}, si, nil
+}
+
+func (c *Cache) hasUnsealed(ctx context.Context, sector abi.SectorID) (bool, error) {
+	si, err := c.index.StorageFindSector(ctx, sector, storiface.FTUnsealed, 0, false)
+	if err != nil {
+		return false, err
+	}
+	return len(si) > 0, nil
+}
+
+// onCachePath checks that an unsealed copy of the sector is stored on one of
+// the paths designated for the cache
+func (c *Cache) onCachePath(ctx context.Context, sector abi.SectorID) (bool, error) {
+	if len(c.paths) == 0 {
+		return true, nil
+	}
+
+	si, err := c.index.StorageFindSector(ctx, sector, storiface.FTUnsealed, 0, false)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range si {
+		if _, ok := c.paths[s.ID]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// entry returns the entry for the sector, creating it if needed. Must be
+// called with c.lk held.
+func (c *Cache) entry(sector abi.SectorNumber) *Entry {
+	e, ok := c.entries[sector]
+	if !ok {
+		e = &Entry{Sector: sector}
+		c.entries[sector] = e
+	}
+	return e
+}
+
+func entryKey(sector abi.SectorNumber) datastore.Key {
+	return datastore.NewKey(fmt.Sprint(sector))
+}
+
+// persist stores the entry. Must be called with c.lk held.
+func (c *Cache) persist(e *Entry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Errorf("marshaling unsealed cache entry: %s", err)
+		return
+	}
+
+	if err := c.ds.Put(entryKey(e.Sector), b); err != nil {
+		log.Errorf("storing unsealed cache entry: %s", err)
+	}
+}
+
+func (c *Cache) load() error {
+	res, err := c.ds.Query(query.Query{})
+	if err != nil {
+		return err
+	}
+	defer res.Close() // nolint
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+
+		var e Entry
+		if err := json.Unmarshal(r.Value, &e); err != nil {
+			return xerrors.Errorf("unmarshaling entry %s: %w", r.Key, err)
+		}
+		c.entries[e.Sector] = &e
+	}
+
+	return nil
+}
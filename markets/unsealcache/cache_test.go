@@ -0,0 +1,107 @@
+package unsealcache
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	specstorage "github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/storiface"
+	sealing "github.com/EpiK-Protocol/go-epik/extern/storage-sealing"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+)
+
+type testSealer struct {
+	removed []abi.SectorNumber
+}
+
+func (s *testSealer) ReadPiece(context.Context, io.Writer, specstorage.SectorRef, storiface.UnpaddedByteIndex, abi.UnpaddedPieceSize, abi.SealRandomness, cid.Cid) error {
+	return nil
+}
+
+func (s *testSealer) RemoveUnsealed(ctx context.Context, sector specstorage.SectorRef) error {
+	s.removed = append(s.removed, sector.ID.Number)
+	return nil
+}
+
+func TestPrune(t *testing.T) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	sealer := &testSealer{}
+	sectorInfo := func(sn abi.SectorNumber) (sealing.SectorInfo, error) {
+		return sealing.SectorInfo{SectorNumber: sn, SectorType: abi.RegisteredSealProof_StackedDrg2KiBV1}, nil
+	}
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	c, err := New(config.UnsealedCacheConfig{
+		MaxBytes:      2 * 2048,
+		Policy:        config.UnsealedCachePolicyLRU,
+		MinRetrievals: 2,
+	}, ds, maddr, sealer, nil, sectorInfo, nil, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	c.entries[1] = &Entry{Sector: 1, Retrievals: 5, LastAccess: now.Add(-3 * time.Hour), Cached: true, Size: 2048}
+	c.entries[2] = &Entry{Sector: 2, Retrievals: 2, LastAccess: now.Add(-time.Hour), Cached: true, Size: 2048}
+	c.entries[3] = &Entry{Sector: 3, Retrievals: 3, LastAccess: now, Cached: true, Size: 2048}
+	// not retrieved often enough to be kept
+	c.entries[4] = &Entry{Sector: 4, Retrievals: 1, LastAccess: now, Cached: true, Size: 2048}
+
+	c.prune(context.Background())
+
+	require.Equal(t, []abi.SectorNumber{4, 1}, sealer.removed)
+	require.False(t, c.entries[1].Cached)
+	require.True(t, c.entries[2].Cached)
+	require.True(t, c.entries[3].Cached)
+
+	// entries are persisted
+	c2, err := New(config.UnsealedCacheConfig{}, ds, maddr, sealer, nil, sectorInfo, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, c2.Entries(), 4)
+	require.False(t, c2.entries[4].Cached)
+}
+
+func TestSortForEvictionLFU(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Sector: 1, Retrievals: 10, LastAccess: now.Add(-time.Hour)},
+		{Sector: 2, Retrievals: 3, LastAccess: now},
+		{Sector: 3, Retrievals: 3, LastAccess: now.Add(-time.Minute)},
+	}
+
+	sortForEviction(entries, config.UnsealedCachePolicyLFU)
+	require.Equal(t, abi.SectorNumber(3), entries[0].Sector)
+	require.Equal(t, abi.SectorNumber(2), entries[1].Sector)
+	require.Equal(t, abi.SectorNumber(1), entries[2].Sector)
+}
+
+func TestStartStop(t *testing.T) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// never started
+	c, err := New(config.UnsealedCacheConfig{}, ds, maddr, &testSealer{}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Stop(ctx))
+
+	c, err = New(config.UnsealedCacheConfig{}, ds, maddr, &testSealer{}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	c.Start(ctx)
+	require.NoError(t, c.Stop(ctx))
+	require.Error(t, c.ctx.Err())
+}
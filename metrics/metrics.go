@@ -47,6 +47,10 @@ var (
 	// miner
 	TaskType, _       = tag.NewKey("task_type")
 	WorkerHostname, _ = tag.NewKey("worker_hostname")
+	// sync
+	SyncStage, _ = tag.NewKey("sync_stage")
+	// economics
+	Candidate, _ = tag.NewKey("candidate")
 )
 
 // Measures
@@ -85,6 +89,7 @@ var (
 	ServeSyncSuccess        = stats.Int64("serve/sync_success", "Counter for successes", stats.UnitDimensionless)
 	ServeSyncFailure        = stats.Int64("serve/sync_failure", "Counter for failures", stats.UnitDimensionless)
 	ServeSyncBytes          = stats.Int64("serve/sync_bytes", "Counter for total sent bytes", stats.UnitBytes)
+	ServeSyncRejected       = stats.Int64("serve/sync_rejected", "Counter for rate limited requests", stats.UnitDimensionless)
 	TipsetMessagesCount     = stats.Int64("tipset/messages_count", "Counter of messages in tipsets", stats.UnitDimensionless)
 	TipsetMessagesRate      = stats.Float64("tipset/messages_rate", "Counter of processed messages per second", stats.UnitDimensionless)
 	TipsetPublishDealsCount = stats.Int64("tipset/publishdeals_count", "Counter of publishdeals in tipsets", stats.UnitDimensionless)
@@ -131,6 +136,17 @@ var (
 
 	// mpool
 	MpoolPendingCount = stats.Int64("mpool/pending_count", "Counter of pending messages in mpool", stats.UnitDimensionless)
+
+	// sync
+	SyncStageDuration  = stats.Float64("sync/stage_ms", "Duration of sync pipeline stages in ms", stats.UnitMilliseconds)
+	SyncPipelineQueued = stats.Int64("sync/pipeline_queued", "Number of tipsets queued for execution in the sync pipeline", stats.UnitDimensionless)
+
+	// economics
+	EconomicsHeight    = stats.Int64("economics/height", "Height of the last economics sample", stats.UnitDimensionless)
+	EconomicsExperts   = stats.Int64("economics/experts", "Number of experts by status", stats.UnitDimensionless)
+	EconomicsVotes     = stats.Float64("economics/votes", "Votes in EPK, in total and by candidate", stats.UnitDimensionless)
+	EconomicsRetrieval = stats.Float64("economics/retrieval", "Retrieval pledge and rewards in EPK", stats.UnitDimensionless)
+	EconomicsFunds     = stats.Float64("economics/funds", "Expertfund pool, knowledge fund and vesting locked funds in EPK", stats.UnitDimensionless)
 )
 
 var (
@@ -269,6 +285,11 @@ var (
 		Aggregation: view.Count(),
 		TagKeys:     []tag.Key{FailureType},
 	}
+	ServeSyncRejectedView = &view.View{
+		Measure:     ServeSyncRejected,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{FailureType},
+	}
 	ServeSyncBytesView = &view.View{
 		Measure:     ServeSyncBytes,
 		Aggregation: view.Sum(),
@@ -438,6 +459,41 @@ var (
 		Measure:     MpoolPendingCount,
 		Aggregation: view.LastValue(),
 	}
+	SyncStageDurationView = &view.View{
+		Measure:     SyncStageDuration,
+		Aggregation: defaultMillisecondsDistribution,
+		TagKeys:     []tag.Key{SyncStage},
+	}
+	SyncPipelineQueuedView = &view.View{
+		Measure:     SyncPipelineQueued,
+		Aggregation: view.LastValue(),
+	}
+
+	// economics
+	EconomicsHeightView = &view.View{
+		Measure:     EconomicsHeight,
+		Aggregation: view.LastValue(),
+	}
+	EconomicsExpertsView = &view.View{
+		Measure:     EconomicsExperts,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Type},
+	}
+	EconomicsVotesView = &view.View{
+		Measure:     EconomicsVotes,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Type, Candidate},
+	}
+	EconomicsRetrievalView = &view.View{
+		Measure:     EconomicsRetrieval,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Type},
+	}
+	EconomicsFundsView = &view.View{
+		Measure:     EconomicsFunds,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{Type},
+	}
 )
 
 // DefaultViews is an array of OpenCensus views for metric gathering purposes
@@ -495,11 +551,15 @@ var ChainNodeViews = append([]*view.View{
 
 	MpoolPendingCountView,
 
+	SyncStageDurationView,
+	SyncPipelineQueuedView,
+
 	MessageReceivedBytesView,
 	BlockReceivedBytesView,
 	ServeSyncSuccessView,
 	ServeSyncFailureView,
 	ServeSyncBytesView,
+	ServeSyncRejectedView,
 	TipsetMessagesCountView,
 	TipsetMessagesRateView,
 	TipsetPublishDealsCountView,
@@ -524,6 +584,16 @@ var MinerNodeViews = append([]*view.View{
 	ServeTransferResultView,
 }, DefaultViews...)
 
+// EconomicsViews are the views of the economics gauges sampled by the full
+// node when Metrics.Economics is enabled
+var EconomicsViews = []*view.View{
+	EconomicsHeightView,
+	EconomicsExpertsView,
+	EconomicsVotesView,
+	EconomicsRetrievalView,
+	EconomicsFundsView,
+}
+
 // SinceInMilliseconds returns the duration of time since the provide time as a float64.
 func SinceInMilliseconds(startTime time.Time) float64 {
 	return float64(time.Since(startTime).Nanoseconds()) / 1e6
@@ -41,12 +41,14 @@ import (
 	storage2 "github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/addrindex"
 	"github.com/EpiK-Protocol/go-epik/chain/beacon"
 	"github.com/EpiK-Protocol/go-epik/chain/gen"
 	"github.com/EpiK-Protocol/go-epik/chain/gen/slashfilter"
 	"github.com/EpiK-Protocol/go-epik/chain/messagepool"
 	"github.com/EpiK-Protocol/go-epik/chain/messagesigner"
 	"github.com/EpiK-Protocol/go-epik/chain/metrics"
+	"github.com/EpiK-Protocol/go-epik/chain/msgindex"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	ledgerwallet "github.com/EpiK-Protocol/go-epik/chain/wallet/ledger"
@@ -63,7 +65,9 @@ import (
 	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/bls"
 	_ "github.com/EpiK-Protocol/go-epik/lib/sigs/secp"
 	"github.com/EpiK-Protocol/go-epik/markets/dealfilter"
+	"github.com/EpiK-Protocol/go-epik/markets/retrievaladapter"
 	"github.com/EpiK-Protocol/go-epik/markets/storageadapter"
+	"github.com/EpiK-Protocol/go-epik/markets/unsealcache"
 	"github.com/EpiK-Protocol/go-epik/miner"
 	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/impl"
@@ -132,6 +136,7 @@ const (
 
 	HandleIncomingBlocksKey
 	HandleIncomingMessagesKey
+	RunFeeBumpKey
 	// HandleMigrateClientFundsKey
 	HandlePaymentChannelManagerKey
 	HandleFlowChannelManagerKey
@@ -147,6 +152,7 @@ const (
 	// daemon
 	ExtractApiKey
 	HeadMetricsKey
+	EconomicsMetricsKey
 	SettlePaymentChannelsKey
 	SettleFlowChannelsKey
 	RunPeerTaggerKey
@@ -278,6 +284,8 @@ var ChainNode = Options(
 	// Consensus: Chain storage/access
 	Override(new(*store.ChainStore), modules.ChainStore),
 	Override(new(*stmgr.StateManager), modules.StateManager),
+	Override(new(msgindex.MsgIndex), modules.MsgIndex(config.MsgIndex{})),
+	Override(new(addrindex.AddrIndex), modules.AddrIndex(config.AddrIndex{})),
 	Override(new(dtypes.ChainBitswap), modules.ChainBitswap),
 	Override(new(dtypes.ChainBlockService), modules.ChainBlockService), // todo: unused
 
@@ -397,6 +405,7 @@ var MinerNode = Options(
 	// Mining / proving
 	Override(new(*slashfilter.SlashFilter), modules.NewSlashFilter),
 	Override(new(*storage.Miner), modules.StorageMiner(config.DefaultStorageMiner().Fees)),
+	Override(new(*storage.WindowPoStScheduler), modules.WindowPostScheduler(config.DefaultStorageMiner().Fees)),
 	Override(new(*miner.Miner), modules.SetupBlockProducer),
 	Override(new(gen.WinningPoStProver), storage.NewWinningPoStProver),
 
@@ -413,6 +422,8 @@ var MinerNode = Options(
 	// Markets (retrieval)
 	Override(new(retrievalmarket.RetrievalProvider), modules.RetrievalProvider),
 	Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(nil)),
+	Override(new(retrievaladapter.PricingFunc), modules.RetrievalPricing(config.RetrievalPricing{})),
+	Override(new(*unsealcache.Cache), modules.UnsealedCache(config.UnsealedCacheConfig{})),
 	Override(HandleRetrievalKey, modules.HandleRetrieval),
 
 	// Markets (storage)
@@ -524,7 +535,9 @@ func ConfigCommon(cfg *config.Common) Option {
 		Override(AddrsFactoryKey, lp2p.AddrsFactory(
 			cfg.Libp2p.AnnounceAddresses,
 			cfg.Libp2p.NoAnnounceAddresses)),
-		Override(new(dtypes.MetadataDS), modules.Datastore(cfg.Backup.DisableMetadataLog)),
+		Override(new(dtypes.MetadataDS), modules.Datastore(cfg.Backup)),
+		Override(new(dtypes.IndexDS), modules.IndexDatastore),
+		Override(new(journal.Journal), modules.OpenJournal(cfg.Journal)),
 	)
 }
 
@@ -545,10 +558,20 @@ func ConfigFullNode(c interface{}) Option {
 			),
 		),
 		Override(new(dtypes.Graphsync), modules.Graphsync(cfg.Client.SimultaneousTransfers)),
+		Override(new(exchange.Server), modules.ChainExchangeServer(cfg.Libp2p)),
+		Override(new(msgindex.MsgIndex), modules.MsgIndex(cfg.MsgIndex)),
+		Override(new(addrindex.AddrIndex), modules.AddrIndex(cfg.AddrIndex)),
+
+		If(cfg.FeeBump.Enable,
+			Override(RunFeeBumpKey, modules.RunFeeBump(cfg.FeeBump)),
+		),
 
 		If(cfg.Metrics.HeadNotifs,
 			Override(HeadMetricsKey, metrics.SendHeadNotifs(cfg.Metrics.Nickname)),
 		),
+		If(cfg.Metrics.Economics,
+			Override(EconomicsMetricsKey, modules.RunEconomicsMetrics(cfg.Metrics.EconomicsCandidates)),
+		),
 
 		If(cfg.Wallet.RemoteBackend != "",
 			Override(new(*remotewallet.RemoteWallet), remotewallet.SetupRemoteWallet(cfg.Wallet.RemoteBackend)),
@@ -569,15 +592,35 @@ func ConfigStorageMiner(c interface{}) Option {
 		return Error(xerrors.Errorf("invalid config from repo, got: %T", c))
 	}
 
+	switch cfg.Dealmaking.FilterType {
+	case "", config.DealFilterTypeCli, config.DealFilterTypeRules, config.DealFilterTypeWebhook:
+	default:
+		return Error(xerrors.Errorf("unknown Dealmaking.FilterType %q, expected %q, %q or %q",
+			cfg.Dealmaking.FilterType, config.DealFilterTypeCli, config.DealFilterTypeRules, config.DealFilterTypeWebhook))
+	}
+
 	return Options(
 		ConfigCommon(&cfg.Common),
 
-		If(cfg.Dealmaking.Filter != "",
-			Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(dealfilter.CliStorageDealFilter(cfg.Dealmaking.Filter))),
+		If(cfg.Dealmaking.FilterType == "" || cfg.Dealmaking.FilterType == config.DealFilterTypeCli,
+			If(cfg.Dealmaking.Filter != "",
+				Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(dealfilter.CliStorageDealFilter(cfg.Dealmaking.Filter))),
+			),
+
+			If(cfg.Dealmaking.RetrievalFilter != "",
+				Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(dealfilter.CliRetrievalDealFilter(cfg.Dealmaking.RetrievalFilter))),
+			),
 		),
 
-		If(cfg.Dealmaking.RetrievalFilter != "",
-			Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(dealfilter.CliRetrievalDealFilter(cfg.Dealmaking.RetrievalFilter))),
+		If(cfg.Dealmaking.FilterType == config.DealFilterTypeRules,
+			Override(new(*dealfilter.RuleFilter), dealfilter.NewRuleFilter(cfg.Dealmaking.FilterRules)),
+			Override(new(dtypes.StorageDealFilter), modules.RuleStorageDealFilter),
+			Override(new(dtypes.RetrievalDealFilter), modules.RuleRetrievalDealFilter),
+		),
+
+		If(cfg.Dealmaking.FilterType == config.DealFilterTypeWebhook,
+			Override(new(dtypes.StorageDealFilter), modules.BasicDealFilter(dealfilter.WebhookStorageDealFilter(cfg.Dealmaking.FilterWebhook))),
+			Override(new(dtypes.RetrievalDealFilter), modules.RetrievalDealFilter(dealfilter.WebhookRetrievalDealFilter(cfg.Dealmaking.FilterWebhook))),
 		),
 
 		Override(new(*storageadapter.DealPublisher), storageadapter.NewDealPublisher(&cfg.Fees, storageadapter.PublishMsgConfig{
@@ -586,9 +629,13 @@ func ConfigStorageMiner(c interface{}) Option {
 		})),
 		Override(new(storagemarket.StorageProviderNode), storageadapter.NewProviderNodeAdapter(&cfg.Fees)),
 
+		Override(new(retrievaladapter.PricingFunc), modules.RetrievalPricing(cfg.Dealmaking.RetrievalPricing)),
+		Override(new(*unsealcache.Cache), modules.UnsealedCache(cfg.UnsealedCache)),
+
 		Override(new(sectorstorage.SealerConfig), cfg.Storage),
 		Override(new(*storage.AddressSelector), modules.AddressSelector(&cfg.Addresses)),
 		Override(new(*storage.Miner), modules.StorageMiner(cfg.Fees)),
+		Override(new(*storage.WindowPoStScheduler), modules.WindowPostScheduler(cfg.Fees)),
 	)
 }
 
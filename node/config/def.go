@@ -12,10 +12,11 @@ import (
 
 // Common is common config between full node and miner
 type Common struct {
-	API    API
-	Backup Backup
-	Libp2p Libp2p
-	Pubsub Pubsub
+	API     API
+	Backup  Backup
+	Libp2p  Libp2p
+	Pubsub  Pubsub
+	Journal Journal
 }
 
 // FullNode is a full node config
@@ -26,12 +27,52 @@ type FullNode struct {
 	Wallet     Wallet
 	Fees       FeeConfig
 	Chainstore Chainstore
+	MsgIndex   MsgIndex
+	AddrIndex  AddrIndex
+	FeeBump    FeeBump
 }
 
 // // Common
 
 type Backup struct {
 	DisableMetadataLog bool
+
+	// Path is the directory scheduled backups of the metadata are written to,
+	// scheduled backups are disabled when empty
+	Path string
+	// FullInterval is the time between full backups
+	FullInterval Duration
+	// IncrementalInterval is the time between incremental backups, holding
+	// the metadata changes since the previous backup. Zero disables them,
+	// they require the metadata log.
+	IncrementalInterval Duration
+	// Retention is the number of full backups kept, along with their
+	// incremental backups; zero keeps them all
+	Retention int
+}
+
+type Journal struct {
+	// Sinks are the names of the sinks journal events are written to: file,
+	// memory, socket, or sinks registered by plugins. JournalQuery reads the
+	// events back from the first sink supporting queries.
+	Sinks []string
+
+	// MaxFileSize is the size, in bytes, at which the journal file is rotated
+	MaxFileSize int64
+	// MaxFileAge is the age at which the journal file is rotated, zero
+	// disables time-based rotation
+	MaxFileAge Duration
+	// Retention is the age after which rotated journal files are removed,
+	// zero keeps them
+	Retention Duration
+	// MaxFiles is the number of journal files kept, zero keeps them all
+	MaxFiles int
+
+	// RingSize is the number of recent events the memory sink keeps
+	RingSize int
+	// SocketPath is the unix socket, relative to the repo, on which the socket
+	// sink streams the events as newline-delimited JSON
+	SocketPath string
 }
 
 // StorageMiner is a miner config
@@ -43,6 +84,8 @@ type StorageMiner struct {
 	Storage    sectorstorage.SealerConfig
 	Fees       MinerFeeConfig
 	Addresses  MinerAddressConfig
+
+	UnsealedCache UnsealedCacheConfig
 }
 
 type DealmakingConfig struct {
@@ -61,14 +104,117 @@ type DealmakingConfig struct {
 	// message
 	MaxDealsPerPublishMsg uint64
 
+	// FilterType selects the backend used to filter deals after the basic
+	// checks pass. It can be "cli" (default, runs Filter and RetrievalFilter
+	// as shell commands), "rules" or "webhook".
+	FilterType      string
 	Filter          string
 	RetrievalFilter string
+	FilterRules     DealFilterRules
+	FilterWebhook   DealFilterWebhook
 
 	MaxOngoingServedRetrievals int
 
+	RetrievalPricing RetrievalPricing
+
 	AutoDealExperts []string
 }
 
+const (
+	DealFilterTypeCli     = "cli"
+	DealFilterTypeRules   = "rules"
+	DealFilterTypeWebhook = "webhook"
+)
+
+// DealFilterRules configures the in-process deal filter rule engine
+type DealFilterRules struct {
+	// Client addresses storage deals are accepted from; empty accepts any
+	// client. Addresses are matched in ID form, and in the form used in the
+	// deal proposal.
+	ClientAllowlist []string
+	// Client addresses storage deals are always rejected from
+	ClientDenylist []string
+	// Peer IDs retrieval deals are accepted from; empty accepts any peer
+	PeerAllowlist []string
+	// Peer IDs retrieval deals are always rejected from
+	PeerDenylist []string
+
+	// Only accept deals for pieces registered on chain by an expert
+	RequireExpertData bool
+	// Experts whose data is accepted; empty accepts data of any expert
+	ExpertAllowlist []string
+
+	// Piece size limits in bytes, 0 = no limit
+	MinPieceSize uint64
+	MaxPieceSize uint64
+
+	// Minimum storage price per GiB per epoch
+	MinPricePerGiBEpoch types.EPK
+
+	// Window of accepted deal start epochs, relative to the current chain
+	// head, 0 = no limit
+	MinStartEpochDelay uint64
+	MaxStartEpochDelay uint64
+
+	// Maximum amount of deal data accepted from a single client per day in
+	// bytes, 0 = no limit
+	ClientDailyQuota uint64
+}
+
+// DealFilterWebhook configures a deal filter which asks a HTTP endpoint about
+// each deal
+type DealFilterWebhook struct {
+	URL     string
+	Timeout Duration
+	// FailOpen accepts deals when the webhook fails or can't be reached,
+	// by default such deals are rejected
+	FailOpen bool
+}
+
+// RetrievalPricing configures per-deal retrieval pricing on top of the global
+// retrieval ask
+type RetrievalPricing struct {
+	// When disabled, the global retrieval ask applies to all retrievals
+	Enable bool
+	// Price per byte for data of specific experts, keyed by expert address
+	ExpertPricePerByte map[string]types.EPK
+	// Price per byte for specific pieces, keyed by piece CID
+	PiecePricePerByte map[string]types.EPK
+	// Discount in percent for clients with retrieval pledges bound to this miner
+	BoundClientDiscount uint64
+	// Don't charge the unseal price when an unsealed copy of the data exists
+	WaiveUnsealPriceIfUnsealed bool
+}
+
+// UnsealedCacheConfig configures keeping unsealed copies of frequently
+// retrieved sectors, so that they don't need to be unsealed for every
+// retrieval. Only unsealed copies created for retrievals are managed by the
+// cache, copies kept by the sealing pipeline are never removed.
+type UnsealedCacheConfig struct {
+	Enable bool
+	// Storage path IDs unsealed copies may be kept on; copies unsealed to
+	// other paths are removed after the retrieval. Empty allows any path.
+	Paths []string
+	// Maximum total size of unsealed copies kept by the cache, in bytes
+	MaxBytes uint64
+	// Eviction policy, "lru" or "lfu"
+	Policy string
+	// Sectors unsealed for a retrieval are kept once they were retrieved at
+	// least this many times
+	MinRetrievals uint64
+	// Experts whose data is unsealed before it's retrieved
+	PrewarmExperts []string
+	// Also prewarm data of this many experts with the most retrievals
+	PrewarmTopExperts int
+	// How often the cache is pruned and prewarmed
+	Interval Duration
+}
+
+const (
+	UnsealedCachePolicyLRU = "lru"
+	UnsealedCachePolicyLFU = "lfu"
+)
+
 type SealingConfig struct {
 	// 0 = no limit
 	MaxWaitDealsSectors uint64
@@ -131,6 +277,24 @@ type Libp2p struct {
 	ConnMgrLow   uint
 	ConnMgrHigh  uint
 	ConnMgrGrace Duration
+
+	ChainExchange ChainExchangeLimits
+}
+
+// ChainExchangeLimits bounds the chain data served to each peer over the
+// chain exchange protocol. Budgets refill at the per second rates, up to the
+// burst sizes; zero rates disable the budgets. ProtectedPeers are served
+// even when MaxStreams is reached, with budgets scaled by ProtectedMultiplier.
+type ChainExchangeLimits struct {
+	TipSetsPerSecond  float64
+	TipSetBurst       float64
+	MessagesPerSecond float64
+	MessageBurst      float64
+
+	MaxStreamsPerPeer int
+	MaxStreams        int
+
+	ProtectedMultiplier float64
 }
 
 type Pubsub struct {
@@ -145,6 +309,45 @@ type Chainstore struct {
 	Splitstore       Splitstore
 }
 
+// MsgIndex configures an on-disk index of messages by CID, which makes
+// looking up old messages with StateSearchMsg and StateWaitMsg fast
+type MsgIndex struct {
+	Enable bool
+	// Backfill indexes messages from BackfillFromEpoch up to the point the
+	// index was started at, in the background
+	Backfill          bool
+	BackfillFromEpoch int64
+}
+
+// AddrIndex configures an on-disk index of the messages and internal
+// transfers touching each address, served by StateAddressHistory. Keeping it
+// up to date re-executes every new tipset to collect internal transfers.
+type AddrIndex struct {
+	Enable bool
+}
+
+// FeeBump configures a service which replaces local messages stuck in the
+// mpool with messages paying higher fees
+type FeeBump struct {
+	Enable bool
+	// Messages still pending after StuckEpochs epochs are bumped. Messages
+	// with a fee cap below the base fee trend are bumped right away.
+	StuckEpochs uint64
+	// Number of tipsets the base fee trend is computed over
+	BaseFeeLookback uint64
+	// Senders whose messages are bumped; empty bumps the messages of all
+	// local senders
+	Addresses []string
+	// Fee bump budget keyed by sender address, senders not listed here use
+	// Fees.DefaultMaxFee. It caps the sum of the max fee increases of all
+	// the messages of the sender bumped within BudgetEpochs; once it is
+	// spent, the messages of the sender are not bumped until older bumps
+	// leave the window.
+	MaxFee map[string]types.EPK
+	// Number of epochs the budget of a sender covers
+	BudgetEpochs uint64
+}
+
 type Splitstore struct {
 	// ColdStoreType specifies the type of the coldstore.
 	// It can be "universal" (default) or "discard" for discarding cold blocks.
@@ -170,6 +373,14 @@ type Splitstore struct {
 type Metrics struct {
 	Nickname   string
 	HeadNotifs bool
+
+	// Economics samples the EpiK economics (experts, votes, retrieval
+	// pledges and rewards, expertfund pool, knowledge fund, vesting) from the
+	// state at each head change, exported as gauges on the metrics endpoint
+	Economics bool
+	// EconomicsCandidates is the number of candidates with the most votes
+	// whose votes are exported by candidate, besides the total
+	EconomicsCandidates int
 }
 
 type Client struct {
@@ -207,12 +418,37 @@ func defCommon() Common {
 			ConnMgrLow:   150,
 			ConnMgrHigh:  180,
 			ConnMgrGrace: Duration(20 * time.Second),
+
+			ChainExchange: ChainExchangeLimits{
+				TipSetsPerSecond:  200,
+				TipSetBurst:       2000,
+				MessagesPerSecond: 20000,
+				MessageBurst:      200000,
+
+				MaxStreamsPerPeer: 16,
+				MaxStreams:        256,
+
+				ProtectedMultiplier: 4,
+			},
 		},
 		Pubsub: Pubsub{
 			Bootstrapper: false,
 			DirectPeers:  nil,
 			// RemoteTracer: "/dns4/pubsub-tracer.filecoin.io/tcp/4001/p2p/QmTd6UvR47vUidRNZ1ZKXHrAFhqTJAD27rKL9XYghEKgKX",
 		},
+		Backup: Backup{
+			FullInterval:        Duration(24 * time.Hour),
+			IncrementalInterval: Duration(time.Hour),
+			Retention:           7,
+		},
+		Journal: Journal{
+			Sinks:       []string{"file"},
+			MaxFileSize: 1 << 30,
+			MaxFileAge:  Duration(24 * time.Hour),
+			Retention:   Duration(7 * 24 * time.Hour),
+			RingSize:    4096,
+			SocketPath:  "journal.sock",
+		},
 	}
 
 }
@@ -230,6 +466,11 @@ func DefaultFullNode() *FullNode {
 		Client: Client{
 			SimultaneousTransfers: DefaultSimultaneousTransfers,
 		},
+		FeeBump: FeeBump{
+			StuckEpochs:     10,
+			BaseFeeLookback: 5,
+			BudgetEpochs:    2880, // a day
+		},
 		Chainstore: Chainstore{
 			EnableSplitstore: false,
 			Splitstore: Splitstore{
@@ -264,6 +505,8 @@ func DefaultStorageMiner() *StorageMiner {
 			// Default to 10 - tcp should still be able to figure this out, and
 			// it's the ratio between 10gbit / 1gbit
 			ParallelFetchLimit: 10,
+
+			JobHistoryRetentionDays: 14,
 		},
 
 		Dealmaking: DealmakingConfig{
@@ -281,6 +524,14 @@ func DefaultStorageMiner() *StorageMiner {
 
 			MaxOngoingServedRetrievals: 12,
 			AutoDealExperts:            []string{},
+
+			FilterType: DealFilterTypeCli,
+			FilterRules: DealFilterRules{
+				MinPricePerGiBEpoch: types.MustParseEPK("0"),
+			},
+			FilterWebhook: DealFilterWebhook{
+				Timeout: Duration(10 * time.Second),
+			},
 		},
 
 		Fees: MinerFeeConfig{
@@ -296,6 +547,15 @@ func DefaultStorageMiner() *StorageMiner {
 			PreCommitControl: []string{},
 			CommitControl:    []string{},
 		},
+
+		UnsealedCache: UnsealedCacheConfig{
+			Paths:          []string{},
+			MaxBytes:       1 << 40,
+			Policy:         UnsealedCachePolicyLRU,
+			MinRetrievals:  2,
+			PrewarmExperts: []string{},
+			Interval:       Duration(10 * time.Minute),
+		},
 	}
 	cfg.Common.API.ListenAddress = "/ip4/127.0.0.1/tcp/2345/http"
 	cfg.Common.API.RemoteListenAddress = "127.0.0.1:2345"
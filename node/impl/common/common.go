@@ -2,8 +2,10 @@ package common
 
 import (
 	"context"
+	"encoding/json"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gbrlsnchs/jwt/v3"
 	"github.com/google/uuid"
@@ -25,6 +27,7 @@ import (
 
 	"github.com/EpiK-Protocol/go-epik/api"
 	"github.com/EpiK-Protocol/go-epik/build"
+	"github.com/EpiK-Protocol/go-epik/journal"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 	"github.com/EpiK-Protocol/go-epik/node/modules/lp2p"
 )
@@ -43,6 +46,7 @@ type CommonAPI struct {
 	Reporter       metrics.Reporter
 	Sk             *dtypes.ScoreKeeper
 	ShutdownChan   dtypes.ShutdownChan
+	Journal        journal.Journal
 }
 
 type jwtPayload struct {
@@ -234,6 +238,34 @@ func (a *CommonAPI) LogSetLevel(ctx context.Context, subsystem, level string) er
 	return logging.SetLogLevel(subsystem, level)
 }
 
+func (a *CommonAPI) JournalQuery(ctx context.Context, system, event string, since, until time.Time, limit int) ([]api.JournalEvent, error) {
+	evts, err := a.Journal.Query(journal.Filter{
+		System: system,
+		Event:  event,
+		Since:  since,
+		Until:  until,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.JournalEvent, 0, len(evts))
+	for _, evt := range evts {
+		data, err := json.Marshal(evt.Data)
+		if err != nil {
+			return nil, xerrors.Errorf("marshaling data of %s event: %w", evt.EventType, err)
+		}
+		out = append(out, api.JournalEvent{
+			System:    evt.System,
+			Event:     evt.Event,
+			Timestamp: evt.Timestamp,
+			Data:      data,
+		})
+	}
+	return out, nil
+}
+
 func (a *CommonAPI) GC(ctx context.Context) error {
 	return nil
 }
@@ -34,6 +34,7 @@ import (
 
 	"github.com/EpiK-Protocol/go-epik/api"
 	"github.com/EpiK-Protocol/go-epik/blockstore"
+	"github.com/EpiK-Protocol/go-epik/blockstore/splitstore"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/power"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
@@ -84,6 +85,9 @@ type ChainAPI struct {
 	// expose externally. In the future, this will be segregated into two
 	// blockstores.
 	ExposedBlockstore dtypes.ExposedBlockstore
+
+	// BaseBlockstore is the splitstore when it's enabled
+	BaseBlockstore dtypes.BaseBlockstore
 }
 
 func (m *ChainModule) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
@@ -578,12 +582,35 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 	if err != nil {
 		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
 	}
+
+	return exportStream(ctx, func(w io.Writer) error {
+		return a.Chain.Export(ctx, ts, nroots, skipoldmsgs, w)
+	}), nil
+}
+
+func (a *ChainAPI) ChainExportRange(ctx context.Context, tsk types.TipSetKey, cfg api.ChainExportConfig) (<-chan []byte, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	if cfg.From > ts.Height() {
+		return nil, xerrors.Errorf("export range start %d above the exported tipset at %d", cfg.From, ts.Height())
+	}
+
+	return exportStream(ctx, func(w io.Writer) error {
+		return a.Chain.ExportRange(ctx, ts, cfg, w)
+	}), nil
+}
+
+// exportStream runs export in the background, streaming the written bytes
+// over the returned channel. An empty slice is sent after the last write.
+func exportStream(ctx context.Context, export func(io.Writer) error) <-chan []byte {
 	r, w := io.Pipe()
 	out := make(chan []byte)
 	go func() {
 		bw := bufio.NewWriterSize(w, 1<<20)
 
-		err := a.Chain.Export(ctx, ts, nroots, skipoldmsgs, bw)
+		err := export(bw)
 		bw.Flush()            //nolint:errcheck // it is a write to a pipe
 		w.CloseWithError(err) //nolint:errcheck // it is a pipe
 	}()
@@ -619,5 +646,61 @@ func (a *ChainAPI) ChainExport(ctx context.Context, nroots abi.ChainEpoch, skipo
 		}
 	}()
 
-	return out, nil
+	return out
+}
+
+func (a *ChainAPI) splitstore() (*splitstore.SplitStore, error) {
+	ss, ok := a.BaseBlockstore.(*splitstore.SplitStore)
+	if !ok {
+		return nil, xerrors.Errorf("the node doesn't run a splitstore")
+	}
+	return ss, nil
+}
+
+func (a *ChainAPI) ChainSplitstoreStatus(ctx context.Context) (*api.SplitstoreStatus, error) {
+	ss, err := a.splitstore()
+	if err != nil {
+		return nil, err
+	}
+
+	st := ss.Status()
+	return &api.SplitstoreStatus{
+		BaseEpoch:           st.BaseEpoch,
+		WarmupEpoch:         st.WarmupEpoch,
+		CompactionIndex:     st.CompactionIndex,
+		MarkSetSize:         st.MarkSetSize,
+		HotSize:             st.HotSize,
+		ColdSize:            st.ColdSize,
+		Compacting:          st.Compacting,
+		Stage:               st.Stage,
+		StageStart:          st.StageStart,
+		LastCompactionEpoch: st.LastCompactionEpoch,
+		LastCompactionTime:  st.LastCompactionTime,
+		LastCompactionTook:  st.LastCompactionTook,
+		LastError:           st.LastError,
+	}, nil
+}
+
+func (a *ChainAPI) ChainSplitstoreCompact(ctx context.Context, pruneCold abi.ChainEpoch) error {
+	ss, err := a.splitstore()
+	if err != nil {
+		return err
+	}
+	return ss.Compact(pruneCold)
+}
+
+func (a *ChainAPI) ChainSplitstoreGC(ctx context.Context, full bool) error {
+	ss, err := a.splitstore()
+	if err != nil {
+		return err
+	}
+	return ss.GC(full)
+}
+
+func (a *ChainAPI) ChainSplitstoreWarmup(ctx context.Context) error {
+	ss, err := a.splitstore()
+	if err != nil {
+		return err
+	}
+	return ss.Warmup()
 }
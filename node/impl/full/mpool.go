@@ -26,7 +26,8 @@ type MpoolModuleAPI interface {
 type MpoolModule struct {
 	fx.In
 
-	Mpool *messagepool.MessagePool
+	Mpool  *messagepool.MessagePool
+	Wallet api.WalletAPI
 }
 
 var _ MpoolModuleAPI = (*MpoolModule)(nil)
@@ -52,6 +53,14 @@ func (a *MpoolAPI) MpoolSetConfig(ctx context.Context, cfg *types.MpoolConfig) e
 	return a.Mpool.SetConfig(cfg)
 }
 
+func (a *MpoolAPI) MpoolExport(context.Context) ([]api.MpoolPendingMessage, error) {
+	return a.Mpool.Export(), nil
+}
+
+func (a *MpoolAPI) MpoolImport(ctx context.Context, msgs []api.MpoolPendingMessage) (*api.MpoolImportResult, error) {
+	return a.Mpool.Import(msgs), nil
+}
+
 func (a *MpoolAPI) MpoolSelect(ctx context.Context, tsk types.TipSetKey, ticketQuality float64) ([]*types.SignedMessage, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -125,7 +134,16 @@ func (a *MpoolAPI) MpoolClear(ctx context.Context, local bool) error {
 }
 
 func (m *MpoolModule) MpoolPush(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
-	return m.Mpool.Push(smsg)
+	// only the messages of our own keys are trusted as local
+	has, err := m.Wallet.WalletHas(ctx, smsg.Message.From)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("checking wallet for %s: %w", smsg.Message.From, err)
+	}
+	if has {
+		return m.Mpool.Push(smsg)
+	}
+
+	return m.Mpool.PushRemote(smsg)
 }
 
 func (a *MpoolAPI) MpoolPushUntrusted(ctx context.Context, smsg *types.SignedMessage) (cid.Cid, error) {
@@ -33,9 +33,12 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/reward"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vesting"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/vote"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/policy"
+	"github.com/EpiK-Protocol/go-epik/chain/addrindex"
 	"github.com/EpiK-Protocol/go-epik/chain/beacon"
 	"github.com/EpiK-Protocol/go-epik/chain/gen"
 	"github.com/EpiK-Protocol/go-epik/chain/state"
+	"github.com/EpiK-Protocol/go-epik/chain/statediff"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
@@ -93,6 +96,7 @@ type StateAPI struct {
 	StateManager  *stmgr.StateManager
 	Chain         *store.ChainStore
 	Beacon        beacon.Schedule
+	AddrIndex     addrindex.AddrIndex
 }
 
 func (a *StateAPI) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
@@ -983,6 +987,29 @@ func (a *StateAPI) StateListMessages(ctx context.Context, match *api.MessageMatc
 	return out, nil
 }
 
+const defaultAddressHistoryLimit = 100
+
+func (a *StateAPI) StateAddressHistory(ctx context.Context, addr address.Address, cursor string, limit int) (*api.AddressHistory, error) {
+	if limit <= 0 {
+		limit = defaultAddressHistoryLimit
+	}
+
+	// the index is keyed by ID addresses
+	if idAddr, err := a.StateManager.LookupID(ctx, addr, a.Chain.GetHeaviestTipSet()); err == nil {
+		addr = idAddr
+	}
+
+	entries, next, err := a.AddrIndex.History(ctx, addr, cursor, limit)
+	if err != nil {
+		return nil, xerrors.Errorf("getting address history: %w", err)
+	}
+
+	return &api.AddressHistory{
+		Entries: entries,
+		Cursor:  next,
+	}, nil
+}
+
 func (a *StateAPI) StateCompute(ctx context.Context, height abi.ChainEpoch, msgs []*types.Message, tsk types.TipSetKey) (*api.ComputeStateOutput, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -999,6 +1026,68 @@ func (a *StateAPI) StateCompute(ctx context.Context, height abi.ChainEpoch, msgs
 	}, nil
 }
 
+func (a *StateAPI) StateSimulate(ctx context.Context, spec *api.SimulateSpec, tsk types.TipSetKey) (*api.SimulateOutput, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+
+	height := spec.Height
+	if height == 0 {
+		height = ts.Height() + 1
+	}
+	// cron runs for every skipped epoch, keep the work a read caller can ask
+	// for bounded
+	if height-ts.Height() > policy.ChainFinality {
+		return nil, xerrors.Errorf("simulation height %d is more than %d epochs after the tipset at %d", height, policy.ChainFinality, ts.Height())
+	}
+
+	us := a.StateManager.Upgrades()
+	for _, o := range spec.Upgrades {
+		us = us.Override(o.Network, o.Height)
+	}
+
+	root, bs, trace, err := a.StateManager.Simulate(ctx, ts, height, spec.Messages, spec.Implicit, us)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _, err := a.StateManager.TipSetState(ctx, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("computing tipset state: %w", err)
+	}
+
+	// migrations rewrite actors outside of the message traces, diff the whole
+	// state when one may have run
+	migrated := len(spec.Upgrades) > 0
+	for _, u := range us {
+		if u.Height > ts.Height() && u.Height <= height {
+			migrated = true
+		}
+	}
+
+	var diff []*statediff.ActorDiff
+	if migrated {
+		diff, err = statediff.Diff(ctx, bs, base, root, height)
+	} else {
+		// gas fees are paid to these actors outside of the message traces
+		addrs := []address.Address{reward.Address, builtin.BurntFundsActorAddr}
+		for _, ir := range trace {
+			addrs = append(addrs, statediff.TraceActors(ir.ExecutionTrace)...)
+		}
+		diff, err = statediff.DiffActors(ctx, bs, base, root, height, addrs...)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("diffing simulated state: %w", err)
+	}
+
+	return &api.SimulateOutput{
+		Root:  root,
+		Trace: trace,
+		Diff:  diff,
+	}, nil
+}
+
 func (m *StateModule) MsigGetAvailableBalance(ctx context.Context, addr address.Address, tsk types.TipSetKey) (types.BigInt, error) {
 	ts, err := m.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -152,3 +152,22 @@ func (a *SyncAPI) SyncValidateTipset(ctx context.Context, tsk types.TipSetKey) (
 
 	return true, nil
 }
+
+func (a *SyncAPI) SyncPeerScores(ctx context.Context) ([]api.SyncPeerScore, error) {
+	scores := a.Syncer.Exchange.PeerScores()
+	now := build.Clock.Now()
+
+	out := make([]api.SyncPeerScore, len(scores))
+	for i, s := range scores {
+		out[i] = api.SyncPeerScore{
+			ID:          s.ID,
+			Score:       s.Score,
+			Banned:      now.Before(s.BannedUntil),
+			BannedUntil: s.BannedUntil,
+			Bans:        s.Bans,
+			Penalties:   s.Penalties,
+		}
+	}
+
+	return out, nil
+}
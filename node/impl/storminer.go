@@ -30,6 +30,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/api"
 	"github.com/EpiK-Protocol/go-epik/api/apistruct"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
+	"github.com/EpiK-Protocol/go-epik/markets/retrievaladapter"
 	"github.com/EpiK-Protocol/go-epik/markets/storageadapter"
 	"github.com/EpiK-Protocol/go-epik/miner"
 	"github.com/EpiK-Protocol/go-epik/node/impl/common"
@@ -48,6 +49,7 @@ type StorageMinerAPI struct {
 	StorageProvider   storagemarket.StorageProvider
 	RetrievalProvider retrievalmarket.RetrievalProvider
 	Miner             *storage.Miner
+	WdPoSt            *storage.WindowPoStScheduler
 	BlockMiner        *miner.Miner
 	Full              api.FullNode
 	StorageMgr        *sectorstorage.Manager `optional:"true"`
@@ -59,6 +61,8 @@ type StorageMinerAPI struct {
 	AddrSel       *storage.AddressSelector
 	DealPublisher *storageadapter.DealPublisher
 
+	RetrievalPricing retrievaladapter.PricingFunc
+
 	DS dtypes.MetadataDS
 
 	ConsiderOnlineStorageDealsConfigFunc       dtypes.ConsiderOnlineStorageDealsConfigFunc
@@ -382,6 +386,10 @@ func (sm *StorageMinerAPI) SealingAbort(ctx context.Context, call storiface.Call
 	return sm.StorageMgr.Abort(ctx, call)
 }
 
+func (sm *StorageMinerAPI) SealingJobHistory(ctx context.Context, filter storiface.WorkerJobFilter) ([]storiface.WorkerJobRecord, error) {
+	return sm.StorageMgr.JobHistory(filter)
+}
+
 func (sm *StorageMinerAPI) MarketImportDealData(ctx context.Context, propCid cid.Cid, path string) error {
 	fi, err := os.Open(path)
 	if err != nil {
@@ -471,6 +479,17 @@ func (sm *StorageMinerAPI) MarketGetRetrievalAsk(ctx context.Context) (*retrieva
 	return sm.RetrievalProvider.GetAsk(), nil
 }
 
+func (sm *StorageMinerAPI) MarketRetrievalQuote(ctx context.Context, payloadCid cid.Cid, piece *cid.Cid, client address.Address) (*retrievalmarket.Ask, error) {
+	in := retrievaladapter.NewPricingInput(ctx, sm.PieceStore, sm.Index, sm.Miner.Address(), payloadCid, piece, client, *sm.RetrievalProvider.GetAsk())
+
+	ask, err := sm.RetrievalPricing(ctx, in)
+	if err != nil {
+		return nil, xerrors.Errorf("computing retrieval price: %w", err)
+	}
+
+	return &ask, nil
+}
+
 func (sm *StorageMinerAPI) MarketListDataTransfers(ctx context.Context) ([]api.DataTransferChannel, error) {
 	inProgressChannels, err := sm.DataTransfer.InProgressChannels(ctx)
 	if err != nil {
@@ -661,6 +680,10 @@ func (sm *StorageMinerAPI) CheckProvable(ctx context.Context, pp abi.RegisteredP
 	return out, nil
 }
 
+func (sm *StorageMinerAPI) ProvingSimulate(ctx context.Context, deadline uint64) (*api.WdPoStSimulation, error) {
+	return sm.WdPoSt.SimulatePost(ctx, deadline)
+}
+
 func (sm *StorageMinerAPI) ActorAddressConfig(ctx context.Context) (api.AddressConfig, error) {
 	return sm.AddrSel.AddressConfig, nil
 }
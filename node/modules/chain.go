@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/go-bitswap/network"
 	"github.com/ipfs/go-blockservice"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/routing"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.uber.org/fx"
@@ -26,6 +27,7 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/vm"
 	"github.com/EpiK-Protocol/go-epik/extern/sector-storage/ffiwrapper"
 	"github.com/EpiK-Protocol/go-epik/journal"
+	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 	"github.com/EpiK-Protocol/go-epik/node/modules/helpers"
 )
@@ -59,9 +61,9 @@ func ChainBlockService(bs dtypes.ExposedBlockstore, rem dtypes.ChainBitswap) dty
 	return blockservice.New(bs, rem)
 }
 
-func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS, nn dtypes.NetworkName, j journal.Journal, protector dtypes.GCReferenceProtector) (*messagepool.MessagePool, error) {
+func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS, ids dtypes.IndexDS, nn dtypes.NetworkName, j journal.Journal, protector dtypes.GCReferenceProtector) (*messagepool.MessagePool, error) {
 	mpp := messagepool.NewProvider(sm, ps)
-	mp, err := messagepool.New(mpp, ds, nn, j)
+	mp, err := messagepool.New(mpp, ds, ids, nn, j)
 	if err != nil {
 		return nil, xerrors.Errorf("constructing mpool: %w", err)
 	}
@@ -160,6 +162,30 @@ func NewSyncer(params SyncerParams) (*chain.Syncer, error) {
 	return syncer, nil
 }
 
+func ChainExchangeServer(cfg config.Libp2p) func(cs *store.ChainStore) (exchange.Server, error) {
+	return func(cs *store.ChainStore) (exchange.Server, error) {
+		limits := exchange.ServerLimits{
+			TipSetsPerSecond:    cfg.ChainExchange.TipSetsPerSecond,
+			TipSetBurst:         cfg.ChainExchange.TipSetBurst,
+			MessagesPerSecond:   cfg.ChainExchange.MessagesPerSecond,
+			MessageBurst:        cfg.ChainExchange.MessageBurst,
+			MaxStreamsPerPeer:   cfg.ChainExchange.MaxStreamsPerPeer,
+			MaxStreams:          cfg.ChainExchange.MaxStreams,
+			ProtectedMultiplier: cfg.ChainExchange.ProtectedMultiplier,
+		}
+
+		for _, p := range cfg.ProtectedPeers {
+			pid, err := peer.IDFromString(p)
+			if err != nil {
+				return nil, xerrors.Errorf("failed to parse peer ID in protected peers array: %w", err)
+			}
+			limits.ProtectedPeers = append(limits.ProtectedPeers, pid)
+		}
+
+		return exchange.NewServerWithLimits(cs, limits), nil
+	}
+}
+
 func NewSlashFilter(ds dtypes.MetadataDS) *slashfilter.SlashFilter {
 	return slashfilter.New(ds)
 }
@@ -23,6 +23,10 @@ import (
 // main repo datastore.
 type MetadataDS datastore.Batching
 
+// IndexDS stores chain-derived indexes and caches which can be rebuilt. Unlike
+// MetadataDS it isn't backed up.
+type IndexDS datastore.Batching
+
 type (
 	// UniversalBlockstore is the universal blockstore backend.
 	UniversalBlockstore blockstore.Blockstore
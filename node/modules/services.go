@@ -16,14 +16,18 @@ import (
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/discovery"
 	discoveryimpl "github.com/filecoin-project/go-fil-markets/discovery/impl"
+	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain"
 	"github.com/EpiK-Protocol/go-epik/chain/beacon"
 	"github.com/EpiK-Protocol/go-epik/chain/beacon/drand"
+	"github.com/EpiK-Protocol/go-epik/chain/economics"
 	"github.com/EpiK-Protocol/go-epik/chain/exchange"
+	"github.com/EpiK-Protocol/go-epik/chain/feebump"
 	"github.com/EpiK-Protocol/go-epik/chain/messagepool"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
@@ -32,7 +36,9 @@ import (
 	"github.com/EpiK-Protocol/go-epik/journal"
 	"github.com/EpiK-Protocol/go-epik/lib/peermgr"
 	marketevents "github.com/EpiK-Protocol/go-epik/markets/loggers"
+	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/hello"
+	"github.com/EpiK-Protocol/go-epik/node/impl/full"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 	"github.com/EpiK-Protocol/go-epik/node/modules/helpers"
 	"github.com/EpiK-Protocol/go-epik/node/repo"
@@ -188,6 +194,75 @@ func HandleIncomingMessages(mctx helpers.MetricsCtx, lc fx.Lifecycle, ps *pubsub
 	waitForSync(stmgr, pubsubMsgsSyncEpochs, subscribe)
 }
 
+// feeBumpAPI is the full node API the fee bumper replaces messages with
+type feeBumpAPI struct {
+	fx.In
+
+	full.ChainModuleAPI
+	full.MpoolAPI
+}
+
+func RunFeeBump(cfg config.FeeBump) func(lc fx.Lifecycle, api feeBumpAPI, mff dtypes.DefaultMaxFeeFunc, j journal.Journal) error {
+	return func(lc fx.Lifecycle, api feeBumpAPI, mff dtypes.DefaultMaxFeeFunc, j journal.Journal) error {
+		fcfg := feebump.Config{
+			StuckEpochs:     cfg.StuckEpochs,
+			BaseFeeLookback: cfg.BaseFeeLookback,
+			MaxFee:          map[address.Address]abi.TokenAmount{},
+			BudgetEpochs:    cfg.BudgetEpochs,
+		}
+
+		for _, s := range cfg.Addresses {
+			addr, err := address.NewFromString(s)
+			if err != nil {
+				return xerrors.Errorf("parsing fee bump address %q: %w", s, err)
+			}
+			fcfg.Addresses = append(fcfg.Addresses, addr)
+		}
+
+		for s, mf := range cfg.MaxFee {
+			addr, err := address.NewFromString(s)
+			if err != nil {
+				return xerrors.Errorf("parsing fee bump max fee address %q: %w", s, err)
+			}
+			fcfg.MaxFee[addr] = abi.TokenAmount(mf)
+		}
+
+		fb := feebump.NewFeeBumper(&api, fcfg, mff, j)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				fb.Start()
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				fb.Stop()
+				return nil
+			},
+		})
+		return nil
+	}
+}
+
+// economicsAPI is the full node API the economics collector samples with
+type economicsAPI struct {
+	full.ChainModuleAPI
+	*full.StateAPI
+}
+
+func RunEconomicsMetrics(candidates int) func(lc fx.Lifecycle, chain full.ChainModuleAPI, state full.StateAPI) {
+	return func(lc fx.Lifecycle, chain full.ChainModuleAPI, state full.StateAPI) {
+		c := economics.NewCollector(&economicsAPI{ChainModuleAPI: chain, StateAPI: &state}, candidates)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				return c.Start()
+			},
+			OnStop: func(context.Context) error {
+				c.Stop()
+				return nil
+			},
+		})
+	}
+}
+
 func NewLocalDiscovery(lc fx.Lifecycle, ds dtypes.MetadataDS) (*discoveryimpl.Local, error) {
 	local, err := discoveryimpl.NewLocal(namespace.Wrap(ds, datastore.NewKey("/deals/local")))
 	if err != nil {
@@ -248,3 +323,20 @@ func OpenFilesystemJournal(lr repo.LockedRepo, lc fx.Lifecycle, disabled journal
 
 	return jrnl, err
 }
+
+// OpenJournal opens the journal sinks enabled in the config, among the sinks
+// of journal.DefaultRegistry.
+func OpenJournal(cfg config.Journal) func(lr repo.LockedRepo, lc fx.Lifecycle, disabled journal.DisabledEvents) (journal.Journal, error) {
+	return func(lr repo.LockedRepo, lc fx.Lifecycle, disabled journal.DisabledEvents) (journal.Journal, error) {
+		jrnl, err := journal.OpenJournal(lr, disabled, cfg, journal.DefaultRegistry)
+		if err != nil {
+			return nil, err
+		}
+
+		lc.Append(fx.Hook{
+			OnStop: func(_ context.Context) error { return jrnl.Close() },
+		})
+
+		return jrnl, nil
+	}
+}
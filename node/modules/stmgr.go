@@ -1,20 +1,74 @@
 package modules
 
 import (
+	"context"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
 	"go.uber.org/fx"
 
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/EpiK-Protocol/go-epik/chain/addrindex"
+	"github.com/EpiK-Protocol/go-epik/chain/msgindex"
 	"github.com/EpiK-Protocol/go-epik/chain/stmgr"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
+	"github.com/EpiK-Protocol/go-epik/node/config"
+	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 )
 
-func StateManager(lc fx.Lifecycle, cs *store.ChainStore, us stmgr.UpgradeSchedule) (*stmgr.StateManager, error) {
+func StateManager(lc fx.Lifecycle, cs *store.ChainStore, us stmgr.UpgradeSchedule, mi msgindex.MsgIndex) (*stmgr.StateManager, error) {
 	sm, err := stmgr.NewStateManagerWithUpgradeSchedule(cs, us)
 	if err != nil {
 		return nil, err
 	}
+	sm.SetMsgIndex(mi)
 	lc.Append(fx.Hook{
 		OnStart: sm.Start,
 		OnStop:  sm.Stop,
 	})
 	return sm, nil
 }
+
+func MsgIndex(cfg config.MsgIndex) func(lc fx.Lifecycle, ds dtypes.IndexDS, cs *store.ChainStore) msgindex.MsgIndex {
+	return func(lc fx.Lifecycle, ds dtypes.IndexDS, cs *store.ChainStore) msgindex.MsgIndex {
+		if !cfg.Enable {
+			return msgindex.DummyMsgIndex
+		}
+
+		mi := msgindex.New(namespace.Wrap(ds, datastore.NewKey("/msgindex")), cs)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				mi.Start()
+				if cfg.Backfill {
+					mi.Backfill(abi.ChainEpoch(cfg.BackfillFromEpoch))
+				}
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				return mi.Close()
+			},
+		})
+		return mi
+	}
+}
+
+func AddrIndex(cfg config.AddrIndex) func(lc fx.Lifecycle, ds dtypes.IndexDS, cs *store.ChainStore, sm *stmgr.StateManager) addrindex.AddrIndex {
+	return func(lc fx.Lifecycle, ds dtypes.IndexDS, cs *store.ChainStore, sm *stmgr.StateManager) addrindex.AddrIndex {
+		if !cfg.Enable {
+			return addrindex.DisabledAddrIndex
+		}
+
+		ai := addrindex.New(namespace.Wrap(ds, datastore.NewKey("/addrindex")), cs, sm)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				ai.Start()
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				return ai.Close()
+			},
+		})
+		return ai
+	}
+}
@@ -3,12 +3,17 @@ package modules
 import (
 	"context"
 	"path/filepath"
+	"time"
 
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/mitchellh/go-homedir"
 	"go.uber.org/fx"
 	"golang.org/x/xerrors"
 
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/lib/backupds"
+	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
 	"github.com/EpiK-Protocol/go-epik/node/modules/helpers"
 	"github.com/EpiK-Protocol/go-epik/node/repo"
@@ -30,7 +35,59 @@ func KeyStore(lr repo.LockedRepo) (types.KeyStore, error) {
 	return lr.KeyStore()
 }
 
-func Datastore(disableLog bool) func(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo) (dtypes.MetadataDS, error) {
+// legacyIndexPrefixes are the keys of the indexes and caches stored in the
+// metadata datastore before they moved to the index datastore
+var legacyIndexPrefixes = []string{"/msgindex", "/addrindex", "/mpool/snapshot"}
+
+// IndexDatastore opens the datastore of the rebuildable indexes and caches,
+// dropping their copies left in the metadata datastore
+func IndexDatastore(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo, mds dtypes.MetadataDS) (dtypes.IndexDS, error) {
+	ctx := helpers.LifecycleCtx(mctx, lc)
+	ids, err := r.Datastore(ctx, "/index")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prefix := range legacyIndexPrefixes {
+		if err := dropPrefix(mds, prefix); err != nil {
+			return nil, xerrors.Errorf("dropping %s from the metadata datastore: %w", prefix, err)
+		}
+	}
+
+	return ids, nil
+}
+
+func dropPrefix(ds datastore.Batching, prefix string) error {
+	res, err := ds.Query(query.Query{Prefix: prefix, KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer res.Close() //nolint:errcheck
+
+	b, err := ds.Batch()
+	if err != nil {
+		return err
+	}
+
+	var dropped int
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		if err := b.Delete(datastore.NewKey(r.Key)); err != nil {
+			return err
+		}
+		dropped++
+	}
+	if dropped == 0 {
+		return nil
+	}
+
+	log.Infow("dropping index keys from the metadata datastore", "prefix", prefix, "keys", dropped)
+	return b.Commit()
+}
+
+func Datastore(cfg config.Backup) func(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo) (dtypes.MetadataDS, error) {
 	return func(lc fx.Lifecycle, mctx helpers.MetricsCtx, r repo.LockedRepo) (dtypes.MetadataDS, error) {
 		ctx := helpers.LifecycleCtx(mctx, lc)
 		mds, err := r.Datastore(ctx, "/metadata")
@@ -39,7 +96,7 @@ func Datastore(disableLog bool) func(lc fx.Lifecycle, mctx helpers.MetricsCtx, r
 		}
 
 		var logdir string
-		if !disableLog {
+		if !cfg.DisableMetadataLog {
 			logdir = filepath.Join(r.Path(), "kvlog/metadata")
 		}
 
@@ -54,6 +111,40 @@ func Datastore(disableLog bool) func(lc fx.Lifecycle, mctx helpers.MetricsCtx, r
 			},
 		})
 
+		// appended after the log hook, stopped before the log is closed
+		if cfg.Path != "" && cfg.FullInterval > 0 {
+			if cfg.DisableMetadataLog {
+				return nil, xerrors.Errorf("scheduled backups require the metadata log, unset Backup.DisableMetadataLog")
+			}
+
+			dir, err := homedir.Expand(cfg.Path)
+			if err != nil {
+				return nil, xerrors.Errorf("expanding backup path: %w", err)
+			}
+
+			sctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			lc.Append(fx.Hook{
+				OnStart: func(context.Context) error {
+					go func() {
+						defer close(done)
+						bds.RunSchedule(sctx, backupds.Schedule{
+							Dir:         dir,
+							Full:        time.Duration(cfg.FullInterval),
+							Incremental: time.Duration(cfg.IncrementalInterval),
+							Keep:        cfg.Retention,
+						})
+					}()
+					return nil
+				},
+				OnStop: func(context.Context) error {
+					cancel()
+					<-done
+					return nil
+				},
+			})
+		}
+
 		return bds, nil
 	}
 }
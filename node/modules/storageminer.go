@@ -68,8 +68,10 @@ import (
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/EpiK-Protocol/go-epik/journal"
 	"github.com/EpiK-Protocol/go-epik/markets"
+	"github.com/EpiK-Protocol/go-epik/markets/dealfilter"
 	marketevents "github.com/EpiK-Protocol/go-epik/markets/loggers"
 	"github.com/EpiK-Protocol/go-epik/markets/retrievaladapter"
+	"github.com/EpiK-Protocol/go-epik/markets/unsealcache"
 	lotusminer "github.com/EpiK-Protocol/go-epik/miner"
 	"github.com/EpiK-Protocol/go-epik/node/config"
 	"github.com/EpiK-Protocol/go-epik/node/modules/dtypes"
@@ -233,12 +235,42 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 
 		ctx := helpers.LifecycleCtx(mctx, lc)
 
-		fps, err := storage.NewWindowedPoStScheduler(api, fc, as, sealer, verif, sealer, j, maddr)
+		sm, err := storage.NewMiner(api, maddr, h, ds, sealer, sc, verif, gsd, fc, j, as)
 		if err != nil {
 			return nil, err
 		}
 
-		sm, err := storage.NewMiner(api, maddr, h, ds, sealer, sc, verif, gsd, fc, j, as)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				return sm.Run(ctx)
+			},
+			OnStop: sm.Stop,
+		})
+
+		return sm, nil
+	}
+}
+
+func WindowPostScheduler(fc config.MinerFeeConfig) func(params StorageMinerParams) (*storage.WindowPoStScheduler, error) {
+	return func(params StorageMinerParams) (*storage.WindowPoStScheduler, error) {
+		var (
+			mctx   = params.MetricsCtx
+			lc     = params.Lifecycle
+			api    = params.API
+			sealer = params.Sealer
+			verif  = params.Verifier
+			j      = params.Journal
+			as     = params.AddrSel
+		)
+
+		maddr, err := minerAddrFromDS(params.MetadataDS)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
+		fps, err := storage.NewWindowedPoStScheduler(api, fc, as, sealer, verif, sealer, j, maddr)
 		if err != nil {
 			return nil, err
 		}
@@ -246,12 +278,11 @@ func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*st
 		lc.Append(fx.Hook{
 			OnStart: func(context.Context) error {
 				go fps.Run(ctx)
-				return sm.Run(ctx)
+				return nil
 			},
-			OnStop: sm.Stop,
 		})
 
-		return sm, nil
+		return fps, nil
 	}
 }
 
@@ -515,16 +546,19 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 	// unverifiedOk dtypes.ConsiderUnverifiedStorageDealsConfigFunc,
 	blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 	expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
-	spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
+	spn storagemarket.StorageProviderNode,
+	j journal.Journal) dtypes.StorageDealFilter {
 	return func(onlineOk dtypes.ConsiderOnlineStorageDealsConfigFunc,
 		offlineOk dtypes.ConsiderOfflineStorageDealsConfigFunc,
 		// verifiedOk dtypes.ConsiderVerifiedStorageDealsConfigFunc,
 		// unverifiedOk dtypes.ConsiderUnverifiedStorageDealsConfigFunc,
 		blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
 		expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
-		spn storagemarket.StorageProviderNode) dtypes.StorageDealFilter {
+		spn storagemarket.StorageProviderNode,
+		j journal.Journal) dtypes.StorageDealFilter {
 
-		return func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
+		evtType := j.RegisterEventType("markets/storage/provider", "deal_filter")
+		return markets.StorageDealFilterJournaler(j, evtType, func(ctx context.Context, deal storagemarket.MinerDeal) (bool, string, error) {
 			b, err := onlineOk()
 			if err != nil {
 				return false, "miner error", err
@@ -605,7 +639,7 @@ func BasicDealFilter(user dtypes.StorageDealFilter) func(onlineOk dtypes.Conside
 			}
 
 			return true, "", nil
-		}
+		})
 	}
 }
 
@@ -631,10 +665,14 @@ func StorageProvider(minerAddress dtypes.MinerAddress,
 }
 
 func RetrievalDealFilter(userFilter dtypes.RetrievalDealFilter) func(onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalDealFilter {
+	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc,
+	j journal.Journal) dtypes.RetrievalDealFilter {
 	return func(onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
-		offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc) dtypes.RetrievalDealFilter {
-		return func(ctx context.Context, state retrievalmarket.ProviderDealState) (bool, string, error) {
+		offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc,
+		j journal.Journal) dtypes.RetrievalDealFilter {
+
+		evtType := j.RegisterEventType("markets/retrieval/provider", "deal_filter")
+		return markets.RetrievalDealFilterJournaler(j, evtType, func(ctx context.Context, state retrievalmarket.ProviderDealState) (bool, string, error) {
 			b, err := onlineOk()
 			if err != nil {
 				return false, "miner error", err
@@ -659,10 +697,31 @@ func RetrievalDealFilter(userFilter dtypes.RetrievalDealFilter) func(onlineOk dt
 			}
 
 			return true, "", nil
-		}
+		})
 	}
 }
 
+// RuleStorageDealFilter applies the basic storage deal checks followed by the
+// deal filter rule engine
+func RuleStorageDealFilter(rf *dealfilter.RuleFilter,
+	onlineOk dtypes.ConsiderOnlineStorageDealsConfigFunc,
+	offlineOk dtypes.ConsiderOfflineStorageDealsConfigFunc,
+	blocklistFunc dtypes.StorageDealPieceCidBlocklistConfigFunc,
+	expectedSealTimeFunc dtypes.GetExpectedSealDurationFunc,
+	spn storagemarket.StorageProviderNode,
+	j journal.Journal) dtypes.StorageDealFilter {
+	return BasicDealFilter(rf.StorageDealFilter)(onlineOk, offlineOk, blocklistFunc, expectedSealTimeFunc, spn, j)
+}
+
+// RuleRetrievalDealFilter applies the basic retrieval deal checks followed by
+// the deal filter rule engine
+func RuleRetrievalDealFilter(rf *dealfilter.RuleFilter,
+	onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
+	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc,
+	j journal.Journal) dtypes.RetrievalDealFilter {
+	return RetrievalDealFilter(rf.RetrievalDealFilter)(onlineOk, offlineOk, j)
+}
+
 // RetrievalProvider creates a new retrieval provider attached to the provider blockstore
 func RetrievalProvider(h host.Host,
 	miner *storage.Miner,
@@ -675,22 +734,80 @@ func RetrievalProvider(h host.Host,
 	onlineOk dtypes.ConsiderOnlineRetrievalDealsConfigFunc,
 	offlineOk dtypes.ConsiderOfflineRetrievalDealsConfigFunc,
 	userFilter dtypes.RetrievalDealFilter,
+	pricing retrievaladapter.PricingFunc,
+	si stores.SectorIndex,
+	cache *unsealcache.Cache,
 ) (retrievalmarket.RetrievalProvider, error) {
-	adapter := retrievaladapter.NewRetrievalProviderNode(miner, sealer, full)
+	adapter := retrievaladapter.NewRetrievalProviderNode(miner, sealer, full, cache)
 
 	maddr, err := minerAddrFromDS(ds)
 	if err != nil {
 		return nil, err
 	}
 
-	netwk := rmnet.NewFromLibp2pHost(h)
-	opt := retrievalimpl.DealDeciderOpt(retrievalimpl.DealDecider(userFilter))
+	var provider retrievalmarket.RetrievalProvider
+	priceFilter := retrievaladapter.PricingDealFilter(pricing, func() *retrievalmarket.Ask {
+		return provider.GetAsk()
+	}, pieceStore, si, maddr)
+
+	netwk := retrievaladapter.PricingNetwork(rmnet.NewFromLibp2pHost(h), pricing, func() *retrievalmarket.Ask {
+		return provider.GetAsk()
+	}, pieceStore, si, maddr)
+	opt := retrievalimpl.DealDeciderOpt(retrievalimpl.DealDecider(func(ctx context.Context, state retrievalmarket.ProviderDealState) (bool, string, error) {
+		accept, reason, err := userFilter(ctx, state)
+		if err != nil || !accept {
+			return accept, reason, err
+		}
 
-	return retrievalimpl.NewProvider(maddr, adapter, netwk, pieceStore, mds, dt, namespace.Wrap(ds, datastore.NewKey("/retrievals/provider")), opt)
+		return priceFilter(ctx, state)
+	}))
+
+	provider, err = retrievalimpl.NewProvider(maddr, adapter, netwk, pieceStore, mds, dt, namespace.Wrap(ds, datastore.NewKey("/retrievals/provider")), opt)
+	return provider, err
+}
+
+// RetrievalPricing constructs the retrieval pricing function
+func RetrievalPricing(cfg config.RetrievalPricing) func(maddr dtypes.MinerAddress, full lapi.FullNode) (retrievaladapter.PricingFunc, error) {
+	return func(maddr dtypes.MinerAddress, full lapi.FullNode) (retrievaladapter.PricingFunc, error) {
+		if !cfg.Enable {
+			return retrievaladapter.DefaultPricing, nil
+		}
+
+		return retrievaladapter.NewPricingPolicy(cfg, address.Address(maddr), full)
+	}
+}
+
+var UnsealedCachePrefix = datastore.NewKey("/unsealcache")
+
+// UnsealedCache constructs the unsealed copy cache, returning nil when it's
+// disabled
+func UnsealedCache(cfg config.UnsealedCacheConfig) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ds dtypes.MetadataDS, maddr dtypes.MinerAddress, sealer *sectorstorage.Manager, si stores.SectorIndex, miner *storage.Miner, ps dtypes.ProviderPieceStore, full lapi.FullNode) (*unsealcache.Cache, error) {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, ds dtypes.MetadataDS, maddr dtypes.MinerAddress, sealer *sectorstorage.Manager, si stores.SectorIndex, miner *storage.Miner, ps dtypes.ProviderPieceStore, full lapi.FullNode) (*unsealcache.Cache, error) {
+		if !cfg.Enable {
+			return nil, nil
+		}
+
+		c, err := unsealcache.New(cfg, namespace.Wrap(ds, UnsealedCachePrefix), address.Address(maddr), sealer, si, miner.GetSectorInfo, ps, full)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := helpers.LifecycleCtx(mctx, lc)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error {
+				c.Start(ctx)
+				return nil
+			},
+			OnStop: c.Stop,
+		})
+
+		return c, nil
+	}
 }
 
 var WorkerCallsPrefix = datastore.NewKey("/worker/calls")
 var ManagerWorkPrefix = datastore.NewKey("/stmgr/calls")
+var ManagerHistoryPrefix = datastore.NewKey("/stmgr/history")
 
 func SectorStorage(mctx helpers.MetricsCtx, lc fx.Lifecycle, ls stores.LocalStorage, si stores.SectorIndex, sc sectorstorage.SealerConfig, urls sectorstorage.URLs, sa sectorstorage.StorageAuth, ds dtypes.MetadataDS) (*sectorstorage.Manager, error) {
 	ctx := helpers.LifecycleCtx(mctx, lc)
@@ -698,7 +815,7 @@ func SectorStorage(mctx helpers.MetricsCtx, lc fx.Lifecycle, ls stores.LocalStor
 	wsts := statestore.New(namespace.Wrap(ds, WorkerCallsPrefix))
 	smsts := statestore.New(namespace.Wrap(ds, ManagerWorkPrefix))
 
-	sst, err := sectorstorage.New(ctx, ls, si, sc, urls, sa, wsts, smsts)
+	sst, err := sectorstorage.New(ctx, ls, si, sc, urls, sa, wsts, smsts, namespace.Wrap(ds, ManagerHistoryPrefix))
 	if err != nil {
 		return nil, err
 	}
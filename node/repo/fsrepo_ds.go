@@ -24,6 +24,10 @@ var fsDatastores = map[string]dsCtor{
 	"staging": badgerDs, // miner specific
 
 	"client": badgerDs, // client specific
+
+	// chain-derived indexes and caches, which can be rebuilt, so they are
+	// kept out of the metadata backups
+	"index": levelDs,
 }
 
 func badgerDs(path string, readonly bool) (datastore.Batching, error) {
@@ -36,3 +36,22 @@ func TestNextDeadline(t *testing.T) {
 		require.EqualValues(t, expChallenge, di.Challenge)
 	}
 }
+
+func TestSimDeadline(t *testing.T) {
+	cur := NewDeadlineInfo(0, 5, abi.ChainEpoch(5*miner.WPoStChallengeWindow+10))
+
+	di := simDeadline(cur, 5)
+	require.Equal(t, cur, di)
+
+	// later deadlines are in the current proving period
+	di = simDeadline(cur, 7)
+	require.EqualValues(t, 7, di.Index)
+	require.EqualValues(t, 0, di.PeriodStart)
+	require.EqualValues(t, 7*miner.WPoStChallengeWindow, di.Open)
+
+	// earlier deadlines are in the next one
+	di = simDeadline(cur, 2)
+	require.EqualValues(t, 2, di.Index)
+	require.EqualValues(t, miner.WPoStProvingPeriod, di.PeriodStart)
+	require.EqualValues(t, miner.WPoStProvingPeriod+2*miner.WPoStChallengeWindow, di.Open)
+}
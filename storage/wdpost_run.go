@@ -499,6 +499,18 @@ func (s *WindowPoStScheduler) runPost(ctx context.Context, di dline.Info, ts *ty
 		}
 	}
 
+	return s.provePartitions(ctx, di, ts, rand, true)
+}
+
+// provePartitions generates proofs for all partitions of the deadline with
+// the given randomness. With recheckRand set, proofs are discarded and
+// regenerated when the chain randomness changed while proving.
+func (s *WindowPoStScheduler) provePartitions(ctx context.Context, di dline.Info, ts *types.TipSet, rand abi.Randomness, recheckRand bool) ([]miner.SubmitWindowedPoStParams, error) {
+	buf := new(bytes.Buffer)
+	if err := s.actor.MarshalCBOR(buf); err != nil {
+		return nil, xerrors.Errorf("failed to marshal address to cbor: %w", err)
+	}
+
 	// Get the partitions for the given deadline
 	partitions, err := s.api.StateMinerPartitions(ctx, s.actor, di.Index, ts.Key())
 	if err != nil {
@@ -620,19 +632,22 @@ func (s *WindowPoStScheduler) runPost(ctx context.Context, di dline.Info, ts *ty
 					return nil, xerrors.Errorf("received no proofs back from generate window post")
 				}
 
-				headTs, err := s.api.ChainHead(ctx)
-				if err != nil {
-					return nil, xerrors.Errorf("getting current head: %w", err)
-				}
-
-				checkRand, err := s.api.ChainGetRandomnessFromBeacon(ctx, headTs.Key(), crypto.DomainSeparationTag_WindowedPoStChallengeSeed, di.Challenge, buf.Bytes())
-				if err != nil {
-					return nil, xerrors.Errorf("failed to get chain randomness from beacon for window post (ts=%d; deadline=%d): %w", ts.Height(), di, err)
-				}
-
-				if !bytes.Equal(checkRand, rand) {
-					log.Warnw("windowpost randomness changed", "old", rand, "new", checkRand, "ts-height", ts.Height(), "challenge-height", di.Challenge, "tsk", ts.Key())
-					continue
+				checkRand := rand
+				if recheckRand {
+					headTs, err := s.api.ChainHead(ctx)
+					if err != nil {
+						return nil, xerrors.Errorf("getting current head: %w", err)
+					}
+
+					checkRand, err = s.api.ChainGetRandomnessFromBeacon(ctx, headTs.Key(), crypto.DomainSeparationTag_WindowedPoStChallengeSeed, di.Challenge, buf.Bytes())
+					if err != nil {
+						return nil, xerrors.Errorf("failed to get chain randomness from beacon for window post (ts=%d; deadline=%d): %w", ts.Height(), di, err)
+					}
+
+					if !bytes.Equal(checkRand, rand) {
+						log.Warnw("windowpost randomness changed", "old", rand, "new", checkRand, "ts-height", ts.Height(), "challenge-height", di.Challenge, "tsk", ts.Key())
+						continue
+					}
 				}
 
 				// If we generated an incorrect proof, try again.
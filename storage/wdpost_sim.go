@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/go-state-types/dline"
+
+	"github.com/EpiK-Protocol/go-epik/api"
+	"github.com/EpiK-Protocol/go-epik/chain/actors"
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
+	"github.com/EpiK-Protocol/go-epik/chain/types"
+)
+
+// simPoStGasPerPartition is the gas limit per partition assumed for
+// SubmitWindowedPoSt messages which can't be estimated, it's above the gas
+// used to verify the proof of a full partition
+const simPoStGasPerPartition = 100_000_000
+
+// SimulatePost runs the WindowPoSt flow for the next instance of the deadline
+// against the current chain state without submitting anything. Challenges
+// are generated from the real randomness when the deadline is open, from mock
+// randomness otherwise, as the real randomness is only known once the
+// deadline opens.
+func (s *WindowPoStScheduler) SimulatePost(ctx context.Context, dlIdx uint64) (*api.WdPoStSimulation, error) {
+	ts, err := s.api.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	cur, err := s.api.StateMinerProvingDeadline(ctx, s.actor, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting proving deadline: %w", err)
+	}
+
+	if dlIdx >= cur.WPoStPeriodDeadlines {
+		return nil, xerrors.Errorf("deadline %d out of range (0-%d)", dlIdx, cur.WPoStPeriodDeadlines-1)
+	}
+
+	di := simDeadline(cur, dlIdx)
+
+	partitions, err := s.api.StateMinerPartitions(ctx, s.actor, dlIdx, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting partitions: %w", err)
+	}
+
+	out := &api.WdPoStSimulation{
+		Deadline:     dlIdx,
+		Open:         di.Open,
+		FaultCutoff:  di.FaultCutoff,
+		CurrentEpoch: di.CurrentEpoch,
+		MaxFee:       abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee),
+	}
+
+	for partIdx, partition := range partitions {
+		risk, err := s.partitionRisk(ctx, partition, ts.Key())
+		if err != nil {
+			return nil, xerrors.Errorf("checking partition %d: %w", partIdx, err)
+		}
+		risk.Index = uint64(partIdx)
+		out.Partitions = append(out.Partitions, risk)
+	}
+
+	// the proofs of the open deadline can be verified, so that their gas is
+	// estimated by executing the messages
+	realProof := di.Index == cur.Index && cur.IsOpen() && ts.Height() > di.Challenge
+	postRand := make(abi.Randomness, 32)
+	if realProof {
+		buf := new(bytes.Buffer)
+		if err := s.actor.MarshalCBOR(buf); err != nil {
+			return nil, xerrors.Errorf("failed to marshal address to cbor: %w", err)
+		}
+		postRand, err = s.api.ChainGetRandomnessFromBeacon(ctx, ts.Key(), crypto.DomainSeparationTag_WindowedPoStChallengeSeed, di.Challenge, buf.Bytes())
+		if err != nil {
+			return nil, xerrors.Errorf("getting window post randomness: %w", err)
+		}
+	} else if _, err := rand.Read(postRand); err != nil {
+		return nil, xerrors.Errorf("generating mock randomness: %w", err)
+	}
+
+	start := time.Now()
+	posts, err := s.provePartitions(ctx, *di, ts, postRand, false)
+	if err != nil {
+		return nil, xerrors.Errorf("running window post: %w", err)
+	}
+	out.ProveTime = time.Since(start)
+
+	mi, err := s.api.StateMinerInfo(ctx, s.actor, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting miner info: %w", err)
+	}
+
+	for i := range posts {
+		msim, err := s.simulateSubmit(ctx, &posts[i], mi, realProof)
+		if err != nil {
+			return nil, err
+		}
+		out.Messages = append(out.Messages, msim)
+
+		// sectors skipped while proving are at risk as well
+		for _, p := range posts[i].Partitions {
+			if p.Index >= uint64(len(out.Partitions)) {
+				continue
+			}
+			risk := &out.Partitions[p.Index]
+			risk.Unprovable, err = bitfield.MergeBitFields(risk.Unprovable, p.Skipped)
+			if err != nil {
+				return nil, xerrors.Errorf("merging skipped sectors: %w", err)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// simDeadline returns info of the next instance of the deadline, which is the
+// current one if it's open
+func simDeadline(cur *dline.Info, dlIdx uint64) *dline.Info {
+	if dlIdx == cur.Index {
+		return cur
+	}
+
+	periodStart := cur.PeriodStart
+	if dlIdx < cur.Index {
+		periodStart += cur.WPoStProvingPeriod
+	}
+
+	return NewDeadlineInfo(periodStart, dlIdx, cur.CurrentEpoch)
+}
+
+func (s *WindowPoStScheduler) partitionRisk(ctx context.Context, partition api.Partition, tsk types.TipSetKey) (api.WdPoStPartitionRisk, error) {
+	var risk api.WdPoStPartitionRisk
+
+	var err error
+	if risk.Live, err = partition.LiveSectors.Count(); err != nil {
+		return risk, err
+	}
+	if risk.Faulty, err = partition.FaultySectors.Count(); err != nil {
+		return risk, err
+	}
+	if risk.Recovering, err = partition.RecoveringSectors.Count(); err != nil {
+		return risk, err
+	}
+
+	toProve, err := bitfield.SubtractBitField(partition.LiveSectors, partition.FaultySectors)
+	if err != nil {
+		return risk, xerrors.Errorf("removing faults from set of sectors to prove: %w", err)
+	}
+	toProve, err = bitfield.MergeBitFields(toProve, partition.RecoveringSectors)
+	if err != nil {
+		return risk, xerrors.Errorf("adding recoveries to set of sectors to prove: %w", err)
+	}
+
+	good, err := s.checkSectors(ctx, toProve, tsk)
+	if err != nil {
+		return risk, xerrors.Errorf("checking sectors: %w", err)
+	}
+	if risk.Unprovable, err = bitfield.SubtractBitField(toProve, good); err != nil {
+		return risk, err
+	}
+
+	unrecovered, err := bitfield.SubtractBitField(partition.FaultySectors, partition.RecoveringSectors)
+	if err != nil {
+		return risk, err
+	}
+	if risk.Recoverable, err = s.checkSectors(ctx, unrecovered, tsk); err != nil {
+		return risk, xerrors.Errorf("checking faulty sectors: %w", err)
+	}
+
+	return risk, nil
+}
+
+// simulateSubmit builds the SubmitWindowedPoSt message for the proof and
+// estimates its gas. Messages with mock proofs, or which fail estimation, get
+// simPoStGasPerPartition for each partition, so that their fee can still be
+// compared with MaxWindowPoStGasFee.
+func (s *WindowPoStScheduler) simulateSubmit(ctx context.Context, post *miner.SubmitWindowedPoStParams, mi miner.MinerInfo, realProof bool) (api.WdPoStMessageSim, error) {
+	var out api.WdPoStMessageSim
+
+	for _, p := range post.Partitions {
+		out.Partitions = append(out.Partitions, p.Index)
+
+		sc, err := p.Skipped.Count()
+		if err != nil {
+			return out, xerrors.Errorf("counting skipped sectors: %w", err)
+		}
+		out.Skipped += sc
+	}
+
+	enc, aerr := actors.SerializeParams(post)
+	if aerr != nil {
+		return out, xerrors.Errorf("could not serialize submit window post parameters: %w", aerr)
+	}
+	out.ParamsSize = len(enc)
+
+	msg := &types.Message{
+		To:     s.actor,
+		From:   mi.Worker,
+		Method: miner.Methods.SubmitWindowedPoSt,
+		Params: enc,
+		Value:  types.NewInt(0),
+	}
+
+	out.GasFeeCap = big.Zero()
+	out.Fee = big.Zero()
+
+	if realProof {
+		// no MaxFee in the spec, so that the estimate can be compared with
+		// the configured limit
+		gm, err := s.api.GasEstimateMessageGas(ctx, msg, nil, types.EmptyTSK)
+		if err == nil {
+			out.GasEstimated = true
+			out.GasLimit = gm.GasLimit
+			out.GasFeeCap = gm.GasFeeCap
+			out.Fee = big.Mul(gm.GasFeeCap, big.NewInt(gm.GasLimit))
+			return out, nil
+		}
+		out.GasError = err.Error()
+	} else {
+		out.GasError = "proof made with mock randomness"
+	}
+
+	msg.GasLimit = simPoStGasPerPartition * int64(len(post.Partitions))
+	feeCap, err := s.api.GasEstimateFeeCap(ctx, msg, 20, types.EmptyTSK)
+	if err != nil {
+		out.GasError = xerrors.Errorf("estimating fee cap: %w", err).Error()
+		return out, nil
+	}
+
+	out.GasLimit = msg.GasLimit
+	out.GasFeeCap = feeCap
+	out.Fee = big.Mul(feeCap, big.NewInt(msg.GasLimit))
+
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/big"
+	tutils "github.com/filecoin-project/specs-actors/v2/support/testing"
+
+	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/miner"
+	"github.com/EpiK-Protocol/go-epik/journal"
+)
+
+func TestSimulateSubmitGas(t *testing.T) {
+	ctx := context.Background()
+
+	s := &WindowPoStScheduler{
+		api:     newMockStorageMinerAPI(),
+		actor:   tutils.NewIDAddr(t, 1000),
+		journal: journal.NilJournal(),
+	}
+	mi := miner.MinerInfo{Worker: tutils.NewIDAddr(t, 1001)}
+
+	post := &miner.SubmitWindowedPoStParams{
+		Partitions: []miner.PoStPartition{
+			{Index: 0, Skipped: bitfield.New()},
+			{Index: 1, Skipped: bitfield.NewFromSet([]uint64{3})},
+		},
+	}
+
+	// real proofs are estimated by executing the message
+	msim, err := s.simulateSubmit(ctx, post, mi, true)
+	require.NoError(t, err)
+	require.True(t, msim.GasEstimated)
+	require.Empty(t, msim.GasError)
+	require.Equal(t, int64(2), msim.GasLimit)
+	require.True(t, msim.Fee.Equals(big.NewInt(2)))
+	require.Equal(t, []uint64{0, 1}, msim.Partitions)
+	require.Equal(t, uint64(1), msim.Skipped)
+
+	// mock proofs get a fixed limit per partition, so that the fee can be
+	// compared with the configured limit
+	msim, err = s.simulateSubmit(ctx, post, mi, false)
+	require.NoError(t, err)
+	require.False(t, msim.GasEstimated)
+	require.NotEmpty(t, msim.GasError)
+	require.Equal(t, int64(2*simPoStGasPerPartition), msim.GasLimit)
+	require.True(t, msim.Fee.Equals(big.Mul(msim.GasFeeCap, big.NewInt(msim.GasLimit))))
+}
@@ -38,6 +38,11 @@ func Collect(ctx context.Context, api api.FullNode, influx client.Client, databa
 			continue
 		}
 
+		// the economics are best effort, the other points are written anyway
+		if err := RecordTipsetEconomicsPoints(ctx, api, pl, tipset); err != nil {
+			log.Warnw("Failed to record economics", "height", height, "error", err)
+		}
+
 		// Instead of having to pass around a bunch of generic stuff we want for each point
 		// we will just add them at the end.
 
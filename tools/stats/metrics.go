@@ -14,9 +14,11 @@ import (
 	"github.com/EpiK-Protocol/go-epik/build"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/power"
 	"github.com/EpiK-Protocol/go-epik/chain/actors/builtin/reward"
+	"github.com/EpiK-Protocol/go-epik/chain/economics"
 	"github.com/EpiK-Protocol/go-epik/chain/store"
 	"github.com/EpiK-Protocol/go-epik/chain/types"
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/ipfs/go-cid"
 	"github.com/multiformats/go-multihash"
@@ -293,6 +295,57 @@ func RecordTipsetStatePoints(ctx context.Context, api api.FullNode, pl *PointLis
 	})
 }
 
+// economicsCandidates is the number of candidates with the most votes whose
+// votes are recorded by candidate
+const economicsCandidates = 20
+
+// RecordTipsetEconomicsPoints records the same economics series as the
+// gauges of the full node economics collector, for the top candidates only.
+func RecordTipsetEconomicsPoints(ctx context.Context, api api.FullNode, pl *PointList, tipset *types.TipSet) error {
+	s, err := economics.Take(ctx, api, tipset)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range economics.ExpertStatuses {
+		p := NewPoint("economics.experts", s.Experts[status])
+		p.AddTag("type", status)
+		pl.AddPoint(p)
+	}
+
+	p := NewPoint("economics.votes", economics.EPK(s.TotalVotes))
+	p.AddTag("type", "total")
+	pl.AddPoint(p)
+	for _, candidate := range s.TopCandidates(economicsCandidates) {
+		p := NewPoint("economics.votes", economics.EPK(s.CandidateVotes[candidate]))
+		p.AddTag("type", "candidate")
+		p.AddTag("candidate", candidate)
+		pl.AddPoint(p)
+	}
+
+	for typ, amt := range map[string]abi.TokenAmount{
+		"pledge":         s.RetrievalPledge,
+		"reward":         s.RetrievalReward,
+		"pending_reward": s.RetrievalPendingReward,
+	} {
+		p := NewPoint("economics.retrieval", economics.EPK(amt))
+		p.AddTag("type", typ)
+		pl.AddPoint(p)
+	}
+
+	for typ, amt := range map[string]abi.TokenAmount{
+		"expertfund":     s.ExpertFundPool,
+		"knowledge":      s.KnowledgeFund,
+		"vesting_locked": s.VestingLocked,
+	} {
+		p := NewPoint("economics.funds", economics.EPK(amt))
+		p.AddTag("type", typ)
+		pl.AddPoint(p)
+	}
+
+	return nil
+}
+
 type msgTag struct {
 	actor    string
 	method   uint64